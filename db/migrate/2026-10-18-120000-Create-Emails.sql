@@ -0,0 +1,15 @@
+/* Add the queue of outgoing mail */
+CREATE TABLE emails (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+recipients text,
+subject text,
+message text,
+attempts integer,
+last_error text,
+next_attempt_at timestamp,
+sent_at timestamp
+);
+ALTER TABLE emails OWNER TO "[[.fragmenta_db_user]]";
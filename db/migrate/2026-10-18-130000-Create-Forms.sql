@@ -0,0 +1,24 @@
+/* Add forms and their submissions */
+CREATE TABLE forms (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+name text,
+summary text,
+fields text,
+notify text,
+message text
+);
+ALTER TABLE forms OWNER TO "[[.fragmenta_db_user]]";
+
+CREATE TABLE submissions (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+form_id integer,
+data text,
+ip text
+);
+ALTER TABLE submissions OWNER TO "[[.fragmenta_db_user]]";
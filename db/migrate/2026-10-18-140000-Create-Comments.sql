@@ -0,0 +1,17 @@
+/* Add comments on posts, and a count of approved comments to posts */
+CREATE TABLE comments (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+post_id integer,
+parent_id integer,
+author_id integer,
+name text,
+email text,
+text text,
+ip text
+);
+ALTER TABLE comments OWNER TO "[[.fragmenta_db_user]]";
+
+ALTER TABLE posts ADD COLUMN comment_count integer DEFAULT 0;
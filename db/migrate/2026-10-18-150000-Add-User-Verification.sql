@@ -0,0 +1,4 @@
+/* Add email verification for readers who register themselves */
+ALTER TABLE users ADD COLUMN verification_token text;
+ALTER TABLE users ADD COLUMN verification_at timestamp;
+ALTER TABLE users ADD COLUMN verified_at timestamp;
@@ -0,0 +1,5 @@
+/* Add visibility to pages and posts, so that they may be restricted to logged in users or selected roles */
+ALTER TABLE pages ADD COLUMN visibility integer DEFAULT 0;
+ALTER TABLE pages ADD COLUMN visible_roles text;
+ALTER TABLE posts ADD COLUMN visibility integer DEFAULT 0;
+ALTER TABLE posts ADD COLUMN visible_roles text;
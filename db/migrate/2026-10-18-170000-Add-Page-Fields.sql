@@ -0,0 +1,2 @@
+/* Add custom fields to pages, stored as json, pages without values use the defaults from their template */
+ALTER TABLE pages ADD COLUMN fields text;
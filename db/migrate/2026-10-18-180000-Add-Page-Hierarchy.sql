@@ -0,0 +1,14 @@
+/* Add parents and a sort order to pages, and navigation menus */
+ALTER TABLE pages ADD COLUMN parent_id integer DEFAULT 0;
+ALTER TABLE pages ADD COLUMN sort integer DEFAULT 0;
+
+CREATE TABLE menus (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+name text,
+location text,
+items text
+);
+ALTER TABLE menus OWNER TO "[[.fragmenta_db_user]]";
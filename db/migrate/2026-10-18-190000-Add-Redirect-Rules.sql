@@ -0,0 +1,7 @@
+/* Add status codes, match types and hit counts to redirects, existing redirects keep responding with 302 Found */
+ALTER TABLE redirects ADD COLUMN status_code integer DEFAULT 301;
+ALTER TABLE redirects ADD COLUMN match_type integer DEFAULT 0;
+ALTER TABLE redirects ADD COLUMN keep_query integer DEFAULT 1;
+ALTER TABLE redirects ADD COLUMN hits integer DEFAULT 0;
+ALTER TABLE redirects ADD COLUMN last_hit_at timestamp;
+UPDATE redirects SET status_code=302;
@@ -0,0 +1,12 @@
+/* Record requests for urls which were not found */
+CREATE TABLE notfounds (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+path text,
+referrer text,
+count integer DEFAULT 0,
+last_seen_at timestamp,
+ignored integer DEFAULT 0
+);
+ALTER TABLE notfounds OWNER TO "[[.fragmenta_db_user]]";
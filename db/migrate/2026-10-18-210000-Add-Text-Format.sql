@@ -0,0 +1,3 @@
+/* Add the format of text to pages and posts, html from the editor or markdown */
+ALTER TABLE pages ADD COLUMN format integer DEFAULT 0;
+ALTER TABLE posts ADD COLUMN format integer DEFAULT 0;
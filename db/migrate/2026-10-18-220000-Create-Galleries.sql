@@ -0,0 +1,23 @@
+/* Add galleries, named collections of images with captions and alt text in their own order */
+CREATE TABLE galleries (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+status integer,
+author_id integer,
+name text,
+summary text
+);
+ALTER TABLE galleries OWNER TO "[[.fragmenta_db_user]]";
+
+CREATE TABLE gallery_images (
+id SERIAL NOT NULL,
+created_at timestamp,
+updated_at timestamp,
+gallery_id integer,
+image_id integer,
+caption text,
+alt text,
+sort integer DEFAULT 0
+);
+ALTER TABLE gallery_images OWNER TO "[[.fragmenta_db_user]]";
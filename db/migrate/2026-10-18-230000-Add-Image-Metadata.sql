@@ -0,0 +1,7 @@
+/* Add alt text, captions and credits to images, with the dimensions and type of uploaded files */
+ALTER TABLE images ADD COLUMN alt text;
+ALTER TABLE images ADD COLUMN caption text;
+ALTER TABLE images ADD COLUMN credit text;
+ALTER TABLE images ADD COLUMN width integer;
+ALTER TABLE images ADD COLUMN height integer;
+ALTER TABLE images ADD COLUMN mime_type text;
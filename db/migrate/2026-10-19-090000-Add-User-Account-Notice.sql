@@ -0,0 +1,2 @@
+/* Record notices sent to users when someone registers with their email */
+ALTER TABLE users ADD COLUMN account_notice_at timestamp;
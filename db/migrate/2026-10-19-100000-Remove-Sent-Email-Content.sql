@@ -0,0 +1,2 @@
+/* Remove the content of emails already sent, which may contain reset and verification tokens */
+UPDATE emails SET message='{}' WHERE status=100;
@@ -2,18 +2,31 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/fragmenta/server"
-	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/server/log"
 
 	"github.com/fragmenta/fragmenta-cms/src/app"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 )
 
 // Main entrypoint for the server which performs bootstrap, setup
 // then runs the server. Most setup is delegated to the src/app pkg.
 func main() {
 
+	// If a command is given, run it instead of the server.
+	if len(os.Args) > 1 {
+		err := app.RunCommand(os.Args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error running command %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Bootstrap if required (no config file found).
 	if app.RequiresBootStrap() {
 		err := app.Bootstrap()
@@ -31,51 +44,71 @@ func main() {
 	}
 
 	// If in production on port 443, set up a server instead
-	if server.Production() && server.Port() == 443 {
+	if settings.Current.Production() && settings.Current.Port == 443 {
 
 		// Redirect http traffic to https
-		server.StartRedirectAll(80, server.Config("root_url"))
+		server.StartRedirectAll(80, settings.Current.RootURL)
 
 		// Serve https directly using autocert
-		err = server.StartTLSAutocert(server.Config("autocert_email"), server.Config("autocert_domains"))
+		err = server.StartTLSAutocert(settings.Current.Autocert.Email, settings.Current.Autocert.Domains)
 		if err != nil {
-			server.Fatalf("Error starting server %s", err)
+			log.Fatal(log.V{"msg": "unable to start server", "error": err})
 		}
 
 	} else {
 		// Start the server using http
 		err = server.Start()
 		if err != nil {
-			server.Fatalf("Error starting server %s", err)
+			log.Fatal(log.V{"msg": "unable to start server", "port": settings.Current.Port, "error": err})
 		}
 	}
 
 }
 
-// SetupServer creates a new server, and delegates setup to the app pkg.
-func SetupServer() (*server.Server, error) {
+// Server serves the app using the port and environment from settings,
+// rather than the config the fragmenta server reads for itself.
+type Server struct {
+	*server.Server
 
-	// Setup server
-	s, err := server.New()
-	if err != nil {
-		return nil, err
+	// Handler serves requests with the routes set up by the app
+	Handler http.Handler
+}
+
+// PortString returns the address to serve http on, from the port in settings.
+func (s *Server) PortString() string {
+	return fmt.Sprintf(":%d", settings.Current.Port)
+}
+
+// Start serves http on the port from settings with the routes set up by the app.
+func (s *Server) Start() error {
+	srv := &http.Server{
+		Addr:              s.PortString(),
+		Handler:           s.Handler,
+		ReadHeaderTimeout: 30 * time.Second,
+		ReadTimeout:       60 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		MaxHeaderBytes:    1 << 20,
 	}
+	return srv.ListenAndServe()
+}
+
+// SetupServer loads our settings, creates a new server, and delegates setup to the app pkg.
+func SetupServer() (*Server, error) {
 
 	// Load the appropriate config
-	c := config.New()
-	err = c.Load("secrets/fragmenta.json")
+	err := app.SetupConfig()
 	if err != nil {
 		return nil, err
 	}
-	config.Current = c
 
-	// Check environment variable to see if we are in production mode
-	if os.Getenv("FRAG_ENV") == "production" {
-		config.Current.Mode = config.ModeProduction
+	// Setup server, which is used for serving https with autocert
+	s, err := server.New()
+	if err != nil {
+		return nil, err
 	}
 
 	// Call the app to perform additional setup
-	app.Setup()
+	router := app.Setup()
 
-	return s, nil
+	return &Server{Server: s, Handler: router}, nil
 }
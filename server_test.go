@@ -4,11 +4,17 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/app"
 )
 
 // TestServer tests running the server and using http client to GET /
-// this test will fail if there is no secrets file.
+// this test is skipped if there is no secrets file.
 func TestServer(t *testing.T) {
+	if app.RequiresBootStrap() {
+		t.Skip("server: no secrets file, skipping server test")
+	}
+
 	// Setup our server from config
 	s, err := SetupServer()
 	if err != nil {
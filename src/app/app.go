@@ -2,25 +2,38 @@ package app
 
 import (
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/fragmenta/assets"
-	"github.com/fragmenta/query"
-	"github.com/fragmenta/server/config"
+	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/images"
 	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail/adapters/maildir"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail/adapters/memory"
 	"github.com/fragmenta/fragmenta-cms/src/lib/mail/adapters/sendgrid"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail/adapters/smtp"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+	"github.com/fragmenta/fragmenta-cms/src/lib/theme"
+	"github.com/fragmenta/fragmenta-cms/src/menus"
+	"github.com/fragmenta/fragmenta-cms/src/pages"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // appAssets is a pkg global used in our default handlers to serve asset files.
 var appAssets *assets.Collection
 
-// Setup sets up our application.
-func Setup() {
+// Setup sets up our application, and returns the router which serves it.
+func Setup() *mux.Mux {
 
 	// Setup log
 	err := SetupLog()
@@ -31,11 +44,11 @@ func Setup() {
 
 	// Log server startup
 	msg := "Starting server"
-	if config.Production() {
+	if settings.Current.Production() {
 		msg = msg + " in production"
 	}
 
-	log.Info(log.Values{"msg": msg, "port": config.Get("port")})
+	log.Info(log.Values{"msg": msg, "port": settings.Current.Port})
 	defer log.Time(time.Now(), log.Values{"msg": "Finished loading server"})
 
 	// Set up our mail adapter
@@ -50,46 +63,38 @@ func Setup() {
 	// Setup our database
 	SetupDatabase()
 
+	// Start sending queued mail
+	SetupMailQueue()
+
 	// Set up auth pkg and authorisation for access
 	SetupAuth()
 
-	// Set up our app routes
-	SetupRoutes()
+	// Set up the shortcodes used in content
+	SetupShortcodes()
 
+	// Set up our app routes
+	return SetupRoutes()
 }
 
 // SetupDatabase sets up the db with query given our server config.
 func SetupDatabase() {
-	defer log.Time(time.Now(), log.V{"msg": "Finished opening database", "db": config.Get("db"), "user": config.Get("db_user")})
-
-	options := map[string]string{
-		"adapter":  config.Get("db_adapter"),
-		"user":     config.Get("db_user"),
-		"password": config.Get("db_pass"),
-		"db":       config.Get("db"),
-	}
-
-	// Optionally Support remote databases
-	if len(config.Get("db_host")) > 0 {
-		options["host"] = config.Get("db_host")
-	}
-	if len(config.Get("db_port")) > 0 {
-		options["port"] = config.Get("db_port")
-	}
-	if len(config.Get("db_params")) > 0 {
-		options["params"] = config.Get("db_params")
-	}
-
-	// Ask query to open the database
-	err := query.OpenDatabase(options)
+	defer log.Time(time.Now(), log.V{"msg": "Finished opening database", "db": settings.Current.DB.Name, "user": settings.Current.DB.User})
 
+	err := OpenDatabase()
 	if err != nil {
-		log.Fatal(log.V{"msg": "unable to read database", "db": config.Get("db"), "error": err})
+		log.Fatal(log.V{"msg": "unable to read database", "db": settings.Current.DB.Name, "error": err})
 		os.Exit(1)
 	}
 
 }
 
+// OpenDatabase opens the db with query given our server config.
+func OpenDatabase() error {
+
+	// Ask query to open the database
+	return resource.OpenDatabase(settings.Current.DB.Options())
+}
+
 // SetupLog sets up logging
 func SetupLog() error {
 
@@ -101,7 +106,7 @@ func SetupLog() error {
 	log.Add(logger)
 
 	// Set up a file logger pointing at the right location for this config.
-	fileLog, err := log.NewFile(config.Get("log"))
+	fileLog, err := log.NewFile(settings.Current.Log)
 	if err != nil {
 		return err
 	}
@@ -110,10 +115,58 @@ func SetupLog() error {
 	return nil
 }
 
-// SetupMail sets us up to send mail via sendgrid (requires key).
+// SetupMail sets up the mail adapter chosen with mail_adapter, which defaults
+// to sendgrid in production. With no adapter mail is logged rather than sent.
 func SetupMail() {
-	mail.Production = config.Production()
-	mail.Service = sendgrid.New(config.Get("mail_from"), config.Get("mail_secret"))
+	c := settings.Current.Mail
+
+	adapter := c.Adapter
+	if adapter == "" && settings.Current.Production() {
+		adapter = "sendgrid"
+	}
+
+	switch adapter {
+	case "sendgrid":
+		mail.Service = sendgrid.New(c.From, c.Secret)
+	case "smtp":
+		mail.Service = smtp.New(smtp.Config{
+			From:     c.From,
+			Host:     c.Host,
+			Port:     c.Port,
+			User:     c.User,
+			Password: c.Password,
+			Security: c.Security,
+		})
+	case "maildir":
+		mail.Service = maildir.New(c.From, c.Path)
+	case "memory":
+		mail.Service = memory.New(c.From)
+	default:
+		mail.Service = nil
+	}
+}
+
+// SetupMailQueue queues mail in the database to be sent by a background worker,
+// unless mail_queue is disabled, in which case mail is sent during requests.
+func SetupMailQueue() {
+	if !settings.Current.Mail.Queue {
+		mail.Queue = nil
+		return
+	}
+
+	mail.Queue = &emails.Queue{}
+	emails.StartWorker(time.Minute)
+}
+
+// SetupShortcodes registers the handlers for shortcodes in the text of pages and posts,
+// other packages may register their own with shortcodes.Register.
+func SetupShortcodes() {
+	shortcodes.Register("form", forms.Shortcode)
+	shortcodes.Register("gallery", galleries.Shortcode)
+	shortcodes.Register("image", images.Shortcode)
+	shortcodes.Register("images", images.ListShortcode)
+	shortcodes.Register("posts", posts.Shortcode)
+	shortcodes.Register("youtube", shortcodes.YouTube)
 }
 
 // SetupAssets compiles or copies our assets from src into the public assets folder.
@@ -122,14 +175,14 @@ func SetupAssets() {
 
 	// Compilation of assets is done on deploy
 	// We just load them here
-	assetsCompiled := config.GetBool("assets_compiled")
+	assetsCompiled := settings.Current.Assets.Compiled
 
 	// Init the pkg global for use in ServeAssets
 	appAssets = assets.New(assetsCompiled)
 
 	// Load assets in production, always compile if in dev
 	load := true
-	if config.Production() {
+	if settings.Current.Production() {
 		load = (appAssets.Load() != nil)
 	}
 	if load {
@@ -142,8 +195,8 @@ func SetupAssets() {
 			os.Exit(1)
 		}
 		// If we have a theme, load assets from the them as well
-		if themePath() != "" {
-			err = appAssets.Compile(themePath(), "public")
+		if theme.Path() != "" {
+			err = appAssets.Compile(theme.Path(), "public")
 			if err != nil {
 				log.Fatal(log.V{"a": "unable to compile assets", "error": err})
 				os.Exit(1)
@@ -158,15 +211,15 @@ func SetupAssets() {
 func SetupView() {
 	defer log.Time(time.Now(), log.V{"msg": "Finished loading templates"})
 
-	view.Production = config.Production()
+	view.Production = settings.Current.Production()
 
 	// Start with default source path
 	paths := []string{"src"}
 
 	// Add a theme path if we have one
-	if themePath() != "" {
-		log.Log(log.V{"msg": "loading templates for theme", "theme": config.Get("theme")})
-		paths = append(paths, themePath())
+	if theme.Path() != "" {
+		log.Log(log.V{"msg": "loading templates for theme", "theme": settings.Current.Theme})
+		paths = append(paths, theme.Path())
 	}
 
 	err := view.LoadTemplatesAtPaths(paths, helperFuncs())
@@ -175,6 +228,13 @@ func SetupView() {
 		os.Exit(1)
 	}
 
+	// Index the templates for pages and posts with their fields, as they are loaded
+	err = theme.Load(pages.TemplatesDir, posts.TemplatesDir)
+	if err != nil {
+		log.Fatal(log.V{"msg": "unable to read page templates", "error": err})
+		os.Exit(1)
+	}
+
 }
 
 // helperFuncs returns a setr of helper functions for view templates
@@ -186,11 +246,14 @@ func helperFuncs() map[string]interface{} {
 	// These behave differently depending on the compile flag above
 	// when compile is set to no, they use precompiled assets
 	// otherwise they serve all files in a group separately
-	helpers["style"] = appAssets.StyleLink
-	helpers["script"] = appAssets.ScriptLink
+	// If assets are not set up (in tests) the default helpers are used
+	if appAssets != nil {
+		helpers["style"] = appAssets.StyleLink
+		helpers["script"] = appAssets.ScriptLink
+	}
 
 	// Get the server config for the root_url
-	rootURL := config.Get("root_url")
+	rootURL := settings.Current.RootURL
 
 	// If running locally use localhost instead
 	host, err := os.Hostname()
@@ -202,13 +265,17 @@ func helperFuncs() map[string]interface{} {
 		return rootURL
 	}
 
-	return helpers
-}
+	// Themes show the published menu for a location with menu "primary"
+	helpers["menu"] = menus.ForLocation
 
-// themePath returns the path to the theme src dir (if a theme is chosen)
-func themePath() string {
-	if config.Get("theme") == "" {
-		return ""
-	}
-	return filepath.Join("themes", config.Get("theme"), "src")
+	// Themes show a published gallery with gallery "name"
+	helpers["gallery"] = galleries.ForName
+
+	// Themes show an image with its alt text, dimensions and srcset with img .image
+	helpers["img"] = images.Tag
+
+	// Themes show the avatar of a user at a size in pixels with avatar .author 48
+	helpers["avatar"] = users.AvatarTag
+
+	return helpers
 }
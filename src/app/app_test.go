@@ -1,76 +1,59 @@
-package app
+package app_test
 
 import (
 	"net/http"
-	"net/http/httptest"
-	"os"
 	"testing"
 
 	"github.com/fragmenta/auth/can"
-	"github.com/fragmenta/server/config"
 
-	"github.com/fragmenta/fragmenta-cms/src/pages"
+	"github.com/fragmenta/fragmenta-cms/src/app"
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // TestRouter tests our routes are functioning correctly.
 func TestRouter(t *testing.T) {
 
-	// chdir into the root to load config/assets to test this code
-	err := os.Chdir("../../")
+	// Setup the test database, views and our router
+	router, err := apptest.Setup()
 	if err != nil {
-		t.Errorf("Chdir error: %s", err)
+		t.Fatalf("app: failed to set up %s", err)
 	}
 
-	c := config.New()
-	c.Load("secrets/fragmenta.json")
-	c.Mode = config.ModeTest
-	config.Current = c
+	// Without users the home page redirects to setup
+	w, err := apptest.Request(router, "GET", "/", nil, nil)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("app: error code on / expected:%d got:%d", http.StatusFound, w.Code)
+	}
 
-	// First, set up the logger
-	err = SetupLog()
+	// Insert a user and a page at / in the test db
+	_, err = apptest.CreateAdmin()
 	if err != nil {
-		t.Fatalf("app: failed to set up log %s", err)
+		t.Fatalf("app: failed to create user %s", err)
 	}
-
-	// Set up our assets
-	SetupAssets()
-
-	// Setup our view templates
-	SetupView()
-
-	// Setup our database
-	SetupDatabase()
-
-	// Set up auth pkg and authorisation for access
-	SetupAuth()
-
-	// Setup our router and handlers
-	router := SetupRoutes()
-
-	// Insert a page at / in the test db
-	pageParams := map[string]string{"url": "/", "name": "test"}
-	_, err = pages.New().Create(pageParams)
+	_, err = apptest.CreatePage(map[string]string{"url": "/", "name": "test"})
 	if err != nil {
-		t.Fatalf("app: failed to create page")
+		t.Fatalf("app: failed to create page %s", err)
 	}
 
 	// Test serving the route / which should always exist
-	r := httptest.NewRequest("GET", "/", nil)
-	w := httptest.NewRecorder()
-	router.ServeHTTP(w, r)
-
-	// Test code on response
-	if w.Code != http.StatusOK {
+	w, err = apptest.Request(router, "GET", "/", nil, nil)
+	if err != nil || w.Code != http.StatusOK {
 		t.Fatalf("app: error code on / expected:%d got:%d", http.StatusOK, w.Code)
 	}
 
+	// Test a missing page returns not found
+	w, err = apptest.Request(router, "GET", "/missing", nil, nil)
+	if err != nil || w.Code != http.StatusNotFound {
+		t.Fatalf("app: error code on /missing expected:%d got:%d", http.StatusNotFound, w.Code)
+	}
+
 }
 
 // TestAuth tests our authentication is functioning after setup.
 func TestAuth(t *testing.T) {
 
-	SetupAuth()
+	app.SetupAuth()
 
 	user := &users.User{}
 
@@ -99,3 +82,23 @@ func TestAuth(t *testing.T) {
 	}
 
 }
+
+// TestRoutes tests the routes added by SetupRoutes are recorded for listing.
+func TestRoutes(t *testing.T) {
+
+	app.SetupRoutes()
+
+	found := false
+	for _, r := range app.Routes() {
+		if r.Method == "POST" && r.Pattern == "/users/login" {
+			found = true
+			if r.Handler != "users/actions.HandleLogin" {
+				t.Fatalf("app: unexpected handler for /users/login got:%s", r.Handler)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("app: route POST /users/login not recorded")
+	}
+
+}
@@ -0,0 +1,215 @@
+// Package apptest provides helpers for testing handlers through the app router,
+// with an isolated test database and fixtures for resources.
+package apptest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app"
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail/adapters/memory"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+// Mail records the mail sent by handlers, so that tests may check it.
+var Mail = memory.New("test@example.com")
+
+// Setup prepares the app for the tests in the calling package - it sets up an
+// isolated test database loaded with the schema, loads the views and auth,
+// records mail sent in Mail (delivered synchronously from the queue),
+// and returns the router built by app.SetupRoutes.
+// The working directory is changed to the project root, as for the server.
+func Setup() (*mux.Mux, error) {
+
+	// Find the project root from the location of this file
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return nil, errors.New("apptest: unable to find project root")
+	}
+	root, err := filepath.Abs(filepath.Join(filepath.Dir(file), "..", "..", ".."))
+	if err != nil {
+		return nil, err
+	}
+
+	// Name the test database after the calling package path
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	name, err := filepath.Rel(root, wd)
+	if err != nil {
+		return nil, err
+	}
+
+	err = resource.SetupTestDatabaseAt(root, name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.Chdir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use default test settings, so that no secrets are required
+	settings.Current, err = settings.New(settings.Test, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load templates for rendering
+	app.SetupView()
+
+	// Record mail rather than sending it, mail is queued as in production
+	// but delivered synchronously so that tests may check it immediately
+	Mail.Reset()
+	mail.Service = Mail
+	mail.Queue = &emails.Queue{Synchronous: true}
+
+	// Set up authorisation for roles, then keys for sessions
+	app.SetupAuth()
+	resource.SetupAuthorisation()
+
+	// Register the shortcodes used in content
+	app.SetupShortcodes()
+
+	return app.SetupRoutes(), nil
+}
+
+// Request performs a request with method on path against router, as user
+// (or as anon if user is nil). The form is sent as the body of POST requests
+// along with a valid authenticity token, or as the query for other methods.
+func Request(router http.Handler, method, path string, form url.Values, user *users.User) (*httptest.ResponseRecorder, error) {
+
+	var id int64
+	if user != nil {
+		id = user.ID
+	}
+
+	cookies, token, err := sessionCookies(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if form == nil {
+		form = url.Values{}
+	}
+
+	var body io.Reader
+	if method == http.MethodPost {
+		form.Set(auth.SessionTokenKey, token)
+		body = strings.NewReader(form.Encode())
+	} else if len(form) > 0 {
+		path = path + "?" + form.Encode()
+	}
+
+	r := httptest.NewRequest(method, path, body)
+	if method == http.MethodPost {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w, nil
+}
+
+// Upload performs a multipart POST request on path against router as user, sending
+// the form with a file called filename containing data in the field given.
+func Upload(router http.Handler, path string, form url.Values, field, filename string, data []byte, user *users.User) (*httptest.ResponseRecorder, error) {
+
+	var id int64
+	if user != nil {
+		id = user.ID
+	}
+
+	cookies, token, err := sessionCookies(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set(auth.SessionTokenKey, token)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for k, values := range form {
+		for _, v := range values {
+			err = mw.WriteField(k, v)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		return nil, err
+	}
+	_, err = fw.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = mw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	r := httptest.NewRequest(http.MethodPost, path, &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	for _, c := range cookies {
+		r.AddCookie(c)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w, nil
+}
+
+// sessionCookies returns the cookies for a session with user id (0 for anon),
+// and an authenticity token valid for that session.
+func sessionCookies(id int64) ([]*http.Cookie, string, error) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	session, err := auth.Session(w, r)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := auth.BytesToBase64(auth.RandomToken(auth.TokenLength))
+	session.Set(auth.SessionTokenKey, secret)
+	if id > 0 {
+		session.Set(auth.SessionUserKey, fmt.Sprintf("%d", id))
+	}
+
+	err = session.Save(w)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Generate a masked token from the secret, as the session middleware does
+	token := auth.BytesToBase64(auth.AuthenticityTokenWithSecret(auth.Base64ToBytes(secret)))
+
+	return w.Result().Cookies(), token, nil
+}
@@ -0,0 +1,173 @@
+package apptest
+
+import (
+	"fmt"
+
+	"github.com/fragmenta/auth"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/pages"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/tags"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+// This file contains factories which create resources in the test database.
+// Each accepts params which override the defaults, and may be nil.
+
+// Password is the password set for users created with CreateUser.
+const Password = "Hunter2"
+
+// sequence is used to generate unique default values.
+var sequence int
+
+// next returns the next value in our sequence.
+func next() int {
+	sequence++
+	return sequence
+}
+
+// withDefaults returns params with any missing keys set from defaults.
+func withDefaults(params, defaults map[string]string) map[string]string {
+	result := map[string]string{}
+	for k, v := range defaults {
+		result[k] = v
+	}
+	for k, v := range params {
+		result[k] = v
+	}
+	return result
+}
+
+// CreateUser creates a published reader with the password Password.
+func CreateUser(params map[string]string) (*users.User, error) {
+	hash, err := auth.HashPassword(Password)
+	if err != nil {
+		return nil, err
+	}
+
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":          fmt.Sprintf("user %d", n),
+		"email":         fmt.Sprintf("user%d@example.com", n),
+		"role":          fmt.Sprintf("%d", users.Reader),
+		"status":        "100",
+		"password_hash": hash,
+	})
+
+	id, err := users.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return users.Find(id)
+}
+
+// CreateAdmin creates a published admin user with the password Password.
+func CreateAdmin() (*users.User, error) {
+	return CreateUser(map[string]string{
+		"role":  fmt.Sprintf("%d", users.Admin),
+		"title": "Administrator",
+	})
+}
+
+// CreatePage creates a published page.
+func CreatePage(params map[string]string) (*pages.Page, error) {
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":   fmt.Sprintf("page %d", n),
+		"url":    fmt.Sprintf("/page-%d", n),
+		"status": "100",
+		"text":   fmt.Sprintf("<p>Page %d</p>", n),
+	})
+
+	id, err := pages.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return pages.Find(id)
+}
+
+// CreatePost creates a published post.
+func CreatePost(params map[string]string) (*posts.Post, error) {
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":    fmt.Sprintf("post %d", n),
+		"summary": fmt.Sprintf("Summary %d", n),
+		"status":  "100",
+		"text":    fmt.Sprintf("<p>Post %d</p>", n),
+	})
+
+	id, err := posts.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return posts.Find(id)
+}
+
+// CreateTag creates a published tag.
+func CreateTag(params map[string]string) (*tags.Tag, error) {
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":   fmt.Sprintf("tag %d", n),
+		"url":    fmt.Sprintf("/tags/tag-%d", n),
+		"status": "100",
+	})
+
+	id, err := tags.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return tags.Find(id)
+}
+
+// CreateImage creates a published image record (no file is stored).
+func CreateImage(params map[string]string) (*images.Image, error) {
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":   fmt.Sprintf("image %d", n),
+		"path":   fmt.Sprintf("/files/images/image-%d.jpg", n),
+		"status": "100",
+	})
+
+	id, err := images.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return images.Find(id)
+}
+
+// CreateGallery creates a published gallery with no images.
+func CreateGallery(params map[string]string) (*galleries.Gallery, error) {
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":   fmt.Sprintf("gallery %d", n),
+		"status": "100",
+	})
+
+	id, err := galleries.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return galleries.Find(id)
+}
+
+// CreateForm creates a published form with name, email and message fields,
+// which notifies admin@example.com of submissions.
+func CreateForm(params map[string]string) (*forms.Form, error) {
+	n := next()
+	params = withDefaults(params, map[string]string{
+		"name":    fmt.Sprintf("form %d", n),
+		"status":  "100",
+		"fields":  "Name | text | required\nEmail | email | required\nMessage | textarea",
+		"notify":  "admin@example.com",
+		"message": fmt.Sprintf("Thanks from form %d", n),
+	})
+
+	id, err := forms.New().Create(params)
+	if err != nil {
+		return nil, err
+	}
+	return forms.Find(id)
+}
@@ -3,8 +3,10 @@ package app
 import (
 	"github.com/fragmenta/auth"
 	"github.com/fragmenta/auth/can"
-	"github.com/fragmenta/server/config"
 
+	"github.com/fragmenta/fragmenta-cms/src/comments"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -12,12 +14,19 @@ import (
 func SetupAuth() {
 
 	// Set up the auth package with our secrets from config
-	auth.HMACKey = auth.HexToBytes(config.Get("hmac_key"))
-	auth.SecretKey = auth.HexToBytes(config.Get("secret_key"))
-	auth.SessionName = config.Get("session_name")
+	auth.HMACKey = auth.HexToBytes(settings.Current.Auth.HMACKey)
+	auth.SecretKey = auth.HexToBytes(settings.Current.Auth.SecretKey)
+	auth.SessionName = settings.Current.Auth.SessionName
+
+	// Accept sessions signed with the previous keys during the grace period after rotation
+	if settings.Current.Auth.PreviousHMACKey != "" {
+		session.PreviousHMACKey = auth.HexToBytes(settings.Current.Auth.PreviousHMACKey)
+		session.PreviousSecretKey = auth.HexToBytes(settings.Current.Auth.PreviousSecretKey)
+		session.PreviousExpires = settings.Current.Auth.PreviousExpires()
+	}
 
 	// Enable https cookies on production server - everyone should be on https
-	if config.Production() {
+	if settings.Current.Production() {
 		auth.SecureCookies = true
 	}
 
@@ -26,11 +35,18 @@ func SetupAuth() {
 	// Admins are allowed to manage all resources
 	can.Authorise(users.Admin, can.ManageResource, can.Anything)
 
-	// Editors may edit their user
+	// Editors may see and edit their user
+	can.AuthoriseOwner(users.Editor, can.ShowResource, users.TableName)
 	can.AuthoriseOwner(users.Editor, can.UpdateResource, users.TableName)
-	// ...
 
-	// Readers may edit their user
+	// Editors may comment on posts
+	can.Authorise(users.Editor, can.CreateResource, comments.TableName)
+
+	// Readers may see and edit their user
+	can.AuthoriseOwner(users.Reader, can.ShowResource, users.TableName)
 	can.AuthoriseOwner(users.Reader, can.UpdateResource, users.TableName)
 
+	// Readers may comment on posts, visitors may comment only if comments_anon is set
+	can.Authorise(users.Reader, can.CreateResource, comments.TableName)
+
 }
@@ -3,7 +3,6 @@ package app
 import (
 	"crypto/rand"
 	"encoding/hex"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,6 +16,9 @@ import (
 	"time"
 
 	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 )
 
 // TODO: This should probably go into a bootstrap package within fragmenta?
@@ -24,9 +26,11 @@ import (
 const (
 	fragmentaVersion = "1.2"
 
-	permissions                 = 0744
 	createDatabaseMigrationName = "Create-Database"
 	createTablesMigrationName   = "Create-Tables"
+
+	// adapterEnv is the environment variable used to choose a db adapter on bootstrap
+	adapterEnv = "FRAG_DB_ADAPTER"
 )
 
 var (
@@ -88,10 +92,29 @@ func projectPathRelative(projectPath string) string {
 	return strings.Replace(projectPath, goSrc, "", 1)
 }
 
+// bootstrapAdapter returns the db adapter to bootstrap with, postgres unless
+// sqlite3 is requested with the FRAG_DB_ADAPTER environment variable.
+func bootstrapAdapter() string {
+	if os.Getenv(adapterEnv) == resource.AdapterSQLite {
+		return resource.AdapterSQLite
+	}
+	return resource.AdapterPostgres
+}
+
+// databaseName returns the name of the database for this prefix and environment,
+// for sqlite this is the path to the database file.
+func databaseName(adapter, prefix, env string) string {
+	if adapter == resource.AdapterSQLite {
+		return path.Join("db", prefix+"_"+env+".db")
+	}
+	return prefix + "_" + env
+}
+
 func generateConfig(projectPath string) error {
 	configPath := configPath()
 	prefix := path.Base(projectPath)
 	prefix = strings.Replace(prefix, "-", "_", -1)
+	adapter := bootstrapAdapter()
 	log.Printf("Generating new config at %s", configPath)
 
 	ConfigProduction = map[string]string{}
@@ -99,10 +122,8 @@ func generateConfig(projectPath string) error {
 	ConfigTest = map[string]string{
 		"port":            "3000",
 		"log":             "log/test.log",
-		"db_adapter":      "postgres",
-		"db":              prefix + "_test",
-		"db_user":         prefix + "_server",
-		"db_pass":         randomKey(8),
+		"db_adapter":      adapter,
+		"db":              databaseName(adapter, prefix, "test"),
 		"assets_compiled": "no",
 		"path":            projectPathRelative(projectPath),
 		"hmac_key":        randomKey(32),
@@ -110,16 +131,22 @@ func generateConfig(projectPath string) error {
 		"session_name":    prefix,
 	}
 
+	// Sqlite has no database users
+	if adapter != resource.AdapterSQLite {
+		ConfigTest["db_user"] = prefix + "_server"
+		ConfigTest["db_pass"] = randomKey(8)
+	}
+
 	for k, v := range ConfigTest {
 		ConfigDevelopment[k] = v
 		ConfigProduction[k] = v
 	}
-	ConfigDevelopment["db"] = prefix + "_development"
+	ConfigDevelopment["db"] = databaseName(adapter, prefix, "development")
 	ConfigDevelopment["log"] = "log/development.log"
 	ConfigDevelopment["hmac_key"] = randomKey(32)
 	ConfigDevelopment["secret_key"] = randomKey(32)
 
-	ConfigProduction["db"] = prefix + "_production"
+	ConfigProduction["db"] = databaseName(adapter, prefix, "production")
 	ConfigProduction["log"] = "log/production.log"
 	ConfigProduction["port"] = "80" //FIXME set up for https with port 443
 	ConfigProduction["assets_compiled"] = "yes"
@@ -127,19 +154,27 @@ func generateConfig(projectPath string) error {
 	ConfigProduction["secret_key"] = randomKey(32)
 
 	configs := map[string]map[string]string{
-		"production":  ConfigProduction,
-		"development": ConfigDevelopment,
-		"test":        ConfigTest,
+		"production":  copyConfig(ConfigProduction),
+		"development": copyConfig(ConfigDevelopment),
+		"test":        copyConfig(ConfigTest),
 	}
 
-	configJSON, err := json.MarshalIndent(configs, "", "\t")
+	// If a master key is set, keep secrets in the encrypted section
+	key, err := settings.MasterKey()
 	if err != nil {
-		log.Printf("Error parsing config %s %v", configPath, err)
 		return err
 	}
+	if key != nil {
+		for _, c := range configs {
+			err = settings.EncryptSecrets(c, key)
+			if err != nil {
+				return err
+			}
+		}
+	}
 
-	// Write the config json file
-	err = ioutil.WriteFile(configPath, configJSON, permissions)
+	// Write the config json file, readable only by the owner
+	err = settings.WriteFile(configPath, configs)
 	if err != nil {
 		log.Printf("Error writing config %s %v", configPath, err)
 		return err
@@ -148,21 +183,35 @@ func generateConfig(projectPath string) error {
 	return nil
 }
 
+// copyConfig returns a copy of the config values given.
+func copyConfig(config map[string]string) map[string]string {
+	c := make(map[string]string)
+	for k, v := range config {
+		c[k] = v
+	}
+	return c
+}
+
 // generateCreateSQL generates an SQL migration file to create the database user and database referred to in config
 func generateCreateSQL(projectPath string) error {
-
-	// Set up a Create-Database migration, which comes first
-	name := path.Base(projectPath)
-	d := ConfigDevelopment["db"]
+	adapter := ConfigDevelopment["db_adapter"]
 	u := ConfigDevelopment["db_user"]
-	p := ConfigDevelopment["db_pass"]
-	sql := fmt.Sprintf("/* Setup database for %s */\nCREATE USER \"%s\" WITH PASSWORD '%s';\nCREATE DATABASE \"%s\" WITH OWNER \"%s\";", name, u, p, d, u)
 
-	// Generate a migration to create db with today's date
-	file := migrationPath(projectPath, createDatabaseMigrationName)
-	err := ioutil.WriteFile(file, []byte(sql), 0744)
-	if err != nil {
-		return err
+	// Set up a Create-Database migration, which comes first
+	// sqlite databases are created when first opened so require no migration
+	if adapter != resource.AdapterSQLite {
+		name := path.Base(projectPath)
+		d := ConfigDevelopment["db"]
+		p := ConfigDevelopment["db_pass"]
+		sql := fmt.Sprintf("/* Setup database for %s */\nCREATE USER \"%s\" WITH PASSWORD '%s';\nCREATE DATABASE \"%s\" WITH OWNER \"%s\";", name, u, p, d, u)
+
+		// Generate a migration to create db with today's date
+		file := migrationPath(projectPath, createDatabaseMigrationName)
+		// This migration includes the db password, so it is readable only by the owner
+		err := ioutil.WriteFile(file, []byte(sql), settings.FilePermissions)
+		if err != nil {
+			return err
+		}
 	}
 
 	// If we have a Create-Tables file, copy it out to a new migration with today's date
@@ -173,10 +222,10 @@ func generateCreateSQL(projectPath string) error {
 			return err
 		}
 
-		// Now vivify the template, for now we just replace one key
-		sqlString := strings.Replace(string(sql), "[[.fragmenta_db_user]]", u, -1)
+		// Now vivify the template for our user and adapter
+		sqlString := resource.AdaptSQL(string(sql), adapter, u)
 
-		file = migrationPath(projectPath, createTablesMigrationName)
+		file := migrationPath(projectPath, createTablesMigrationName)
 		err = ioutil.WriteFile(file, []byte(sqlString), 0744)
 		if err != nil {
 			return err
@@ -193,11 +242,20 @@ func generateCreateSQL(projectPath string) error {
 
 // runMigrations at projectPath
 func runMigrations(projectPath string) error {
+	config := ConfigDevelopment
+
+	// Sqlite migrations are run directly, for both development and test dbs
+	if config["db_adapter"] == resource.AdapterSQLite {
+		err := runSQLiteMigrations(ConfigTest)
+		if err != nil {
+			return err
+		}
+		return runSQLiteMigrations(config)
+	}
+
 	var migrations []string
 	var migrationCount int
 
-	config := ConfigDevelopment
-
 	// Get a list of migration files
 	files, err := filepath.Glob("./db/migrate/*.sql")
 	if err != nil {
@@ -210,6 +268,12 @@ func runMigrations(projectPath string) error {
 	for _, file := range files {
 		filename := path.Base(file)
 
+		// Create-Tables includes all later schema changes, so other migrations are recorded as complete
+		if !bootstrapMigration(filename) {
+			migrations = append(migrations, filename)
+			continue
+		}
+
 		log.Printf("Running migration %s", filename)
 
 		args := []string{"-d", config["db"], "-f", file}
@@ -245,6 +309,66 @@ func runMigrations(projectPath string) error {
 	return nil
 }
 
+// runSQLiteMigrations runs the migrations against the sqlite db in config using query,
+// so that the sqlite3 command line tool is not required.
+func runSQLiteMigrations(config map[string]string) error {
+	var migrations []string
+
+	// Get a sorted list of migration files
+	files, err := filepath.Glob("./db/migrate/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	err = openDatabase(config)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		filename := path.Base(file)
+
+		// Create-Tables includes all later schema changes, so other migrations are recorded as complete
+		if !bootstrapMigration(filename) {
+			migrations = append(migrations, filename)
+			continue
+		}
+
+		log.Printf("Running migration %s", filename)
+
+		sql, err := ioutil.ReadFile(file)
+		if err == nil {
+			_, err = query.ExecSQL(string(sql))
+		}
+		if err != nil {
+			// If at any point we fail, log it and break
+			log.Printf("ERROR loading sql migration:%s\n", err)
+			log.Printf("All further migrations cancelled\n\n")
+			query.CloseDatabase()
+			return err
+		}
+
+		migrations = append(migrations, filename)
+		log.Printf("Completed migration %s\n%s", filename, "-")
+	}
+
+	// Close the db, writeMetadata opens it again
+	query.CloseDatabase()
+
+	if len(migrations) > 0 {
+		writeMetadata(config, migrations)
+		log.Printf("Migrations complete up to migration %v on db %s\n\n", migrations, config["db"])
+	}
+
+	return nil
+}
+
+// bootstrapMigration returns true if this migration is generated by bootstrap.
+func bootstrapMigration(filename string) bool {
+	return strings.Contains(filename, createDatabaseMigrationName) || strings.Contains(filename, createTablesMigrationName)
+}
+
 // Oh, we need to write the full list of migrations, not just one migration version
 
 // Update the database with a line recording what we have done
@@ -256,14 +380,24 @@ func writeMetadata(config map[string]string, migrations []string) {
 	}
 	defer query.CloseDatabase()
 
+	err = recordMigrations(migrations)
+	if err != nil {
+		log.Printf("Database ERROR %s", err)
+	}
+
+}
+
+// recordMigrations records the migrations given as complete in the open database.
+func recordMigrations(migrations []string) error {
+	now := query.TimeString(time.Now().UTC())
 	for _, m := range migrations {
-		sql := "Insert into fragmenta_metadata(updated_at,fragmenta_version,migration_version,status) VALUES(NOW(),$1,$2,100);"
-		result, err := query.ExecSQL(sql, fragmentaVersion, m)
+		sql := "Insert into fragmenta_metadata(updated_at,fragmenta_version,migration_version,status) VALUES($1,$2,$3,100);"
+		_, err := query.ExecSQL(sql, now, fragmentaVersion, m)
 		if err != nil {
-			log.Printf("Database ERROR %s %s", err, result)
+			return err
 		}
 	}
-
+	return nil
 }
 
 // Open our database
@@ -277,7 +411,7 @@ func openDatabase(config map[string]string) error {
 		// "debug"     : "true",
 	}
 
-	err := query.OpenDatabase(options)
+	err := resource.OpenDatabase(options)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,634 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+// This file contains admin commands run with the server binary for ops tasks,
+// for example: server user create -role admin me@example.com
+
+// commandUsage describes the commands available.
+const commandUsage = `Usage: server [command] [arguments]
+
+Run without a command to start the server. Commands use the config for
+the current environment (set FRAGMENTA_ENV=production for production),
+config keys may be overridden with FRAGMENTA_ environment variables.
+
+Commands:
+  user create [-name name] [-role role] [-password password] email
+        create a user, role is one of admin, editor or reader (default admin)
+  user passwd [-password password] email
+        set the password for a user
+  user role -role role email
+        set the role for a user
+  migrate
+        run any migrations in db/migrate which have not yet been run
+  export [-o file]
+        export content as json to file (default stdout), including user password hashes
+  import file
+        import content as json from file (- for stdin), replacing records with the same id
+  routes
+        list the routes handled by the server
+  check-config
+        check the config for the current environment and try to open the db
+  config
+        print the config for the current environment with secrets redacted
+  secrets key
+        print a new random master key for encrypting secrets
+  secrets encrypt
+        move secrets in the config file into the encrypted section, using FRAGMENTA_MASTER_KEY
+  secrets rotate
+        replace hmac_key and secret_key for the current environment, sessions signed with
+        the previous keys are accepted until keys_grace_period hours have passed (default 168)
+
+If a password is not given with -password, it is read from the first line of stdin.
+`
+
+// exportTables lists the tables included in export and import.
+var exportTables = []string{"users", "pages", "posts", "tags", "images", "redirects", "forms", "submissions", "comments", "menus", "galleries", "gallery_images"}
+
+// RunCommand runs the command given by args (excluding the program name).
+func RunCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("no command given")
+	}
+
+	switch args[0] {
+	case "user":
+		return runUserCommand(args[1:])
+	case "migrate":
+		return runMigrate()
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "routes":
+		return runRoutes()
+	case "check-config":
+		return runCheckConfig()
+	case "config":
+		return runConfig()
+	case "secrets":
+		return runSecretsCommand(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Print(commandUsage)
+		return nil
+	}
+
+	return fmt.Errorf("unknown command %s, see server help for usage", args[0])
+}
+
+// setupCommand loads the config and opens the database for commands which require it,
+// callers should close the database with query.CloseDatabase when done.
+func setupCommand() error {
+	if RequiresBootStrap() {
+		return fmt.Errorf("no config found at %s, run the server to bootstrap first", configPath())
+	}
+
+	err := SetupConfig()
+	if err != nil {
+		return err
+	}
+
+	return OpenDatabase()
+}
+
+// runUserCommand runs the user subcommands create, passwd and role.
+func runUserCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("user requires a subcommand: create, passwd or role")
+	}
+
+	flags := flag.NewFlagSet("user "+args[0], flag.ContinueOnError)
+	name := flags.String("name", "", "user name (default from email)")
+	role := flags.String("role", "", "user role: admin, editor or reader")
+	password := flags.String("password", "", "user password (default read from stdin)")
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("user %s requires an email", args[0])
+	}
+	email := flags.Arg(0)
+
+	err = setupCommand()
+	if err != nil {
+		return err
+	}
+	defer query.CloseDatabase()
+
+	switch args[0] {
+	case "create":
+		return createUser(email, *name, *role, *password)
+	case "passwd":
+		return setUserPassword(email, *password)
+	case "role":
+		return setUserRole(email, *role)
+	}
+
+	return fmt.Errorf("unknown user subcommand %s", args[0])
+}
+
+// createUser creates a published user with the details given, as an admin if no role is given.
+func createUser(email, name, role, password string) error {
+	_, err := users.FindFirst("email=?", email)
+	if err == nil {
+		return fmt.Errorf("user already exists with email %s", email)
+	}
+
+	if role == "" {
+		role = "admin"
+	}
+	roleID, err := parseRole(role)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"email":         email,
+		"name":          name,
+		"password_hash": hash,
+		"role":          fmt.Sprintf("%d", roleID),
+		"status":        fmt.Sprintf("%d", status.Published),
+	}
+
+	user := users.New()
+	id, err := user.Create(params)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created user %d %s with role %s\n", id, email, role)
+	return nil
+}
+
+// setUserPassword sets the password for the user with email.
+func setUserPassword(email, password string) error {
+	user, err := users.FindFirst("email=?", email)
+	if err != nil {
+		return fmt.Errorf("user not found with email %s", email)
+	}
+
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = user.Update(map[string]string{"password_hash": hash})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated password for user %d %s\n", user.ID, email)
+	return nil
+}
+
+// setUserRole sets the role for the user with email.
+func setUserRole(email, role string) error {
+	roleID, err := parseRole(role)
+	if err != nil {
+		return err
+	}
+
+	user, err := users.FindFirst("email=?", email)
+	if err != nil {
+		return fmt.Errorf("user not found with email %s", email)
+	}
+
+	err = user.Update(map[string]string{"role": fmt.Sprintf("%d", roleID)})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated role for user %d %s to %s\n", user.ID, email, role)
+	return nil
+}
+
+// parseRole returns the user role for the role name or id given.
+func parseRole(role string) (int64, error) {
+	if strings.EqualFold(role, "admin") {
+		return users.Admin, nil
+	}
+	for _, o := range (&users.User{}).RoleOptions() {
+		if strings.EqualFold(role, o.Name) || role == strconv.FormatInt(o.Id, 10) {
+			return o.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown role %s, role should be admin, editor or reader", role)
+}
+
+// hashPassword hashes the password given, reading it from stdin if it is empty.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", errors.New("no password given")
+	}
+	return auth.HashPassword(password)
+}
+
+// runMigrate runs any pending migrations, recording each one as complete when it has run.
+func runMigrate() error {
+	err := setupCommand()
+	if err != nil {
+		return err
+	}
+	defer query.CloseDatabase()
+
+	pending, err := pendingMigrations()
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		fmt.Printf("No pending migrations for db %s\n", settings.Current.DB.Name)
+		return nil
+	}
+
+	for _, file := range pending {
+		filename := path.Base(file)
+
+		sql, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		// Migrations are written for postgres, so adapt them for the db in use
+		_, err = query.ExecSQL(resource.AdaptSQL(string(sql), settings.Current.DB.Adapter, settings.Current.DB.User))
+		if err != nil {
+			return fmt.Errorf("error running migration %s, further migrations cancelled: %s", filename, err)
+		}
+
+		err = recordMigrations([]string{filename})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Completed migration %s\n", filename)
+	}
+
+	return nil
+}
+
+// pendingMigrations returns the sorted paths of migrations not yet recorded in fragmenta_metadata,
+// the Create-Database migration is never pending as it must be run by a superuser.
+func pendingMigrations() ([]string, error) {
+	var pending []string
+
+	files, err := filepath.Glob("./db/migrate/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	results, err := query.New("fragmenta_metadata", "id").Results()
+	if err != nil {
+		return nil, err
+	}
+
+	completed := make(map[string]bool)
+	for _, r := range results {
+		completed[resource.ValidateString(r["migration_version"])] = true
+	}
+
+	for _, file := range files {
+		filename := path.Base(file)
+		if !completed[filename] && !strings.Contains(filename, createDatabaseMigrationName) {
+			pending = append(pending, file)
+		}
+	}
+
+	return pending, nil
+}
+
+// runExport writes the records in exportTables as json, with all values as strings.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("o", "", "file to write (default stdout)")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	err = setupCommand()
+	if err != nil {
+		return err
+	}
+	defer query.CloseDatabase()
+
+	data := make(map[string][]map[string]string)
+	for _, table := range exportTables {
+		results, err := query.New(table, "id").Order("id asc").Results()
+		if err != nil {
+			return err
+		}
+
+		rows := []map[string]string{}
+		for _, r := range results {
+			row := make(map[string]string)
+			for k, v := range r {
+				switch value := v.(type) {
+				case nil:
+					// Null values are left out
+				case time.Time:
+					row[k] = query.TimeString(value.UTC())
+				case []byte:
+					row[k] = string(value)
+				default:
+					row[k] = fmt.Sprintf("%v", value)
+				}
+			}
+			rows = append(rows, row)
+		}
+		data[table] = rows
+	}
+
+	dataJSON, err := json.MarshalIndent(data, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = fmt.Println(string(dataJSON))
+		return err
+	}
+
+	return ioutil.WriteFile(*out, dataJSON, 0600)
+}
+
+// runImport reads records as written by export, and inserts them or updates existing records
+// with the same id, then resets the id sequences so that new records follow those imported.
+func runImport(args []string) error {
+	if len(args) != 1 {
+		return errors.New("import requires a file")
+	}
+
+	var dataJSON []byte
+	var err error
+	if args[0] == "-" {
+		dataJSON, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		dataJSON, err = ioutil.ReadFile(args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	var data map[string][]map[string]string
+	err = json.Unmarshal(dataJSON, &data)
+	if err != nil {
+		return err
+	}
+
+	err = setupCommand()
+	if err != nil {
+		return err
+	}
+	defer query.CloseDatabase()
+
+	for _, table := range exportTables {
+		rows, ok := data[table]
+		if !ok {
+			continue
+		}
+
+		var maxID int64
+		for _, row := range rows {
+			id, err := strconv.ParseInt(row["id"], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid id for %s record: %s", table, row["id"])
+			}
+			if id > maxID {
+				maxID = id
+			}
+
+			count, err := query.New(table, "id").Where("id=?", id).Count()
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				err = query.New(table, "id").Where("id=?", id).Update(row)
+			} else {
+				_, err = query.New(table, "id").Insert(row)
+			}
+			if err != nil {
+				return fmt.Errorf("error importing %s record %d: %s", table, id, err)
+			}
+		}
+
+		// Make sure new records are created after those imported
+		if maxID > 0 {
+			err = resource.ResetSequence(table, maxID+1)
+			if err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("Imported %d %s\n", len(rows), table)
+	}
+
+	return nil
+}
+
+// runRoutes lists the routes added by SetupRoutes in the order they are evaluated.
+func runRoutes() error {
+	SetupRoutes()
+	for _, r := range Routes() {
+		fmt.Printf("%-5s %-40s %s\n", r.Method, r.Pattern, r.Handler)
+	}
+	return nil
+}
+
+// runCheckConfig checks the config for the current environment,
+// reporting any problems found and returning an error if there were any.
+func runCheckConfig() error {
+	s, err := loadCommandSettings()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Checking %s config at %s\n", s.Env, configPath())
+
+	problems := s.Problems()
+
+	// Check we can open the database and read the migrations table
+	settings.Current = s
+	err = OpenDatabase()
+	if err == nil {
+		_, err = query.New("fragmenta_metadata", "id").Count()
+		query.CloseDatabase()
+	}
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("unable to read database %s: %s", s.DB.Name, err))
+	}
+
+	for _, p := range problems {
+		fmt.Printf("  %s\n", p)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems found in config", len(problems))
+	}
+
+	fmt.Printf("Config OK\n")
+	return nil
+}
+
+// runConfig prints the config for the current environment with secrets redacted.
+func runConfig() error {
+	s, err := loadCommandSettings()
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(s.Dump())
+	return nil
+}
+
+// loadCommandSettings loads the settings for the current environment without validating them.
+func loadCommandSettings() (*settings.Settings, error) {
+	if RequiresBootStrap() {
+		return nil, fmt.Errorf("no config found at %s, run the server to bootstrap first", configPath())
+	}
+	return settings.Load(configPath(), settings.Env())
+}
+
+// runSecretsCommand runs the secrets subcommands key, encrypt and rotate.
+func runSecretsCommand(args []string) error {
+	if len(args) != 1 {
+		return errors.New("secrets requires a subcommand: key, encrypt or rotate")
+	}
+
+	switch args[0] {
+	case "key":
+		fmt.Println(randomKey(32))
+		return nil
+	case "encrypt":
+		return encryptSecrets()
+	case "rotate":
+		return rotateKeys()
+	}
+
+	return fmt.Errorf("unknown secrets subcommand %s", args[0])
+}
+
+// encryptSecrets moves the secrets for every environment in the config file into the encrypted section.
+func encryptSecrets() error {
+	key, err := settings.MasterKey()
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return fmt.Errorf("no master key, set %s (generate one with server secrets key)", settings.MasterKeyEnv)
+	}
+
+	configs, err := settings.ReadFile(configPath())
+	if err != nil {
+		return err
+	}
+
+	for env, values := range configs {
+		err = settings.EncryptSecrets(values, key)
+		if err != nil {
+			return fmt.Errorf("error encrypting %s secrets: %s", env, err)
+		}
+	}
+
+	err = settings.WriteFile(configPath(), configs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Encrypted secrets in %s\n", configPath())
+	return nil
+}
+
+// rotateKeys replaces the hmac and secret keys for the current environment in the config file,
+// keeping the current keys as the previous keys so that existing sessions remain valid.
+func rotateKeys() error {
+	env := settings.Env()
+
+	configs, err := settings.ReadFile(configPath())
+	if err != nil {
+		return err
+	}
+
+	key, err := settings.MasterKey()
+	if err != nil {
+		return err
+	}
+
+	// Decrypt any encrypted secrets, and encrypt them again once rotated
+	encrypted := configs[env][settings.SecretsKey] != ""
+	values, err := settings.DecryptSecrets(configs[env], key)
+	if err != nil {
+		return err
+	}
+
+	if values["hmac_key"] == "" || values["secret_key"] == "" {
+		return fmt.Errorf("no keys found for %s in %s to rotate", env, configPath())
+	}
+
+	values["hmac_key_previous"] = values["hmac_key"]
+	values["secret_key_previous"] = values["secret_key"]
+	values["hmac_key"] = randomKey(32)
+	values["secret_key"] = randomKey(32)
+	values["keys_rotated_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	if encrypted {
+		err = settings.EncryptSecrets(values, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	configs[env] = values
+	err = settings.WriteFile(configPath(), configs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Rotated keys for %s in %s, restart the server to use them\n", env, configPath())
+	for _, k := range []string{"hmac_key", "secret_key"} {
+		if os.Getenv(settings.EnvKey(k)) != "" || os.Getenv(settings.EnvKey(k)+"_FILE") != "" {
+			fmt.Printf("Warning: %s is overridden by the environment, so must be rotated there\n", k)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// SetupConfig loads our settings for the current environment from secrets/fragmenta.json,
+// with any FRAGMENTA_* environment variable overrides, and validates them.
+func SetupConfig() error {
+
+	s, err := settings.Load(configPath(), settings.Env())
+	if err != nil {
+		return err
+	}
+
+	err = s.Validate()
+	if err != nil {
+		return err
+	}
+
+	settings.Current = s
+	return nil
+}
@@ -1,15 +1,20 @@
 package app
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"path"
 	"strings"
 
 	"github.com/fragmenta/server"
-	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/notfounds"
+	"github.com/fragmenta/fragmenta-cms/src/redirects"
 )
 
 // Serve static files (assets, images etc)
@@ -47,7 +52,7 @@ func serveFile(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// If the file exists and we can access it, serve it with cache control
-	w.Header().Set("Cache-Control", "max-age:3456000, public")
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public", settings.Current.Cache.MaxAge))
 	http.ServeFile(w, r, localPath)
 	return nil
 }
@@ -63,6 +68,9 @@ func serveAsset(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// Try to find an asset in our list
+	if appAssets == nil {
+		return server.NotFoundError(nil)
+	}
 	f := appAssets.File(path.Base(p))
 	if f == nil {
 		return server.NotFoundError(nil)
@@ -70,7 +78,7 @@ func serveAsset(w http.ResponseWriter, r *http.Request) error {
 
 	// Serve the local file, with cache control
 	localPath := "./" + f.LocalPath()
-	w.Header().Set("Cache-Control", "max-age:3456000, public")
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d, public", settings.Current.Cache.MaxAge))
 	http.ServeFile(w, r, localPath)
 	return nil
 }
@@ -82,11 +90,19 @@ func errHandler(w http.ResponseWriter, r *http.Request, e error) {
 	err := server.ToStatusError(e)
 	log.Error(log.V{"error": err})
 
+	// Record requests for urls not found, so that they can be redirected
+	if err.Status == http.StatusNotFound && r.Method == http.MethodGet {
+		recordErr := notfounds.Record(r.URL.Path, r.Referer())
+		if recordErr != nil {
+			log.Error(log.V{"msg": "failed to record url not found", "error": recordErr})
+		}
+	}
+
 	view := view.NewWithPath("", w)
 	view.AddKey("title", err.Title)
 	view.AddKey("message", err.Message)
 	// In production, provide no detail for security reasons
-	if !config.Production() {
+	if !settings.Current.Production() {
 		view.AddKey("status", err.Status)
 		view.AddKey("file", err.FileLine())
 		view.AddKey("error", err.Err)
@@ -95,3 +111,40 @@ func errHandler(w http.ResponseWriter, r *http.Request, e error) {
 	w.WriteHeader(err.Status)
 	view.Render()
 }
+
+// redirectMiddleware redirects GET requests which match a redirect before
+// they are routed, or responds that the content has gone, recording a hit.
+func redirectMiddleware(h http.HandlerFunc) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h(w, r)
+			return
+		}
+
+		// Ignore redirects which would lead back to the url requested
+		redirect, url := redirects.Match(r.URL)
+		if redirect == nil || url == r.URL.RequestURI() {
+			h(w, r)
+			return
+		}
+
+		err := redirect.Hit()
+		if err != nil {
+			log.Error(log.V{"msg": "failed to record redirect hit", "redirect_id": redirect.ID, "error": err})
+		}
+
+		if redirect.IsGone() {
+			errHandler(w, r, &server.StatusError{
+				Err:     errors.New("redirects: content gone"),
+				Status:  http.StatusGone,
+				Title:   "Gone",
+				Message: "Sorry, this page has been removed.",
+			})
+			return
+		}
+
+		http.Redirect(w, r, url, int(redirect.StatusCode))
+	}
+}
@@ -1,24 +1,51 @@
 package app
 
 import (
+	"reflect"
+	"runtime"
+	"strings"
+
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server/log"
 
 	// Resource Actions
+	"github.com/fragmenta/fragmenta-cms/src/comments/actions"
+	"github.com/fragmenta/fragmenta-cms/src/emails/actions"
+	"github.com/fragmenta/fragmenta-cms/src/forms/actions"
+	"github.com/fragmenta/fragmenta-cms/src/galleries/actions"
 	"github.com/fragmenta/fragmenta-cms/src/images/actions"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/menus/actions"
+	"github.com/fragmenta/fragmenta-cms/src/notfounds/actions"
 	"github.com/fragmenta/fragmenta-cms/src/pages/actions"
 	"github.com/fragmenta/fragmenta-cms/src/posts/actions"
 	"github.com/fragmenta/fragmenta-cms/src/redirects/actions"
+	"github.com/fragmenta/fragmenta-cms/src/submissions/actions"
 	"github.com/fragmenta/fragmenta-cms/src/tags/actions"
 	"github.com/fragmenta/fragmenta-cms/src/users/actions"
 )
 
+// Route describes a route added to the app router, for listing routes.
+type Route struct {
+	Method  string
+	Pattern string
+	Handler string
+}
+
+// routes records the routes added by SetupRoutes.
+var routes []Route
+
+// Routes returns the routes added by SetupRoutes in the order they are evaluated.
+func Routes() []Route {
+	return routes
+}
+
 // SetupRoutes creates a new router and adds the routes for this app to it.
 func SetupRoutes() *mux.Mux {
 
-	router := mux.New()
-	mux.SetDefault(router)
+	routes = nil
+	router := recorder{mux.New()}
+	mux.SetDefault(router.Mux)
 
 	// Set the default file handler
 	router.FileHandler = fileHandler
@@ -26,6 +53,7 @@ func SetupRoutes() *mux.Mux {
 
 	// Add middleware
 	router.AddMiddleware(log.Middleware)
+	router.AddMiddleware(redirectMiddleware)
 	router.AddMiddleware(session.Middleware)
 
 	// Add the home page route
@@ -41,14 +69,49 @@ func SetupRoutes() *mux.Mux {
 	router.Get("/redirects", redirectactions.HandleIndex)
 	router.Get("/redirects/create", redirectactions.HandleCreateShow)
 	router.Post("/redirects/create", redirectactions.HandleCreate)
+	router.Get("/redirects/export", redirectactions.HandleExport)
+	router.Get("/redirects/import", redirectactions.HandleImportShow)
+	router.Post("/redirects/import", redirectactions.HandleImport)
 	router.Get("/redirects/{id:[0-9]+}/update", redirectactions.HandleUpdateShow)
 	router.Post("/redirects/{id:[0-9]+}/update", redirectactions.HandleUpdate)
 	router.Post("/redirects/{id:[0-9]+}/destroy", redirectactions.HandleDestroy)
 	router.Get("/redirects/{id:[0-9]+}", redirectactions.HandleShow)
 
+	router.Get("/notfounds", notfoundactions.HandleIndex)
+	router.Post("/notfounds/{id:[0-9]+}/ignore", notfoundactions.HandleIgnore)
+	router.Post("/notfounds/{id:[0-9]+}/destroy", notfoundactions.HandleDestroy)
+
+	router.Get("/emails", emailactions.HandleIndex)
+	router.Post("/emails/{id:[0-9]+}/resend", emailactions.HandleResend)
+	router.Post("/emails/{id:[0-9]+}/destroy", emailactions.HandleDestroy)
+	router.Get("/emails/{id:[0-9]+}", emailactions.HandleShow)
+
+	router.Get("/forms", formactions.HandleIndex)
+	router.Get("/forms/create", formactions.HandleCreateShow)
+	router.Post("/forms/create", formactions.HandleCreate)
+	router.Get("/forms/{id:[0-9]+}/update", formactions.HandleUpdateShow)
+	router.Post("/forms/{id:[0-9]+}/update", formactions.HandleUpdate)
+	router.Post("/forms/{id:[0-9]+}/destroy", formactions.HandleDestroy)
+	router.Post("/forms/{id:[0-9]+}/submit", submissionactions.HandleCreate)
+	router.Get("/forms/{id:[0-9]+}", formactions.HandleShow)
+
+	router.Get("/menus", menuactions.HandleIndex)
+	router.Get("/menus/create", menuactions.HandleCreateShow)
+	router.Post("/menus/create", menuactions.HandleCreate)
+	router.Get("/menus/{id:[0-9]+}/update", menuactions.HandleUpdateShow)
+	router.Post("/menus/{id:[0-9]+}/update", menuactions.HandleUpdate)
+	router.Post("/menus/{id:[0-9]+}/destroy", menuactions.HandleDestroy)
+
+	router.Get("/submissions", submissionactions.HandleIndex)
+	router.Get("/submissions/export", submissionactions.HandleExport)
+	router.Post("/submissions/{id:[0-9]+}/destroy", submissionactions.HandleDestroy)
+	router.Get("/submissions/{id:[0-9]+}", submissionactions.HandleShow)
+
 	router.Get("/pages", pageactions.HandleIndex)
 	router.Get("/pages/create", pageactions.HandleCreateShow)
 	router.Post("/pages/create", pageactions.HandleCreate)
+	router.Post("/pages/reorder", pageactions.HandleReorder)
+	router.Post("/pages/preview", pageactions.HandlePreview)
 	router.Get("/pages/{id:[0-9]+}/update", pageactions.HandleUpdateShow)
 	router.Post("/pages/{id:[0-9]+}/update", pageactions.HandleUpdate)
 	router.Post("/pages/{id:[0-9]+}/destroy", pageactions.HandleDestroy)
@@ -59,21 +122,39 @@ func SetupRoutes() *mux.Mux {
 	router.Get("/images", imageactions.HandleIndex)
 	router.Get("/images/create", imageactions.HandleCreateShow)
 	router.Post("/images/create", imageactions.HandleCreate)
+	router.Get("/images/library", imageactions.HandleLibrary)
+	router.Post("/images/library", imageactions.HandleLibraryUpload)
 	router.Get("/images/{id:[0-9]+}/update", imageactions.HandleUpdateShow)
 	router.Post("/images/{id:[0-9]+}/update", imageactions.HandleUpdate)
 	router.Post("/images/{id:[0-9]+}/destroy", imageactions.HandleDestroy)
 	router.Get("/images/{id:[0-9]+}", imageactions.HandleShow)
 
+	router.Get("/galleries", galleryactions.HandleIndex)
+	router.Get("/galleries/create", galleryactions.HandleCreateShow)
+	router.Post("/galleries/create", galleryactions.HandleCreate)
+	router.Get("/galleries/{id:[0-9]+}/update", galleryactions.HandleUpdateShow)
+	router.Post("/galleries/{id:[0-9]+}/update", galleryactions.HandleUpdate)
+	router.Post("/galleries/{id:[0-9]+}/reorder", galleryactions.HandleReorder)
+	router.Post("/galleries/{id:[0-9]+}/destroy", galleryactions.HandleDestroy)
+	router.Get("/galleries/{id:[0-9]+}", galleryactions.HandleShow)
+
 	router.Get("/posts", postactions.HandleIndex)
 	router.Get("/posts/create", postactions.HandleCreateShow)
 	router.Post("/posts/create", postactions.HandleCreate)
+	router.Post("/posts/preview", postactions.HandlePreview)
 	router.Get("/posts/{id:[0-9]+}/update", postactions.HandleUpdateShow)
 	router.Post("/posts/{id:[0-9]+}/update", postactions.HandleUpdate)
 	router.Post("/posts/{id:[0-9]+}/destroy", postactions.HandleDestroy)
 	router.Get("/posts/{id:[0-9]+}", postactions.HandleShow)
 	router.Get("/blog", postactions.HandleShowBlog)
+	router.Post("/blog/{id:[0-9]+}/comments", commentactions.HandleCreate)
 	router.Get("/blog/{id:[0-9]+}", postactions.HandleShow)
 
+	router.Get("/comments", commentactions.HandleIndex)
+	router.Post("/comments/{id:[0-9]+}/approve", commentactions.HandleApprove)
+	router.Post("/comments/{id:[0-9]+}/reject", commentactions.HandleReject)
+	router.Post("/comments/{id:[0-9]+}/destroy", commentactions.HandleDestroy)
+
 	router.Get("/tags", tagactions.HandleIndex)
 	router.Get("/tags/create", tagactions.HandleCreateShow)
 	router.Post("/tags/create", tagactions.HandleCreate)
@@ -88,6 +169,13 @@ func SetupRoutes() *mux.Mux {
 	router.Get("/users/login", useractions.HandleLoginShow)
 	router.Post("/users/login", useractions.HandleLogin)
 	router.Post("/users/logout", useractions.HandleLogout)
+	router.Get("/users/register", useractions.HandleRegisterShow)
+	router.Post("/users/register", useractions.HandleRegister)
+	router.Get("/users/verify/sent", useractions.HandleVerifySentShow)
+	router.Get("/users/verify/resend", useractions.HandleVerifyResendShow)
+	router.Post("/users/verify/resend", useractions.HandleVerifyResend)
+	router.Get("/users/verify", useractions.HandleVerify)
+	router.Post("/users/{id:[0-9]+}/approve", useractions.HandleApprove)
 	router.Get("/users/{id:[0-9]+}/update", useractions.HandleUpdateShow)
 	router.Post("/users/{id:[0-9]+}/update", useractions.HandleUpdate)
 	router.Post("/users/{id:[0-9]+}/destroy", useractions.HandleDestroy)
@@ -96,5 +184,35 @@ func SetupRoutes() *mux.Mux {
 	// Add catch-all for custom page routes - this must be evaluated last.
 	router.Get("/{path:.+}", pageactions.HandleShowPath)
 
-	return router
+	return router.Mux
+}
+
+// recorder wraps the router to record the routes added to it.
+type recorder struct {
+	*mux.Mux
+}
+
+// Add adds a route to the router, routes added with Add accept GET by default.
+func (r recorder) Add(pattern string, handler mux.HandlerFunc) {
+	r.record("GET", pattern, handler)
+	r.Mux.Add(pattern, handler)
+}
+
+// Get adds a GET route to the router.
+func (r recorder) Get(pattern string, handler mux.HandlerFunc) {
+	r.record("GET", pattern, handler)
+	r.Mux.Get(pattern, handler)
+}
+
+// Post adds a POST route to the router.
+func (r recorder) Post(pattern string, handler mux.HandlerFunc) {
+	r.record("POST", pattern, handler)
+	r.Mux.Post(pattern, handler)
+}
+
+// record appends the route to routes, with the handler name relative to src.
+func (r recorder) record(method, pattern string, handler mux.HandlerFunc) {
+	name := runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	name = strings.TrimPrefix(name, "github.com/fragmenta/fragmenta-cms/src/")
+	routes = append(routes, Route{Method: method, Pattern: pattern, Handler: name})
 }
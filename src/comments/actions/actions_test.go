@@ -0,0 +1,218 @@
+package commentactions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/comments"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation, and writes the post.
+	admin *users.User
+
+	// reader comments on the post.
+	reader *users.User
+
+	// post is the post commented on in tests.
+	post *posts.Post
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, an admin, a reader and a post.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("commentactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("commentactions: error creating admin %s", err)
+	}
+
+	reader, err = apptest.CreateUser(nil)
+	if err != nil {
+		t.Fatalf("commentactions: error creating reader %s", err)
+	}
+
+	post, err = apptest.CreatePost(map[string]string{"author_id": fmt.Sprintf("%d", admin.ID)})
+	if err != nil {
+		t.Fatalf("commentactions: error creating post %s", err)
+	}
+}
+
+// comment posts values as a comment on the test post as user.
+func comment(values url.Values, user *users.User) (int, string, error) {
+	w, err := apptest.Request(router, "POST", fmt.Sprintf("/blog/%d/comments", post.ID), values, user)
+	if err != nil {
+		return 0, "", err
+	}
+	return w.Code, w.Body.String(), nil
+}
+
+// Test POST /blog/1/comments
+func TestCreateComment(t *testing.T) {
+	apptest.Mail.Reset()
+
+	values := url.Values{}
+	values.Add("text", "Nice post")
+
+	// Test anon may not comment unless anonymous comments are enabled
+	code, _, err := comment(values, nil)
+	if err != nil || code == http.StatusOK || code == http.StatusFound {
+		t.Fatalf("commentactions: unexpected response for HandleCreate as anon, expected failure %d", code)
+	}
+
+	// Test comments by readers are held for moderation
+	code, body, err := comment(values, reader)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("commentactions: error handling HandleCreate %v %d", err, code)
+	}
+	if !strings.Contains(body, "approved") {
+		t.Fatalf("commentactions: unexpected response for HandleCreate got:%s", body)
+	}
+
+	c, err := comments.Find(1)
+	if err != nil || c.Name != reader.Name || c.AuthorID != reader.ID || c.IsApproved() {
+		t.Fatalf("commentactions: unexpected comment %v %v", c, err)
+	}
+
+	// Test the post author is notified with a link to moderate the comment
+	m := apptest.Mail.Last()
+	if m == nil || m.Recipients[0] != admin.Email || !strings.Contains(m.Body, "Nice post") || !strings.Contains(m.Body, "/comments") {
+		t.Fatalf("commentactions: unexpected notification %v", m)
+	}
+
+	// Test anon may comment with a name when enabled, but links are treated as spam
+	settings.Current.Comments.Anon = true
+	defer func() { settings.Current.Comments.Anon = false }()
+
+	values = url.Values{}
+	values.Add("text", "Hello")
+	code, _, err = comment(values, nil)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("commentactions: unexpected response for anon comment without name %v %d", err, code)
+	}
+
+	values.Add("name", "Visitor")
+	values.Set("text", "[url=http://example.com]cheap[/url]")
+	code, _, err = comment(values, nil)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("commentactions: unexpected response for spam comment %v %d", err, code)
+	}
+	c, err = comments.Find(2)
+	if err != nil || c.Name != "Visitor" || !c.IsRejected() {
+		t.Fatalf("commentactions: unexpected spam comment %v %v", c, err)
+	}
+}
+
+// Test GET /comments and POST /comments/1/approve
+func TestModerateComment(t *testing.T) {
+
+	// Test listing comments as a reader
+	w, err := apptest.Request(router, "GET", "/comments", nil, reader)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("commentactions: unexpected response for HandleIndex as reader, expected failure")
+	}
+
+	w, err = apptest.Request(router, "GET", "/comments", nil, admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("commentactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "Nice post") || strings.Contains(w.Body.String(), "cheap") {
+		t.Fatalf("commentactions: unexpected response for HandleIndex got:%s", w.Body.String())
+	}
+
+	// Test approving as a reader
+	w, err = apptest.Request(router, "POST", "/comments/1/approve", nil, reader)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("commentactions: unexpected response for HandleApprove as reader, expected failure")
+	}
+
+	w, err = apptest.Request(router, "POST", "/comments/1/approve", nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("commentactions: error handling HandleApprove %v %d", err, w.Code)
+	}
+
+	p, err := posts.Find(post.ID)
+	if err != nil || p.CommentCount != 1 {
+		t.Fatalf("commentactions: unexpected comment count after approve %v %v", p, err)
+	}
+}
+
+// Test replies and comments shown on the post and blog
+func TestShowComments(t *testing.T) {
+
+	// Test a reply by an admin is approved immediately
+	values := url.Values{}
+	values.Add("text", "Thanks")
+	values.Add("parent_id", "1")
+	w, err := apptest.Request(router, "POST", fmt.Sprintf("/blog/%d/comments", post.ID), values, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("commentactions: error handling HandleCreate reply %v %d", err, w.Code)
+	}
+	if !strings.HasSuffix(w.Header().Get("Location"), "#comment-3") {
+		t.Fatalf("commentactions: unexpected redirect for reply %s", w.Header().Get("Location"))
+	}
+
+	// Test replies to rejected comments are not accepted
+	values.Set("parent_id", "2")
+	values.Set("text", "Spam reply")
+	w, err = apptest.Request(router, "POST", fmt.Sprintf("/blog/%d/comments", post.ID), values, admin)
+	if err != nil || w.Code != http.StatusNotFound {
+		t.Fatalf("commentactions: unexpected response for reply to rejected comment %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", post.ShowURL(), nil, reader)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("commentactions: error showing post %v %d", err, w.Code)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `id="comment-1"`) || !strings.Contains(body, "comment-replies") || strings.Contains(body, "cheap") {
+		t.Fatalf("commentactions: unexpected comments on post got:%s", body)
+	}
+	if !strings.Contains(body, "comment-form") || strings.Contains(body, `name="name"`) {
+		t.Fatalf("commentactions: unexpected comment form for reader got:%s", body)
+	}
+
+	w, err = apptest.Request(router, "GET", "/blog", nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("commentactions: error showing blog %v %d", err, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "2 comments") {
+		t.Fatalf("commentactions: comment count not shown on blog got:%s", w.Body.String())
+	}
+}
+
+// Test of POST /comments/1/destroy
+func TestDeleteComment(t *testing.T) {
+
+	w, err := apptest.Request(router, "POST", "/comments/3/destroy", nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("commentactions: error handling HandleDestroy %v %d", err, w.Code)
+	}
+
+	_, err = comments.Find(3)
+	if err == nil {
+		t.Fatalf("commentactions: comment found after HandleDestroy")
+	}
+
+	p, err := posts.Find(post.ID)
+	if err != nil || p.CommentCount != 1 {
+		t.Fatalf("commentactions: unexpected comment count after destroy %v %v", p, err)
+	}
+}
@@ -0,0 +1,224 @@
+package commentactions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/comments"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+// HandleCreate handles the POST of a comment on a post, or a reply to another comment,
+// and notifies the author of the post by email.
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the post
+	post, err := posts.Find(params.GetInt(posts.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access to the post
+	user := session.CurrentUser(w, r)
+	if !post.IsPublished() {
+		err = can.Show(post, user)
+		if err != nil {
+			return server.NotAuthorizedError(err)
+		}
+	}
+	if !post.VisibleTo(user) {
+		return server.NotAuthorizedError(errors.New("comments: post is restricted"))
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise create comment, visitors may comment only if anonymous comments are enabled
+	comment := comments.New()
+	if user.Anon() {
+		if !settings.Current.Comments.Anon {
+			return server.NotAuthorizedError(errors.New("comments: anonymous comments are disabled"))
+		}
+	} else {
+		err = can.Create(comment, user)
+		if err != nil {
+			return server.NotAuthorizedError(err)
+		}
+	}
+
+	// Spam bots fill in the hidden honeypot field, pretend to accept their comment
+	ip := session.ClientIP(r)
+	if params.Get(comments.Honeypot) != "" {
+		log.Info(log.V{"msg": "discarded spam comment", "post_id": post.ID, "ip": ip})
+		return renderModerated(w, r, post)
+	}
+
+	// Limit the comments from each address
+	limited, err := comments.Limited(ip)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	if limited {
+		return &server.StatusError{
+			Err:     errors.New("comments: rate limit exceeded"),
+			Status:  http.StatusTooManyRequests,
+			Title:   "Too many comments",
+			Message: "Sorry, you have made too many comments recently, please try again later.",
+		}
+	}
+
+	commentParams := map[string]string{
+		"post_id": fmt.Sprintf("%d", post.ID),
+		"name":    strings.TrimSpace(params.Get("name")),
+		"email":   strings.TrimSpace(params.Get("email")),
+		"text":    strings.TrimSpace(params.Get("text")),
+		"ip":      ip,
+	}
+
+	// Users comment with their own name and email
+	if !user.Anon() {
+		commentParams["author_id"] = fmt.Sprintf("%d", user.ID)
+		commentParams["name"] = user.Name
+		commentParams["email"] = user.Email
+	}
+
+	err = comments.Validate(commentParams)
+	if err != nil {
+		return server.BadRequestError(err, "Please check your comment", err.Error())
+	}
+
+	// Replies must be to an approved comment on the same post, which is not too deeply nested
+	parentID := params.GetInt("parent_id")
+	if parentID > 0 {
+		parent, err := comments.Find(parentID)
+		if err != nil {
+			return server.NotFoundError(err)
+		}
+		if parent.PostID != post.ID || !parent.IsApproved() {
+			return server.NotFoundError(errors.New("comments: reply to unapproved comment"))
+		}
+		depth, err := comments.FindDepth(parent)
+		if err != nil {
+			return server.InternalError(err)
+		}
+		if depth >= comments.MaxDepth {
+			err = errors.New("comments: reply nested too deeply")
+			return server.BadRequestError(err, "Please check your comment", "Sorry, you can't reply to this comment.")
+		}
+		commentParams["parent_id"] = fmt.Sprintf("%d", parent.ID)
+	}
+
+	// Ignore comments submitted twice
+	duplicate, err := comments.Duplicate(post.ID, ip, commentParams["text"])
+	if err != nil {
+		return server.InternalError(err)
+	}
+	if duplicate {
+		return server.Redirect(w, r, post.ShowURL())
+	}
+
+	// Comments by admins are approved, those which look like spam are rejected,
+	// and others are held for approval if moderation is enabled
+	switch {
+	case user.Admin():
+		comment.Status = comments.Approved
+	case comments.Spam(commentParams["text"]):
+		comment.Status = comments.Rejected
+	case settings.Current.Comments.Moderate:
+		comment.Status = comments.Pending
+	default:
+		comment.Status = comments.Approved
+	}
+	commentParams["status"] = fmt.Sprintf("%d", comment.Status)
+
+	id, err := comment.Create(commentParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	comment, err = comments.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	if comment.IsApproved() {
+		err = comments.UpdateCount(post.ID)
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
+	// The comment is saved, so log any failure to notify rather than reporting it
+	if comment.Status != comments.Rejected {
+		err = notify(post, comment, user)
+		if err != nil {
+			log.Error(log.V{"msg": "unable to send comment notification", "post_id": post.ID, "comment_id": comment.ID, "error": err})
+		}
+	}
+
+	if !comment.IsApproved() {
+		return renderModerated(w, r, post)
+	}
+
+	// Redirect to the comment on the post
+	return server.Redirect(w, r, post.ShowURL()+"#"+comment.Anchor())
+}
+
+// renderModerated tells the commenter their comment is awaiting moderation.
+func renderModerated(w http.ResponseWriter, r *http.Request, post *posts.Post) error {
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", session.CurrentUser(w, r))
+	view.AddKey("post", post)
+	view.Template("comments/views/moderated.html.got")
+	return view.Render()
+}
+
+// notify emails the author of the post about the comment, unless they made it.
+func notify(post *posts.Post, comment *comments.Comment, user *users.User) error {
+	if post.AuthorID == 0 || post.AuthorID == user.ID {
+		return nil
+	}
+
+	author, err := users.Find(post.AuthorID)
+	if err != nil || author.Email == "" {
+		return nil
+	}
+
+	// Link to the comment if it is shown, or the moderation queue if not
+	url := fmt.Sprintf("%s%s#%s", settings.Current.RootURL, post.ShowURL(), comment.Anchor())
+	if !comment.IsApproved() {
+		url = fmt.Sprintf("%s/comments", settings.Current.RootURL)
+	}
+
+	emailContext := map[string]interface{}{
+		"post":    post,
+		"comment": comment,
+		"url":     url,
+	}
+
+	e := mail.New(author.Email)
+	e.Subject = fmt.Sprintf("New comment on %s", post.Name)
+	e.Template = "comments/views/notification_mail.html.got"
+	return mail.Send(e, emailContext)
+}
@@ -0,0 +1,52 @@
+package commentactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/comments"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleDestroy responds to /comments/n/destroy by deleting the comment.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy comment
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(comment, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the comment, replies to it are then shown at the top level
+	comment.Destroy()
+
+	err = comments.UpdateCount(comment.PostID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the moderation queue
+	return server.Redirect(w, r, comment.IndexURL())
+}
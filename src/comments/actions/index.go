@@ -0,0 +1,80 @@
+package commentactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/comments"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+)
+
+// HandleIndex displays the moderation queue of comments, by default those pending approval.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list comment
+	user := session.CurrentUser(w, r)
+	err := can.List(comments.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Build a query
+	q := comments.Query()
+
+	// Filter by status, or show those awaiting approval
+	switch params.Get("status") {
+
+	case "rejected":
+		q.Where("status=?", comments.Rejected)
+
+	case "approved":
+		q.Where("status=?", comments.Approved)
+
+	case "all":
+
+	default:
+		q.Where("status=?", comments.Pending)
+	}
+
+	// Filter by post if requested
+	postID := params.GetInt("post_id")
+	if postID > 0 {
+		q.Where("post_id=?", postID)
+	}
+
+	// Fetch the comments
+	results, err := comments.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Fetch the posts commented on for their names
+	allPosts, err := posts.FindAll(posts.Query())
+	if err != nil {
+		return server.InternalError(err)
+	}
+	postNames := make(map[int64]string)
+	for _, p := range allPosts {
+		postNames[p.ID] = p.Name
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("status", params.Get("status"))
+	view.AddKey("postID", postID)
+	view.AddKey("postNames", postNames)
+	view.AddKey("comments", results)
+	return view.Render()
+}
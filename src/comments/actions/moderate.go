@@ -0,0 +1,65 @@
+package commentactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/comments"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleApprove responds to POST /comments/n/approve by showing the comment on its post.
+func HandleApprove(w http.ResponseWriter, r *http.Request) error {
+	return moderate(w, r, comments.Approved)
+}
+
+// HandleReject responds to POST /comments/n/reject by hiding the comment.
+func HandleReject(w http.ResponseWriter, r *http.Request) error {
+	return moderate(w, r, comments.Rejected)
+}
+
+// moderate sets the status of the comment and updates the comment count of its post.
+func moderate(w http.ResponseWriter, r *http.Request, status int64) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the comment
+	comment, err := comments.Find(params.GetInt(comments.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update comment
+	user := session.CurrentUser(w, r)
+	err = can.Update(comment, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	err = comment.Update(map[string]string{"status": fmt.Sprintf("%d", status)})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = comments.UpdateCount(comment.PostID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the moderation queue
+	return server.Redirect(w, r, comment.IndexURL())
+}
@@ -0,0 +1,187 @@
+// Package comments represents the comments made on blog posts, which may be
+// threaded as replies to other comments and are moderated with their status.
+package comments
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/view/helpers"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// Moderation status values for comments, using the values from lib/status.
+const (
+	Pending  = status.Draft
+	Rejected = status.Suspended
+	Approved = status.Published
+)
+
+// MaxDepth is the maximum depth of replies to comments.
+const MaxDepth = 4
+
+// MaxLength is the maximum length of comment text.
+const MaxLength = 5000
+
+// MaxNameLength is the maximum length of the names of commenters.
+const MaxNameLength = 100
+
+// MaxLinks is the maximum number of links in a comment before it is treated as spam.
+const MaxLinks = 2
+
+// Visitors may make RateLimit comments within RatePeriod from one address.
+const (
+	RateLimit  = 5
+	RatePeriod = 10 * time.Minute
+)
+
+// Honeypot is the name of a hidden field in the comment form, which is left
+// empty by visitors but often filled in by spam bots.
+const Honeypot = "website"
+
+// Comment handles saving and retreiving comments from the database
+type Comment struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// status.ResourceStatus defines a status field and associated behaviour
+	status.ResourceStatus
+
+	PostID   int64
+	ParentID int64
+	AuthorID int64
+	Name     string
+	Email    string
+	Text     string
+	IP       string
+
+	// Replies and Depth are set when comments are threaded
+	Replies []*Comment
+	Depth   int
+}
+
+// StatusOptions returns the moderation statuses for comments.
+func (c *Comment) StatusOptions() []helpers.Option {
+	var options []helpers.Option
+
+	options = append(options, helpers.Option{Id: Pending, Name: "Pending"})
+	options = append(options, helpers.Option{Id: Rejected, Name: "Rejected"})
+	options = append(options, helpers.Option{Id: Approved, Name: "Approved"})
+
+	return options
+}
+
+// StatusDisplay returns a string representation of the comment status.
+func (c *Comment) StatusDisplay() string {
+	for _, o := range c.StatusOptions() {
+		if o.Id == c.Status {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// IsApproved returns true if this comment has been approved.
+func (c *Comment) IsApproved() bool {
+	return c.Status == Approved
+}
+
+// IsRejected returns true if this comment has been rejected.
+func (c *Comment) IsRejected() bool {
+	return c.Status == Rejected
+}
+
+// CanReply returns true if replies may be made to this comment.
+func (c *Comment) CanReply() bool {
+	return c.Depth < MaxDepth
+}
+
+// Anchor returns the id of the comment element on the post page.
+func (c *Comment) Anchor() string {
+	return fmt.Sprintf("comment-%d", c.ID)
+}
+
+// Thread arranges the comments given into threads, returning the top level
+// comments with their replies, oldest first. Replies to comments not
+// included are shown at the top level.
+func Thread(list []*Comment) []*Comment {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+
+	byID := make(map[int64]*Comment)
+	for _, c := range list {
+		c.Replies = nil
+		byID[c.ID] = c
+	}
+
+	var threads []*Comment
+	for _, c := range list {
+		parent := byID[c.ParentID]
+		if c.ParentID == 0 || parent == nil {
+			threads = append(threads, c)
+			continue
+		}
+		parent.Replies = append(parent.Replies, c)
+	}
+
+	setDepth(threads, 0)
+	return threads
+}
+
+// setDepth sets the depth of comments and their replies.
+func setDepth(list []*Comment, depth int) {
+	for _, c := range list {
+		c.Depth = depth
+		setDepth(c.Replies, depth+1)
+	}
+}
+
+// linkRegexp matches links in comment text.
+var linkRegexp = regexp.MustCompile(`(?i)(https?://|www\.|\[url|<a\s)`)
+
+// Spam returns true if the comment text looks like spam, because it includes
+// too many links or markup used by spam bots.
+func Spam(text string) bool {
+	if len(linkRegexp.FindAllString(text, -1)) > MaxLinks {
+		return true
+	}
+	lower := strings.ToLower(text)
+	return strings.Contains(lower, "[url") || strings.Contains(lower, "<a ")
+}
+
+// Validate checks the name, email and text of comment params are acceptable,
+// the email is optional.
+func Validate(params map[string]string) error {
+	name := strings.TrimSpace(params["name"])
+	if name == "" {
+		return errors.New("Please enter your name")
+	}
+	if len(name) > MaxNameLength {
+		return errors.New("Your name is too long")
+	}
+
+	if params["email"] != "" {
+		_, err := mail.ParseAddress(params["email"])
+		if err != nil {
+			return errors.New("Please enter a valid email address")
+		}
+	}
+
+	text := strings.TrimSpace(params["text"])
+	if text == "" {
+		return errors.New("Please enter a comment")
+	}
+	if len(text) > MaxLength {
+		return fmt.Errorf("Comments should be at most %d characters", MaxLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+// Tests for the comments package
+package comments
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+var testIP = "192.0.2.10"
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("comments: Setup db failed %s", err)
+	}
+}
+
+// Test Create method
+func TestCreateComment(t *testing.T) {
+	params := map[string]string{
+		"status":  fmt.Sprintf("%d", Approved),
+		"post_id": "1",
+		"name":    "Alice",
+		"text":    "First!",
+		"ip":      testIP,
+	}
+
+	id, err := New().Create(params)
+	if err != nil {
+		t.Fatalf("comments: Create comment failed :%s", err)
+	}
+
+	comment, err := Find(id)
+	if err != nil {
+		t.Fatalf("comments: Create comment find failed")
+	}
+
+	if comment.PostID != 1 || comment.Name != "Alice" || !comment.IsApproved() {
+		t.Fatalf("comments: Create comment failed got:%v", comment)
+	}
+
+	// Test replies find their depth
+	params["parent_id"] = fmt.Sprintf("%d", id)
+	id, err = New().Create(params)
+	if err != nil {
+		t.Fatalf("comments: Create reply failed :%s", err)
+	}
+	reply, err := Find(id)
+	if err != nil {
+		t.Fatalf("comments: Create reply find failed")
+	}
+	depth, err := FindDepth(reply)
+	if err != nil || depth != 1 {
+		t.Fatalf("comments: FindDepth unexpected depth for reply :%d %s", depth, err)
+	}
+}
+
+// TestLimited tests comments from an address are limited.
+func TestLimited(t *testing.T) {
+	limited, err := Limited(testIP)
+	if err != nil || limited {
+		t.Fatalf("comments: Limited after two comments :%v %s", limited, err)
+	}
+
+	for i := 2; i < RateLimit; i++ {
+		_, err = New().Create(map[string]string{"post_id": "2", "ip": testIP})
+		if err != nil {
+			t.Fatalf("comments: Create comment failed :%s", err)
+		}
+	}
+
+	limited, err = Limited(testIP)
+	if err != nil || !limited {
+		t.Fatalf("comments: not Limited after %d comments :%s", RateLimit, err)
+	}
+
+	duplicate, err := Duplicate(1, testIP, "First!")
+	if err != nil || !duplicate {
+		t.Fatalf("comments: Duplicate not found :%s", err)
+	}
+}
+
+func TestThread(t *testing.T) {
+	now := time.Now()
+	list := []*Comment{
+		{ParentID: 9, Text: "reply to missing"},
+		{ParentID: 2, Text: "reply to reply"},
+		{ParentID: 1, Text: "reply"},
+		{Text: "first"},
+	}
+	for i, c := range list {
+		c.ID = int64(len(list) - i)
+		c.CreatedAt = now.Add(time.Duration(c.ID) * time.Minute)
+	}
+
+	threads := Thread(list)
+	if len(threads) != 2 || threads[0].Text != "first" || threads[1].Text != "reply to missing" {
+		t.Fatalf("comments: Thread unexpected threads :%v", threads)
+	}
+
+	reply := threads[0].Replies[0]
+	if reply.Text != "reply" || reply.Depth != 1 || reply.Replies[0].Depth != 2 || !reply.CanReply() {
+		t.Fatalf("comments: Thread unexpected replies :%v", reply)
+	}
+}
+
+func TestSpam(t *testing.T) {
+	if Spam("Great post, see https://example.com for more") {
+		t.Fatalf("comments: Spam rejected comment with one link")
+	}
+	spam := []string{
+		"http://a.example.com http://b.example.com www.c.example.com",
+		"[url=http://example.com]cheap[/url]",
+		`<a href="http://example.com">cheap</a>`,
+	}
+	for _, s := range spam {
+		if !Spam(s) {
+			t.Fatalf("comments: Spam accepted :%s", s)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	params := map[string]string{"name": "Alice", "email": "alice@example.com", "text": "Hello"}
+	err := Validate(params)
+	if err != nil {
+		t.Fatalf("comments: Validate failed :%s", err)
+	}
+
+	invalid := map[string]string{
+		"name":  " ",
+		"email": "alice",
+		"text":  strings.Repeat("a", MaxLength+1),
+	}
+	for k, v := range invalid {
+		old := params[k]
+		params[k] = v
+		err = Validate(params)
+		if err == nil {
+			t.Fatalf("comments: Validate accepted invalid %s :%s", k, v)
+		}
+		params[k] = old
+	}
+}
@@ -0,0 +1,151 @@
+package comments
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "comments"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// NewWithColumns creates a new comment instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Comment {
+
+	comment := New()
+	comment.ID = resource.ValidateInt(cols["id"])
+	comment.CreatedAt = resource.ValidateTime(cols["created_at"])
+	comment.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	comment.Status = resource.ValidateInt(cols["status"])
+	comment.PostID = resource.ValidateInt(cols["post_id"])
+	comment.ParentID = resource.ValidateInt(cols["parent_id"])
+	comment.AuthorID = resource.ValidateInt(cols["author_id"])
+	comment.Name = resource.ValidateString(cols["name"])
+	comment.Email = resource.ValidateString(cols["email"])
+	comment.Text = resource.ValidateString(cols["text"])
+	comment.IP = resource.ValidateString(cols["ip"])
+
+	return comment
+}
+
+// New creates and initialises a new comment instance.
+func New() *Comment {
+	comment := &Comment{}
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = time.Now()
+	comment.TableName = TableName
+	comment.KeyName = KeyName
+	comment.Status = Pending
+	return comment
+}
+
+// FindFirst fetches a single comment record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Comment, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single comment record from the database by id.
+func Find(id int64) (*Comment, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all comment records matching this query from the database.
+func FindAll(q *query.Query) ([]*Comment, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of comments constructed from the results
+	var comments []*Comment
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		comments = append(comments, p)
+	}
+
+	return comments, nil
+}
+
+// Query returns a new query for comments with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for comments with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// ApprovedFor returns a query for the approved comments on the post with id.
+func ApprovedFor(postID int64) *query.Query {
+	return Where("post_id=? AND status=?", postID, Approved)
+}
+
+// FindDepth returns the depth of the comment in its thread, by finding its parents.
+func FindDepth(c *Comment) (int, error) {
+	depth := 0
+	for c.ParentID > 0 && depth <= MaxDepth {
+		parent, err := Find(c.ParentID)
+		if err != nil {
+			return depth, err
+		}
+		c = parent
+		depth++
+	}
+	return depth, nil
+}
+
+// Limited returns true if the address given has made RateLimit comments within RatePeriod.
+func Limited(ip string) (bool, error) {
+	since := query.TimeString(time.Now().Add(-RatePeriod).UTC())
+	count, err := Where("ip=? AND created_at>?", ip, since).Count()
+	if err != nil {
+		return false, err
+	}
+	return count >= RateLimit, nil
+}
+
+// Duplicate returns true if the address given has already made a comment with text on the post.
+func Duplicate(postID int64, ip, text string) (bool, error) {
+	count, err := Where("post_id=? AND ip=? AND text=?", postID, ip, text).Count()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// UpdateCount sets the comment count of the post with id to the number of approved comments.
+func UpdateCount(postID int64) error {
+	post, err := posts.Find(postID)
+	if err != nil {
+		return err
+	}
+
+	count, err := ApprovedFor(postID).Count()
+	if err != nil {
+		return err
+	}
+
+	return post.Update(map[string]string{"comment_count": fmt.Sprintf("%d", count)})
+}
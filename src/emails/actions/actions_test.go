@@ -0,0 +1,149 @@
+package emailactions_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var testSubject = "Welcome to the queue"
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user,
+// then queues an email without sending it.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("emailactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("emailactions: error creating admin %s", err)
+	}
+
+	m := mail.New("to@example.com")
+	m.Subject = testSubject
+	m.Body = "<p>Hello</p>"
+	err = (&emails.Queue{}).Add(m)
+	if err != nil {
+		t.Fatalf("emailactions: error queueing email %s", err)
+	}
+}
+
+// Test GET /emails
+func TestListEmails(t *testing.T) {
+
+	// Test listing emails as anon
+	w, err := apptest.Request(router, "GET", "/emails", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("emailactions: unexpected response for HandleIndex as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "GET", "/emails", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("emailactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := testSubject
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("emailactions: unexpected response for HandleIndex expected:%s got:%s", pattern, w.Body.String())
+	}
+
+}
+
+// Test of GET /emails/1
+func TestShowEmail(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/emails/1", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("emailactions: error handling HandleShow %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := "Pending"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("emailactions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test of POST /emails/1/resend
+func TestResendEmail(t *testing.T) {
+
+	// Test resending as anon
+	w, err := apptest.Request(router, "POST", "/emails/1/resend", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("emailactions: unexpected response for HandleResend as anon, expected failure")
+	}
+	if apptest.Mail.Last() != nil {
+		t.Fatalf("emailactions: email sent by anon")
+	}
+
+	w, err = apptest.Request(router, "POST", "/emails/1/resend", nil, admin)
+	if err != nil {
+		t.Fatalf("emailactions: error handling HandleResend %s", err)
+	}
+
+	// Test we get a redirect after resend
+	if w.Code != http.StatusFound {
+		t.Fatalf("emailactions: unexpected response code for HandleResend expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	m := apptest.Mail.Last()
+	if m == nil || m.Subject != testSubject {
+		t.Fatalf("emailactions: email not sent by HandleResend %v", m)
+	}
+
+	email, err := emails.Find(1)
+	if err != nil || !email.IsSent() {
+		t.Fatalf("emailactions: email not marked sent by HandleResend %v", email)
+	}
+}
+
+// Test of POST /emails/1/destroy
+func TestDeleteEmail(t *testing.T) {
+
+	// Test deleting the email as anon
+	w, err := apptest.Request(router, "POST", "/emails/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("emailactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	// Now test deleting the email as admin
+	w, err = apptest.Request(router, "POST", "/emails/1/destroy", nil, admin)
+	if err != nil {
+		t.Fatalf("emailactions: error handling HandleDestroy %s", err)
+	}
+
+	// Test we get a redirect after delete
+	if w.Code != http.StatusFound {
+		t.Fatalf("emailactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	_, err = emails.Find(1)
+	if err == nil {
+		t.Fatalf("emailactions: email found after HandleDestroy")
+	}
+
+}
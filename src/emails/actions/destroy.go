@@ -0,0 +1,48 @@
+package emailactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleDestroy responds to /emails/n/destroy by deleting the email.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the email
+	email, err := emails.Find(params.GetInt(emails.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy email
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(email, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the email
+	email.Destroy()
+
+	// Redirect to emails root
+	return server.Redirect(w, r, email.IndexURL())
+
+}
@@ -0,0 +1,72 @@
+package emailactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleIndex displays a list of queued emails, by default those pending or failed.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list email
+	user := session.CurrentUser(w, r)
+	err := can.List(emails.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Build a query
+	q := emails.Query()
+
+	// Filter by status, or show those which are not yet sent
+	switch params.Get("status") {
+
+	case "pending":
+		q.Where("status=?", emails.Pending)
+
+	case "failed":
+		q.Where("status=?", emails.Failed)
+
+	case "sent":
+		q.Where("status=?", emails.Sent)
+
+	case "all":
+
+	default:
+		q.Where("status<>?", emails.Sent)
+	}
+
+	// Filter if requested
+	filter := params.Get("filter")
+	if len(filter) > 0 {
+		q.Where("("+resource.ILike("recipients")+" OR "+resource.ILike("subject")+")", filter, filter)
+	}
+
+	// Fetch the emails
+	results, err := emails.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("filter", filter)
+	view.AddKey("status", params.Get("status"))
+	view.AddKey("emails", results)
+	return view.Render()
+}
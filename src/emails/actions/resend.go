@@ -0,0 +1,51 @@
+package emailactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleResend responds to POST /emails/n/resend by attempting to send the email again now.
+func HandleResend(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the email
+	email, err := emails.Find(params.GetInt(emails.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update email
+	user := session.CurrentUser(w, r)
+	err = can.Update(email, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Resend the email, failures are recorded on the email and shown on redirect
+	err = email.Resend()
+	if err != nil {
+		log.Info(log.V{"msg": "resend email failed", "email_id": email.ID, "error": err})
+	}
+
+	// Redirect to the email
+	return server.Redirect(w, r, email.ShowURL())
+}
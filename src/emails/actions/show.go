@@ -0,0 +1,49 @@
+package emailactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/emails"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleShow displays a single queued email.
+func HandleShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the email
+	email, err := emails.Find(params.GetInt(emails.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access
+	user := session.CurrentUser(w, r)
+	err = can.Show(email, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Decode the message stored
+	message, err := email.Mail()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("email", email)
+	view.AddKey("message", message)
+	return view.Render()
+}
@@ -0,0 +1,202 @@
+// Package emails represents the queue of outgoing mail, which is stored in the
+// database and delivered by a background worker with retries.
+package emails
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+// Status values for queued emails, sending emails have been claimed for delivery,
+// failed emails have used all their attempts.
+const (
+	Pending = 1
+	Sending = 10
+	Failed  = 50
+	Sent    = 100
+)
+
+// MaxAttempts is the number of attempts made to deliver an email before it is marked as failed.
+const MaxAttempts = 8
+
+// SendTimeout is the time after which an email claimed for delivery which has not
+// been marked sent or failed is due to be attempted again.
+const SendTimeout = 10 * time.Minute
+
+// ErrClaimed is returned by Deliver when the email has already been claimed for
+// delivery by another worker, or has changed since it was loaded.
+var ErrClaimed = errors.New("emails: email already claimed for delivery")
+
+// Email handles saving and retreiving queued emails from the database
+type Email struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	Status        int64
+	Recipients    string
+	Subject       string
+	Message       string
+	Attempts      int64
+	LastError     string
+	NextAttemptAt time.Time
+	SentAt        time.Time
+}
+
+// Mail returns the mail stored for this email.
+func (e *Email) Mail() (*mail.Email, error) {
+	m := &mail.Email{}
+	err := json.Unmarshal([]byte(e.Message), m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Deliver attempts to send this email now, recording the result. On failure the
+// next attempt is scheduled with Backoff, until MaxAttempts have been made.
+// The email is claimed before sending, so that it is only sent once if more
+// than one worker attempts to deliver it, ErrClaimed is returned if it has been
+// claimed already. Once sent the content of the message is removed.
+func (e *Email) Deliver() error {
+	err := e.claim(e.Attempts + 1)
+	if err != nil {
+		return err
+	}
+	return e.deliver()
+}
+
+// Resend resets the attempts for this email and attempts to send it again now.
+// Emails which have been sent or are being sent may not be sent again.
+func (e *Email) Resend() error {
+	if !e.CanResend() {
+		return fmt.Errorf("emails: email with status %s may not be resent", e.StatusDisplay())
+	}
+	err := e.claim(1)
+	if err != nil {
+		return err
+	}
+	return e.deliver()
+}
+
+// claim marks this email as sending with the attempts given, if it has not changed
+// since it was loaded. Attempts are compared as well as status so that an email
+// which has timed out while sending is claimed by only one worker.
+func (e *Email) claim(attempts int64) error {
+	next := time.Now().UTC().Add(SendTimeout)
+	sql := fmt.Sprintf("UPDATE %s SET status=$1, attempts=$2, next_attempt_at=$3 WHERE id=$4 AND status=$5 AND attempts=$6", TableName)
+	result, err := query.ExecSQL(sql, Sending, attempts, query.TimeString(next), e.ID, e.Status, e.Attempts)
+	if err != nil {
+		return err
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if claimed != 1 {
+		return ErrClaimed
+	}
+
+	e.Status = Sending
+	e.Attempts = attempts
+	e.NextAttemptAt = next
+	return nil
+}
+
+// deliver sends the email claimed and records the result.
+func (e *Email) deliver() error {
+	m, err := e.Mail()
+	if err == nil {
+		err = mail.Deliver(m)
+	}
+
+	now := time.Now().UTC()
+	params := map[string]string{}
+
+	if err == nil {
+		e.Status = Sent
+		e.SentAt = now
+		e.LastError = ""
+		params["sent_at"] = query.TimeString(now)
+
+		// Remove the content of sent mail, which may contain tokens
+		// for password resets or verification, keeping the envelope
+		e.Message = redact(m)
+		params["message"] = e.Message
+	} else {
+		e.LastError = err.Error()
+		e.Status = Pending
+		if e.Attempts >= MaxAttempts {
+			e.Status = Failed
+		}
+		e.NextAttemptAt = now.Add(Backoff(e.Attempts))
+		params["next_attempt_at"] = query.TimeString(e.NextAttemptAt)
+	}
+	params["status"] = fmt.Sprintf("%d", e.Status)
+	params["last_error"] = e.LastError
+
+	updateErr := e.Update(params)
+	if updateErr != nil {
+		return updateErr
+	}
+
+	return err
+}
+
+// redact returns the message stored for mail which has been sent,
+// without the body, text or attachment data.
+func redact(m *mail.Email) string {
+	envelope := *m
+	envelope.Body = ""
+	envelope.Text = ""
+	envelope.Attachments = nil
+	for _, a := range m.Attachments {
+		a.Data = nil
+		envelope.Attachments = append(envelope.Attachments, a)
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// Backoff returns the delay before the next attempt after the given number of
+// attempts, which doubles from one minute after each attempt.
+func Backoff(attempts int64) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+	return time.Minute << uint(attempts-1)
+}
+
+// IsSent returns true if this email has been sent.
+func (e *Email) IsSent() bool {
+	return e.Status == Sent
+}
+
+// CanResend returns true if this email is waiting to be sent or has failed.
+func (e *Email) CanResend() bool {
+	return e.Status == Pending || e.Status == Failed
+}
+
+// StatusDisplay returns a string representation of the email status.
+func (e *Email) StatusDisplay() string {
+	switch e.Status {
+	case Pending:
+		return "Pending"
+	case Sending:
+		return "Sending"
+	case Failed:
+		return "Failed"
+	case Sent:
+		return "Sent"
+	}
+	return ""
+}
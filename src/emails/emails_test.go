@@ -0,0 +1,199 @@
+// Tests for the emails package
+package emails
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail/adapters/memory"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+var testSubject = "Queued"
+
+// failSender fails to send all mail, as a mail service might when unavailable.
+type failSender struct{}
+
+func (s failSender) Send(email *mail.Email) error {
+	return errors.New("service unavailable")
+}
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("emails: Setup db failed %s", err)
+	}
+}
+
+// testMail returns a new email ready to queue.
+func testMail() *mail.Email {
+	m := mail.New("to@example.com")
+	m.Subject = testSubject
+	m.Body = "<p>Hello</p>"
+	m.Text = "Hello"
+	m.Attach("hello.txt", []byte("hello"))
+	return m
+}
+
+// TestQueue tests mail is stored then sent by Process.
+func TestQueue(t *testing.T) {
+	service := memory.New("from@example.com")
+	mail.Service = service
+	defer func() { mail.Service = nil }()
+
+	q := &Queue{}
+	err := q.Add(testMail())
+	if err != nil {
+		t.Fatalf("emails: Add failed :%s", err)
+	}
+
+	email, err := FindFirst("subject=?", testSubject)
+	if err != nil {
+		t.Fatalf("emails: Add email not found :%s", err)
+	}
+	if email.Status != Pending || email.Recipients != "to@example.com" {
+		t.Fatalf("emails: Add unexpected email status:%d recipients:%s", email.Status, email.Recipients)
+	}
+	if service.Last() != nil {
+		t.Fatalf("emails: Add sent email before processing")
+	}
+
+	sent, err := Process()
+	if err != nil || sent != 1 {
+		t.Fatalf("emails: Process failed sent:%d err:%s", sent, err)
+	}
+
+	m := service.Last()
+	if m == nil || m.Subject != testSubject || m.Text != "Hello" || len(m.Attachments) != 1 {
+		t.Fatalf("emails: Process sent unexpected email %v", m)
+	}
+
+	email, err = Find(email.ID)
+	if err != nil {
+		t.Fatalf("emails: Process email not found :%s", err)
+	}
+	if !email.IsSent() || email.Attempts != 1 || email.SentAt.IsZero() {
+		t.Fatalf("emails: Process email not marked sent status:%d attempts:%d", email.Status, email.Attempts)
+	}
+
+	// The content of sent mail is removed
+	stored, err := email.Mail()
+	if err != nil || stored.Subject != testSubject || stored.Body != "" || stored.Text != "" || stored.Attachments[0].Data != nil {
+		t.Fatalf("emails: Process did not remove content of sent email %v %s", stored, err)
+	}
+
+	// Sent mail is not sent again
+	sent, err = Process()
+	if err != nil || sent != 0 {
+		t.Fatalf("emails: Process sent mail again sent:%d err:%s", sent, err)
+	}
+	err = email.Resend()
+	if err == nil || len(service.Sent()) != 1 {
+		t.Fatalf("emails: Resend sent mail again")
+	}
+}
+
+// TestClaim tests mail loaded by two workers is only sent once.
+func TestClaim(t *testing.T) {
+	service := memory.New("from@example.com")
+	mail.Service = service
+	defer func() { mail.Service = nil }()
+
+	m := testMail()
+	m.Subject = "Claim"
+	err := (&Queue{}).Add(m)
+	if err != nil {
+		t.Fatalf("emails: Add failed :%s", err)
+	}
+
+	first, err := FindFirst("subject=?", "Claim")
+	if err != nil {
+		t.Fatalf("emails: Claim email not found :%s", err)
+	}
+	second, err := Find(first.ID)
+	if err != nil {
+		t.Fatalf("emails: Claim email not found :%s", err)
+	}
+
+	err = first.Deliver()
+	if err != nil {
+		t.Fatalf("emails: Deliver failed :%s", err)
+	}
+	err = second.Deliver()
+	if err != ErrClaimed || len(service.Sent()) != 1 {
+		t.Fatalf("emails: Deliver sent claimed email again sent:%d err:%v", len(service.Sent()), err)
+	}
+}
+
+// TestRetry tests failed mail is retried with back off, then marked as failed.
+func TestRetry(t *testing.T) {
+	mail.Service = failSender{}
+	defer func() { mail.Service = nil }()
+
+	q := &Queue{Synchronous: true}
+	m := testMail()
+	m.Subject = "Retry"
+	err := q.Add(m)
+	if err == nil {
+		t.Fatalf("emails: Add synchronous did not return error")
+	}
+
+	email, err := FindFirst("subject=?", "Retry")
+	if err != nil {
+		t.Fatalf("emails: Retry email not found :%s", err)
+	}
+	if email.Status != Pending || email.Attempts != 1 || email.LastError != "service unavailable" {
+		t.Fatalf("emails: Retry unexpected email status:%d attempts:%d error:%s", email.Status, email.Attempts, email.LastError)
+	}
+	if !email.NextAttemptAt.After(time.Now().UTC()) {
+		t.Fatalf("emails: Retry next attempt not delayed %s", email.NextAttemptAt)
+	}
+
+	// The email is not due until the back off has passed
+	due, err := FindAll(WhereDue())
+	if err != nil || len(due) != 0 {
+		t.Fatalf("emails: Retry email due before back off %d %v", len(due), err)
+	}
+
+	for i := email.Attempts; i < MaxAttempts; i++ {
+		email.Deliver()
+	}
+	email, err = Find(email.ID)
+	if err != nil {
+		t.Fatalf("emails: Retry email not found :%s", err)
+	}
+	if email.Status != Failed || email.Attempts != MaxAttempts {
+		t.Fatalf("emails: Retry email not failed status:%d attempts:%d", email.Status, email.Attempts)
+	}
+
+	// Resend resets the attempts
+	mail.Service = memory.New("from@example.com")
+	err = email.Resend()
+	if err != nil || !email.IsSent() || email.Attempts != 1 {
+		t.Fatalf("emails: Resend failed status:%d attempts:%d err:%s", email.Status, email.Attempts, err)
+	}
+}
+
+// TestAddInvalid tests incomplete mail is not queued.
+func TestAddInvalid(t *testing.T) {
+	err := (&Queue{}).Add(mail.New("to@example.com"))
+	if err == nil {
+		t.Fatalf("emails: Add accepted email without subject")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := map[int64]time.Duration{
+		0: 0,
+		1: time.Minute,
+		2: 2 * time.Minute,
+		4: 8 * time.Minute,
+	}
+	for attempts, want := range tests {
+		if got := Backoff(attempts); got != want {
+			t.Fatalf("emails: Backoff(%d) expected:%s got:%s", attempts, want, got)
+		}
+	}
+}
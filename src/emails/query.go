@@ -0,0 +1,102 @@
+package emails
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "emails"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc"
+)
+
+// NewWithColumns creates a new email instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Email {
+
+	email := New()
+	email.ID = resource.ValidateInt(cols["id"])
+	email.CreatedAt = resource.ValidateTime(cols["created_at"])
+	email.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	email.Status = resource.ValidateInt(cols["status"])
+	email.Recipients = resource.ValidateString(cols["recipients"])
+	email.Subject = resource.ValidateString(cols["subject"])
+	email.Message = resource.ValidateString(cols["message"])
+	email.Attempts = resource.ValidateInt(cols["attempts"])
+	email.LastError = resource.ValidateString(cols["last_error"])
+	email.NextAttemptAt = resource.ValidateTime(cols["next_attempt_at"])
+	email.SentAt = resource.ValidateTime(cols["sent_at"])
+
+	return email
+}
+
+// New creates and initialises a new email instance.
+func New() *Email {
+	email := &Email{}
+	email.CreatedAt = time.Now()
+	email.UpdatedAt = time.Now()
+	email.TableName = TableName
+	email.KeyName = KeyName
+	return email
+}
+
+// FindFirst fetches a single email record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Email, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single email record from the database by id.
+func Find(id int64) (*Email, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all email records matching this query from the database.
+func FindAll(q *query.Query) ([]*Email, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of emails constructed from the results
+	var emails []*Email
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		emails = append(emails, p)
+	}
+
+	return emails, nil
+}
+
+// Query returns a new query for emails with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for emails with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// WhereDue returns a query for pending emails due to be sent, and emails which
+// were claimed but have not been sent within SendTimeout, oldest first.
+func WhereDue() *query.Query {
+	now := query.TimeString(time.Now().UTC())
+	return Query().Where("status IN (?,?) AND next_attempt_at<=?", Pending, Sending, now).Order("next_attempt_at asc")
+}
@@ -0,0 +1,113 @@
+package emails
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server/log"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// BatchSize is the maximum number of emails sent by the worker each time it runs.
+const BatchSize = 50
+
+// wake is signalled when mail is queued, so that the worker sends it without waiting.
+var wake = make(chan struct{}, 1)
+
+// Queue stores mail in the database to be delivered by the worker,
+// it should be set as the mail.Queue on startup.
+type Queue struct {
+	// Synchronous queues deliver mail as it is added and return any error,
+	// which is useful in tests
+	Synchronous bool
+}
+
+// Add stores the rendered email in the queue to be delivered.
+func (q *Queue) Add(m *mail.Email) error {
+	if len(m.Recipients) == 0 || m.Subject == "" || m.Body == "" {
+		return errors.New("emails: attempt to queue invalid email")
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"status":          fmt.Sprintf("%d", Pending),
+		"recipients":      strings.Join(m.Recipients, ", "),
+		"subject":         m.Subject,
+		"message":         string(data),
+		"attempts":        "0",
+		"next_attempt_at": query.TimeString(time.Now().UTC()),
+	}
+
+	id, err := New().Create(params)
+	if err != nil {
+		return err
+	}
+
+	if q.Synchronous {
+		email, err := Find(id)
+		if err != nil {
+			return err
+		}
+		return email.Deliver()
+	}
+
+	// Wake the worker if it is not already due to run
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Process delivers the pending emails which are due, returning the number sent.
+func Process() (int, error) {
+	due, err := FindAll(WhereDue().Limit(BatchSize))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range due {
+		err = email.Deliver()
+		if err == ErrClaimed {
+			// Another worker is delivering this email
+			continue
+		}
+		if err != nil {
+			log.Error(log.V{"msg": "failed to send email", "email_id": email.ID, "attempts": email.Attempts, "status": email.StatusDisplay(), "error": err})
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// StartWorker starts a background worker which delivers queued email as it
+// is added, and checks for emails due to be retried at interval.
+func StartWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			_, err := Process()
+			if err != nil {
+				log.Error(log.V{"msg": "failed to process email queue", "error": err})
+			}
+
+			select {
+			case <-ticker.C:
+			case <-wake:
+			}
+		}
+	}()
+}
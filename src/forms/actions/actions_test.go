@@ -0,0 +1,213 @@
+package formactions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var testNames = []string{"Contact", "Enquiries"}
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("formactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("formactions: error creating admin %s", err)
+	}
+}
+
+// Test GET /forms/create
+func TestShowCreateForm(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/forms/create", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("formactions: error handling HandleCreateShow %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := "resource-update-form"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("formactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+	}
+
+}
+
+// Test POST /forms/create
+func TestCreateForm(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", testNames[0])
+	form.Add("status", "100")
+	form.Add("fields", "Name | text | required\nTopic | select")
+
+	// Test a select without options is rejected
+	w, err := apptest.Request(router, "POST", "/forms/create", form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("formactions: unexpected response for HandleCreate with invalid fields %v %d", err, w.Code)
+	}
+
+	form.Set("fields", "Name | text | required\nTopic | select | Sales, Support")
+	w, err = apptest.Request(router, "POST", "/forms/create", form, admin)
+	if err != nil {
+		t.Fatalf("formactions: error handling HandleCreate %s", err)
+	}
+
+	// Test we get a redirect after create
+	if w.Code != http.StatusFound {
+		t.Fatalf("formactions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	allForms, err := forms.FindAll(forms.Query().Order("id desc"))
+	if err != nil || len(allForms) == 0 {
+		t.Fatalf("formactions: error finding created form %s", err)
+	}
+	newForm := allForms[0]
+	if newForm.ID != 1 || newForm.Name != testNames[0] || len(newForm.FieldList()) != 2 {
+		t.Fatalf("formactions: error with created form values: %v %s", newForm.ID, newForm.Name)
+	}
+}
+
+// Test GET /forms
+func TestListForms(t *testing.T) {
+
+	// Test listing forms as anon
+	w, err := apptest.Request(router, "GET", "/forms", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("formactions: unexpected response for HandleIndex as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "GET", "/forms", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("formactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := testNames[0]
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("formactions: unexpected response for HandleIndex expected:%s got:%s", pattern, w.Body.String())
+	}
+
+}
+
+// Test of GET /forms/1
+func TestShowForm(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/forms/1", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("formactions: error handling HandleShow %v %d", err, w.Code)
+	}
+
+	// Test the body contains a preview of the form
+	pattern := `name="field_topic"`
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("formactions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test forms are embedded in pages with shortcodes
+func TestEmbedForm(t *testing.T) {
+
+	page, err := apptest.CreatePage(map[string]string{
+		"url":  "/contact",
+		"text": `<p>Get in touch</p>[[form id="1"]]`,
+	})
+	if err != nil {
+		t.Fatalf("formactions: error creating page %s", err)
+	}
+
+	w, err := apptest.Request(router, "GET", page.URL, nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("formactions: error showing page %v %d", err, w.Code)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `action="/forms/1/submit"`) || !strings.Contains(body, fmt.Sprintf(`name="%s"`, forms.Honeypot)) {
+		t.Fatalf("formactions: form not embedded in page got:%s", body)
+	}
+	if strings.Contains(body, "[[form") {
+		t.Fatalf("formactions: shortcode not replaced in page got:%s", body)
+	}
+}
+
+// Test POST /forms/123/update
+func TestUpdateForm(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", testNames[1])
+	form.Add("notify", "sales@example.com")
+
+	w, err := apptest.Request(router, "POST", "/forms/1/update", form, admin)
+	if err != nil {
+		t.Fatalf("formactions: error handling HandleUpdateForm %s", err)
+	}
+
+	// Test we get a redirect after update (to the form concerned)
+	if w.Code != http.StatusFound {
+		t.Fatalf("formactions: unexpected response code for HandleUpdateForm expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	f, err := forms.Find(1)
+	if err != nil {
+		t.Fatalf("formactions: error finding updated form %s", err)
+	}
+	if f.Name != testNames[1] || f.Notify != "sales@example.com" || len(f.FieldList()) != 2 {
+		t.Fatalf("formactions: error with updated form values: %v", f)
+	}
+
+}
+
+// Test of POST /forms/123/destroy
+func TestDeleteForm(t *testing.T) {
+
+	// Test deleting the form created above as anon
+	w, err := apptest.Request(router, "POST", "/forms/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("formactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	// Now test deleting the form as admin
+	w, err = apptest.Request(router, "POST", "/forms/1/destroy", nil, admin)
+	if err != nil {
+		t.Fatalf("formactions: error handling HandleDestroy %s", err)
+	}
+
+	// Test we get a redirect after delete
+	if w.Code != http.StatusFound {
+		t.Fatalf("formactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	_, err = forms.Find(1)
+	if err == nil {
+		t.Fatalf("formactions: form found after HandleDestroy")
+	}
+
+}
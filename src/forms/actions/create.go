@@ -0,0 +1,79 @@
+package formactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleCreateShow serves the create form via GET for forms.
+func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
+
+	form := forms.New()
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err := can.Create(form, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("form", form)
+	return view.Render()
+}
+
+// HandleCreate handles the POST of the create form for forms
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	form := forms.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err = can.Create(form, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Setup context
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Check the field definitions and notify addresses
+	err = forms.ValidateFields(params.Map())
+	if err != nil {
+		return server.BadRequestError(err, "Invalid form", err.Error())
+	}
+
+	// Validate the params, removing any we don't accept
+	formParams := form.ValidateParams(params.Map(), forms.AllowedParams())
+
+	id, err := form.Create(formParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the new form
+	form, err = forms.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, form.IndexURL())
+}
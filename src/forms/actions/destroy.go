@@ -0,0 +1,48 @@
+package formactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleDestroy responds to /forms/n/destroy by deleting the form.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the form
+	form, err := forms.Find(params.GetInt(forms.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy form
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(form, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the form
+	form.Destroy()
+
+	// Redirect to forms root
+	return server.Redirect(w, r, form.IndexURL())
+
+}
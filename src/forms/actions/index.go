@@ -0,0 +1,66 @@
+package formactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleIndex displays a list of forms.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list form
+	user := session.CurrentUser(w, r)
+	err := can.List(forms.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Build a query
+	q := forms.Query()
+
+	// Order by required order, or default to id asc
+	switch params.Get("order") {
+
+	case "1":
+		q.Order("created_at desc")
+
+	case "2":
+		q.Order("updated_at desc")
+
+	default:
+		q.Order("id asc")
+	}
+
+	// Filter if requested
+	filter := params.Get("filter")
+	if len(filter) > 0 {
+		q.Where(resource.ILike("name"), filter)
+	}
+
+	// Fetch the forms
+	results, err := forms.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("filter", filter)
+	view.AddKey("forms", results)
+	return view.Render()
+}
@@ -0,0 +1,57 @@
+package formactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+)
+
+// HandleShow displays a single form with a preview.
+func HandleShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the form
+	form, err := forms.Find(params.GetInt(forms.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access
+	user := session.CurrentUser(w, r)
+	err = can.Show(form, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render a preview of the form as embedded in pages
+	preview, err := form.Render()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Count the unread submissions for the inbox link
+	unread, err := submissions.Where("form_id=? AND status=?", form.ID, submissions.Unread).Count()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("form", form)
+	view.AddKey("preview", preview)
+	view.AddKey("unread", unread)
+	return view.Render()
+}
@@ -0,0 +1,88 @@
+package formactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleUpdateShow renders the form to update a form.
+func HandleUpdateShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the form
+	form, err := forms.Find(params.GetInt(forms.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise update form
+	user := session.CurrentUser(w, r)
+	err = can.Update(form, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("form", form)
+	return view.Render()
+}
+
+// HandleUpdate handles the POST of the form to update a form
+func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the form
+	form, err := forms.Find(params.GetInt(forms.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update form
+	user := session.CurrentUser(w, r)
+	err = can.Update(form, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Check the field definitions and notify addresses
+	err = forms.ValidateFields(params.Map())
+	if err != nil {
+		return server.BadRequestError(err, "Invalid form", err.Error())
+	}
+
+	// Validate the params, removing any we don't accept
+	formParams := form.ValidateParams(params.Map(), forms.AllowedParams())
+
+	err = form.Update(formParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to form
+	return server.Redirect(w, r, form.ShowURL())
+}
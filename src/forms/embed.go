@@ -0,0 +1,33 @@
+package forms
+
+import (
+	"html/template"
+
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+)
+
+// Shortcode returns the html for shortcodes which embed forms in content such as
+// [[form id="1"]], shortcodes for forms which are missing or unpublished are removed.
+func Shortcode(s *shortcodes.Shortcode) (template.HTML, error) {
+	form, err := Find(s.AttrInt("id", 0))
+	if err != nil || !form.IsPublished() {
+		return "", nil
+	}
+	return form.Render()
+}
+
+// Render returns the html for the form to embed in a page.
+func (f *Form) Render() (template.HTML, error) {
+	view := view.NewWithPath("", nil)
+	view.Template("forms/views/embed.html.got")
+	view.AddKey("form", f)
+	view.AddKey("fields", f.FieldList())
+	view.AddKey("honeypot", Honeypot)
+	html, err := view.RenderToString()
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(html), nil
+}
@@ -0,0 +1,224 @@
+// Package forms represents the form resource, forms are defined by admins
+// and embedded in pages to collect submissions from visitors.
+package forms
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// Form handles saving and retreiving forms from the database
+type Form struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// status.ResourceStatus defines a status field and associated behaviour
+	status.ResourceStatus
+
+	Name    string
+	Summary string
+	Fields  string
+	Notify  string
+	Message string
+}
+
+// Field types which may be used in forms.
+const (
+	Text     = "text"
+	Email    = "email"
+	Select   = "select"
+	Checkbox = "checkbox"
+	Textarea = "textarea"
+)
+
+// Honeypot is the name of a hidden field in embedded forms, which is left empty by
+// visitors but often filled in by spam bots.
+const Honeypot = "website"
+
+// MaxLength is the maximum length of a value submitted for a field.
+const MaxLength = 10000
+
+// Field is a field in a form, defined in Form.Fields with one field per line as:
+// Label | type | required | option, option
+// The type defaults to text, and options are used for select fields.
+type Field struct {
+	Name     string
+	Label    string
+	Type     string
+	Required bool
+	Options  []string
+}
+
+// InputName returns the name used for the field input, prefixed so that
+// it cannot clash with other params.
+func (f Field) InputName() string {
+	return "field_" + f.Name
+}
+
+// nameRegexp matches the characters replaced to form field names from labels.
+var nameRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ParseFields parses the field definitions given, one per line.
+func ParseFields(definition string) ([]Field, error) {
+	var fields []Field
+	names := make(map[string]bool)
+
+	for i, line := range strings.Split(definition, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.Split(line, "|")
+		for j := range parts {
+			parts[j] = strings.TrimSpace(parts[j])
+		}
+
+		f := Field{Label: parts[0], Type: Text}
+		f.Name = strings.Trim(nameRegexp.ReplaceAllString(strings.ToLower(f.Label), "_"), "_")
+		if f.Name == "" {
+			return nil, fmt.Errorf("forms: line %d has no label", i+1)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("forms: line %d repeats the label %s", i+1, f.Label)
+		}
+		names[f.Name] = true
+
+		if len(parts) > 1 && parts[1] != "" {
+			f.Type = strings.ToLower(parts[1])
+		}
+		switch f.Type {
+		case Text, Email, Select, Checkbox, Textarea:
+		default:
+			return nil, fmt.Errorf("forms: line %d has invalid type %s, it should be text, email, select, checkbox or textarea", i+1, f.Type)
+		}
+
+		for j := 2; j < len(parts); j++ {
+			p := parts[j]
+			if strings.ToLower(p) == "required" {
+				f.Required = true
+				continue
+			}
+			for _, o := range strings.Split(p, ",") {
+				o = strings.TrimSpace(o)
+				if o != "" {
+					f.Options = append(f.Options, o)
+				}
+			}
+		}
+
+		if f.Type == Select && len(f.Options) == 0 {
+			return nil, fmt.Errorf("forms: line %d is a select with no options", i+1)
+		}
+
+		fields = append(fields, f)
+	}
+
+	return fields, nil
+}
+
+// FieldList returns the fields defined for this form, invalid definitions are
+// rejected on save so any error here is ignored.
+func (f *Form) FieldList() []Field {
+	fields, _ := ParseFields(f.Fields)
+	return fields
+}
+
+// NotifyAddresses returns the addresses notified of submissions.
+func (f *Form) NotifyAddresses() []string {
+	var addresses []string
+	for _, a := range strings.Split(f.Notify, ",") {
+		a = strings.TrimSpace(a)
+		if a != "" {
+			addresses = append(addresses, a)
+		}
+	}
+	return addresses
+}
+
+// ValidateFields returns an error if the field definitions or notify addresses
+// in the params given are invalid.
+func ValidateFields(params map[string]string) error {
+	_, err := ParseFields(params["fields"])
+	if err != nil {
+		return err
+	}
+
+	for _, a := range (&Form{Notify: params["notify"]}).NotifyAddresses() {
+		_, err = mail.ParseAddress(a)
+		if err != nil {
+			return fmt.Errorf("forms: invalid notify address %s", a)
+		}
+	}
+
+	return nil
+}
+
+// Values returns the submitted values for each field of the form, using
+// the param function given to read them, or an error describing the first
+// invalid value.
+func (f *Form) Values(param func(string) string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	for _, field := range f.FieldList() {
+		v := strings.TrimSpace(param(field.InputName()))
+		if len(v) > MaxLength {
+			return nil, fmt.Errorf("%s is too long", field.Label)
+		}
+
+		switch field.Type {
+		case Checkbox:
+			if v != "" {
+				v = "yes"
+			}
+		case Email:
+			if v != "" {
+				_, err := mail.ParseAddress(v)
+				if err != nil {
+					return nil, fmt.Errorf("%s should be an email address", field.Label)
+				}
+			}
+		case Select:
+			if v != "" && !contains(field.Options, v) {
+				return nil, fmt.Errorf("%s should be one of %s", field.Label, strings.Join(field.Options, ", "))
+			}
+		}
+
+		if field.Required && v == "" {
+			return nil, fmt.Errorf("%s is required", field.Label)
+		}
+
+		if field.Type == Checkbox && v == "" {
+			v = "no"
+		}
+
+		values[field.Name] = v
+	}
+
+	return values, nil
+}
+
+// ReplyTo returns the first email submitted in values, for replies to notifications.
+func (f *Form) ReplyTo(values map[string]string) string {
+	for _, field := range f.FieldList() {
+		if field.Type == Email && values[field.Name] != "" {
+			return values[field.Name]
+		}
+	}
+	return ""
+}
+
+// contains returns true if s is in list.
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+// Tests for the forms package
+package forms
+
+import (
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+)
+
+var testName = "Contact"
+
+var testFields = `Name | text | required
+Email | email | required
+Topic | select | Sales, Support
+Subscribe | checkbox
+Your Message | textarea`
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("forms: Setup db failed %s", err)
+	}
+}
+
+// Test Create method
+func TestCreateForm(t *testing.T) {
+	formParams := map[string]string{
+		"name":   testName,
+		"fields": testFields,
+		"status": "100",
+	}
+
+	id, err := New().Create(formParams)
+	if err != nil {
+		t.Fatalf("forms: Create form failed :%s", err)
+	}
+
+	form, err := Find(id)
+	if err != nil {
+		t.Fatalf("forms: Create form find failed")
+	}
+
+	if form.Name != testName || len(form.FieldList()) != 5 {
+		t.Fatalf("forms: Create form failed expected:%s got:%s fields:%d", testName, form.Name, len(form.FieldList()))
+	}
+
+}
+
+// Test Index (List) method
+func TestListForms(t *testing.T) {
+
+	results, err := FindAll(Published())
+	if err != nil {
+		t.Fatalf("forms: List no form found :%s", err)
+	}
+
+	if len(results) < 1 {
+		t.Fatalf("forms: List no forms found :%s", err)
+	}
+
+}
+
+// Test Update method
+func TestUpdateForm(t *testing.T) {
+
+	form, err := FindFirst("name=?", testName)
+	if err != nil {
+		t.Fatalf("forms: Update no form found :%s", err)
+	}
+
+	notify := "sales@example.com, support@example.com"
+	err = form.Update(map[string]string{"notify": notify})
+	if err != nil {
+		t.Fatalf("forms: Update form failed :%s", err)
+	}
+
+	form, err = Find(form.ID)
+	if err != nil {
+		t.Fatalf("forms: Update form fetch failed :%s", err)
+	}
+
+	addresses := form.NotifyAddresses()
+	if len(addresses) != 2 || addresses[1] != "support@example.com" {
+		t.Fatalf("forms: Update form notify failed :%v", addresses)
+	}
+
+}
+
+// TestEmbed tests shortcodes for missing forms are removed.
+func TestEmbed(t *testing.T) {
+	got, err := Shortcode(shortcodes.Parse(`[[form id=&#34;999&#34;]]`))
+	if err != nil || got != "" {
+		t.Fatalf("forms: Shortcode unexpected result for missing form :%s", got)
+	}
+}
+
+// Test Destroy method
+func TestDestroyForm(t *testing.T) {
+
+	results, err := FindAll(Query())
+	if err != nil || len(results) == 0 {
+		t.Fatalf("forms: Destroy no form found :%s", err)
+	}
+	count := len(results)
+
+	err = results[0].Destroy()
+	if err != nil {
+		t.Fatalf("forms: Destroy form failed :%s", err)
+	}
+
+	results, err = FindAll(Query())
+	if err != nil {
+		t.Fatalf("forms: Destroy error getting results :%s", err)
+	}
+
+	if len(results) != count-1 {
+		t.Fatalf("forms: Destroy form count wrong :%d", len(results))
+	}
+
+}
+
+func TestParseFields(t *testing.T) {
+	fields, err := ParseFields(testFields)
+	if err != nil {
+		t.Fatalf("forms: ParseFields failed :%s", err)
+	}
+
+	f := fields[2]
+	if f.Name != "topic" || f.Type != Select || f.Required || len(f.Options) != 2 || f.Options[1] != "Support" {
+		t.Fatalf("forms: ParseFields unexpected select :%v", f)
+	}
+
+	f = fields[4]
+	if f.Name != "your_message" || f.Type != Textarea || f.InputName() != "field_your_message" {
+		t.Fatalf("forms: ParseFields unexpected textarea :%v", f)
+	}
+
+	invalid := []string{
+		"Name | password",
+		"Name\nName | email",
+		"Topic | select | required",
+		" | text",
+	}
+	for _, d := range invalid {
+		_, err = ParseFields(d)
+		if err == nil {
+			t.Fatalf("forms: ParseFields accepted invalid definition :%s", d)
+		}
+	}
+
+	err = ValidateFields(map[string]string{"fields": testFields, "notify": "a@example.com, not an address"})
+	if err == nil {
+		t.Fatalf("forms: ValidateFields accepted invalid notify address")
+	}
+}
+
+func TestValues(t *testing.T) {
+	form := New()
+	form.Fields = testFields
+
+	params := map[string]string{
+		"field_name":         "Alice",
+		"field_email":        "alice@example.com",
+		"field_topic":        "Support",
+		"field_subscribe":    "on",
+		"field_your_message": " Hello ",
+	}
+	param := func(k string) string { return params[k] }
+
+	values, err := form.Values(param)
+	if err != nil {
+		t.Fatalf("forms: Values failed :%s", err)
+	}
+	if values["subscribe"] != "yes" || values["your_message"] != "Hello" || form.ReplyTo(values) != "alice@example.com" {
+		t.Fatalf("forms: Values unexpected values :%v", values)
+	}
+
+	invalid := map[string]string{
+		"field_name":  "",
+		"field_email": "alice",
+		"field_topic": "Billing",
+	}
+	for k, v := range invalid {
+		old := params[k]
+		params[k] = v
+		_, err = form.Values(param)
+		if err == nil {
+			t.Fatalf("forms: Values accepted invalid %s :%s", k, v)
+		}
+		params[k] = old
+	}
+}
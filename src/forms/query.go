@@ -0,0 +1,106 @@
+package forms
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "forms"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "name asc, id desc"
+)
+
+// AllowedParams returns an array of allowed param keys for Update and Create.
+func AllowedParams() []string {
+	return []string{"status", "name", "summary", "fields", "notify", "message"}
+}
+
+// NewWithColumns creates a new form instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Form {
+
+	form := New()
+	form.ID = resource.ValidateInt(cols["id"])
+	form.CreatedAt = resource.ValidateTime(cols["created_at"])
+	form.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	form.Status = resource.ValidateInt(cols["status"])
+	form.Name = resource.ValidateString(cols["name"])
+	form.Summary = resource.ValidateString(cols["summary"])
+	form.Fields = resource.ValidateString(cols["fields"])
+	form.Notify = resource.ValidateString(cols["notify"])
+	form.Message = resource.ValidateString(cols["message"])
+
+	return form
+}
+
+// New creates and initialises a new form instance.
+func New() *Form {
+	form := &Form{}
+	form.CreatedAt = time.Now()
+	form.UpdatedAt = time.Now()
+	form.TableName = TableName
+	form.KeyName = KeyName
+	form.Status = status.Draft
+	form.Message = "Thank you, we'll be in touch soon."
+	return form
+}
+
+// FindFirst fetches a single form record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Form, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single form record from the database by id.
+func Find(id int64) (*Form, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all form records matching this query from the database.
+func FindAll(q *query.Query) ([]*Form, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of forms constructed from the results
+	var forms []*Form
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		forms = append(forms, p)
+	}
+
+	return forms, nil
+}
+
+// Query returns a new query for forms with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for forms with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Published returns a query for all forms with status >= published.
+func Published() *query.Query {
+	return Query().Where("status>=?", status.Published)
+}
@@ -0,0 +1,176 @@
+package galleryactions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("galleryactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("galleryactions: error creating admin %s", err)
+	}
+}
+
+// Test POST /galleries/create
+func TestCreateGallery(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", "Events")
+	form.Add("status", "100")
+
+	// Test creating the gallery as anon
+	w, err := apptest.Request(router, "POST", "/galleries/create", form, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("galleryactions: unexpected response for HandleCreate as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "POST", "/galleries/create", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("galleryactions: error handling HandleCreate %v %d", err, w.Code)
+	}
+
+	gallery, err := galleries.Find(1)
+	if err != nil || gallery.Name != "Events" || gallery.AuthorID != admin.ID {
+		t.Fatalf("galleryactions: error with created gallery values: %v %s", gallery, err)
+	}
+}
+
+// Test images are added to galleries, captioned, reordered and shown in pages
+func TestGalleryImages(t *testing.T) {
+
+	gallery, err := apptest.CreateGallery(map[string]string{"name": "Party"})
+	if err != nil {
+		t.Fatalf("galleryactions: error creating gallery %s", err)
+	}
+	first, err := apptest.CreateImage(nil)
+	if err != nil {
+		t.Fatalf("galleryactions: error creating image %s", err)
+	}
+	second, err := apptest.CreateImage(nil)
+	if err != nil {
+		t.Fatalf("galleryactions: error creating image %s", err)
+	}
+
+	// Add the images with the gallery form
+	path := fmt.Sprintf("/galleries/%d/update", gallery.ID)
+	for _, image := range []int64{first.ID, second.ID} {
+		form := url.Values{}
+		form.Add("name", gallery.Name)
+		form.Add("add_image_id", fmt.Sprintf("%d", image))
+		w, err := apptest.Request(router, "POST", path, form, admin)
+		if err != nil || w.Code != http.StatusFound {
+			t.Fatalf("galleryactions: error handling HandleUpdate %v %d", err, w.Code)
+		}
+	}
+
+	items, err := gallery.AllItems()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("galleryactions: images not added to gallery %v %s", items, err)
+	}
+
+	// Set a caption on the second image
+	form := url.Values{}
+	form.Add("name", gallery.Name)
+	form.Add(fmt.Sprintf("caption_%d", items[1].ID), "The <b>second</b>")
+	w, err := apptest.Request(router, "POST", path, form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("galleryactions: error handling HandleUpdate %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", path, nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), fmt.Sprintf(`name="caption_%d"`, items[1].ID)) {
+		t.Fatalf("galleryactions: error handling HandleUpdateShow %v %d", err, w.Code)
+	}
+
+	// Move the second image first, only admins may reorder
+	form = url.Values{}
+	form.Add("ids", fmt.Sprintf("%d,%d", items[1].ID, items[0].ID))
+	reorder := fmt.Sprintf("/galleries/%d/reorder", gallery.ID)
+	w, err = apptest.Request(router, "POST", reorder, form, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("galleryactions: unexpected response for HandleReorder as anon, expected failure")
+	}
+	w, err = apptest.Request(router, "POST", reorder, form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("galleryactions: error handling HandleReorder %v %d", err, w.Code)
+	}
+
+	// Show the gallery in a page with a shortcode
+	page, err := apptest.CreatePage(map[string]string{"text": `<p>[[gallery name="Party"]]</p>`})
+	if err != nil {
+		t.Fatalf("galleryactions: error creating page %s", err)
+	}
+	w, err = apptest.Request(router, "GET", page.URL, nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("galleryactions: error showing page %v %d", err, w.Code)
+	}
+
+	body := w.Body.String()
+	link := fmt.Sprintf(`<a href="%s" class="gallery-link" data-lightbox="gallery-%d" data-caption="The &lt;b&gt;second&lt;/b&gt;"`, second.Path, gallery.ID)
+	if !strings.Contains(body, link) || !strings.Contains(body, "<figcaption>The &lt;b&gt;second&lt;/b&gt;</figcaption>") {
+		t.Fatalf("galleryactions: gallery not shown in page got:%s", body)
+	}
+	if strings.Index(body, second.Path) > strings.Index(body, first.Path) {
+		t.Fatalf("galleryactions: gallery images not in order got:%s", body)
+	}
+}
+
+// Test GET /galleries
+func TestListGalleries(t *testing.T) {
+
+	// Test listing galleries as anon
+	w, err := apptest.Request(router, "GET", "/galleries", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("galleryactions: unexpected response for HandleIndex as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "GET", "/galleries", nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Events") {
+		t.Fatalf("galleryactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+}
+
+// Test POST /galleries/1/destroy
+func TestDeleteGallery(t *testing.T) {
+
+	w, err := apptest.Request(router, "POST", "/galleries/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("galleryactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "POST", "/galleries/1/destroy", nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("galleryactions: error handling HandleDestroy %v %d", err, w.Code)
+	}
+
+	_, err = galleries.Find(1)
+	if err == nil {
+		t.Fatalf("galleryactions: gallery not destroyed")
+	}
+}
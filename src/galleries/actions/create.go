@@ -0,0 +1,75 @@
+package galleryactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleCreateShow serves the create form via GET for galleries.
+func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
+
+	gallery := galleries.New()
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err := can.Create(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("gallery", gallery)
+	return view.Render()
+}
+
+// HandleCreate handles the POST of the create form for galleries
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	gallery := galleries.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err = can.Create(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Setup context
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Validate the params, removing any we don't accept
+	galleryParams := gallery.ValidateParams(params.Map(), galleries.AllowedParams())
+	galleryParams["author_id"] = fmt.Sprintf("%d", user.ID)
+
+	id, err := gallery.Create(galleryParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the new gallery to add images
+	gallery, err = galleries.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, gallery.UpdateURL())
+}
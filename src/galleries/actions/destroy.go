@@ -0,0 +1,50 @@
+package galleryactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleDestroy responds to /galleries/n/destroy by deleting the gallery.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the gallery
+	gallery, err := galleries.Find(params.GetInt(galleries.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy gallery
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the gallery and its items
+	err = gallery.Destroy()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to galleries root
+	return server.Redirect(w, r, gallery.IndexURL())
+}
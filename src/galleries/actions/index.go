@@ -0,0 +1,63 @@
+package galleryactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleIndex displays a list of galleries.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list gallery
+	user := session.CurrentUser(w, r)
+	err := can.List(galleries.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Build a query
+	q := galleries.Query()
+
+	// Order by required order, or default to name
+	switch params.Get("order") {
+
+	case "1":
+		q.Order("created_at desc")
+
+	case "2":
+		q.Order("updated_at desc")
+	}
+
+	// Filter if requested
+	filter := params.Get("filter")
+	if len(filter) > 0 {
+		q.Where(resource.ILike("name"), filter)
+	}
+
+	// Fetch the galleries
+	results, err := galleries.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("filter", filter)
+	view.AddKey("galleries", results)
+	return view.Render()
+}
@@ -0,0 +1,62 @@
+package galleryactions
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleReorder handles the POST of a new order for the images in a gallery, as a
+// list of item ids separated by commas, from drag and drop in the gallery form.
+func HandleReorder(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the gallery
+	gallery, err := galleries.Find(params.GetInt(galleries.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update gallery
+	user := session.CurrentUser(w, r)
+	err = can.Update(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	var ids []int64
+	for _, s := range strings.Split(params.Get("ids"), ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return server.BadRequestError(err, "Invalid order", "Please send a list of gallery image ids.")
+		}
+		ids = append(ids, id)
+	}
+
+	// Update the order of the images in the gallery
+	err = gallery.Reorder(ids)
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Redirect to the gallery form
+	return server.Redirect(w, r, gallery.UpdateURL())
+}
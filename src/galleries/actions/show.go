@@ -0,0 +1,49 @@
+package galleryactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleShow displays a single gallery with a preview.
+func HandleShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the gallery
+	gallery, err := galleries.Find(params.GetInt(galleries.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access
+	user := session.CurrentUser(w, r)
+	err = can.Show(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render a preview of the gallery as shown in pages
+	preview, err := gallery.Render()
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("gallery", gallery)
+	view.AddKey("preview", preview)
+	return view.Render()
+}
@@ -0,0 +1,102 @@
+package galleryactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/galleries"
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+)
+
+// HandleUpdateShow renders the form to update a gallery and its images.
+func HandleUpdateShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the gallery
+	gallery, err := galleries.Find(params.GetInt(galleries.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise update gallery
+	user := session.CurrentUser(w, r)
+	err = can.Update(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Fetch the images in the gallery, and the images which may be added
+	items, err := gallery.AllItems()
+	if err != nil {
+		return server.InternalError(err)
+	}
+	imageList, err := images.FindAll(images.Query())
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("gallery", gallery)
+	view.AddKey("items", items)
+	view.AddKey("images", imageList)
+	return view.Render()
+}
+
+// HandleUpdate handles the POST of the form to update a gallery
+func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the gallery
+	gallery, err := galleries.Find(params.GetInt(galleries.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update gallery
+	user := session.CurrentUser(w, r)
+	err = can.Update(gallery, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Validate the params, removing any we don't accept
+	galleryParams := gallery.ValidateParams(params.Map(), galleries.AllowedParams())
+	delete(galleryParams, "author_id")
+
+	err = gallery.Update(galleryParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Update the captions and alt text of images, removing or adding images
+	err = gallery.UpdateItems(params.Map())
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect back to the gallery form to continue editing
+	return server.Redirect(w, r, gallery.UpdateURL())
+}
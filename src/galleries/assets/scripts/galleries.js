@@ -0,0 +1,55 @@
+/* JS for galleries */
+DOM.Ready(function() {
+    // Reorder images in galleries by drag and drop in the gallery form
+    ActivateGalleryReorder();
+});
+
+// Allow rows of images in the gallery form to be dragged before other images,
+// then post the new order.
+function ActivateGalleryReorder() {
+    var dragged = null;
+
+    DOM.On(".gallery-items tr[draggable]", "dragstart", function(e) {
+        dragged = this;
+        DOM.AddClass(this, "dragging");
+        e.dataTransfer.effectAllowed = "move";
+        e.dataTransfer.setData("text/plain", this.getAttribute("data-id"));
+    });
+
+    DOM.On(".gallery-items tr[draggable]", "dragend", function(e) {
+        DOM.RemoveClass(this, "dragging");
+        DOM.RemoveClass(".gallery-items tr", "drop-target");
+        dragged = null;
+    });
+
+    DOM.On(".gallery-items tr[draggable]", "dragover", function(e) {
+        if (dragged === null || dragged === this) {
+            return;
+        }
+        e.preventDefault();
+        DOM.RemoveClass(".gallery-items tr", "drop-target");
+        DOM.AddClass(this, "drop-target");
+    });
+
+    DOM.On(".gallery-items tr[draggable]", "drop", function(e) {
+        e.preventDefault();
+        if (dragged === null || dragged === this) {
+            return;
+        }
+
+        // Move the dragged row before the target
+        this.parentNode.insertBefore(dragged, this);
+
+        // Post the ids of the images in their new order
+        var ids = [];
+        DOM.Each(".gallery-items tr[draggable]", function(el) {
+            ids.push(el.getAttribute("data-id"));
+        });
+        var url = DOM.First(".gallery-items").getAttribute("data-reorder");
+        var data = "authenticity_token=" + authenticityToken() + "&ids=" + ids.join(",");
+        DOM.Post(url, data, function(request) {}, function(request) {
+            console.log("error reordering gallery:", request);
+            window.location.reload();
+        });
+    });
+}
\ No newline at end of file
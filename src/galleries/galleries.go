@@ -0,0 +1,210 @@
+// Package galleries represents named galleries, collections of images with
+// captions and alt text in their own order, shown by themes and in content.
+package galleries
+
+import (
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// Gallery handles saving and retreiving galleries from the database
+type Gallery struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// status.ResourceStatus defines a status field and associated behaviour
+	status.ResourceStatus
+
+	AuthorID int64
+	Name     string
+	Summary  string
+}
+
+// Items returns the items in the gallery in their order with their images,
+// leaving out images which are missing or unpublished.
+func (g *Gallery) Items() ([]*Item, error) {
+	all, err := g.AllItems()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+	for _, item := range all {
+		if item.Image.IsPublished() {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// AllItems returns the items in the gallery in their order with their images,
+// including unpublished images for editing, items for missing images are left out.
+func (g *Gallery) AllItems() ([]*Item, error) {
+	list, err := FindItems(ItemsFor(g.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+	for _, item := range list {
+		item.Image, err = images.Find(item.ImageID)
+		if err == nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// AddImage adds the image with id to the end of the gallery.
+func (g *Gallery) AddImage(id int64, caption, alt string) error {
+	_, err := images.Find(id)
+	if err != nil {
+		return err
+	}
+
+	count, err := ItemsFor(g.ID).Count()
+	if err != nil {
+		return err
+	}
+
+	_, err = NewItem().Create(map[string]string{
+		"gallery_id": fmt.Sprintf("%d", g.ID),
+		"image_id":   fmt.Sprintf("%d", id),
+		"caption":    caption,
+		"alt":        alt,
+		"sort":       fmt.Sprintf("%d", count),
+	})
+	return err
+}
+
+// UpdateItems updates the items in the gallery from the params of the gallery form,
+// which has caption_n, alt_n and remove_n for each item with id n, and add_image_id
+// for an image to add to the gallery.
+func (g *Gallery) UpdateItems(params map[string]string) error {
+	list, err := FindItems(ItemsFor(g.ID))
+	if err != nil {
+		return err
+	}
+
+	for _, item := range list {
+		if params[fmt.Sprintf("remove_%d", item.ID)] != "" {
+			err = item.Destroy()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		itemParams := make(map[string]string)
+		for _, key := range []string{"caption", "alt"} {
+			if v, ok := params[fmt.Sprintf("%s_%d", key, item.ID)]; ok {
+				itemParams[key] = strings.TrimSpace(v)
+			}
+		}
+		if len(itemParams) > 0 {
+			err = item.Update(itemParams)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	id, err := strconv.ParseInt(params["add_image_id"], 10, 64)
+	if err == nil && id > 0 {
+		return g.AddImage(id, "", "")
+	}
+	return nil
+}
+
+// Reorder sets the order of the items in the gallery with ids to their position in the list.
+func (g *Gallery) Reorder(ids []int64) error {
+	for i, id := range ids {
+		item, err := FindItem(id)
+		if err != nil {
+			return err
+		}
+		if item.GalleryID != g.ID {
+			return fmt.Errorf("galleries: item %d is not in gallery %d", id, g.ID)
+		}
+		err = item.Update(map[string]string{"sort": strconv.Itoa(i)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Destroy removes the gallery and its items, the images themselves are kept.
+func (g *Gallery) Destroy() error {
+	list, err := FindItems(ItemsFor(g.ID))
+	if err != nil {
+		return err
+	}
+	for _, item := range list {
+		err = item.Destroy()
+		if err != nil {
+			return err
+		}
+	}
+	return g.Base.Destroy()
+}
+
+// Render returns the html for the gallery, with links to each image for a lightbox.
+func (g *Gallery) Render() (template.HTML, error) {
+	items, err := g.Items()
+	if err != nil {
+		return "", err
+	}
+
+	view := view.NewWithPath("", nil)
+	view.Template("galleries/views/gallery.html.got")
+	view.AddKey("gallery", g)
+	view.AddKey("items", items)
+	html, err := view.RenderToString()
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(html), nil
+}
+
+// ForName returns the html for the published gallery called name, or an empty
+// string if there is none, for use in templates with the gallery helper:
+//
+//	{{ gallery "Events" }}
+func ForName(name string) template.HTML {
+	gallery, err := FindFirst("name=? AND status>=?", name, status.Published)
+	if err != nil {
+		return ""
+	}
+	html, err := gallery.Render()
+	if err != nil {
+		return ""
+	}
+	return html
+}
+
+// Shortcode returns the html for shortcodes which show a gallery in content such
+// as [[gallery name="Events"]] or [[gallery id="1"]], shortcodes for galleries
+// which are missing or unpublished are removed.
+func Shortcode(s *shortcodes.Shortcode) (template.HTML, error) {
+	var gallery *Gallery
+	var err error
+	if s.Attr("name") != "" {
+		gallery, err = FindFirst("name=?", s.Attr("name"))
+	} else {
+		gallery, err = Find(s.AttrInt("id", 0))
+	}
+	if err != nil || !gallery.IsPublished() {
+		return "", nil
+	}
+	return gallery.Render()
+}
@@ -0,0 +1,106 @@
+// Tests for the galleries package
+package galleries
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("galleries: Setup db failed %s", err)
+	}
+}
+
+// createImage creates an image with the name and status given.
+func createImage(t *testing.T, name, status string) int64 {
+	id, err := images.New().Create(map[string]string{"name": name, "path": "/files/" + name + ".jpg", "status": status})
+	if err != nil {
+		t.Fatalf("galleries: Create image failed :%s", err)
+	}
+	return id
+}
+
+// TestItems tests images are added to galleries, edited and reordered.
+func TestItems(t *testing.T) {
+	id, err := New().Create(map[string]string{"name": "Events", "status": "100"})
+	if err != nil {
+		t.Fatalf("galleries: Create gallery failed :%s", err)
+	}
+	gallery, err := Find(id)
+	if err != nil {
+		t.Fatalf("galleries: Create gallery find failed")
+	}
+
+	beach := createImage(t, "beach", "100")
+	party := createImage(t, "party", "100")
+	draft := createImage(t, "draft", "0")
+	for _, image := range []int64{beach, party, draft} {
+		err = gallery.AddImage(image, "", "")
+		if err != nil {
+			t.Fatalf("galleries: AddImage failed :%s", err)
+		}
+	}
+	if gallery.AddImage(999, "", "") == nil {
+		t.Fatalf("galleries: AddImage added missing image")
+	}
+
+	// Draft images are only included in AllItems for editing
+	all, err := gallery.AllItems()
+	if err != nil || len(all) != 3 {
+		t.Fatalf("galleries: AllItems unexpected items :%v %s", all, err)
+	}
+	items, err := gallery.Items()
+	if err != nil || len(items) != 2 || items[0].ImageID != beach || items[0].AltText() != "beach" {
+		t.Fatalf("galleries: Items unexpected items :%v %s", items, err)
+	}
+
+	// Set a caption and alt text for the second, and remove the draft
+	params := map[string]string{
+		fmt.Sprintf("caption_%d", all[1].ID): " Dancing ",
+		fmt.Sprintf("alt_%d", all[1].ID):     "People dancing",
+		fmt.Sprintf("remove_%d", all[2].ID):  "1",
+	}
+	err = gallery.UpdateItems(params)
+	if err != nil {
+		t.Fatalf("galleries: UpdateItems failed :%s", err)
+	}
+
+	// Move the second image first
+	err = gallery.Reorder([]int64{all[1].ID, all[0].ID})
+	if err != nil {
+		t.Fatalf("galleries: Reorder failed :%s", err)
+	}
+	all, err = gallery.AllItems()
+	if err != nil || len(all) != 2 || all[0].ImageID != party || all[0].Caption != "Dancing" || all[0].AltText() != "People dancing" {
+		t.Fatalf("galleries: unexpected items after update :%v %s", all, err)
+	}
+
+	// Items from other galleries may not be reordered
+	other, err := New().Create(map[string]string{"name": "Other"})
+	if err != nil {
+		t.Fatalf("galleries: Create gallery failed :%s", err)
+	}
+	otherGallery, err := Find(other)
+	if err != nil || otherGallery.Reorder([]int64{all[0].ID}) == nil {
+		t.Fatalf("galleries: Reorder accepted items from another gallery")
+	}
+
+	// Destroying the gallery removes its items but not the images
+	err = gallery.Destroy()
+	if err != nil {
+		t.Fatalf("galleries: Destroy failed :%s", err)
+	}
+	count, err := ItemsFor(gallery.ID).Count()
+	if err != nil || count != 0 {
+		t.Fatalf("galleries: Destroy left items :%d %s", count, err)
+	}
+	_, err = images.Find(party)
+	if err != nil {
+		t.Fatalf("galleries: Destroy removed image :%s", err)
+	}
+}
@@ -0,0 +1,107 @@
+package galleries
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+const (
+	// ItemsTableName is the database table for the images in galleries
+	ItemsTableName = "gallery_images"
+	// ItemsOrder defines the order of images in a gallery
+	ItemsOrder = "sort asc, id asc"
+)
+
+// Item is an image in a gallery, with the caption and alt text used in the gallery.
+type Item struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	GalleryID int64
+	ImageID   int64
+	Caption   string
+	Alt       string
+	Sort      int64
+
+	// Image is the image shown, set by Gallery.Items
+	Image *images.Image
+}
+
+// AltText returns the alt text for the item, or the alt text of the image if it has none.
+func (i *Item) AltText() string {
+	if i.Alt != "" || i.Image == nil {
+		return i.Alt
+	}
+	return i.Image.AltText()
+}
+
+// CaptionText returns the caption for the item, or the caption of the image if it has none.
+func (i *Item) CaptionText() string {
+	if i.Caption != "" || i.Image == nil {
+		return i.Caption
+	}
+	return i.Image.Caption
+}
+
+// NewItemWithColumns creates a new item instance and fills it with data from the database cols provided.
+func NewItemWithColumns(cols map[string]interface{}) *Item {
+
+	item := NewItem()
+	item.ID = resource.ValidateInt(cols["id"])
+	item.CreatedAt = resource.ValidateTime(cols["created_at"])
+	item.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	item.GalleryID = resource.ValidateInt(cols["gallery_id"])
+	item.ImageID = resource.ValidateInt(cols["image_id"])
+	item.Caption = resource.ValidateString(cols["caption"])
+	item.Alt = resource.ValidateString(cols["alt"])
+	item.Sort = resource.ValidateInt(cols["sort"])
+
+	return item
+}
+
+// NewItem creates and initialises a new item instance.
+func NewItem() *Item {
+	item := &Item{}
+	item.CreatedAt = time.Now()
+	item.UpdatedAt = time.Now()
+	item.TableName = ItemsTableName
+	item.KeyName = KeyName
+	return item
+}
+
+// FindItem fetches a single item record from the database by id.
+func FindItem(id int64) (*Item, error) {
+	result, err := ItemsQuery().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewItemWithColumns(result), nil
+}
+
+// FindItems fetches all item records matching this query from the database.
+func FindItems(q *query.Query) ([]*Item, error) {
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+	for _, cols := range results {
+		items = append(items, NewItemWithColumns(cols))
+	}
+	return items, nil
+}
+
+// ItemsQuery returns a new query for items in the order shown in galleries.
+func ItemsQuery() *query.Query {
+	return query.New(ItemsTableName, KeyName).Order(ItemsOrder)
+}
+
+// ItemsFor returns a query for the items in the gallery with id.
+func ItemsFor(id int64) *query.Query {
+	return ItemsQuery().Where("gallery_id=?", id)
+}
@@ -0,0 +1,103 @@
+package galleries
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "galleries"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "name asc, id desc"
+)
+
+// AllowedParams returns an array of allowed param keys for Update and Create.
+func AllowedParams() []string {
+	return []string{"status", "author_id", "name", "summary"}
+}
+
+// NewWithColumns creates a new gallery instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Gallery {
+
+	gallery := New()
+	gallery.ID = resource.ValidateInt(cols["id"])
+	gallery.CreatedAt = resource.ValidateTime(cols["created_at"])
+	gallery.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	gallery.Status = resource.ValidateInt(cols["status"])
+	gallery.AuthorID = resource.ValidateInt(cols["author_id"])
+	gallery.Name = resource.ValidateString(cols["name"])
+	gallery.Summary = resource.ValidateString(cols["summary"])
+
+	return gallery
+}
+
+// New creates and initialises a new gallery instance.
+func New() *Gallery {
+	gallery := &Gallery{}
+	gallery.CreatedAt = time.Now()
+	gallery.UpdatedAt = time.Now()
+	gallery.TableName = TableName
+	gallery.KeyName = KeyName
+	gallery.Status = status.Draft
+	return gallery
+}
+
+// FindFirst fetches a single gallery record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Gallery, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single gallery record from the database by id.
+func Find(id int64) (*Gallery, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all gallery records matching this query from the database.
+func FindAll(q *query.Query) ([]*Gallery, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of galleries constructed from the results
+	var galleries []*Gallery
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		galleries = append(galleries, p)
+	}
+
+	return galleries, nil
+}
+
+// Query returns a new query for galleries with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for galleries with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Published returns a query for all galleries with status >= published.
+func Published() *query.Query {
+	return Query().Where("status>=?", status.Published)
+}
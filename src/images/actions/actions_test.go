@@ -1,79 +1,60 @@
-package imageactions
+package imageactions_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/fragmenta/mux"
-	"github.com/fragmenta/query"
 
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
 	"github.com/fragmenta/fragmenta-cms/src/images"
-	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // names is used to test setting and getting the first string field of the image.
 var names = []string{"foo", "bar"}
 
-// testSetup performs setup for integration tests
-// using the test database, real views, and mock authorisation
-// If we can run this once for global tests it might be more efficient?
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
 func TestSetup(t *testing.T) {
-	err := resource.SetupTestDatabase(3)
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("imageactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
 	if err != nil {
-		fmt.Printf("images: Setup db failed %s", err)
-	}
-
-	// Set up mock auth
-	resource.SetupAuthorisation()
-
-	// Load templates for rendering
-	resource.SetupView(3)
-
-	router := mux.New()
-	mux.SetDefault(router)
-
-	// FIXME - Need to write routes out here again, but without pkg prefix
-	// Any neat way to do this instead? We'd need a separate routes package under app...
-	router.Add("/images", nil)
-	router.Add("/images/create", nil)
-	router.Add("/images/create", nil).Post()
-	router.Add("/images/login", nil)
-	router.Add("/images/login", nil).Post()
-	router.Add("/images/login", nil).Post()
-	router.Add("/images/logout", nil).Post()
-	router.Add("/images/{id:\\d+}/update", nil)
-	router.Add("/images/{id:\\d+}/update", nil).Post()
-	router.Add("/images/{id:\\d+}/destroy", nil).Post()
-	router.Add("/images/{id:\\d+}", nil)
-
-	// Delete all images to ensure we get consistent results
-	query.ExecSQL("delete from images;")
-	query.ExecSQL("ALTER SEQUENCE images_id_seq RESTART WITH 1;")
+		t.Fatalf("imageactions: error creating admin %s", err)
+	}
 }
 
 // Test GET /images/create
 func TestShowCreateImage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/images/create", nil)
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleCreateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/images/create", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("imageactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("imageactions: error handling HandleCreateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -89,20 +70,8 @@ func TestCreateImage(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("name", names[0])
-	body := strings.NewReader(form.Encode())
 
-	r := httptest.NewRequest("POST", "/images/create", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
-	}
-
-	// Run the handler to update the image
-	err = HandleCreate(w, r)
+	w, err := apptest.Request(router, "POST", "/images/create", form, admin)
 	if err != nil {
 		t.Fatalf("imageactions: error handling HandleCreate %s", err)
 	}
@@ -112,7 +81,7 @@ func TestCreateImage(t *testing.T) {
 		t.Fatalf("imageactions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
 	}
 
-	// Check the image name is in now value names[1]
+	// Check the image name is in now value names[0]
 	allImage, err := images.FindAll(images.Query().Order("id desc"))
 	if err != nil || len(allImage) == 0 {
 		t.Fatalf("imageactions: error finding created image %s", err)
@@ -126,22 +95,11 @@ func TestCreateImage(t *testing.T) {
 // Test GET /images
 func TestListImage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/images", nil)
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleIndex(w, r)
+	w, err := apptest.Request(router, "GET", "/images", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("imageactions: error handling HandleIndex %s", err)
+		t.Fatalf("imageactions: error handling HandleIndex %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -155,22 +113,11 @@ func TestListImage(t *testing.T) {
 // Test of GET /images/1
 func TestShowImage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/images/1", nil)
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleShow(w, r)
+	w, err := apptest.Request(router, "GET", "/images/1", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("imageactions: error handling HandleShow %s", err)
+		t.Fatalf("imageactions: error handling HandleShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -183,28 +130,17 @@ func TestShowImage(t *testing.T) {
 // Test GET /images/123/update
 func TestShowUpdateImage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/images/1/update", nil)
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleUpdateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/images/1/update", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("imageactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("imageactions: error handling HandleUpdateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
 	pattern := "resource-update-form"
 	if !strings.Contains(w.Body.String(), pattern) {
-		t.Fatalf("imageactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+		t.Fatalf("imageactions: unexpected response for HandleUpdateShow expected:%s got:%s", pattern, w.Body.String())
 	}
 
 }
@@ -214,20 +150,8 @@ func TestUpdateImage(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("name", names[1])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/images/1/update", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
-	}
 
-	// Run the handler to update the image
-	err = HandleUpdate(w, r)
+	w, err := apptest.Request(router, "POST", "/images/1/update", form, admin)
 	if err != nil {
 		t.Fatalf("imageactions: error handling HandleUpdateImage %s", err)
 	}
@@ -251,23 +175,14 @@ func TestUpdateImage(t *testing.T) {
 // Test of POST /images/123/destroy
 func TestDeleteImage(t *testing.T) {
 
-	body := strings.NewReader(``)
-
-	// Now test deleting the image created above as admin
-	r := httptest.NewRequest("POST", "/images/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up image session cookie for admin image
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("imageactions: error setting session %s", err)
+	// Test deleting the image created above as anon
+	w, err := apptest.Request(router, "POST", "/images/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("imageactions: unexpected response for HandleDestroy as anon, expected failure")
 	}
 
-	// Run the handler
-	err = HandleDestroy(w, r)
-
-	// Test the error response is 302 StatusFound
+	// Now test deleting the image as admin
+	w, err = apptest.Request(router, "POST", "/images/1/destroy", nil, admin)
 	if err != nil {
 		t.Fatalf("imageactions: error handling HandleDestroy %s", err)
 	}
@@ -276,15 +191,139 @@ func TestDeleteImage(t *testing.T) {
 	if w.Code != http.StatusFound {
 		t.Fatalf("imageactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
 	}
-	// Now test as anon
-	r = httptest.NewRequest("POST", "/images/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w = httptest.NewRecorder()
 
-	// Run the handler to test failure as anon
-	err = HandleDestroy(w, r)
-	if err == nil { // failure expected
-		t.Fatalf("imageactions: unexpected response for HandleDestroy as anon, expected failure")
+	_, err = images.Find(1)
+	if err == nil {
+		t.Fatalf("imageactions: image found after HandleDestroy")
+	}
+
+}
+
+// Test POST /images/create with a file
+func TestUploadImage(t *testing.T) {
+	settings.Current.Uploads.Path = t.TempDir()
+
+	var b bytes.Buffer
+	err := png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 1000, 500)))
+	if err != nil {
+		t.Fatalf("imageactions: error encoding png %s", err)
+	}
+
+	form := url.Values{}
+	form.Add("alt", "A blank image")
+	w, err := apptest.Upload(router, "/images/create", form, "file", "Blank Image.png", b.Bytes(), admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("imageactions: unexpected response for upload %v %d %s", err, w.Code, w.Body.String())
+	}
+
+	allImage, err := images.FindAll(images.Query().Order("id desc"))
+	if err != nil || len(allImage) == 0 {
+		t.Fatalf("imageactions: error finding uploaded image %s", err)
 	}
+	uploaded := allImage[0]
+	if uploaded.Name != "Blank Image" || uploaded.Alt != "A blank image" || uploaded.Width != 1000 || uploaded.Height != 500 || uploaded.MimeType != "image/png" {
+		t.Fatalf("imageactions: unexpected values for uploaded image %v", uploaded)
+	}
+
+	file := filepath.Join(settings.Current.Uploads.Path, "images", filepath.Base(uploaded.Path))
+	if _, err = os.Stat(file); err != nil {
+		t.Fatalf("imageactions: uploaded file not found %s", err)
+	}
+
+	// Files which are not images should be rejected
+	w, err = apptest.Upload(router, "/images/create", nil, "file", "page.html", []byte("<html></html>"), admin)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("imageactions: unexpected response for upload of html %v %d", err, w.Code)
+	}
+
+	// Destroying the image should remove its files
+	w, err = apptest.Request(router, "POST", uploaded.DestroyURL(), nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("imageactions: error handling HandleDestroy for upload %v %d", err, w.Code)
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("imageactions: uploaded file not removed %s", err)
+	}
+}
+
+// Test GET /images/library and POST /images/library
+func TestLibrary(t *testing.T) {
+	settings.Current.Uploads.Path = t.TempDir()
 
+	for i := 0; i <= images.LibraryPageSize; i++ {
+		_, err := apptest.CreateImage(map[string]string{"caption": fmt.Sprintf("Harbour view %d", i)})
+		if err != nil {
+			t.Fatalf("imageactions: error creating image %s", err)
+		}
+	}
+	_, err := apptest.CreateImage(map[string]string{"name": "Castle", "alt": "The castle at dawn"})
+	if err != nil {
+		t.Fatalf("imageactions: error creating image %s", err)
+	}
+
+	// Anon may not list the library
+	w, err := apptest.Request(router, "GET", "/images/library", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("imageactions: unexpected response for HandleLibrary as anon %v %d", err, w.Code)
+	}
+
+	tests := []struct {
+		search string
+		page   string
+		count  int
+		more   bool
+	}{
+		{"harbour", "1", images.LibraryPageSize, true},
+		{"harbour", "2", 1, false},
+		{"dawn", "", 1, false},
+		{"nothing", "", 0, false},
+	}
+	for _, test := range tests {
+		form := url.Values{"q": {test.search}, "page": {test.page}}
+		w, err = apptest.Request(router, "GET", "/images/library", form, admin)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("imageactions: error handling HandleLibrary %v %d", err, w.Code)
+		}
+		if w.Header().Get("Content-Type") != "application/json; charset=utf-8" {
+			t.Fatalf("imageactions: unexpected content type for HandleLibrary got:%s", w.Header().Get("Content-Type"))
+		}
+
+		var library images.Library
+		err = json.Unmarshal(w.Body.Bytes(), &library)
+		if err != nil || len(library.Images) != test.count || library.More != test.more {
+			t.Fatalf("imageactions: unexpected library for %s page %s %v %s", test.search, test.page, library, err)
+		}
+	}
+
+	// Images uploaded from the library are published and returned with the html to insert
+	var b bytes.Buffer
+	err = png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 1000, 500)))
+	if err != nil {
+		t.Fatalf("imageactions: error encoding png %s", err)
+	}
+	w, err = apptest.Upload(router, "/images/library", nil, "file", "Lighthouse.png", b.Bytes(), admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("imageactions: error handling HandleLibraryUpload %v %d %s", err, w.Code, w.Body.String())
+	}
+
+	var uploaded images.LibraryImage
+	err = json.Unmarshal(w.Body.Bytes(), &uploaded)
+	if err != nil || uploaded.Name != "Lighthouse" || uploaded.Width != 1000 {
+		t.Fatalf("imageactions: unexpected image for HandleLibraryUpload %v %s", uploaded, err)
+	}
+	expected := fmt.Sprintf(`<figure class="image"><img src="%s" alt="Lighthouse" width="1000" height="500" srcset="`, uploaded.Path)
+	if !strings.HasPrefix(uploaded.HTML, expected) || !strings.HasSuffix(uploaded.Thumbnail, "-480.png") {
+		t.Fatalf("imageactions: unexpected html for HandleLibraryUpload expected:%s got:%s %s", expected, uploaded.HTML, uploaded.Thumbnail)
+	}
+
+	found, err := images.Find(uploaded.ID)
+	if err != nil || !found.IsPublished() || found.AuthorID != admin.ID {
+		t.Fatalf("imageactions: unexpected image uploaded %v %s", found, err)
+	}
+
+	// A file is required
+	w, err = apptest.Request(router, "POST", "/images/library", nil, admin)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("imageactions: unexpected response for HandleLibraryUpload without a file %v %d", err, w.Code)
+	}
 }
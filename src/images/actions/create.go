@@ -57,6 +57,15 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 	// Validate the params, removing any we don't accept
 	imageParams := image.ValidateParams(params.Map(), images.AllowedParams())
 
+	// Store the file uploaded, setting the path, dimensions and type of the image
+	fileParams, err := upload(params.Files["file"], imageParams["name"])
+	if err != nil {
+		return server.BadRequestError(err, "Invalid image", "Please upload a jpeg, png or gif image no larger than the maximum size.")
+	}
+	for k, v := range fileParams {
+		imageParams[k] = v
+	}
+
 	id, err := image.Create(imageParams)
 	if err != nil {
 		return server.InternalError(err)
@@ -39,8 +39,15 @@ func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Destroy the image
-	image.Destroy()
+	// Destroy the image and its files
+	err = image.Destroy()
+	if err != nil {
+		return server.InternalError(err)
+	}
+	err = image.RemoveFiles()
+	if err != nil {
+		return server.InternalError(err)
+	}
 
 	// Redirect to images root
 	return server.Redirect(w, r, image.IndexURL())
@@ -0,0 +1,109 @@
+package imageactions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// HandleLibrary responds to GET /images/library with json for a page of the
+// media library in the editor, with images matching the search in q.
+func HandleLibrary(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list image
+	user := session.CurrentUser(w, r)
+	err := can.List(images.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	library, err := images.FindLibrary(params.Get("q"), int(params.GetInt("page")))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return writeJSON(w, library)
+}
+
+// HandleLibraryUpload responds to POST /images/library with the file uploaded
+// from the media library in the editor, and returns json for the published image.
+func HandleLibraryUpload(w http.ResponseWriter, r *http.Request) error {
+
+	image := images.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err = can.Create(image, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Setup context
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Validate the params, removing any we don't accept
+	imageParams := image.ValidateParams(params.Map(), images.AllowedParams())
+
+	// Store the file uploaded, which is required here
+	fileParams, err := upload(params.Files["file"], imageParams["name"])
+	if err == nil && fileParams == nil {
+		err = errors.New("images: no file uploaded")
+	}
+	if err != nil {
+		return server.BadRequestError(err, "Invalid image", "Please upload a jpeg, png or gif image no larger than the maximum size.")
+	}
+	for k, v := range fileParams {
+		imageParams[k] = v
+	}
+
+	// Images uploaded while editing are inserted into content, so they are published
+	imageParams["status"] = fmt.Sprintf("%d", status.Published)
+	imageParams["author_id"] = fmt.Sprintf("%d", user.ID)
+
+	id, err := image.Create(imageParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	image, err = images.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return writeJSON(w, image.LibraryImage())
+}
+
+// writeJSON writes v to w as json.
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, err = w.Write(data)
+	return err
+}
@@ -71,11 +71,29 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 	// Validate the params, removing any we don't accept
 	imageParams := image.ValidateParams(params.Map(), images.AllowedParams())
 
+	// Store a new file if one was uploaded, replacing the file of the image
+	fileParams, err := upload(params.Files["file"], image.Name)
+	if err != nil {
+		return server.BadRequestError(err, "Invalid image", "Please upload a jpeg, png or gif image no larger than the maximum size.")
+	}
+	delete(fileParams, "name")
+	for k, v := range fileParams {
+		imageParams[k] = v
+	}
+
 	err = image.Update(imageParams)
 	if err != nil {
 		return server.InternalError(err)
 	}
 
+	// Remove the files replaced, image still has the previous path
+	if fileParams != nil {
+		err = image.RemoveFiles()
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
 	// Redirect to image
 	return server.Redirect(w, r, image.ShowURL())
 }
@@ -0,0 +1,42 @@
+package imageactions
+
+import (
+	"fmt"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// upload stores the first of the files uploaded for an image called name, and
+// returns the params for its path, dimensions and mime type, or nil if there are
+// no files. If name is empty the name of the file is used, and returned as well.
+func upload(files []*multipart.FileHeader, name string) (map[string]string, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	fh := files[0]
+	if fh.Size > settings.Current.Uploads.MaxSize {
+		return nil, fmt.Errorf("images: file %s is larger than %d bytes", fh.Filename, settings.Current.Uploads.MaxSize)
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(fh.Filename), filepath.Ext(fh.Filename))
+	}
+
+	params, err := images.Upload(file, name)
+	if err != nil {
+		return nil, err
+	}
+	params["name"] = name
+	return params, nil
+}
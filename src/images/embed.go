@@ -0,0 +1,54 @@
+package images
+
+import (
+	"html/template"
+
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+)
+
+// Shortcode returns the html for shortcodes which embed an image in content such as
+// [[image id="1" caption="Caption" class="right"]], shortcodes for images which are
+// missing or unpublished are removed.
+func Shortcode(s *shortcodes.Shortcode) (template.HTML, error) {
+	image, err := Find(s.AttrInt("id", 0))
+	if err != nil || !image.IsPublished() {
+		return "", nil
+	}
+
+	view := view.NewWithPath("", nil)
+	view.Template("images/views/embed.html.got")
+	view.AddKey("image", image)
+	view.AddKey("caption", s.Attr("caption"))
+	view.AddKey("class", s.Attr("class"))
+	html, err := view.RenderToString()
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(html), nil
+}
+
+// ListShortcode returns the html for shortcodes which embed published images
+// in content in their sort order such as [[images limit="12" class="grid"]].
+func ListShortcode(s *shortcodes.Shortcode) (template.HTML, error) {
+	limit := s.AttrInt("limit", 12)
+	if limit < 1 || limit > 100 {
+		limit = 12
+	}
+
+	list, err := FindAll(Published().Order("sort asc, name asc").Limit(int(limit)))
+	if err != nil {
+		return "", err
+	}
+
+	view := view.NewWithPath("", nil)
+	view.Template("images/views/embed_list.html.got")
+	view.AddKey("images", list)
+	view.AddKey("class", s.Attr("class"))
+	html, err := view.RenderToString()
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(html), nil
+}
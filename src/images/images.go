@@ -18,4 +18,18 @@ type Image struct {
 	Name     string
 	Path     string
 	Sort     int64
+	Alt      string
+	Caption  string
+	Credit   string
+	Width    int64
+	Height   int64
+	MimeType string
+}
+
+// AltText returns the alt text for the image, or its name if it has none.
+func (i *Image) AltText() string {
+	if i.Alt != "" {
+		return i.Alt
+	}
+	return i.Name
 }
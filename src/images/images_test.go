@@ -2,9 +2,17 @@
 package images
 
 import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 )
 
 var testName = "foo"
@@ -137,3 +145,100 @@ func TestAllowedParams(t *testing.T) {
 		t.Fatalf("images: no allowed params")
 	}
 }
+
+// TestUpload tests uploaded images are stored with smaller copies and shown with srcset.
+func TestUpload(t *testing.T) {
+	var err error
+	settings.Current, err = settings.New(settings.Test, nil)
+	if err != nil {
+		t.Fatalf("images: error loading settings %s", err)
+	}
+	settings.Current.Uploads.Path = t.TempDir()
+
+	var b bytes.Buffer
+	err = png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 1200, 800)))
+	if err != nil {
+		t.Fatalf("images: error encoding png %s", err)
+	}
+
+	params, err := Upload(&b, "Beach Party")
+	if err != nil {
+		t.Fatalf("images: Upload failed :%s", err)
+	}
+	if !strings.HasPrefix(params["path"], "/files/images/beach-party-") || params["width"] != "1200" || params["height"] != "800" || params["mime_type"] != "image/png" {
+		t.Fatalf("images: Upload unexpected params :%v", params)
+	}
+
+	i := New()
+	i.Name = "Beach party"
+	i.Path = params["path"]
+	i.Width = 1200
+	i.Height = 800
+	i.MimeType = params["mime_type"]
+
+	local := filepath.Join(settings.Current.Uploads.Path, "images", strings.TrimPrefix(i.Path, "/files/images/"))
+	for _, f := range []string{local, strings.Replace(local, ".png", "-480.png", 1), strings.Replace(local, ".png", "-960.png", 1)} {
+		if _, err = os.Stat(f); err != nil {
+			t.Fatalf("images: Upload file missing :%s", err)
+		}
+	}
+
+	expected := fmt.Sprintf(`<img src="%s" alt="Beach party" width="1200" height="800" srcset="%s 480w, %s 960w, %s 1200w" sizes="(max-width: 1200px) 100vw, 1200px" loading="lazy">`, i.Path, i.SizedPath(480), i.SizedPath(960), i.Path)
+	if got := Tag(i); string(got) != expected {
+		t.Fatalf("images: Tag unexpected html\nexpected:%s\ngot:     %s", expected, got)
+	}
+	i.Alt = `A "party"`
+	if got := Tag(i); !strings.Contains(string(got), `alt="A &#34;party&#34;"`) {
+		t.Fatalf("images: Tag unexpected alt got:%s", got)
+	}
+
+	err = i.RemoveFiles()
+	if err != nil {
+		t.Fatalf("images: RemoveFiles failed :%s", err)
+	}
+	if _, err = os.Stat(local); !os.IsNotExist(err) {
+		t.Fatalf("images: RemoveFiles left file :%s", local)
+	}
+
+	_, err = Upload(strings.NewReader("not an image"), "text")
+	if err == nil {
+		t.Fatalf("images: Upload accepted text")
+	}
+
+	// Square uploads are cropped and resized
+	b.Reset()
+	err = png.Encode(&b, image.NewRGBA(image.Rect(0, 0, 600, 400)))
+	if err != nil {
+		t.Fatalf("images: error encoding png %s", err)
+	}
+	params, err = UploadSquare(&b, "Avatar", 256)
+	if err != nil || params["width"] != "256" || params["height"] != "256" {
+		t.Fatalf("images: UploadSquare unexpected params :%v %s", params, err)
+	}
+}
+
+// TestFigure tests the html inserted from the media library.
+func TestFigure(t *testing.T) {
+	i := New()
+	i.Name = "Harbour"
+	i.Path = "/files/images/harbour-1a2b3c4d.jpg"
+	i.Width = 1000
+	i.Height = 600
+	i.MimeType = "image/jpeg"
+	i.Caption = "The <harbour>"
+
+	if got := i.Thumbnail(); got != "/files/images/harbour-1a2b3c4d-480.jpg" {
+		t.Fatalf("images: Thumbnail unexpected path got:%s", got)
+	}
+
+	expected := `<figure class="image">` + string(Tag(i)) + `<figcaption>The &lt;harbour&gt;</figcaption></figure>`
+	if got := i.Figure(); string(got) != expected {
+		t.Fatalf("images: Figure unexpected html\nexpected:%s\ngot:     %s", expected, got)
+	}
+
+	// Images not uploaded have no smaller copies
+	i.Path = "https://example.com/harbour.jpg"
+	if got := i.Thumbnail(); got != i.Path {
+		t.Fatalf("images: Thumbnail unexpected path for image not uploaded got:%s", got)
+	}
+}
@@ -0,0 +1,113 @@
+package images
+
+import (
+	"fmt"
+	"html/template"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+// LibraryPageSize is the number of images in each page of the media library.
+const LibraryPageSize = 24
+
+// LibraryImage is an image as listed in the media library of the editor,
+// with the html inserted into content when it is chosen.
+type LibraryImage struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Thumbnail string `json:"thumbnail"`
+	Alt       string `json:"alt"`
+	Caption   string `json:"caption"`
+	Width     int64  `json:"width"`
+	Height    int64  `json:"height"`
+	HTML      string `json:"html"`
+}
+
+// Library is a page of images in the media library.
+type Library struct {
+	Images []*LibraryImage `json:"images"`
+	Page   int             `json:"page"`
+	More   bool            `json:"more"`
+}
+
+// LibraryQuery returns a query for the media library, with the newest images first,
+// filtered by search if it is not empty.
+func LibraryQuery(search string) *query.Query {
+	q := Query().Order("created_at desc, id desc")
+	if search != "" {
+		like := "%" + search + "%"
+		q.Where(fmt.Sprintf("(%s OR %s OR %s)", resource.ILike("name"), resource.ILike("alt"), resource.ILike("caption")), like, like, like)
+	}
+	return q
+}
+
+// FindLibrary returns page (from 1) of the images in the media library matching search.
+func FindLibrary(search string, page int) (*Library, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	// Fetch one more image than required to find out if there are more pages
+	q := LibraryQuery(search).Limit(LibraryPageSize + 1).Offset((page - 1) * LibraryPageSize)
+	list, err := FindAll(q)
+	if err != nil {
+		return nil, err
+	}
+
+	library := &Library{Images: []*LibraryImage{}, Page: page}
+	if len(list) > LibraryPageSize {
+		library.More = true
+		list = list[:LibraryPageSize]
+	}
+	for _, i := range list {
+		library.Images = append(library.Images, i.LibraryImage())
+	}
+	return library, nil
+}
+
+// LibraryImage returns the image as listed in the media library.
+func (i *Image) LibraryImage() *LibraryImage {
+	return &LibraryImage{
+		ID:        i.ID,
+		Name:      i.Name,
+		Path:      i.Path,
+		Thumbnail: i.Thumbnail(),
+		Alt:       i.AltText(),
+		Caption:   i.Caption,
+		Width:     i.Width,
+		Height:    i.Height,
+		HTML:      string(i.Figure()),
+	}
+}
+
+// Thumbnail returns the path of the smallest copy of the image, or the path of the
+// image if it has no smaller copies.
+func (i *Image) Thumbnail() string {
+	if i.Srcset() == "" {
+		return i.Path
+	}
+	smallest := 0
+	for _, width := range Widths() {
+		if int64(width) < i.Width && (smallest == 0 || width < smallest) {
+			smallest = width
+		}
+	}
+	return i.SizedPath(smallest)
+}
+
+// Figure returns the html inserted into content for the image, a figure with
+// the img tag and the caption of the image if it has one.
+func (i *Image) Figure() template.HTML {
+	tag := Tag(i)
+	if tag == "" {
+		return ""
+	}
+	html := `<figure class="image">` + string(tag)
+	if i.Caption != "" {
+		html += "<figcaption>" + template.HTMLEscapeString(i.Caption) + "</figcaption>"
+	}
+	return template.HTML(html + "</figure>")
+}
@@ -20,7 +20,7 @@ const (
 
 // AllowedParams returns an array of allowed param keys for Update and Create.
 func AllowedParams() []string {
-	return []string{"status", "author_id", "name", "path", "sort", "status"}
+	return []string{"status", "author_id", "name", "path", "sort", "status", "alt", "caption", "credit"}
 }
 
 // NewWithColumns creates a new image instance and fills it with data from the database cols provided.
@@ -35,6 +35,12 @@ func NewWithColumns(cols map[string]interface{}) *Image {
 	image.Name = resource.ValidateString(cols["name"])
 	image.Path = resource.ValidateString(cols["path"])
 	image.Sort = resource.ValidateInt(cols["sort"])
+	image.Alt = resource.ValidateString(cols["alt"])
+	image.Caption = resource.ValidateString(cols["caption"])
+	image.Credit = resource.ValidateString(cols["credit"])
+	image.Width = resource.ValidateInt(cols["width"])
+	image.Height = resource.ValidateInt(cols["height"])
+	image.MimeType = resource.ValidateString(cols["mime_type"])
 	image.Status = resource.ValidateInt(cols["status"])
 
 	return image
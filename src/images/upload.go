@@ -0,0 +1,236 @@
+package images
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/imagefile"
+	"github.com/fragmenta/fragmenta-cms/src/lib/sanitize"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// Upload reads the image uploaded in r, and stores it upright and without metadata
+// in the uploads path, with smaller copies for srcset. It returns the params for an
+// image with the path, dimensions and mime type of the file, which is named after name.
+func Upload(r io.Reader, name string) (map[string]string, error) {
+	f, err := imagefile.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, base, err := prepareUpload(name)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeFile(filepath.Join(dir, base+f.Extension()), f.Write)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gifs are kept as uploaded so that they stay animated, and are not resized
+	if f.MimeType != "image/gif" {
+		for _, width := range Widths() {
+			if width >= f.Width() {
+				continue
+			}
+			resized := imagefile.ResizeWidth(f.Image, width)
+			err = writeFile(filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, width, f.Extension())), func(w io.Writer) error {
+				return imagefile.Encode(w, resized, f.MimeType)
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return uploadParams(base+f.Extension(), f.Width(), f.Height(), f.MimeType), nil
+}
+
+// UploadSquare reads the image uploaded in r, and stores the centre of it cropped
+// to a square of size without metadata, for avatars. It returns params as Upload does.
+func UploadSquare(r io.Reader, name string, size int) (map[string]string, error) {
+	f, err := imagefile.Read(r)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, base, err := prepareUpload(name)
+	if err != nil {
+		return nil, err
+	}
+
+	square := imagefile.Square(f.Image, size)
+	err = writeFile(filepath.Join(dir, base+f.Extension()), func(w io.Writer) error {
+		return imagefile.Encode(w, square, f.MimeType)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	side := square.Bounds().Dx()
+	return uploadParams(base+f.Extension(), side, side, f.MimeType), nil
+}
+
+// Widths returns the widths of the copies of uploaded images made for srcset.
+func Widths() []int {
+	var widths []int
+	for _, s := range strings.Split(settings.Current.Uploads.ImageWidths, ",") {
+		w, err := strconv.Atoi(strings.TrimSpace(s))
+		if err == nil && w > 0 {
+			widths = append(widths, w)
+		}
+	}
+	return widths
+}
+
+// Uploaded returns true if the image file was uploaded and is stored in the uploads path.
+func (i *Image) Uploaded() bool {
+	return strings.HasPrefix(path.Clean(i.Path), uploadsURL())
+}
+
+// SizedPath returns the path of the copy of the image resized to width.
+func (i *Image) SizedPath(width int) string {
+	ext := path.Ext(i.Path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(i.Path, ext), width, ext)
+}
+
+// Srcset returns the srcset for the uploaded image and its smaller copies,
+// or an empty string if it has none.
+func (i *Image) Srcset() string {
+	if !i.Uploaded() || i.Width == 0 || i.MimeType == "image/gif" {
+		return ""
+	}
+
+	var sources []string
+	for _, width := range Widths() {
+		if int64(width) < i.Width {
+			sources = append(sources, fmt.Sprintf("%s %dw", i.SizedPath(width), width))
+		}
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	sources = append(sources, fmt.Sprintf("%s %dw", i.Path, i.Width))
+	return strings.Join(sources, ", ")
+}
+
+// RemoveFiles removes the uploaded file for the image and its smaller copies,
+// images with a path outside the uploads path are left alone.
+func (i *Image) RemoveFiles() error {
+	if !i.Uploaded() {
+		return nil
+	}
+
+	local := filepath.Join(settings.Current.Uploads.Path, "images", filepath.FromSlash(strings.TrimPrefix(path.Clean(i.Path), uploadsURL())))
+	files := []string{local}
+	for _, width := range Widths() {
+		ext := filepath.Ext(local)
+		files = append(files, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(local, ext), width, ext))
+	}
+
+	for _, f := range files {
+		err := os.Remove(f)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tag returns an img tag for the image with its alt text, width, height and srcset,
+// loaded lazily, for use in templates with the img helper:
+//
+//	{{ img .image }} or {{ img .image "Alt text" }}
+//
+// The alt text of the image may be replaced with the alt text given.
+func Tag(i *Image, alt ...string) template.HTML {
+	if i == nil || i.Path == "" || !sanitize.AllowURL(i.Path) {
+		return ""
+	}
+
+	text := i.AltText()
+	if len(alt) > 0 && alt[0] != "" {
+		text = alt[0]
+	}
+
+	html := fmt.Sprintf(`<img src="%s" alt="%s"`, template.HTMLEscapeString(i.Path), template.HTMLEscapeString(text))
+	if i.Width > 0 && i.Height > 0 {
+		html += fmt.Sprintf(` width="%d" height="%d"`, i.Width, i.Height)
+	}
+	if srcset := i.Srcset(); srcset != "" {
+		html += fmt.Sprintf(` srcset="%s" sizes="(max-width: %dpx) 100vw, %dpx"`, template.HTMLEscapeString(srcset), i.Width, i.Width)
+	}
+	return template.HTML(html + ` loading="lazy">`)
+}
+
+// uploadsURL returns the url of the directory for uploaded images, ending in /.
+func uploadsURL() string {
+	return strings.TrimSuffix(settings.Current.Uploads.URL, "/") + "/images/"
+}
+
+// prepareUpload creates the directory for uploaded images, and returns it
+// with a unique name for the files of an image called name.
+func prepareUpload(name string) (string, string, error) {
+	dir := filepath.Join(settings.Current.Uploads.Path, "images")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", "", err
+	}
+
+	base, err := fileName(name)
+	if err != nil {
+		return "", "", err
+	}
+	return dir, base, nil
+}
+
+// uploadParams returns the params for an image uploaded to the file given.
+func uploadParams(file string, width, height int, mimeType string) map[string]string {
+	return map[string]string{
+		"path":      uploadsURL() + file,
+		"width":     fmt.Sprintf("%d", width),
+		"height":    fmt.Sprintf("%d", height),
+		"mime_type": mimeType,
+	}
+}
+
+// fileName returns a unique name for the files of an image called name.
+func fileName(name string) (string, error) {
+	b := make([]byte, 4)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	slug := New().ToSlug(name)
+	if len(slug) > 50 {
+		slug = slug[:50]
+	}
+	if slug == "" {
+		slug = "image"
+	}
+	return slug + "-" + hex.EncodeToString(b), nil
+}
+
+// writeFile creates the file at path with the contents written by write.
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -70,6 +70,10 @@ var Editable = (function() {
                             case "formatblock":
                                 insert = this.getAttribute('data-format');
                                 break;
+                            case "insertImage":
+                                // Choose an image to insert from the media library
+                                MediaLibrary.Show(toolbar, this.getAttribute('data-library'));
+                                return false;
                             default:
                                 break;
                         }
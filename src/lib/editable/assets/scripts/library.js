@@ -0,0 +1,188 @@
+// Media library for the editable toolbar, to search for images, upload them and insert them into content
+
+var MediaLibrary = (function() {
+    return {
+        // Show the media library for the toolbar, listing images from the json at url
+        Show: function(toolbar, url) {
+            // Remember the selection, as focus moves to the library
+            toolbar.range = MediaLibrary.selectionRange(toolbar.editable);
+
+            if (toolbar.library === undefined) {
+                toolbar.library = MediaLibrary.build(toolbar, url);
+                toolbar.parentNode.insertBefore(toolbar.library, toolbar.nextSibling);
+            }
+            toolbar.library.style.display = '';
+            toolbar.library.search.focus();
+            MediaLibrary.load(toolbar.library, 1);
+        },
+
+        // Hide the media library
+        Hide: function(library) {
+            library.style.display = 'none';
+        },
+
+        // build returns the elements of the media library for the toolbar
+        build: function(toolbar, url) {
+            var library = document.createElement('div');
+            library.className = 'media-library';
+            library.url = url;
+            library.innerHTML = '<div class="media-library-bar">' +
+                '<input type="search" class="media-library-search" placeholder="Search images">' +
+                '<label class="button media-library-upload">Upload<input type="file" accept="image/jpeg,image/png,image/gif"></label>' +
+                '<a href="#" class="media-library-close" title="Close">×</a>' +
+                '</div>' +
+                '<p class="media-library-status"></p>' +
+                '<ul class="media-library-images"></ul>' +
+                '<a href="#" class="button media-library-more">More images</a>';
+
+            library.search = library.querySelector('.media-library-search');
+            library.status = library.querySelector('.media-library-status');
+            library.list = library.querySelector('.media-library-images');
+            library.more = library.querySelector('.media-library-more');
+            library.toolbar = toolbar;
+
+            // Wait for a pause in typing before searching
+            var timer = null;
+            library.search.addEventListener('input', function(e) {
+                clearTimeout(timer);
+                timer = setTimeout(function() {
+                    MediaLibrary.load(library, 1);
+                }, 300);
+            });
+
+            // Don't submit the page form when return is pressed in search
+            library.search.addEventListener('keydown', function(e) {
+                if (e.keyCode == 13) {
+                    e.preventDefault();
+                }
+            });
+
+            library.more.addEventListener('click', function(e) {
+                e.preventDefault();
+                MediaLibrary.load(library, library.page + 1);
+            });
+
+            library.querySelector('.media-library-close').addEventListener('click', function(e) {
+                e.preventDefault();
+                MediaLibrary.Hide(library);
+            });
+
+            library.querySelector('.media-library-upload input').addEventListener('change', function(e) {
+                if (this.files.length > 0) {
+                    MediaLibrary.upload(library, this.files[0]);
+                }
+                this.value = '';
+            });
+
+            return library;
+        },
+
+        // load fetches page of the images matching the search, replacing the list for page 1
+        load: function(library, page) {
+            var url = library.url + '?page=' + page + '&q=' + encodeURIComponent(library.search.value);
+            library.status.textContent = 'Loading…';
+            DOM.Get(url, function(request) {
+                var data = JSON.parse(request.responseText);
+                if (page == 1) {
+                    library.list.innerHTML = '';
+                }
+                data.images.forEach(function(image) {
+                    library.list.appendChild(MediaLibrary.item(library, image));
+                });
+                library.page = data.page;
+                library.more.style.display = data.more ? '' : 'none';
+                library.status.textContent = library.list.children.length > 0 ? '' : 'No images found';
+            }, function() {
+                library.status.textContent = 'Sorry, the images could not be loaded';
+            });
+        },
+
+        // item returns a list item for the image, which inserts it when clicked
+        item: function(library, image) {
+            var li = document.createElement('li');
+            var a = document.createElement('a');
+            var img = document.createElement('img');
+            var name = document.createElement('span');
+            a.href = '#';
+            a.title = image.name;
+            img.src = image.thumbnail;
+            img.alt = image.alt;
+            img.loading = 'lazy';
+            name.textContent = image.name;
+            a.appendChild(img);
+            a.appendChild(name);
+            li.appendChild(a);
+
+            a.addEventListener('click', function(e) {
+                e.preventDefault();
+                MediaLibrary.insert(library.toolbar, image.html);
+                MediaLibrary.Hide(library);
+            });
+            return li;
+        },
+
+        // upload posts the file to the library, and inserts the image created
+        upload: function(library, file) {
+            var data = new FormData();
+            data.append('authenticity_token', authenticityToken());
+            data.append('file', file);
+
+            library.status.textContent = 'Uploading ' + file.name + '…';
+            var request = new XMLHttpRequest();
+            request.open('POST', library.url, true);
+            request.onload = function() {
+                if (request.status >= 200 && request.status < 400) {
+                    var image = JSON.parse(request.responseText);
+                    library.status.textContent = '';
+                    library.list.insertBefore(MediaLibrary.item(library, image), library.list.firstChild);
+                    MediaLibrary.insert(library.toolbar, image.html);
+                    MediaLibrary.Hide(library);
+                } else {
+                    library.status.textContent = 'Sorry, ' + file.name + ' could not be uploaded, please choose a jpeg, png or gif image';
+                }
+            };
+            request.onerror = function() {
+                library.status.textContent = 'Sorry, ' + file.name + ' could not be uploaded';
+            };
+            request.send(data);
+        },
+
+        // insert adds html at the selection remembered when the library was shown,
+        // in the html source if it is shown, or at the end of the content if there was no selection
+        insert: function(toolbar, html) {
+            var textarea = toolbar.textarea;
+            if (textarea.style.display !== 'none') {
+                var start = textarea.selectionStart;
+                textarea.value = textarea.value.slice(0, start) + html + textarea.value.slice(textarea.selectionEnd);
+                textarea.focus();
+                return;
+            }
+
+            toolbar.editable.focus();
+            var selection = window.getSelection();
+            selection.removeAllRanges();
+            if (toolbar.range) {
+                selection.addRange(toolbar.range);
+            } else {
+                var range = document.createRange();
+                range.selectNodeContents(toolbar.editable);
+                range.collapse(false);
+                selection.addRange(range);
+            }
+            document.execCommand('insertHTML', false, html);
+        },
+
+        // selectionRange returns the range selected within el, or null if the selection is elsewhere
+        selectionRange: function(el) {
+            var selection = window.getSelection();
+            if (!selection.rangeCount) {
+                return null;
+            }
+            var range = selection.getRangeAt(0);
+            if (!el.contains(range.commonAncestorContainer)) {
+                return null;
+            }
+            return range.cloneRange();
+        }
+    };
+}());
\ No newline at end of file
@@ -0,0 +1,44 @@
+// Show a preview of markdown beside the textarea as it is edited
+
+DOM.Ready(function() {
+    // Activate markdown editors
+    Markdown.Activate('.markdown-editor');
+});
+
+var Markdown = (function() {
+    return {
+        // Activate markdown editors with selector s, which post their text to the url in data-preview
+        Activate: function(s) {
+            if (!DOM.Exists(s)) {
+                return;
+            }
+
+            DOM.Each(s, function(editor) {
+                var textarea = editor.querySelector('.markdown-textarea');
+                var preview = editor.querySelector('.markdown-preview');
+                var url = editor.getAttribute('data-preview');
+                var timer = null;
+
+                // Wait for a pause in typing before updating the preview
+                textarea.addEventListener('input', function(e) {
+                    clearTimeout(timer);
+                    timer = setTimeout(function() {
+                        Markdown.updatePreview(url, textarea, preview);
+                    }, 300);
+                });
+
+                Markdown.updatePreview(url, textarea, preview);
+            });
+        },
+
+        // updatePreview posts the text of the textarea to url, and shows the html returned in preview
+        updatePreview: function(url, textarea, preview) {
+            var data = "authenticity_token=" + encodeURIComponent(authenticityToken()) + "&text=" + encodeURIComponent(textarea.value);
+            DOM.Post(url, data, function(request) {
+                preview.innerHTML = request.responseText;
+            }, function(request) {
+                console.log("error with markdown preview:", request);
+            });
+        }
+    };
+}());
\ No newline at end of file
@@ -0,0 +1,409 @@
+// Package fields defines the typed custom fields which templates may declare
+// for structured content, and reads, stores and returns their values.
+package fields
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/view/helpers"
+)
+
+// Field types which may be declared by templates.
+const (
+	Text     = "text"
+	RichText = "richtext"
+	Image    = "image"
+	Link     = "link"
+	Group    = "group"
+)
+
+// MaxLength is the maximum length of a value for a field.
+const MaxLength = 50000
+
+// MaxRows is the maximum number of rows in a repeatable group.
+const MaxRows = 50
+
+// Field is a custom field declared by a template, defined as:
+// Label | type | required | default: value
+// The type defaults to text. Groups are repeatable sets of fields, listed
+// in place of the default as Label, Label: type, for example:
+// Features | group | Title, Text: richtext
+type Field struct {
+	Name     string
+	Label    string
+	Type     string
+	Required bool
+	Default  string
+
+	// Fields are the fields in each row of a group
+	Fields []Field
+}
+
+// LinkValue is the value of a link field.
+type LinkValue struct {
+	URL  string
+	Text string
+}
+
+// Values are the values stored for fields, by field name. Text, rich text
+// and image values are strings, links are maps with url and text keys,
+// and groups are lists of rows of values.
+type Values map[string]interface{}
+
+// nameRegexp matches the characters replaced to form field names from labels.
+var nameRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Parse parses the field definition given.
+func Parse(definition string) (Field, error) {
+	parts := strings.Split(definition, "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+
+	f, err := newField(parts[0], Text)
+	if err != nil {
+		return f, err
+	}
+	if len(parts) > 1 && parts[1] != "" {
+		f.Type = strings.ToLower(parts[1])
+	}
+	switch f.Type {
+	case Text, RichText, Image, Link, Group:
+	default:
+		return f, fmt.Errorf("fields: %s has invalid type %s, it should be text, richtext, image, link or group", f.Label, f.Type)
+	}
+
+	for i := 2; i < len(parts); i++ {
+		p := parts[i]
+		switch {
+		case strings.ToLower(p) == "required":
+			f.Required = true
+		case strings.HasPrefix(strings.ToLower(p), "default:"):
+			f.Default = strings.TrimSpace(p[len("default:"):])
+		case f.Type == Group:
+			f.Fields, err = parseGroup(p)
+			if err != nil {
+				return f, err
+			}
+		case p != "":
+			return f, fmt.Errorf("fields: %s has invalid option %s", f.Label, p)
+		}
+	}
+
+	if f.Type == Group && len(f.Fields) == 0 {
+		return f, fmt.Errorf("fields: %s is a group with no fields", f.Label)
+	}
+	if f.Type == Group && f.Default != "" {
+		return f, fmt.Errorf("fields: %s is a group and cannot have a default", f.Label)
+	}
+
+	return f, nil
+}
+
+// ParseAll parses the field definitions given, rejecting repeated names.
+func ParseAll(definitions []string) ([]Field, error) {
+	var fields []Field
+	names := make(map[string]bool)
+	for _, d := range definitions {
+		f, err := Parse(d)
+		if err != nil {
+			return nil, err
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("fields: the field %s is repeated", f.Label)
+		}
+		names[f.Name] = true
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// parseGroup parses the fields of a group, listed as Label, Label: type.
+func parseGroup(list string) ([]Field, error) {
+	var fields []Field
+	names := make(map[string]bool)
+	for _, item := range strings.Split(list, ",") {
+		parts := strings.SplitN(item, ":", 2)
+		label := strings.TrimSpace(parts[0])
+		if label == "" {
+			continue
+		}
+
+		f, err := newField(label, Text)
+		if err != nil {
+			return nil, err
+		}
+		if len(parts) > 1 {
+			f.Type = strings.ToLower(strings.TrimSpace(parts[1]))
+		}
+		switch f.Type {
+		case Text, RichText, Image, Link:
+		default:
+			return nil, fmt.Errorf("fields: %s has invalid type %s in a group, it should be text, richtext, image or link", f.Label, f.Type)
+		}
+		if names[f.Name] {
+			return nil, fmt.Errorf("fields: the field %s is repeated in a group", f.Label)
+		}
+		names[f.Name] = true
+
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// newField returns a field with the label and type given, and a name formed from the label.
+func newField(label, fieldType string) (Field, error) {
+	f := Field{Label: label, Type: fieldType}
+	f.Name = strings.Trim(nameRegexp.ReplaceAllString(strings.ToLower(label), "_"), "_")
+	if f.Name == "" {
+		return f, fmt.Errorf("fields: a field has no label")
+	}
+	return f, nil
+}
+
+// Find returns the field with name in fields, and false if it is not found.
+func Find(fields []Field, name string) (Field, bool) {
+	for _, f := range fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Decode returns the values stored in data, values which cannot be
+// decoded are ignored so that the defaults are used instead.
+func Decode(data string) Values {
+	values := make(Values)
+	if data != "" {
+		json.Unmarshal([]byte(data), &values)
+	}
+	return values
+}
+
+// Encode returns the values encoded for storage.
+func (v Values) Encode() (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Value returns the value stored for the field in values, or the default
+// if none is stored. Text and rich text values are strings, images are
+// image ids, links are a LinkValue, and groups are a list of rows.
+func (f Field) Value(values map[string]interface{}) interface{} {
+	v, ok := values[f.Name]
+	switch f.Type {
+	case Image:
+		id, _ := strconv.ParseInt(toString(v, f.Default), 10, 64)
+		return id
+	case Link:
+		m, _ := v.(map[string]interface{})
+		if !ok || m == nil {
+			return LinkValue{URL: f.Default}
+		}
+		return LinkValue{URL: toString(m["url"], ""), Text: toString(m["text"], "")}
+	case Group:
+		var rows []map[string]interface{}
+		list, _ := v.([]interface{})
+		for _, r := range list {
+			row, _ := r.(map[string]interface{})
+			if row == nil {
+				continue
+			}
+			values := make(map[string]interface{})
+			for _, rf := range f.Fields {
+				values[rf.Name] = rf.Value(row)
+			}
+			rows = append(rows, values)
+		}
+		return rows
+	default:
+		return toString(v, f.Default)
+	}
+}
+
+// toString returns v if it is a string or number, or def if it is not set.
+func toString(v interface{}, def string) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64)
+	}
+	return def
+}
+
+// Read returns values for the fields given, using the param function given to
+// read them from inputs named by InputName, or an error describing the
+// first invalid value. Values stored in existing for fields not given are
+// kept, so that they are not lost if a template is changed.
+func Read(fields []Field, param func(string) string, existing Values) (Values, error) {
+	values := make(Values)
+	for k, v := range existing {
+		values[k] = v
+	}
+
+	for _, f := range fields {
+		v, err := f.read(f.InputName(), param)
+		if err != nil {
+			return nil, err
+		}
+		if f.Required && v == nil {
+			return nil, fmt.Errorf("%s is required", f.Label)
+		}
+		if v == nil {
+			delete(values, f.Name)
+			continue
+		}
+		values[f.Name] = v
+	}
+
+	return values, nil
+}
+
+// read returns the value of the field from the inputs named with prefix,
+// or nil if it is empty.
+func (f Field) read(prefix string, param func(string) string) (interface{}, error) {
+	switch f.Type {
+	case Link:
+		url := strings.TrimSpace(param(prefix + ".url"))
+		text := strings.TrimSpace(param(prefix + ".text"))
+		if url == "" && text == "" {
+			return nil, nil
+		}
+		if !validURL(url) {
+			return nil, fmt.Errorf("%s should have a link starting with /, http:// or https://", f.Label)
+		}
+		if len(text) > MaxLength {
+			return nil, fmt.Errorf("%s is too long", f.Label)
+		}
+		return map[string]interface{}{"url": url, "text": text}, nil
+
+	case Group:
+		var rows []interface{}
+		for i := 0; i < MaxRows; i++ {
+			row := make(map[string]interface{})
+			for _, rf := range f.Fields {
+				v, err := rf.read(fmt.Sprintf("%s.%d.%s", prefix, i, rf.Name), param)
+				if err != nil {
+					return nil, err
+				}
+				if v != nil {
+					row[rf.Name] = v
+				}
+			}
+			if len(row) > 0 {
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return rows, nil
+
+	default:
+		v := strings.TrimSpace(param(prefix))
+		if v == "" {
+			return nil, nil
+		}
+		if len(v) > MaxLength {
+			return nil, fmt.Errorf("%s is too long", f.Label)
+		}
+		if f.Type == Image {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || id < 1 {
+				return nil, fmt.Errorf("%s should be an image", f.Label)
+			}
+		}
+		return v, nil
+	}
+}
+
+// validURL returns true if url is a relative or web url.
+func validURL(url string) bool {
+	for _, prefix := range []string{"/", "http://", "https://", "mailto:"} {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// InputName returns the name used for the field input, link fields use two
+// inputs with this name followed by .url and .text.
+func (f Field) InputName() string {
+	return "fields." + f.Name
+}
+
+// Input describes an input for a field in an edit form, with the current value.
+type Input struct {
+	Field
+
+	// Name is the name of the input, rows in groups are numbered from 0
+	Name string
+
+	// Value is the current value, or the url for links
+	Value string
+
+	// LinkText is the current text for links
+	LinkText string
+
+	// Rows are the inputs for each row of a group, followed by an empty row for adding another
+	Rows [][]Input
+
+	// Options are the choices for image fields, set by the resource using the fields
+	Options []helpers.Option
+}
+
+// Selected returns true if id is the current value of an image input.
+func (i Input) Selected(id int64) bool {
+	return i.Value == strconv.FormatInt(id, 10)
+}
+
+// IsGroup returns true if this is the input for a group.
+func (i Input) IsGroup() bool {
+	return i.Type == Group
+}
+
+// Inputs returns inputs for the fields given with the values stored.
+func Inputs(fields []Field, values Values) []Input {
+	var inputs []Input
+	for _, f := range fields {
+		inputs = append(inputs, f.input(f.InputName(), f.Value(values)))
+	}
+	return inputs
+}
+
+// input returns the input named name for the field with the value v.
+func (f Field) input(name string, v interface{}) Input {
+	input := Input{Field: f, Name: name}
+	switch value := v.(type) {
+	case int64:
+		if value > 0 {
+			input.Value = strconv.FormatInt(value, 10)
+		}
+	case LinkValue:
+		input.Value = value.URL
+		input.LinkText = value.Text
+	case []map[string]interface{}:
+		// Add an empty row so that another may be added
+		value = append(value, map[string]interface{}{})
+		for i, row := range value {
+			var inputs []Input
+			for _, rf := range f.Fields {
+				inputs = append(inputs, rf.input(fmt.Sprintf("%s.%d.%s", name, i, rf.Name), rf.Value(row)))
+			}
+			input.Rows = append(input.Rows, inputs)
+		}
+	case string:
+		input.Value = value
+	}
+	return input
+}
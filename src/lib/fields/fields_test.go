@@ -0,0 +1,149 @@
+package fields
+
+import (
+	"testing"
+)
+
+var testDefinitions = []string{
+	"Hero Image | image | required",
+	"Subtitle | text | default: Welcome",
+	"Call To Action | link",
+	"Features | group | Title, Text: richtext, Link: link",
+}
+
+func TestParse(t *testing.T) {
+	fields, err := ParseAll(testDefinitions)
+	if err != nil || len(fields) != 4 {
+		t.Fatalf("fields: ParseAll failed :%v %s", fields, err)
+	}
+
+	f := fields[0]
+	if f.Name != "hero_image" || f.Type != Image || !f.Required || f.InputName() != "fields.hero_image" {
+		t.Fatalf("fields: Parse unexpected image field :%v", f)
+	}
+
+	f = fields[1]
+	if f.Name != "subtitle" || f.Type != Text || f.Required || f.Default != "Welcome" {
+		t.Fatalf("fields: Parse unexpected text field :%v", f)
+	}
+
+	f = fields[3]
+	if f.Type != Group || len(f.Fields) != 3 || f.Fields[0].Type != Text || f.Fields[1].Type != RichText || f.Fields[2].Name != "link" {
+		t.Fatalf("fields: Parse unexpected group field :%v", f)
+	}
+
+	invalid := [][]string{
+		{"Name | password"},
+		{" | text"},
+		{"Features | group"},
+		{"Features | group | Title, More: group"},
+		{"Features | group | Title | default: A"},
+		{"Name | text | unknown"},
+		{"Name", "Name | richtext"},
+	}
+	for _, d := range invalid {
+		_, err = ParseAll(d)
+		if err == nil {
+			t.Fatalf("fields: ParseAll accepted invalid definition :%v", d)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	fields, err := ParseAll(testDefinitions)
+	if err != nil {
+		t.Fatalf("fields: ParseAll failed :%s", err)
+	}
+
+	params := map[string]string{
+		"fields.hero_image":          "3",
+		"fields.call_to_action.url":  "/contact",
+		"fields.call_to_action.text": "Contact us",
+		"fields.features.0.title":    "Fast",
+		"fields.features.0.text":     "<p>Very fast</p>",
+		"fields.features.1.title":    "",
+		"fields.features.2.title":    "Simple",
+	}
+	param := func(k string) string { return params[k] }
+
+	existing := Values{"removed": "kept", "subtitle": "Old"}
+	values, err := Read(fields, param, existing)
+	if err != nil {
+		t.Fatalf("fields: Read failed :%s", err)
+	}
+
+	// Values for fields not in the template are kept, empty values are removed
+	if values["removed"] != "kept" || values["subtitle"] != nil {
+		t.Fatalf("fields: Read unexpected values :%v", values)
+	}
+
+	// Round trip the values through storage
+	data, err := values.Encode()
+	if err != nil {
+		t.Fatalf("fields: Encode failed :%s", err)
+	}
+	values = Decode(data)
+
+	if fields[0].Value(values) != int64(3) || fields[1].Value(values) != "Welcome" {
+		t.Fatalf("fields: unexpected values :%v", values)
+	}
+
+	link := fields[2].Value(values).(LinkValue)
+	if link.URL != "/contact" || link.Text != "Contact us" {
+		t.Fatalf("fields: unexpected link value :%v", link)
+	}
+
+	rows := fields[3].Value(values).([]map[string]interface{})
+	if len(rows) != 2 || rows[0]["text"] != "<p>Very fast</p>" || rows[1]["title"] != "Simple" || rows[1]["link"] != (LinkValue{}) {
+		t.Fatalf("fields: unexpected group value :%v", rows)
+	}
+
+	invalid := map[string]string{
+		"fields.hero_image":          "",
+		"fields.call_to_action.url":  "javascript:alert(1)",
+		"fields.features.0.link.url": "example.com",
+	}
+	for k, v := range invalid {
+		old := params[k]
+		params[k] = v
+		_, err = Read(fields, param, nil)
+		if err == nil {
+			t.Fatalf("fields: Read accepted invalid %s :%s", k, v)
+		}
+		params[k] = old
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	fields, err := ParseAll(testDefinitions)
+	if err != nil {
+		t.Fatalf("fields: ParseAll failed :%s", err)
+	}
+
+	// Values stored before fields were added, or which cannot be decoded, use the defaults
+	for _, data := range []string{"", "{not json", `{"features":"not a list"}`} {
+		values := Decode(data)
+		if fields[0].Value(values) != int64(0) || fields[1].Value(values) != "Welcome" || len(fields[3].Value(values).([]map[string]interface{})) != 0 {
+			t.Fatalf("fields: unexpected defaults for %s :%v", data, values)
+		}
+	}
+}
+
+func TestInputs(t *testing.T) {
+	fields, err := ParseAll(testDefinitions)
+	if err != nil {
+		t.Fatalf("fields: ParseAll failed :%s", err)
+	}
+
+	values := Decode(`{"hero_image":"3","features":[{"title":"Fast"}]}`)
+	inputs := Inputs(fields, values)
+	if len(inputs) != 4 || !inputs[0].Selected(3) || inputs[1].Value != "Welcome" {
+		t.Fatalf("fields: unexpected inputs :%v", inputs)
+	}
+
+	// Groups have a row for each value and an empty row
+	rows := inputs[3].Rows
+	if len(rows) != 2 || rows[0][0].Name != "fields.features.0.title" || rows[0][0].Value != "Fast" || rows[1][0].Value != "" {
+		t.Fatalf("fields: unexpected group inputs :%v", rows)
+	}
+}
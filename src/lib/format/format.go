@@ -0,0 +1,77 @@
+// Package format lets resources such as pages and posts choose the format of
+// their text, html from the editor or markdown, and renders text in either.
+package format
+
+import (
+	"html/template"
+
+	"github.com/fragmenta/view/helpers"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/markdown"
+	"github.com/fragmenta/fragmenta-cms/src/lib/sanitize"
+)
+
+// Format values valid in the format field added with format.ResourceFormat.
+const (
+	HTML     = 0
+	Markdown = 1
+)
+
+// ResourceFormat adds a format field to resources with text.
+type ResourceFormat struct {
+	Format int64
+}
+
+// Options returns an array of formats for a format select.
+func Options() []helpers.Option {
+	var options []helpers.Option
+
+	options = append(options, helpers.Option{Id: HTML, Name: "HTML"})
+	options = append(options, helpers.Option{Id: Markdown, Name: "Markdown"})
+
+	return options
+}
+
+// FormatOptions returns an array of formats for a format select for this resource.
+func (r *ResourceFormat) FormatOptions() []helpers.Option {
+	return Options()
+}
+
+// FormatDisplay returns a string representation of the resource format.
+func (r *ResourceFormat) FormatDisplay() string {
+	for _, o := range r.FormatOptions() {
+		if o.Id == r.Format {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// IsMarkdown returns true if the text of this resource is markdown.
+func (r *ResourceFormat) IsMarkdown() bool {
+	return r.Format == Markdown
+}
+
+// Render returns the html for text in the format f. Html was cleaned for its
+// author when saved, so is sanitized here with the tags allowed for admins.
+func Render(text string, f int64) template.HTML {
+	if f == Markdown {
+		return template.HTML(markdown.Render(text))
+	}
+	return sanitize.Content(text)
+}
+
+// Text returns text to save in the format to, converting it from the format
+// from if they differ, and cleaning html for the user. Converting html to
+// markdown removes html which markdown can't express, such as iframes.
+func Text(text string, from, to int64, u sanitize.User) string {
+	switch {
+	case to == Markdown && from != Markdown:
+		return markdown.FromHTML(sanitize.HTML(text, u))
+	case to == Markdown:
+		return text
+	case from == Markdown:
+		text = markdown.Render(text)
+	}
+	return sanitize.HTML(text, u)
+}
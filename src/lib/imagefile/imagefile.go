@@ -0,0 +1,200 @@
+// Package imagefile reads uploaded images, turning them upright with their exif
+// orientation, and writes them without metadata, resized or cropped as required.
+package imagefile
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// MaxPixels is the largest image accepted, which limits the memory used to decode it.
+const MaxPixels = 50000000
+
+// Quality is the quality of jpegs written.
+const Quality = 85
+
+// Types maps the image formats accepted to their mime types.
+var Types = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+}
+
+// Extensions maps the mime types accepted to the extension used for their files.
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// File is an uploaded image.
+type File struct {
+	// Image is the image turned upright
+	Image image.Image
+
+	// MimeType is the type of the image, one of those in Types
+	MimeType string
+
+	// data is the file as uploaded
+	data []byte
+}
+
+// Read returns the image read from r, which must be a jpeg, png or gif,
+// with jpegs turned upright with the orientation in their exif metadata.
+func Read(r io.Reader) (*File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("imagefile: the file is not a jpeg, png or gif image")
+	}
+	mimeType, ok := Types[format]
+	if !ok {
+		return nil, errors.New("imagefile: the file is not a jpeg, png or gif image")
+	}
+	if config.Width*config.Height > MaxPixels || config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("imagefile: the image is too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		img = Orient(img, Orientation(data))
+	}
+
+	return &File{Image: img, MimeType: mimeType, data: data}, nil
+}
+
+// Width returns the width of the upright image.
+func (f *File) Width() int {
+	return f.Image.Bounds().Dx()
+}
+
+// Height returns the height of the upright image.
+func (f *File) Height() int {
+	return f.Image.Bounds().Dy()
+}
+
+// Extension returns the extension for files of this type, including the dot.
+func (f *File) Extension() string {
+	return Extensions[f.MimeType]
+}
+
+// Write writes the upright image to w without any metadata. Gifs are written
+// as uploaded to keep their animation, as they don't carry exif metadata.
+func (f *File) Write(w io.Writer) error {
+	if f.MimeType == "image/gif" {
+		_, err := w.Write(f.data)
+		return err
+	}
+	return Encode(w, f.Image, f.MimeType)
+}
+
+// Encode writes img to w in the format of mimeType.
+func Encode(w io.Writer, img image.Image, mimeType string) error {
+	switch mimeType {
+	case "image/png":
+		return png.Encode(w, img)
+	case "image/gif":
+		return gif.Encode(w, img, nil)
+	}
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: Quality})
+}
+
+// Resize returns img scaled to width and height, averaging the pixels of
+// img covered by each pixel of the result.
+func Resize(img image.Image, width, height int) image.Image {
+	src := rgba(img)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if sw == 0 || sh == 0 {
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0, y1 := span(y, height, sh)
+		for x := 0; x < width; x++ {
+			x0, x1 := span(x, width, sw)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+					i += 4
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// ResizeWidth returns img scaled to width, keeping its aspect ratio.
+func ResizeWidth(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	return Resize(img, width, height)
+}
+
+// Square returns the centre of img cropped to a square and scaled to size,
+// images smaller than size are cropped but not enlarged.
+func Square(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	crop := rgba(img).SubImage(image.Rect(x, y, x+side, y+side))
+
+	if side < size {
+		size = side
+	}
+	return Resize(crop, size, size)
+}
+
+// span returns the range of source pixels covered by pixel i of n, from a source of length size.
+func span(i, n, size int) (int, int) {
+	start := i * size / n
+	end := (i + 1) * size / n
+	if end <= start {
+		end = start + 1
+	}
+	return start, end
+}
+
+// rgba returns img as an RGBA image with bounds starting at 0,0.
+func rgba(img image.Image) *image.RGBA {
+	if r, ok := img.(*image.RGBA); ok && r.Bounds().Min == (image.Point{}) {
+		return r
+	}
+	b := img.Bounds()
+	r := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(r, r.Bounds(), img, b.Min, draw.Src)
+	return r
+}
@@ -0,0 +1,131 @@
+package imagefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{255, 0, 0, 255}
+	blue = color.RGBA{0, 0, 255, 255}
+)
+
+// testImage returns an image of width and height, blue with a red pixel at the top left.
+func testImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, blue)
+		}
+	}
+	img.Set(0, 0, red)
+	return img
+}
+
+// exifJPEG returns a jpeg of img with exif metadata containing the orientation and a gps tag.
+func exifJPEG(t *testing.T, img image.Image, orientation uint16) []byte {
+	var b bytes.Buffer
+	err := jpeg.Encode(&b, img, nil)
+	if err != nil {
+		t.Fatalf("imagefile: error encoding jpeg %s", err)
+	}
+
+	// Build a little endian tiff structure with an orientation and a gps ifd pointer
+	tiff := []byte("II\x2a\x00\x08\x00\x00\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 2)
+	for _, e := range [][3]uint16{{0x0112, 3, orientation}, {0x8825, 4, 0}} {
+		tiff = binary.LittleEndian.AppendUint16(tiff, e[0])
+		tiff = binary.LittleEndian.AppendUint16(tiff, e[1])
+		tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+		tiff = binary.LittleEndian.AppendUint32(tiff, uint32(e[2]))
+	}
+	tiff = append(tiff, 0, 0, 0, 0)
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+
+	data := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+	data = append(data, segment...)
+	return append(data, b.Bytes()[2:]...)
+}
+
+// TestOrient tests images are turned upright for each orientation.
+func TestOrient(t *testing.T) {
+	// Expected positions of the red pixel from the top left of a 3x2 image
+	expected := map[int]image.Point{
+		1: {0, 0}, 2: {2, 0}, 3: {2, 1}, 4: {0, 1},
+		5: {0, 0}, 6: {1, 0}, 7: {1, 2}, 8: {0, 2},
+	}
+	for o, p := range expected {
+		img := Orient(testImage(3, 2), o)
+		b := img.Bounds()
+		if (o >= 5 && (b.Dx() != 2 || b.Dy() != 3)) || (o < 5 && (b.Dx() != 3 || b.Dy() != 2)) {
+			t.Fatalf("imagefile: unexpected size for orientation %d got:%v", o, b)
+		}
+		if img.At(p.X, p.Y) != color.Color(red) {
+			t.Errorf("imagefile: unexpected pixels for orientation %d expected red at %v", o, p)
+		}
+	}
+}
+
+// TestRead tests jpegs are turned upright and written without exif metadata.
+func TestRead(t *testing.T) {
+	data := exifJPEG(t, testImage(40, 20), 6)
+	if Orientation(data) != 6 {
+		t.Fatalf("imagefile: unexpected orientation got:%d", Orientation(data))
+	}
+
+	f, err := Read(bytes.NewReader(data))
+	if err != nil || f.MimeType != "image/jpeg" || f.Extension() != ".jpg" || f.Width() != 20 || f.Height() != 40 {
+		t.Fatalf("imagefile: unexpected file for jpeg %v %s", f, err)
+	}
+
+	var out bytes.Buffer
+	err = f.Write(&out)
+	if err != nil || bytes.Contains(out.Bytes(), []byte("Exif")) || Orientation(out.Bytes()) != 1 {
+		t.Fatalf("imagefile: exif not removed from jpeg %s", err)
+	}
+
+	_, err = Read(bytes.NewReader([]byte("<svg></svg>")))
+	if err == nil {
+		t.Fatalf("imagefile: read svg without error")
+	}
+}
+
+// TestResize tests images are resized and cropped.
+func TestResize(t *testing.T) {
+	var b bytes.Buffer
+	err := png.Encode(&b, testImage(300, 200))
+	if err != nil {
+		t.Fatalf("imagefile: error encoding png %s", err)
+	}
+	f, err := Read(&b)
+	if err != nil || f.MimeType != "image/png" {
+		t.Fatalf("imagefile: unexpected file for png %v %s", f, err)
+	}
+
+	img := ResizeWidth(f.Image, 150)
+	if img.Bounds().Dx() != 150 || img.Bounds().Dy() != 100 {
+		t.Fatalf("imagefile: unexpected size after resize got:%v", img.Bounds())
+	}
+
+	// Resizing averages pixels, the red pixel is mixed with blue
+	r, _, bl, _ := img.At(0, 0).RGBA()
+	if r == 0 || bl == 0 {
+		t.Fatalf("imagefile: unexpected pixel after resize got:%v", img.At(0, 0))
+	}
+
+	img = Square(f.Image, 64)
+	if img.Bounds().Dx() != 64 || img.Bounds().Dy() != 64 || img.At(0, 0) != color.Color(blue) {
+		t.Fatalf("imagefile: unexpected square got:%v %v", img.Bounds(), img.At(0, 0))
+	}
+
+	img = Square(testImage(30, 50), 64)
+	if img.Bounds().Dx() != 30 || img.Bounds().Dy() != 30 {
+		t.Fatalf("imagefile: unexpected square for small image got:%v", img.Bounds())
+	}
+}
@@ -0,0 +1,108 @@
+package imagefile
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// orientationTag is the exif tag for the orientation of the image.
+const orientationTag = 0x0112
+
+// Orientation returns the exif orientation of the jpeg data given, from 1 to 8,
+// or 1 (upright) if it has none.
+func Orientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Look through the segments before the image data for the exif segment
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF; {
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || length < 2 || i+2+length > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation returns the orientation in the tiff structure of exif metadata.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == orientationTag {
+			o := int(order.Uint16(tiff[entry+8:]))
+			if o < 1 || o > 8 {
+				return 1
+			}
+			return o
+		}
+	}
+	return 1
+}
+
+// Orient returns img turned upright for the exif orientation given.
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	src := rgba(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flipped horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flipped vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 clockwise to display
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 anticlockwise to display
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
@@ -0,0 +1,88 @@
+package maildir
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	m "github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// Service writes mail to files in a maildir rather than sending it,
+// for staging servers, and conforms to mail.Service.
+type Service struct {
+	from string
+	path string
+}
+
+// New returns a new maildir Service which writes mail to the maildir at path.
+func New(f string, p string) *Service {
+	return &Service{
+		from: f,
+		path: p,
+	}
+}
+
+// Send writes the given message to a new file in the maildir.
+func (s *Service) Send(email *m.Email) error {
+
+	if s.path == "" {
+		return errors.New("mail: invalid mail settings")
+	}
+
+	// Set the default from if required
+	if email.From == "" {
+		email.From = s.from
+	}
+
+	// Check if other fields are filled in on email
+	if email.Invalid() {
+		return errors.New("mail: attempt to send invalid email")
+	}
+
+	message, err := m.Message(email)
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range []string{"tmp", "new", "cur"} {
+		err = os.MkdirAll(filepath.Join(s.path, dir), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write to tmp then move to new, so that readers never see partial messages
+	name, err := uniqueName()
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.path, "tmp", name)
+	err = ioutil.WriteFile(tmp, message, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(s.path, "new", name))
+}
+
+// uniqueName returns a unique name for a message file in the maildir.
+func uniqueName() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+
+	r := make([]byte, 8)
+	_, err = rand.Read(r)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d.%s.%s", time.Now().UnixNano(), hex.EncodeToString(r), host), nil
+}
@@ -0,0 +1,46 @@
+package maildir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// TestMaildir tests mail is written to new in the maildir.
+func TestMaildir(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "fragmenta-maildir")
+	if err != nil {
+		t.Fatalf("maildir: failed to create dir %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := New("from@example.com", dir)
+
+	email := mail.New("example@example.com")
+	err = s.Send(email)
+	if err == nil {
+		t.Fatalf("maildir: failed to error on invalid email")
+	}
+
+	email.Subject = "Subject"
+	email.Body = "<h1>Body</h1>"
+	err = s.Send(email)
+	if err != nil {
+		t.Fatalf("maildir: failed to send %s", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("maildir: expected 1 message got:%d", len(files))
+	}
+
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil || !strings.Contains(string(data), "To: example@example.com") {
+		t.Fatalf("maildir: unexpected message %s", data)
+	}
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"errors"
+	"sync"
+
+	m "github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// Service records mail in memory rather than sending it, so that tests
+// may check the mail sent, and conforms to mail.Service.
+type Service struct {
+	from  string
+	mutex sync.Mutex
+	sent  []*m.Email
+}
+
+// New returns a new memory Service.
+func New(f string) *Service {
+	return &Service{
+		from: f,
+	}
+}
+
+// Send records a copy of the given message.
+func (s *Service) Send(email *m.Email) error {
+
+	// Set the default from if required
+	if email.From == "" {
+		email.From = s.from
+	}
+
+	// Check if other fields are filled in on email
+	if email.Invalid() {
+		return errors.New("mail: attempt to send invalid email")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	e := *email
+	e.Recipients = append([]string(nil), email.Recipients...)
+	e.CC = append([]string(nil), email.CC...)
+	e.BCC = append([]string(nil), email.BCC...)
+	e.Attachments = append([]m.Attachment(nil), email.Attachments...)
+	s.sent = append(s.sent, &e)
+	return nil
+}
+
+// Sent returns the mail sent since the service was created or reset, oldest first.
+func (s *Service) Sent() []*m.Email {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]*m.Email(nil), s.sent...)
+}
+
+// Last returns the last mail sent, or nil if none has been sent.
+func (s *Service) Last() *m.Email {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.sent) == 0 {
+		return nil
+	}
+	return s.sent[len(s.sent)-1]
+}
+
+// Reset clears the mail sent.
+func (s *Service) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sent = nil
+}
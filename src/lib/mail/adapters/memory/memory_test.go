@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// TestMemory tests mail sent is recorded.
+func TestMemory(t *testing.T) {
+
+	s := New("from@example.com")
+
+	email := mail.New("example@example.com")
+	err := s.Send(email)
+	if err == nil || len(s.Sent()) != 0 {
+		t.Fatalf("memory: failed to error on invalid email")
+	}
+
+	email.Subject = "Subject"
+	email.Body = "<h1>Body</h1>"
+	err = s.Send(email)
+	if err != nil {
+		t.Fatalf("memory: failed to send %s", err)
+	}
+
+	last := s.Last()
+	if last == nil || last.Subject != "Subject" || last.From != "from@example.com" {
+		t.Fatalf("memory: unexpected mail %v", last)
+	}
+
+	s.Reset()
+	if len(s.Sent()) != 0 || s.Last() != nil {
+		t.Fatalf("memory: failed to reset")
+	}
+}
@@ -1,6 +1,7 @@
 package sendgrid
 
 import (
+	"encoding/base64"
 	"errors"
 
 	"github.com/sendgrid/sendgrid-go"
@@ -31,8 +32,8 @@ func (s *Service) Send(email *m.Email) error {
 	}
 
 	// Set the default from if required
-	if email.ReplyTo == "" {
-		email.ReplyTo = s.from
+	if email.From == "" {
+		email.From = s.from
 	}
 
 	// Check if other fields are filled in on email
@@ -41,24 +42,41 @@ func (s *Service) Send(email *m.Email) error {
 	}
 
 	// Create a sendgrid message with the byzantine sendgrid API
-	sendgridContent := mail.NewContent("text/html", email.Body)
-	var sendgridRecipients []*mail.Email
-	for _, r := range email.Recipients {
-		// We could possibly split recipients on <> to get email (e.g. name<example@example.com>)
-		// for now we assume they are just an email address
-		sendgridRecipients = append(sendgridRecipients, mail.NewEmail("", r))
-	}
-
 	message := mail.NewV3Mail()
 	message.Subject = email.Subject
-	message.From = mail.NewEmail("", email.ReplyTo)
+	message.From = sendgridEmail(email.From)
 	if email.ReplyTo != "" {
-		message.SetReplyTo(mail.NewEmail("", email.ReplyTo))
+		message.SetReplyTo(sendgridEmail(email.ReplyTo))
 	}
+
 	p := mail.NewPersonalization()
-	p.AddTos(sendgridRecipients...)
+	p.AddTos(sendgridEmails(email.Recipients)...)
+	if len(email.CC) > 0 {
+		p.AddCCs(sendgridEmails(email.CC)...)
+	}
+	if len(email.BCC) > 0 {
+		p.AddBCCs(sendgridEmails(email.BCC)...)
+	}
 	message.AddPersonalizations(p)
-	message.AddContent(sendgridContent)
+
+	// Sendgrid requires the text content before the html
+	if email.Text != "" {
+		message.AddContent(mail.NewContent("text/plain", email.Text))
+	}
+	message.AddContent(mail.NewContent("text/html", email.Body))
+
+	for k, v := range email.Headers {
+		message.SetHeader(k, v)
+	}
+
+	for _, a := range email.Attachments {
+		attachment := mail.NewAttachment()
+		attachment.SetFilename(a.Name)
+		attachment.SetType(a.ContentType)
+		attachment.SetDisposition("attachment")
+		attachment.SetContent(base64.StdEncoding.EncodeToString(a.Data))
+		message.AddAttachment(attachment)
+	}
 
 	request := sendgrid.GetRequest(s.secret, "/v3/mail/send", "https://api.sendgrid.com")
 	request.Method = "POST"
@@ -66,3 +84,18 @@ func (s *Service) Send(email *m.Email) error {
 	_, err := sendgrid.API(request)
 	return err
 }
+
+// sendgridEmail returns a sendgrid email for an address which may include a name.
+func sendgridEmail(address string) *mail.Email {
+	name, email := m.ParseAddress(address)
+	return mail.NewEmail(name, email)
+}
+
+// sendgridEmails returns sendgrid emails for a list of addresses.
+func sendgridEmails(addresses []string) []*mail.Email {
+	var emails []*mail.Email
+	for _, a := range addresses {
+		emails = append(emails, sendgridEmail(a))
+	}
+	return emails
+}
@@ -0,0 +1,157 @@
+package smtp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+
+	m "github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// Security options for the connection to the smtp server.
+const (
+	// StartTLS connects without tls, then upgrades the connection with STARTTLS (usually on port 587).
+	StartTLS = "starttls"
+	// TLS connects with tls from the start (usually on port 465).
+	TLS = "tls"
+	// None uses no encryption, and should only be used for local servers.
+	None = "none"
+)
+
+// timeout is the timeout for connecting to the smtp server.
+const timeout = 30 * time.Second
+
+// Config holds the settings for connecting to an smtp server.
+type Config struct {
+	From     string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Security string
+}
+
+// Service sends mail via an smtp server and conforms to mail.Service.
+type Service struct {
+	config Config
+}
+
+// New returns a new smtp Service, using StartTLS if no security is set.
+func New(c Config) *Service {
+	if c.Security == "" {
+		c.Security = StartTLS
+	}
+	return &Service{
+		config: c,
+	}
+}
+
+// Send the given message to recipients via the smtp server.
+func (s *Service) Send(email *m.Email) error {
+
+	if s.config.Host == "" {
+		return errors.New("mail: invalid mail settings")
+	}
+
+	// Set the default from if required
+	if email.From == "" {
+		email.From = s.config.From
+	}
+
+	// Check if other fields are filled in on email
+	if email.Invalid() {
+		return errors.New("mail: attempt to send invalid email")
+	}
+
+	message, err := m.Message(email)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if s.config.User != "" {
+		err = c.Auth(smtp.PlainAuth("", s.config.User, s.config.Password, s.config.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	_, from := m.ParseAddress(email.From)
+	err = c.Mail(from)
+	if err != nil {
+		return err
+	}
+
+	// Send to all recipients, bcc recipients are left out of the message headers
+	var recipients []string
+	recipients = append(recipients, email.Recipients...)
+	recipients = append(recipients, email.CC...)
+	recipients = append(recipients, email.BCC...)
+	for _, r := range recipients {
+		_, address := m.ParseAddress(r)
+		err = c.Rcpt(address)
+		if err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(message)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// dial connects to the smtp server with the security configured.
+func (s *Service) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.config.Host, s.config.Port)
+	tlsConfig := &tls.Config{ServerName: s.config.Host}
+
+	var conn net.Conn
+	var err error
+	if s.config.Security == TLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := smtp.NewClient(conn, s.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if s.config.Security == StartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			c.Close()
+			return nil, fmt.Errorf("mail: smtp server %s does not support STARTTLS", addr)
+		}
+		err = c.StartTLS(tlsConfig)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,103 @@
+package smtp
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+)
+
+// TestSMTP tests sending mail to a fake local smtp server.
+func TestSMTP(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("smtp: failed to listen %s", err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go fakeServer(l, received)
+
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	s := New(Config{From: "from@example.com", Host: host, Port: port, Security: None})
+
+	email := mail.New("Example <example@example.com>")
+	email.BCC = []string{"bcc@example.com"}
+	email.Subject = "Subject"
+	email.Body = "<h1>Body</h1>"
+	err = s.Send(email)
+	if err != nil {
+		t.Fatalf("smtp: failed to send %s", err)
+	}
+
+	data := <-received
+	if !strings.Contains(data, "MAIL FROM:<from@example.com>") || !strings.Contains(data, "RCPT TO:<example@example.com>") {
+		t.Fatalf("smtp: unexpected commands %s", data)
+	}
+	if !strings.Contains(data, "RCPT TO:<bcc@example.com>") || strings.Contains(data, "Bcc:") {
+		t.Fatalf("smtp: unexpected bcc handling %s", data)
+	}
+	if !strings.Contains(data, "Subject: Subject") || !strings.Contains(data, "<h1>Body</h1>") {
+		t.Fatalf("smtp: unexpected message %s", data)
+	}
+
+	// Servers without STARTTLS should be refused unless security is none
+	go fakeServer(l, received)
+	s = New(Config{From: "from@example.com", Host: host, Port: port})
+	err = s.Send(mail.New("example@example.com"))
+	if err == nil {
+		t.Fatalf("smtp: failed to error on invalid email")
+	}
+	email = mail.New("example@example.com")
+	email.Subject = "Subject"
+	email.Body = "Body"
+	err = s.Send(email)
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("smtp: failed to error without STARTTLS %v", err)
+	}
+}
+
+// fakeServer accepts one connection, replying to smtp commands and sending all data received.
+func fakeServer(l net.Listener, received chan<- string) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var data []string
+	r := bufio.NewReader(conn)
+	reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	inData := false
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		data = append(data, line)
+		switch {
+		case inData:
+			if line == "." {
+				inData = false
+				reply("250 OK")
+			}
+		case strings.HasPrefix(line, "EHLO"):
+			reply("250 localhost")
+		case strings.HasPrefix(line, "DATA"):
+			inData = true
+			reply("354 Go ahead")
+		case strings.HasPrefix(line, "QUIT"):
+			reply("221 Bye")
+			received <- strings.Join(data, "\n")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+	received <- strings.Join(data, "\n")
+}
@@ -2,16 +2,34 @@ package mail
 
 import (
 	"fmt"
+	"io/ioutil"
+	"mime"
+	netmail "net/mail"
+	"path/filepath"
 )
 
-// Email represents an email to be sent.
+// Email represents an email to be sent. Addresses may include a name,
+// for example Example <example@example.com>.
 type Email struct {
-	Recipients []string
-	ReplyTo    string
-	Subject    string
-	Body       string
-	Template   string
-	Layout     string
+	Recipients  []string
+	CC          []string
+	BCC         []string
+	From        string
+	ReplyTo     string
+	Subject     string
+	Body        string
+	Text        string
+	Headers     map[string]string
+	Attachments []Attachment
+	Template    string
+	Layout      string
+}
+
+// Attachment represents a file attached to an email.
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
 }
 
 // New returns a new email with the default tenplates and the given recipient.
@@ -26,10 +44,57 @@ func New(r string) *Email {
 
 // String returns a formatted string representation for debug.
 func (e *Email) String() string {
-	return fmt.Sprintf("email to:%v from:%s subject:%s\n\n%s", e.Recipients, e.ReplyTo, e.Subject, e.Body)
+	return fmt.Sprintf("email to:%v cc:%v bcc:%v from:%s reply to:%s subject:%s attachments:%d\n\n%s", e.Recipients, e.CC, e.BCC, e.From, e.ReplyTo, e.Subject, len(e.Attachments), e.Body)
 }
 
 // Invalid returns true if this email is not ready to send.
 func (e *Email) Invalid() bool {
-	return (e.ReplyTo == "" || e.Subject == "" || e.Body == "")
+	return (e.From == "" || e.Subject == "" || e.Body == "" || len(e.Recipients) == 0)
+}
+
+// Attach adds an attachment with the name and data given, with a content type from the name.
+func (e *Email) Attach(name string, data []byte) {
+	e.Attachments = append(e.Attachments, Attachment{Name: name, ContentType: ContentType(name), Data: data})
+}
+
+// AttachFile adds the file at path as an attachment.
+func (e *Email) AttachFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	e.Attach(filepath.Base(path), data)
+	return nil
+}
+
+// ContentType returns the content type for a file name, from its extension.
+func ContentType(name string) string {
+	t := mime.TypeByExtension(filepath.Ext(name))
+	if t == "" {
+		t = "application/octet-stream"
+	}
+	return t
+}
+
+// ParseAddress splits an address into name and email address,
+// e.g. Example <example@example.com> returns Example and example@example.com.
+// If the address cannot be parsed it is returned as the email address.
+func ParseAddress(s string) (string, string) {
+	a, err := netmail.ParseAddress(s)
+	if err != nil {
+		return "", s
+	}
+	return a.Name, a.Address
+}
+
+// FormatAddress formats an address for use in headers, encoding names if required.
+func FormatAddress(s string) string {
+	a, err := netmail.ParseAddress(s)
+	if err != nil {
+		return s
+	}
+	if a.Name == "" {
+		return a.Address
+	}
+	return a.String()
 }
@@ -7,7 +7,8 @@ import (
 	"github.com/fragmenta/view"
 )
 
-// TODO - add more mail services, at present only sendgrid is supported
+// Adapters for sendgrid, smtp, files (in maildir format) and memory (for tests)
+// are in the adapters dir, and should be set as the Service on startup.
 // Usage:
 // email := mail.New(recipient)
 // email.Subject = "blah"
@@ -19,28 +20,49 @@ type Sender interface {
 	Send(email *Email) error
 }
 
+// Queuer is the interface for queues which store mail to be delivered later.
+type Queuer interface {
+	Add(email *Email) error
+}
+
 // Context defines a simple list of string:value pairs for mail templates.
 type Context map[string]interface{}
 
-// Production should be set to true in production environment.
-var Production = false
-
-// Service is the mail adapter to send with and should be set on startup.
+// Service is the mail adapter to send with and should be set on startup,
+// if it is nil mail is logged to stdout rather than sent (for development).
 var Service Sender
 
-// Send the email using our default adapter and optional context.
+// Queue stores mail to be delivered outside the request, if it is nil mail
+// is delivered immediately by Send.
+var Queue Queuer
+
+// Send the email using our default adapter and optional context, or add it
+// to the Queue if we have one. If the email has no text part, one is generated
+// from the html body.
 func Send(email *Email, context Context) error {
 	// If we have a template, render the email in that template
 	if email.Body == "" && email.Template != "" {
-		var err error
-		email.Body, err = RenderTemplate(email, context)
+		err := RenderTemplate(email, context)
 		if err != nil {
 			return err
 		}
 	}
 
-	// If dev just log and return, don't send messages
-	if !Production {
+	if email.Text == "" {
+		email.Text = HTMLToText(email.Body)
+	}
+
+	if Queue != nil {
+		return Queue.Add(email)
+	}
+
+	return Deliver(email)
+}
+
+// Deliver sends the rendered email immediately using our default adapter.
+func Deliver(email *Email) error {
+	// If we have no adapter just log and return, don't send messages
+	if Service == nil {
 		fmt.Printf("#debug mail sent:%s\n", email)
 		return nil
 	}
@@ -48,10 +70,11 @@ func Send(email *Email, context Context) error {
 	return Service.Send(email)
 }
 
-// RenderTemplate renders the email into its template with context.
-func RenderTemplate(email *Email, context Context) (string, error) {
+// RenderTemplate renders the email body into its template with context,
+// and sets the text part of the email from the html rendered.
+func RenderTemplate(email *Email, context Context) error {
 	if email.Template == "" || context == nil {
-		return "", errors.New("mail: missing template or context")
+		return errors.New("mail: missing template or context")
 	}
 
 	view := view.NewWithPath("", nil)
@@ -60,8 +83,10 @@ func RenderTemplate(email *Email, context Context) (string, error) {
 	view.Context(context)
 	body, err := view.RenderToStringWithLayout()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return body, nil
+	email.Body = body
+	email.Text = HTMLToText(body)
+	return nil
 }
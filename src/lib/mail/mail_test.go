@@ -1,6 +1,7 @@
 package mail
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/fragmenta/view"
@@ -9,7 +10,9 @@ import (
 // TestMail tests that mail formats properly in dev mode
 func TestMail(t *testing.T) {
 
-	// In order to test, we rely on the view pkg being set up
+	// In order to test, we rely on the view pkg being set up, templates
+	// may also use the menu helper added by the app
+	view.Helpers["menu"] = func(string) []interface{} { return nil }
 	err := view.LoadTemplatesAtPaths([]string{"../.."}, view.Helpers)
 	if err != nil {
 		t.Errorf("mail: failed to load views")
@@ -21,15 +24,72 @@ func TestMail(t *testing.T) {
 
 	recipient := "recipient@example.com"
 	email := New(recipient)
-	email.ReplyTo = "sender@example.com"
+	email.From = "sender@example.com"
 	email.Subject = "sub"
 	email.Body = "<h1>Body</h1>"
 	Send(email, context)
 
 	// Try render
-	email.Body, err = RenderTemplate(email, context)
+	err = RenderTemplate(email, context)
 	if err != nil {
 		t.Errorf("mail: failed to render message :%s", err)
 	}
+	if !strings.Contains(email.Body, "hello world") || !strings.Contains(email.Text, "hello world") || strings.Contains(email.Text, "<br>") {
+		t.Errorf("mail: unexpected rendered message :%s %s", email.Body, email.Text)
+	}
+
+}
+
+// TestMessage tests formatting mail as a MIME message.
+func TestMessage(t *testing.T) {
+
+	email := New("Récipient <recipient@example.com>")
+	email.From = "Sender <sender@example.com>"
+	email.CC = []string{"cc@example.com"}
+	email.BCC = []string{"bcc@example.com"}
+	email.Subject = "Héllo"
+	email.Body = "<h1>Body</h1>"
+	email.Headers = map[string]string{"x-test": "test\r\nBcc: injected@example.com"}
 
+	message, err := Message(email)
+	if err != nil {
+		t.Fatalf("mail: failed to format message %s", err)
+	}
+
+	m := string(message)
+	if !strings.Contains(m, "To: =?utf-8?q?R=C3=A9cipient?= <recipient@example.com>\r\n") || !strings.Contains(m, "Subject: =?utf-8?q?H=C3=A9llo?=\r\n") {
+		t.Fatalf("mail: unexpected message headers %s", m)
+	}
+	if !strings.Contains(m, "From: \"Sender\" <sender@example.com>\r\n") || !strings.Contains(m, "Cc: cc@example.com\r\n") {
+		t.Fatalf("mail: unexpected message headers %s", m)
+	}
+	if strings.Contains(m, "bcc@example.com") || strings.Contains(m, "\r\nBcc:") {
+		t.Fatalf("mail: bcc included in message %s", m)
+	}
+	if !strings.Contains(m, "multipart/alternative") || !strings.Contains(m, "text/plain") || !strings.Contains(m, "\r\n\r\n<h1>Body</h1>") {
+		t.Fatalf("mail: unexpected message body %s", m)
+	}
+
+	// Attachments should be added in a mixed message
+	email.Attach("test.txt", []byte("attachment"))
+	message, err = Message(email)
+	if err != nil {
+		t.Fatalf("mail: failed to format message %s", err)
+	}
+	m = string(message)
+	if !strings.Contains(m, "multipart/mixed") || !strings.Contains(m, "filename=test.txt") || !strings.Contains(m, "YXR0YWNobWVudA==") {
+		t.Fatalf("mail: unexpected message with attachment %s", m)
+	}
+}
+
+// TestHTMLToText tests converting html to text.
+func TestHTMLToText(t *testing.T) {
+	html := "<html><head><title>Title</title></head><body>\n<h1>Hello  &amp; welcome</h1>\n<p>Line one<br>Line <b>two</b></p>" +
+		"<ul><li>One</li><li>Two</li></ul><p><a href=\"https://example.com\">Example</a></p></body></html>"
+	expected := "Hello & welcome\n\nLine one\nLine two\n\n- One\n- Two\n\nExample (https://example.com)"
+
+	text := HTMLToText(html)
+	if text != expected {
+		t.Fatalf("mail: unexpected text expected:%q got:%q", expected, text)
+	}
 }
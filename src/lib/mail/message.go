@@ -0,0 +1,199 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message returns the email formatted as a MIME message, for adapters which send
+// or store raw messages rather than using an api. The message has text and html
+// alternative parts, followed by any attachments. BCC recipients are not included.
+func Message(email *Email) ([]byte, error) {
+	var b bytes.Buffer
+
+	header := func(k, v string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, headerValue(v))
+	}
+
+	header("From", FormatAddress(email.From))
+	header("To", formatAddresses(email.Recipients))
+	if len(email.CC) > 0 {
+		header("Cc", formatAddresses(email.CC))
+	}
+	if email.ReplyTo != "" {
+		header("Reply-To", FormatAddress(email.ReplyTo))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(email.From))
+	header("MIME-Version", "1.0")
+
+	// Add any custom headers in a consistent order
+	var keys []string
+	for k := range email.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(headerValue(k)), email.Headers[k])
+	}
+
+	// Write the text and html alternatives
+	alternative, err := alternativeParts(email)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(email.Attachments) == 0 {
+		header("Content-Type", "multipart/alternative; boundary="+alternative.boundary)
+		b.WriteString("\r\n")
+		b.Write(alternative.body.Bytes())
+		return b.Bytes(), nil
+	}
+
+	// Wrap the alternatives with attachments in a mixed multipart message
+	var body bytes.Buffer
+	mixed := multipart.NewWriter(&body)
+	header("Content-Type", "multipart/mixed; boundary="+mixed.Boundary())
+	b.WriteString("\r\n")
+
+	w, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"multipart/alternative; boundary=" + alternative.boundary},
+	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(alternative.body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range email.Attachments {
+		err = writeAttachment(mixed, a)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = mixed.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+// multipartBody holds a multipart body and its boundary.
+type multipartBody struct {
+	boundary string
+	body     bytes.Buffer
+}
+
+// alternativeParts returns a multipart/alternative body with the text and html parts of email.
+func alternativeParts(email *Email) (*multipartBody, error) {
+	m := &multipartBody{}
+	w := multipart.NewWriter(&m.body)
+	m.boundary = w.Boundary()
+
+	text := email.Text
+	if text == "" {
+		text = HTMLToText(email.Body)
+	}
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", email.Body},
+	}
+
+	for _, p := range parts {
+		pw, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		_, err = qw.Write([]byte(p.content))
+		if err != nil {
+			return nil, err
+		}
+		err = qw.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, w.Close()
+}
+
+// writeAttachment writes the attachment as a base64 encoded part.
+func writeAttachment(w *multipart.Writer, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = ContentType(a.Name)
+	}
+
+	pw, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Wrap base64 lines at 76 characters as required for MIME
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > 76 {
+		_, err = io.WriteString(pw, encoded[:76]+"\r\n")
+		if err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(pw, encoded+"\r\n")
+	return err
+}
+
+// formatAddresses formats a list of addresses for use in headers.
+func formatAddresses(addresses []string) string {
+	var formatted []string
+	for _, a := range addresses {
+		formatted = append(formatted, FormatAddress(a))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// headerValue removes line breaks from header values, so that headers cannot be injected.
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
+
+// messageID returns a unique message id at the domain of the address given.
+func messageID(from string) string {
+	domain := "localhost"
+	_, address := ParseAddress(from)
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		domain = address[i+1:]
+	}
+
+	r := make([]byte, 16)
+	rand.Read(r)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(r), domain)
+}
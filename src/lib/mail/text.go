@@ -0,0 +1,67 @@
+package mail
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// hiddenRegexp matches elements with content which is not displayed
+	hiddenRegexp = regexp.MustCompile(`(?is)<(head|style|script|title)[^>]*>.*?</(head|style|script|title)>`)
+
+	// linkRegexp matches links, capturing the url and text
+	linkRegexp = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+
+	// breakRegexp matches elements which end a line
+	breakRegexp = regexp.MustCompile(`(?i)<br\s*/?>|</(tr|li)>`)
+
+	// blockRegexp matches elements which end a paragraph
+	blockRegexp = regexp.MustCompile(`(?i)</(p|div|h[1-6]|section|header|footer|table|ul|ol|blockquote)>`)
+
+	// itemRegexp matches list items
+	itemRegexp = regexp.MustCompile(`(?i)<li[^>]*>`)
+
+	// tagRegexp matches any remaining tags
+	tagRegexp = regexp.MustCompile(`(?s)<[^>]*>`)
+
+	// spaceRegexp matches runs of spaces and tabs
+	spaceRegexp = regexp.MustCompile(`[ \t]+`)
+
+	// linesRegexp matches more than one blank line
+	linesRegexp = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText returns a plain text version of the html given, for the text part of emails.
+// Links are written with their url after the text, and list items are marked with a dash.
+func HTMLToText(s string) string {
+	s = hiddenRegexp.ReplaceAllString(s, "")
+
+	// Whitespace in html is insignificant, so line breaks come only from elements
+	s = strings.Replace(s, "\r", "", -1)
+	s = strings.Replace(s, "\n", " ", -1)
+
+	s = linkRegexp.ReplaceAllStringFunc(s, func(link string) string {
+		m := linkRegexp.FindStringSubmatch(link)
+		text := strings.TrimSpace(tagRegexp.ReplaceAllString(m[2], ""))
+		if text == "" || text == m[1] {
+			return m[1]
+		}
+		return text + " (" + m[1] + ")"
+	})
+	s = itemRegexp.ReplaceAllString(s, "- ")
+	s = breakRegexp.ReplaceAllString(s, "\n")
+	s = blockRegexp.ReplaceAllString(s, "\n\n")
+	s = tagRegexp.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	// Tidy up spaces on each line and blank lines
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(spaceRegexp.ReplaceAllString(l, " "))
+	}
+	s = strings.Join(lines, "\n")
+	s = linesRegexp.ReplaceAllString(s, "\n\n")
+
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,394 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/sanitize"
+)
+
+// element is an element or text parsed from html for conversion to markdown.
+type element struct {
+	tag      *sanitize.Tag
+	text     string
+	parent   *element
+	children []*element
+}
+
+// name returns the tag name of the element, or an empty string for text.
+func (e *element) name() string {
+	if e.tag == nil {
+		return ""
+	}
+	return e.tag.Name
+}
+
+// blockTags are the tags converted to markdown blocks, other tags are converted inline.
+var blockTags = map[string]bool{"address": true, "article": true, "aside": true, "blockquote": true, "dd": true, "div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true, "ul": true}
+
+// voidTags are the tags which have no content or end tag.
+var voidTags = map[string]bool{"area": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true, "source": true, "track": true, "wbr": true}
+
+// droppedTags are the tags removed with their content, as markdown can't express them.
+var droppedTags = map[string]bool{"script": true, "style": true, "iframe": true, "object": true, "embed": true, "video": true, "audio": true, "template": true, "noscript": true, "svg": true, "math": true, "head": true, "title": true, "select": true, "textarea": true}
+
+var (
+	spaceRegexp      = regexp.MustCompile(`[ \t\r\n\f]+`)
+	lineStartRegexp  = regexp.MustCompile(`^(#|>|[-+*] |=+$|-+$)`)
+	orderedRegexp    = regexp.MustCompile(`^([0-9]+)([.)] )`)
+	languageRegexp   = regexp.MustCompile(`language-(\S+)`)
+	markdownEscapes  = strings.NewReplacer(`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `\<`)
+	tableCellEscapes = strings.NewReplacer(`|`, `\|`)
+)
+
+// FromHTML returns markdown for the html s, for converting existing content.
+// Html which markdown can't express, such as iframes and attributes other than
+// links and image sources, is removed, the text of other tags is kept.
+func FromHTML(s string) string {
+	root := parseHTML(s)
+	return strings.Join(blocksFromHTML(root.children), "\n\n")
+}
+
+// parseHTML returns the tree of elements for the html s.
+func parseHTML(s string) *element {
+	root := &element{}
+	current := root
+	for _, token := range sanitize.Tokenize(s) {
+		t := token.Tag
+		if t == nil {
+			current.children = append(current.children, &element{text: html.UnescapeString(token.Text), parent: current})
+			continue
+		}
+		if t.Name == "" {
+			continue
+		}
+
+		if t.End {
+			for e := current; e != root; e = e.parent {
+				if e.name() == t.Name {
+					current = e.parent
+					break
+				}
+			}
+			continue
+		}
+
+		// Paragraphs and list items are closed by the next paragraph or item
+		if (t.Name == "p" || t.Name == "li") && current.name() == t.Name {
+			current = current.parent
+		}
+
+		e := &element{tag: t, parent: current}
+		current.children = append(current.children, e)
+		if !voidTags[t.Name] {
+			current = e
+		}
+	}
+	return root
+}
+
+// blocksFromHTML returns the markdown blocks for the elements, grouping inline elements into paragraphs.
+func blocksFromHTML(elements []*element) []string {
+	var blocks []string
+	var para string
+	flush := func() {
+		p := paragraphText(para)
+		if p != "" {
+			blocks = append(blocks, p)
+		}
+		para = ""
+	}
+
+	for _, e := range elements {
+		name := e.name()
+		if droppedTags[name] {
+			continue
+		}
+		if !blockTags[name] {
+			para += inlineFromHTML(e)
+			continue
+		}
+
+		flush()
+		switch name {
+		case "h1", "h2", "h3", "h4", "h5", "h6":
+			level, _ := strconv.Atoi(name[1:])
+			text := strings.Replace(paragraphText(inlineChildren(e)), "\n", " ", -1)
+			if text != "" {
+				blocks = append(blocks, strings.Repeat("#", level)+" "+text)
+			}
+		case "p", "dt", "dd", "figcaption":
+			para = inlineChildren(e)
+			flush()
+		case "blockquote":
+			quoted := strings.Join(blocksFromHTML(e.children), "\n\n")
+			if quoted != "" {
+				blocks = append(blocks, prefixLines(quoted, "> ", ">"))
+			}
+		case "ul", "ol":
+			list := listFromHTML(e)
+			if list != "" {
+				blocks = append(blocks, list)
+			}
+		case "pre":
+			blocks = append(blocks, codeFromHTML(e))
+		case "hr":
+			blocks = append(blocks, "---")
+		case "table":
+			table := tableFromHTML(e)
+			if table != "" {
+				blocks = append(blocks, table)
+			}
+		default:
+			blocks = append(blocks, blocksFromHTML(e.children)...)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// paragraphText returns the inline markdown in s with whitespace collapsed, and
+// characters at the start of lines which would start a block escaped.
+func paragraphText(s string) string {
+	lines := strings.Split(s, "\n")
+	var out []string
+	for _, l := range lines {
+		l = strings.TrimSpace(spaceRegexp.ReplaceAllString(l, " "))
+		if lineStartRegexp.MatchString(l) {
+			l = `\` + l
+		}
+		l = orderedRegexp.ReplaceAllString(l, `$1\$2`)
+		out = append(out, l)
+	}
+	return strings.TrimSuffix(strings.TrimSpace(strings.Join(out, "\n")), `\`)
+}
+
+// inlineChildren returns the inline markdown for the children of e.
+func inlineChildren(e *element) string {
+	s := ""
+	for _, c := range e.children {
+		s += inlineFromHTML(c)
+	}
+	return s
+}
+
+// inlineFromHTML returns the inline markdown for e.
+func inlineFromHTML(e *element) string {
+	if e.tag == nil {
+		return markdownEscapes.Replace(spaceRegexp.ReplaceAllString(e.text, " "))
+	}
+
+	switch e.name() {
+	case "br":
+		return "\\\n"
+	case "strong", "b":
+		return wrap(inlineChildren(e), "**")
+	case "em", "i":
+		return wrap(inlineChildren(e), "*")
+	case "code", "kbd", "samp":
+		return codeSpan(textContent(e))
+	case "a":
+		content := inlineChildren(e)
+		href := e.tag.Attr("href")
+		if href == "" || strings.TrimSpace(content) == "" {
+			return content
+		}
+		return "[" + content + "](" + linkDestination(href, e.tag.Attr("title")) + ")"
+	case "img":
+		src := e.tag.Attr("src")
+		if src == "" {
+			return ""
+		}
+		return "![" + markdownEscapes.Replace(e.tag.Attr("alt")) + "](" + linkDestination(src, e.tag.Attr("title")) + ")"
+	}
+
+	if droppedTags[e.name()] {
+		return ""
+	}
+	if blockTags[e.name()] {
+		return " " + strings.Join(blocksFromHTML(e.children), " ") + " "
+	}
+	return inlineChildren(e)
+}
+
+// wrap returns s wrapped in the emphasis markers given, keeping spaces at either end outside them.
+func wrap(s, marker string) string {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s
+	}
+	start := s[:strings.Index(s, trimmed)]
+	end := s[len(start)+len(trimmed):]
+	return start + marker + trimmed + marker + end
+}
+
+// codeSpan returns the markdown for inline code with the text given.
+func codeSpan(text string) string {
+	text = spaceRegexp.ReplaceAllString(text, " ")
+	if text == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// linkDestination returns the destination and title of a link or image.
+func linkDestination(url, title string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		url = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	if title != "" {
+		url += ` "` + strings.Replace(title, `"`, `\"`, -1) + `"`
+	}
+	return url
+}
+
+// listFromHTML returns the markdown for a ul or ol element.
+func listFromHTML(e *element) string {
+	start := 1
+	if e.name() == "ol" {
+		if n, err := strconv.Atoi(e.tag.Attr("start")); err == nil && n >= 0 {
+			start = n
+		}
+	}
+
+	var items []string
+	loose := false
+	for _, li := range e.children {
+		if li.name() != "li" {
+			continue
+		}
+
+		marker := "- "
+		if e.name() == "ol" {
+			marker = fmt.Sprintf("%d. ", start+len(items))
+		}
+
+		// Items with more than one block besides nested lists are loose
+		blocks := blocksFromHTML(li.children)
+		paragraphs := 0
+		for _, b := range blocks {
+			if !strings.HasPrefix(b, "- ") && !orderedRegexp.MatchString(b) {
+				paragraphs++
+			}
+		}
+		separator := "\n"
+		if paragraphs > 1 {
+			separator = "\n\n"
+			loose = true
+		}
+
+		content := strings.Join(blocks, separator)
+		items = append(items, marker+prefixLines(content, strings.Repeat(" ", len(marker)), "")[len(marker):])
+	}
+
+	if loose {
+		return strings.Join(items, "\n\n")
+	}
+	return strings.Join(items, "\n")
+}
+
+// codeFromHTML returns a fenced code block for a pre element.
+func codeFromHTML(e *element) string {
+	language := ""
+	for _, c := range e.children {
+		if c.name() == "code" {
+			if m := languageRegexp.FindStringSubmatch(c.tag.Attr("class")); m != nil {
+				language = m[1]
+			}
+		}
+	}
+
+	code := strings.TrimSuffix(textContent(e), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// tableFromHTML returns the markdown for a table element, using the first row as the header.
+func tableFromHTML(e *element) string {
+	var rows [][]string
+	var walk func(*element)
+	walk = func(e *element) {
+		for _, c := range e.children {
+			switch c.name() {
+			case "tr":
+				var row []string
+				for _, cell := range c.children {
+					if cell.name() == "td" || cell.name() == "th" {
+						text := strings.Replace(paragraphText(inlineChildren(cell)), "\n", " ", -1)
+						row = append(row, tableCellEscapes.Replace(text))
+					}
+				}
+				rows = append(rows, row)
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			}
+		}
+	}
+	walk(e)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return ""
+	}
+
+	var lines []string
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// textContent returns the text of e and its children.
+func textContent(e *element) string {
+	if e.tag == nil {
+		return e.text
+	}
+	if e.name() == "br" {
+		return "\n"
+	}
+	s := ""
+	for _, c := range e.children {
+		s += textContent(c)
+	}
+	return s
+}
+
+// prefixLines returns s with prefix added to each line, or blank added to blank lines.
+func prefixLines(s, prefix, blank string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		if l == "" {
+			lines[i] = blank
+		} else {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
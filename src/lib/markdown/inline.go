@@ -0,0 +1,579 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/sanitize"
+)
+
+var (
+	entityRegexp      = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	autolinkRegexp    = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^<>\x00-\x20]*)>`)
+	emailRegexp       = regexp.MustCompile("^<([a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>")
+	footnoteRefRegexp = regexp.MustCompile(`^\[\^([^\]\s]+)\]`)
+	tagRegexp         = regexp.MustCompile(`<[^>]*>`)
+	imageAltRegexp    = regexp.MustCompile(`<img [^>]*alt="([^"]*)"[^>]*>`)
+)
+
+// escaper escapes text for html, as CommonMark does.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// punctuation is the ascii punctuation which may be escaped with a backslash.
+const punctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// urlSafe is the ascii punctuation left unencoded in urls.
+const urlSafe = ";/?:@&=+$,-_.!~*'()#"
+
+// node is a run of html, or of emphasis delimiters, in inline content.
+type node struct {
+	html string
+
+	// delimiter is * or _ for runs of delimiters
+	delimiter byte
+	// count is the number of delimiters left unmatched, and length the number in the run
+	count  int
+	length int
+	open   bool
+	close  bool
+
+	// openTags and closeTags are the emphasis tags for delimiters matched
+	openTags  string
+	closeTags string
+}
+
+// String returns the html for the node.
+func (n *node) String() string {
+	if n.delimiter == 0 {
+		return n.html
+	}
+	return n.closeTags + strings.Repeat(string(n.delimiter), n.count) + n.openTags
+}
+
+// inline returns the html for the inline content s, such as the text of a paragraph.
+func (r *renderer) inline(s string) string {
+	var nodes []*node
+	var text []byte
+	flush := func() {
+		if len(text) > 0 {
+			nodes = append(nodes, &node{html: string(text)})
+			text = nil
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				text = append(text, "<br>\n"...)
+				i = skipSpaces(s, i+2)
+				continue
+			}
+			if i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0 {
+				text = append(text, escape(s[i+1:i+2])...)
+				i += 2
+				continue
+			}
+			text = append(text, '\\')
+			i++
+
+		case '`':
+			n := runLength(s, i, '`')
+			end := closingBackticks(s, i+n, n)
+			if end < 0 {
+				text = append(text, s[i:i+n]...)
+				i += n
+				continue
+			}
+			text = append(text, "<code>"+escape(codeContent(s[i+n:end]))+"</code>"...)
+			i = end + n
+
+		case '<':
+			if m := autolinkRegexp.FindStringSubmatch(s[i:]); m != nil {
+				text = append(text, r.link(m[1], "", escape(m[1]))...)
+				i += len(m[0])
+				continue
+			}
+			if m := emailRegexp.FindStringSubmatch(s[i:]); m != nil {
+				text = append(text, r.link("mailto:"+m[1], "", escape(m[1]))...)
+				i += len(m[0])
+				continue
+			}
+			text = append(text, "&lt;"...)
+			i++
+
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				if out, n := r.linkAt(s[i+1:], true); n > 0 {
+					text = append(text, out...)
+					i += 1 + n
+					continue
+				}
+			}
+			text = append(text, '!')
+			i++
+
+		case '[':
+			if m := footnoteRefRegexp.FindStringSubmatch(s[i:]); m != nil {
+				if out := r.footnoteRef(m[1]); out != "" {
+					text = append(text, out...)
+					i += len(m[0])
+					continue
+				}
+			}
+			if out, n := r.linkAt(s[i:], false); n > 0 {
+				text = append(text, out...)
+				i += n
+				continue
+			}
+			text = append(text, '[')
+			i++
+
+		case '*', '_':
+			n := runLength(s, i, c)
+			flush()
+			nodes = append(nodes, delimiterRun(s, i, n))
+			i += n
+
+		case '\n':
+			// Two or more spaces at the end of a line make a hard line break
+			trailing := 0
+			for trailing < len(text) && text[len(text)-1-trailing] == ' ' {
+				trailing++
+			}
+			text = text[:len(text)-trailing]
+			if trailing >= 2 {
+				text = append(text, "<br>\n"...)
+			} else {
+				text = append(text, '\n')
+			}
+			i = skipSpaces(s, i+1)
+
+		case '&':
+			if m := entityRegexp.FindString(s[i:]); m != "" {
+				text = append(text, escape(html.UnescapeString(m))...)
+				i += len(m)
+				continue
+			}
+			text = append(text, "&amp;"...)
+			i++
+
+		case '>':
+			text = append(text, "&gt;"...)
+			i++
+
+		case '"':
+			text = append(text, "&quot;"...)
+			i++
+
+		default:
+			text = append(text, c)
+			i++
+		}
+	}
+	flush()
+
+	emphasis(nodes)
+
+	var b strings.Builder
+	for _, n := range nodes {
+		b.WriteString(n.String())
+	}
+	return b.String()
+}
+
+// delimiterRun returns the node for the run of n emphasis delimiters at s[i],
+// which may open or close emphasis depending on the characters either side.
+func delimiterRun(s string, i, n int) *node {
+	before, after := ' ', ' '
+	if i > 0 {
+		before, _ = utf8.DecodeLastRuneInString(s[:i])
+	}
+	if i+n < len(s) {
+		after, _ = utf8.DecodeRuneInString(s[i+n:])
+	}
+
+	left := !unicode.IsSpace(after) && (!isPunctuation(after) || unicode.IsSpace(before) || isPunctuation(before))
+	right := !unicode.IsSpace(before) && (!isPunctuation(before) || unicode.IsSpace(after) || isPunctuation(after))
+
+	d := &node{delimiter: s[i], count: n, length: n}
+	if d.delimiter == '*' {
+		d.open, d.close = left, right
+	} else {
+		// Underscores within words are not emphasis
+		d.open = left && (!right || isPunctuation(before))
+		d.close = right && (!left || isPunctuation(after))
+	}
+	return d
+}
+
+// emphasis matches the delimiter runs in nodes, setting the em and strong tags
+// for each pair, following the CommonMark rules for emphasis.
+func emphasis(nodes []*node) {
+	for c, closer := range nodes {
+		if closer.delimiter == 0 || !closer.close {
+			continue
+		}
+
+		for closer.count > 0 {
+			o := -1
+			for i := c - 1; i >= 0; i-- {
+				opener := nodes[i]
+				if opener.delimiter != closer.delimiter || !opener.open || opener.count == 0 {
+					continue
+				}
+				// Runs which may both open and close only match if their lengths are not multiples of 3
+				if (opener.close || closer.open) && (opener.length+closer.length)%3 == 0 && !(opener.length%3 == 0 && closer.length%3 == 0) {
+					continue
+				}
+				o = i
+				break
+			}
+			if o < 0 {
+				break
+			}
+
+			opener := nodes[o]
+			n, tag := 1, "em"
+			if opener.count >= 2 && closer.count >= 2 {
+				n, tag = 2, "strong"
+			}
+			opener.count -= n
+			closer.count -= n
+			opener.openTags = "<" + tag + ">" + opener.openTags
+			closer.closeTags += "</" + tag + ">"
+
+			// Delimiters between the pair can no longer match
+			for _, between := range nodes[o+1 : c] {
+				between.open, between.close = false, false
+			}
+		}
+	}
+}
+
+// linkAt returns the html for the link or image starting with [ at the start of s,
+// and the length of the markdown for it, or a length of 0 if s does not start a link.
+func (r *renderer) linkAt(s string, image bool) (string, int) {
+	end := closingBracket(s)
+	if end < 0 {
+		return "", 0
+	}
+	label := s[1:end]
+	rest := s[end+1:]
+	n := end + 1
+
+	var l link
+	found := false
+	if strings.HasPrefix(rest, "(") {
+		var m int
+		l.url, l.title, m = inlineDestination(rest)
+		if m > 0 {
+			found = true
+			n += m
+		}
+	}
+	// A label after the text is a reference, and the text may not also be one
+	e := labelEnd(rest)
+	if !found && e > 0 {
+		ref := rest[1:e]
+		if ref == "" {
+			ref = label
+		}
+		l, found = r.links[normalizeLabel(ref)]
+		if found {
+			n += e + 1
+		}
+	}
+	if !found && e < 0 {
+		l, found = r.links[normalizeLabel(label)]
+	}
+	if !found {
+		return "", 0
+	}
+
+	if image {
+		alt := imageAltRegexp.ReplaceAllString(r.inline(label), "$1")
+		alt = html.UnescapeString(tagRegexp.ReplaceAllString(alt, ""))
+		return r.image(l.url, l.title, alt), n
+	}
+
+	// Links may not contain other links
+	content := r.inline(label)
+	if strings.Contains(content, "<a ") {
+		return "", 0
+	}
+	return r.link(l.url, l.title, content), n
+}
+
+// link returns the html for a link to url with the html content given,
+// or just the content if the url is not allowed.
+func (r *renderer) link(url, title, content string) string {
+	if !sanitize.AllowURL(url) {
+		return content
+	}
+	s := `<a href="` + escapeURL(url) + `"`
+	if title != "" {
+		s += ` title="` + escape(title) + `"`
+	}
+	return s + ">" + content + "</a>"
+}
+
+// image returns the html for an image at url, or just the alt text if the url is not allowed.
+func (r *renderer) image(url, title, alt string) string {
+	if !sanitize.AllowURL(url) {
+		return escape(alt)
+	}
+	s := `<img src="` + escapeURL(url) + `" alt="` + escape(alt) + `"`
+	if title != "" {
+		s += ` title="` + escape(title) + `"`
+	}
+	return s + ">"
+}
+
+// footnoteRef returns the html for a reference to the footnote label,
+// numbering footnotes in the order they are first referred to.
+func (r *renderer) footnoteRef(label string) string {
+	key := normalizeLabel(label)
+	if _, ok := r.notes[key]; !ok {
+		return ""
+	}
+
+	n, seen := r.noteIndex[key]
+	if seen {
+		return fmt.Sprintf(`<sup class="footnote-ref"><a href="#fn-%s-%d">%d</a></sup>`, r.prefix, n, n)
+	}
+	r.noteOrder = append(r.noteOrder, key)
+	n = len(r.noteOrder)
+	r.noteIndex[key] = n
+	return fmt.Sprintf(`<sup class="footnote-ref"><a href="#fn-%s-%d" id="fnref-%s-%d">%d</a></sup>`, r.prefix, n, r.prefix, n, n)
+}
+
+// closingBracket returns the index of the ] closing the [ at the start of s, or -1.
+// Brackets within code spans and autolinks are skipped, as they bind more tightly.
+func closingBracket(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '`':
+			n := runLength(s, i, '`')
+			if end := closingBackticks(s, i+n, n); end > 0 {
+				i = end + n - 1
+			} else {
+				i += n - 1
+			}
+		case '<':
+			if m := autolinkRegexp.FindString(s[i:]); m != "" {
+				i += len(m) - 1
+			} else if m := emailRegexp.FindString(s[i:]); m != "" {
+				i += len(m) - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// labelEnd returns the index of the ] closing the link label at the start of s,
+// or -1 if s does not start with a label. Labels may not contain brackets.
+func labelEnd(s string) int {
+	if !strings.HasPrefix(s, "[") {
+		return -1
+	}
+	for i := 1; i < len(s) && i <= 1000; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			return -1
+		case ']':
+			return i
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the first c in s from i which is not escaped, or -1.
+func closingQuote(s string, i int, c byte) int {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+// inlineDestination reads the url and title of an inline link from s, which starts
+// with (, returning them and the length read, or a length of 0 if s is not valid.
+func inlineDestination(s string) (string, string, int) {
+	i := skipWhitespace(s, 1)
+
+	url := ""
+	if i < len(s) && s[i] == '<' {
+		e := closingQuote(s, i+1, '>')
+		if e < 0 || strings.ContainsAny(s[i+1:e], "<\n") {
+			return "", "", 0
+		}
+		url = s[i+1 : e]
+		i = e + 1
+	} else {
+		start, depth := i, 0
+	loop:
+		for i < len(s) {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s):
+				i++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break loop
+				}
+				depth--
+			case c <= ' ':
+				break loop
+			}
+			i++
+		}
+		url = s[start:i]
+	}
+
+	i = skipWhitespace(s, i)
+	title := ""
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		end := s[i]
+		if end == '(' {
+			end = ')'
+		}
+		e := closingQuote(s, i+1, end)
+		if e < 0 {
+			return "", "", 0
+		}
+		title = s[i+1 : e]
+		i = skipWhitespace(s, e+1)
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0
+	}
+	return unescape(url), unescape(title), i + 1
+}
+
+// destination returns the url of a link reference definition.
+func destination(s string) string {
+	if strings.HasPrefix(s, "<") && strings.HasSuffix(s, ">") {
+		s = s[1 : len(s)-1]
+	}
+	return unescape(s)
+}
+
+// unescape returns s with backslash escapes and entities replaced by the characters they represent.
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(punctuation, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+// escape returns s with the characters special to html escaped.
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// escapeURL returns the url u percent encoded as CommonMark does, leaving
+// encodings already in u as they are, and escaped for use in an attribute.
+func escapeURL(u string) string {
+	var b strings.Builder
+	for i := 0; i < len(u); i++ {
+		c := u[i]
+		switch {
+		case c == '%' && i+2 < len(u) && isHex(u[i+1]) && isHex(u[i+2]):
+			b.WriteByte(c)
+		case c < utf8.RuneSelf && (unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || strings.IndexByte(urlSafe, c) >= 0):
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return escape(b.String())
+}
+
+// isHex returns true if c is a hexadecimal digit.
+func isHex(c byte) bool {
+	return strings.IndexByte("0123456789abcdefABCDEF", c) >= 0
+}
+
+// codeContent returns the content of a code span, with line endings replaced by
+// spaces, and one space stripped from each end if it has spaces at both ends.
+func codeContent(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	if len(s) >= 2 && s[0] == ' ' && s[len(s)-1] == ' ' && strings.TrimSpace(s) != "" {
+		s = s[1 : len(s)-1]
+	}
+	return s
+}
+
+// closingBackticks returns the index of the next run of exactly n backticks in s from i, or -1.
+func closingBackticks(s string, i, n int) int {
+	for i < len(s) {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(s, i, '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// runLength returns the number of characters c in s starting at i.
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// skipSpaces returns the index of the first character in s from i which is not a space.
+func skipSpaces(s string, i int) int {
+	for i < len(s) && s[i] == ' ' {
+		i++
+	}
+	return i
+}
+
+// skipWhitespace returns the index of the first character in s from i which is not whitespace.
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// isPunctuation returns true if r is unicode punctuation or a symbol.
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,724 @@
+// Package markdown renders markdown as html, following CommonMark with tables
+// and footnotes. Raw html is escaped rather than passed through, and urls are
+// checked with the sanitize package, so the html rendered is safe to display.
+package markdown
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	fenceRegexp      = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	hrRegexp         = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	atxRegexp        = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*))?$`)
+	setextRegexp     = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	quoteRegexp      = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	listRegexp       = regexp.MustCompile(`^( {0,3})([*+-]|[0-9]{1,9}[.)])( *)(.*)$`)
+	tableRegexp      = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	referenceRegexp  = regexp.MustCompile(`^ {0,3}\[((?:[^\[\]\\]|\\.){1,999})\]:[ \t]*\n?[ \t]*(<(?:[^<>\n\\]|\\.)*>|[^\s<]\S*)(?:(?:[ \t]+|[ \t]*\n[ \t]*)("(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'|\((?:[^()\\]|\\.)*\)))?[ \t]*(?:\n|$)`)
+	footnoteRegexp   = regexp.MustCompile(`^ {0,3}\[\^([^\]\s]+)\]:[ \t]?(.*)$`)
+	closingATXRegexp = regexp.MustCompile(`(?:^|[ \t]+)#+[ \t]*$`)
+)
+
+// Render returns the html for the markdown s. Footnote ids are prefixed with
+// a hash of s, so that the ids of documents shown on the same page differ.
+func Render(s string) string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	s = strings.Replace(s, "\r", "\n", -1)
+
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	r := &renderer{
+		prefix: fmt.Sprintf("%08x", h.Sum32()),
+		links:  make(map[string]link),
+		notes:  make(map[string][]string),
+	}
+
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = expandTabs(l)
+	}
+
+	out := r.render(lines)
+	if r.nested {
+		// Definitions in block quotes and lists are found as they are rendered,
+		// so render again for references which come before them
+		out = r.render(lines)
+	}
+	return out
+}
+
+// link is the destination of a link reference definition.
+type link struct {
+	url   string
+	title string
+}
+
+// renderer holds the link references and footnotes of the markdown being rendered.
+type renderer struct {
+	// prefix is added to the ids of footnotes
+	prefix    string
+	links     map[string]link
+	notes     map[string][]string
+	noteOrder []string
+	noteIndex map[string]int
+	// nested is set when definitions are found within block quotes or lists
+	nested bool
+}
+
+// render returns the html for the document in lines.
+func (r *renderer) render(lines []string) string {
+	r.noteOrder = nil
+	r.noteIndex = make(map[string]int)
+	lines = r.definitions(lines)
+	return r.blocks(lines, false) + r.footnotes()
+}
+
+// nestedDefinitions removes the definitions from lines within a block quote or list.
+func (r *renderer) nestedDefinitions(lines []string) []string {
+	n := len(r.links) + len(r.notes)
+	lines = r.definitions(lines)
+	if len(r.links)+len(r.notes) > n {
+		r.nested = true
+	}
+	return lines
+}
+
+// definitions removes link reference and footnote definitions from lines,
+// storing them for use by links and footnote references.
+func (r *renderer) definitions(lines []string) []string {
+	var out []string
+	fence := ""
+	paragraph := false
+	for i := 0; i < len(lines); i++ {
+		l := lines[i]
+
+		// Skip the content of fenced code
+		if m := fenceRegexp.FindStringSubmatch(l); m != nil {
+			if fence == "" {
+				fence = m[2]
+			} else if strings.HasPrefix(m[2], fence) && strings.TrimSpace(m[3]) == "" {
+				fence = ""
+				paragraph = false
+				out = append(out, l)
+				continue
+			}
+		}
+		if fence != "" {
+			out = append(out, l)
+			continue
+		}
+
+		// Definitions may not interrupt a paragraph
+		if paragraph {
+			out = append(out, l)
+			paragraph = !isBlank(l) && !endsParagraph(l) && !setextRegexp.MatchString(l)
+			continue
+		}
+
+		if m := footnoteRegexp.FindStringSubmatch(l); m != nil {
+			label := normalizeLabel(m[1])
+			note := []string{m[2]}
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				if isBlank(next) {
+					// Blank lines continue the note if followed by indented lines
+					j := i + 1
+					for j < len(lines) && isBlank(lines[j]) {
+						j++
+					}
+					if j == len(lines) || indentation(lines[j]) < 4 {
+						break
+					}
+					note = append(note, "")
+					i++
+					continue
+				}
+				if indentation(next) >= 4 {
+					note = append(note, next[4:])
+				} else if !isBlank(note[len(note)-1]) && !startsBlock(next) && !footnoteRegexp.MatchString(next) {
+					note = append(note, next)
+				} else {
+					break
+				}
+				i++
+			}
+			if _, ok := r.notes[label]; !ok {
+				r.notes[label] = note
+			}
+			continue
+		}
+
+		// Definitions may continue over the lines of a paragraph
+		end := i
+		for end < len(lines) && !isBlank(lines[end]) {
+			end++
+		}
+		text := strings.Join(lines[i:end], "\n")
+		if m := referenceRegexp.FindStringSubmatch(text); m != nil && !isBlank(m[1]) && !strings.HasPrefix(m[1], "^") {
+			label := normalizeLabel(m[1])
+			if _, ok := r.links[label]; !ok {
+				title := ""
+				if len(m[3]) > 1 {
+					title = unescape(m[3][1 : len(m[3])-1])
+				}
+				r.links[label] = link{url: destination(m[2]), title: title}
+			}
+			i += strings.Count(strings.TrimSuffix(m[0], "\n"), "\n")
+			continue
+		}
+
+		out = append(out, l)
+		paragraph = !isBlank(l) && indentation(l) < 4 && !endsParagraph(l)
+	}
+	return out
+}
+
+// blocks returns the html for the block content in lines, in tight lists
+// paragraphs are rendered without p tags or a line break after them.
+func (r *renderer) blocks(lines []string, tight bool) string {
+	var b strings.Builder
+
+	// Blocks in tight lists start on a new line, after the text of the item
+	block := func() {
+		if tight {
+			newline(&b)
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		l := lines[i]
+
+		if isBlank(l) {
+			i++
+			continue
+		}
+
+		if m := fenceRegexp.FindStringSubmatch(l); m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`")) {
+			block()
+			i = r.fencedCode(&b, lines, i, m)
+			continue
+		}
+
+		if indentation(l) >= 4 {
+			block()
+			i = r.indentedCode(&b, lines, i)
+			continue
+		}
+
+		if m := atxRegexp.FindStringSubmatch(l); m != nil {
+			text := closingATXRegexp.ReplaceAllString(strings.TrimSpace(m[2]), "")
+			block()
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", len(m[1]), r.inline(text), len(m[1]))
+			i++
+			continue
+		}
+
+		if hrRegexp.MatchString(l) {
+			block()
+			b.WriteString("<hr>\n")
+			i++
+			continue
+		}
+
+		if quoteRegexp.MatchString(l) {
+			block()
+			i = r.blockquote(&b, lines, i)
+			continue
+		}
+
+		if listRegexp.MatchString(l) && listItem(l) != nil {
+			block()
+			i = r.list(&b, lines, i)
+			continue
+		}
+
+		if i+1 < len(lines) && strings.Contains(l, "|") && tableRegexp.MatchString(lines[i+1]) {
+			var t strings.Builder
+			n := r.table(&t, lines, i)
+			if n > i {
+				block()
+				b.WriteString(t.String())
+				i = n
+				continue
+			}
+		}
+
+		i = r.paragraph(&b, lines, i, tight)
+	}
+	return b.String()
+}
+
+// fencedCode renders the fenced code starting at line i, returning the line after it.
+func (r *renderer) fencedCode(b *strings.Builder, lines []string, i int, m []string) int {
+	indent := len(m[1])
+	fence := m[2]
+	info := strings.Fields(unescape(m[3]))
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		l := lines[i]
+		trimmed := strings.TrimSpace(l)
+		if indentation(l) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		for n := 0; n < indent && strings.HasPrefix(l, " "); n++ {
+			l = l[1:]
+		}
+		code = append(code, l)
+	}
+
+	b.WriteString("<pre><code")
+	if len(info) > 0 {
+		b.WriteString(` class="language-` + escape(info[0]) + `"`)
+	}
+	b.WriteString(">")
+	for _, l := range code {
+		b.WriteString(escape(l) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// indentedCode renders the indented code starting at line i, returning the line after it.
+func (r *renderer) indentedCode(b *strings.Builder, lines []string, i int) int {
+	var code []string
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if isBlank(l) {
+			// Spaces after the indentation of blank lines are kept
+			if len(l) > 4 {
+				l = l[4:]
+			} else {
+				l = ""
+			}
+			code = append(code, l)
+			continue
+		}
+		if indentation(l) < 4 {
+			break
+		}
+		code = append(code, l[4:])
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+
+	b.WriteString("<pre><code>")
+	for _, l := range code {
+		b.WriteString(escape(l) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// blockquote renders the block quote starting at line i, returning the line after it.
+func (r *renderer) blockquote(b *strings.Builder, lines []string, i int) int {
+	var quoted []string
+	fence := ""
+	paragraph := false
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if m := quoteRegexp.FindStringSubmatch(l); m != nil {
+			quoted = append(quoted, m[1])
+
+			// Track whether the quote ends with a paragraph, which may be continued
+			if f := fenceRegexp.FindStringSubmatch(m[1]); f != nil && (fence == "" || strings.HasPrefix(f[2], fence)) {
+				if fence == "" {
+					fence = f[2]
+				} else {
+					fence = ""
+				}
+				paragraph = false
+			} else {
+				paragraph = fence == "" && !isBlank(m[1]) && !endsParagraph(m[1]) && (paragraph || indentation(m[1]) < 4)
+			}
+			continue
+		}
+		// Lines may continue a paragraph without the >, but not underline it as a heading
+		if paragraph && !isBlank(l) && !startsBlock(l) {
+			if setextRegexp.MatchString(l) {
+				l = "\\" + strings.TrimLeft(l, " ")
+			}
+			quoted = append(quoted, l)
+			continue
+		}
+		break
+	}
+
+	html := r.blocks(r.nestedDefinitions(quoted), false)
+	if html == "" {
+		b.WriteString("<blockquote></blockquote>\n")
+	} else {
+		b.WriteString("<blockquote>\n" + html + "</blockquote>\n")
+	}
+	return i
+}
+
+// item is the marker of a list item.
+type item struct {
+	ordered bool
+	// delimiter is the bullet, or . or ) for ordered lists
+	delimiter byte
+	start     int
+	// indent is the indentation of the content of the item
+	indent  int
+	content string
+}
+
+// listItem returns the list item started by the line l, or nil if it does not start one.
+func listItem(l string) *item {
+	m := listRegexp.FindStringSubmatch(l)
+	if m == nil || (m[3] == "" && m[4] != "") {
+		return nil
+	}
+
+	it := &item{delimiter: m[2][len(m[2])-1], content: m[4]}
+	if m[2][0] >= '0' && m[2][0] <= '9' {
+		it.ordered = true
+		it.start, _ = strconv.Atoi(m[2][:len(m[2])-1])
+	}
+
+	it.indent = len(m[1]) + len(m[2]) + len(m[3])
+	if m[4] == "" {
+		it.indent = len(m[1]) + len(m[2]) + 1
+	} else if len(m[3]) > 4 {
+		// Content indented further is indented code
+		it.indent = len(m[1]) + len(m[2]) + 1
+		it.content = m[3][1:] + m[4]
+	}
+	return it
+}
+
+// list renders the list starting at line i, returning the line after it.
+func (r *renderer) list(b *strings.Builder, lines []string, i int) int {
+	first := listItem(lines[i])
+	var items [][]string
+	loose := false
+
+	for i < len(lines) {
+		it := listItem(lines[i])
+		if it == nil || it.ordered != first.ordered || it.delimiter != first.delimiter || hrRegexp.MatchString(lines[i]) {
+			break
+		}
+
+		content := []string{it.content}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if isBlank(l) {
+				content = append(content, "")
+				// Items may start with at most one blank line
+				if len(content) == 2 && isBlank(it.content) {
+					for i++; i < len(lines) && isBlank(lines[i]); i++ {
+						content = append(content, "")
+					}
+					break
+				}
+				continue
+			}
+			if indentation(l) >= it.indent {
+				content = append(content, l[it.indent:])
+				continue
+			}
+			// Lines may continue a paragraph without being indented
+			if !isBlank(content[len(content)-1]) && !startsBlock(l) && listItem(l) == nil {
+				content = append(content, l)
+				continue
+			}
+			break
+		}
+
+		// Blank lines between items, or between blocks within an item, make the list loose
+		trailing := 0
+		for len(content) > 1 && content[len(content)-1] == "" {
+			content = content[:len(content)-1]
+			trailing++
+		}
+		if trailing > 0 && i < len(lines) {
+			if next := listItem(lines[i]); next != nil && next.ordered == first.ordered && next.delimiter == first.delimiter {
+				loose = true
+			}
+		}
+		if blankBetweenBlocks(content) {
+			loose = true
+		}
+
+		items = append(items, content)
+
+		if trailing > 0 && (i >= len(lines) || listItem(lines[i]) == nil) {
+			break
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag)
+	if first.ordered && first.start != 1 {
+		fmt.Fprintf(b, ` start="%d"`, first.start)
+	}
+	b.WriteString(">\n")
+	for _, content := range items {
+		html := r.blocks(r.nestedDefinitions(content), !loose)
+		if loose && html != "" {
+			html = "\n" + html
+		}
+		b.WriteString("<li>" + html + "</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// blankBetweenBlocks returns true if the content of a list item has a blank line
+// between blocks, rather than within a fence or a list nested in the item.
+func blankBetweenBlocks(content []string) bool {
+	fence := ""
+	nested := 0
+	for j := 0; j < len(content)-1; j++ {
+		l := content[j]
+		if m := fenceRegexp.FindStringSubmatch(l); m != nil {
+			if fence == "" {
+				fence = m[2]
+			} else if strings.HasPrefix(m[2], fence) {
+				fence = ""
+			}
+		}
+		if fence != "" {
+			continue
+		}
+
+		if it := listItem(l); it != nil {
+			nested = it.indent
+		} else if !isBlank(l) && indentation(l) < nested {
+			nested = 0
+		}
+
+		next := content[j+1]
+		if j > 0 && isBlank(l) && !isBlank(next) {
+			// Blank lines within a nested list leave this list tight
+			if nested > 0 && (indentation(next) >= nested || listItem(next) != nil) {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// table renders the table starting at line i, returning the line after it,
+// or i if the lines do not form a table.
+func (r *renderer) table(b *strings.Builder, lines []string, i int) int {
+	header := splitRow(lines[i])
+	delimiters := splitRow(lines[i+1])
+	if len(header) != len(delimiters) {
+		return i
+	}
+
+	aligns := make([]string, len(delimiters))
+	for j, d := range delimiters {
+		left, right := strings.HasPrefix(d, ":"), strings.HasSuffix(d, ":")
+		switch {
+		case left && right:
+			aligns[j] = "center"
+		case left:
+			aligns[j] = "left"
+		case right:
+			aligns[j] = "right"
+		}
+	}
+
+	cell := func(tag, align, text string) {
+		b.WriteString("<" + tag)
+		if align != "" {
+			b.WriteString(` align="` + align + `"`)
+		}
+		b.WriteString(">" + r.inline(text) + "</" + tag + ">\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n<tr>\n")
+	for j, h := range header {
+		cell("th", aligns[j], h)
+	}
+	b.WriteString("</tr>\n</thead>\n")
+
+	i += 2
+	if i < len(lines) && !isBlank(lines[i]) && !startsBlock(lines[i]) {
+		b.WriteString("<tbody>\n")
+		for ; i < len(lines) && !isBlank(lines[i]) && !startsBlock(lines[i]); i++ {
+			row := splitRow(lines[i])
+			b.WriteString("<tr>\n")
+			for j := range header {
+				text := ""
+				if j < len(row) {
+					text = row[j]
+				}
+				cell("td", aligns[j], text)
+			}
+			b.WriteString("</tr>\n")
+		}
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+	return i
+}
+
+// splitRow returns the cells of a table row, separated by pipes which are not escaped.
+func splitRow(l string) []string {
+	l = strings.TrimSpace(l)
+	l = strings.TrimPrefix(l, "|")
+	if strings.HasSuffix(l, "|") && !strings.HasSuffix(l, `\|`) {
+		l = l[:len(l)-1]
+	}
+
+	var cells []string
+	cell := ""
+	for i := 0; i < len(l); i++ {
+		switch {
+		case l[i] == '\\' && i+1 < len(l) && l[i+1] == '|':
+			cell += "|"
+			i++
+		case l[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell))
+			cell = ""
+		default:
+			cell += l[i : i+1]
+		}
+	}
+	return append(cells, strings.TrimSpace(cell))
+}
+
+// paragraph renders the paragraph starting at line i, or a heading if it is
+// underlined with = or -, returning the line after it.
+func (r *renderer) paragraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	var para []string
+	for ; i < len(lines); i++ {
+		l := lines[i]
+		if isBlank(l) {
+			break
+		}
+		if len(para) > 0 {
+			if m := setextRegexp.FindStringSubmatch(l); m != nil {
+				level := 2
+				if m[1][0] == '=' {
+					level = 1
+				}
+				if tight {
+					newline(b)
+				}
+				text := strings.TrimRight(strings.Join(para, "\n"), " \t")
+				fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, r.inline(text), level)
+				return i + 1
+			}
+			if startsBlock(l) {
+				break
+			}
+		}
+		para = append(para, strings.TrimLeft(l, " "))
+	}
+
+	text := r.inline(strings.TrimRight(strings.Join(para, "\n"), " \t"))
+	if tight {
+		b.WriteString(text)
+	} else {
+		b.WriteString("<p>" + text + "</p>\n")
+	}
+	return i
+}
+
+// footnotes returns the html for the footnotes referred to, in the order of their first reference.
+func (r *renderer) footnotes() string {
+	if len(r.noteOrder) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<section class=\"footnotes\">\n<ol>\n")
+	// Notes may refer to other notes, which are added to the order as they are rendered
+	for i := 0; i < len(r.noteOrder); i++ {
+		n := i + 1
+		html := strings.TrimSuffix(r.blocks(r.notes[r.noteOrder[i]], false), "\n")
+		backref := fmt.Sprintf(` <a href="#fnref-%s-%d" class="footnote-backref">&#8617;</a>`, r.prefix, n)
+		if strings.HasSuffix(html, "</p>") {
+			html = strings.TrimSuffix(html, "</p>") + backref + "</p>"
+		} else {
+			html += backref
+		}
+		fmt.Fprintf(&b, "<li id=\"fn-%s-%d\">\n%s\n</li>\n", r.prefix, n, html)
+	}
+	b.WriteString("</ol>\n</section>\n")
+	return b.String()
+}
+
+// startsBlock returns true if the line l starts a block which may interrupt a paragraph.
+func startsBlock(l string) bool {
+	if atxRegexp.MatchString(l) || hrRegexp.MatchString(l) || quoteRegexp.MatchString(l) {
+		return true
+	}
+	if m := fenceRegexp.FindStringSubmatch(l); m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`")) {
+		return true
+	}
+	// Only non-empty lists, starting at 1 if ordered, interrupt paragraphs
+	it := listItem(l)
+	return it != nil && !isBlank(it.content) && (!it.ordered || it.start == 1)
+}
+
+// endsParagraph returns true if the line l is a block which is complete in one
+// line, so is not followed by the continuation of a paragraph.
+func endsParagraph(l string) bool {
+	return atxRegexp.MatchString(l) || hrRegexp.MatchString(l)
+}
+
+// newline ends the html in b with a line break, if it does not already end with one.
+func newline(b *strings.Builder) {
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+}
+
+// isBlank returns true if the line l is empty or only whitespace.
+func isBlank(l string) bool {
+	return strings.TrimSpace(l) == ""
+}
+
+// indentation returns the number of spaces at the start of the line l.
+func indentation(l string) int {
+	return len(l) - len(strings.TrimLeft(l, " "))
+}
+
+// expandTabs replaces tabs in the indentation and block markers at the start of the
+// line l with spaces, to tab stops of 4 columns. Tabs in content such as code are kept.
+func expandTabs(l string) string {
+	if !strings.Contains(l, "\t") {
+		return l
+	}
+	var b strings.Builder
+	col := 0
+	for i, c := range l {
+		if !strings.ContainsRune(" \t>*+-.)0123456789", c) {
+			b.WriteString(l[i:])
+			break
+		}
+		if c == '\t' {
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(c)
+		col++
+	}
+	return b.String()
+}
+
+// normalizeLabel returns the label of a link reference or footnote, ignoring case and whitespace.
+func normalizeLabel(label string) string {
+	label = strings.ToLower(strings.Join(strings.Fields(label), " "))
+	// Fold the sharp s as unicode case folding does, which ToLower does not
+	return strings.Replace(label, "ß", "ss", -1)
+}
@@ -0,0 +1,127 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// renders maps markdown to the html expected.
+var renders = map[string]string{
+	"# Title #":                             "<h1>Title</h1>\n",
+	"Title\n=====":                          "<h1>Title</h1>\n",
+	"Sub\n---":                              "<h2>Sub</h2>\n",
+	"#hashtag":                              "<p>#hashtag</p>\n",
+	"one\ntwo\n\nthree":                     "<p>one\ntwo</p>\n<p>three</p>\n",
+	"line  \nbreak":                         "<p>line<br>\nbreak</p>\n",
+	"*em* **strong** ***both***":            "<p><em>em</em> <strong>strong</strong> <em><strong>both</strong></em></p>\n",
+	"snake_case_name and _em_":              "<p>snake_case_name and <em>em</em></p>\n",
+	"**not closed":                          "<p>**not closed</p>\n",
+	"`a < b` and `` ` ``":                   "<p><code>a &lt; b</code> and <code>`</code></p>\n",
+	`\*not em\*`:                            "<p>*not em*</p>\n",
+	"[link](/pages/1 \"Title\")":            "<p><a href=\"/pages/1\" title=\"Title\">link</a></p>\n",
+	"[ref][1]\n\n[1]: https://a.com":        "<p><a href=\"https://a.com\">ref</a></p>\n",
+	"![alt *text*](/img.png)":               "<p><img src=\"/img.png\" alt=\"alt text\"></p>\n",
+	"<https://example.com>":                 "<p><a href=\"https://example.com\">https://example.com</a></p>\n",
+	"> quote\ncontinued":                    "<blockquote>\n<p>quote\ncontinued</p>\n</blockquote>\n",
+	"- a\n- b\n  - c":                       "<ul>\n<li>a</li>\n<li>b\n<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n",
+	"1. a\n2. b":                            "<ol>\n<li>a</li>\n<li>b</li>\n</ol>\n",
+	"3) a\n\n4) b":                          "<ol start=\"3\">\n<li>\n<p>a</p>\n</li>\n<li>\n<p>b</p>\n</li>\n</ol>\n",
+	"***":                                   "<hr>\n",
+	"```go\nx := 1 < 2\n```":                "<pre><code class=\"language-go\">x := 1 &lt; 2\n</code></pre>\n",
+	"    indented\n    code":                "<pre><code>indented\ncode\n</code></pre>\n",
+	"AT&T &copy; &nope;":                    "<p>AT&amp;T © &amp;nope;</p>\n",
+	"| a | b |\n|:--|--:|\n| 1 | 2 \\| 3 |": "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">2 | 3</td>\n</tr>\n</tbody>\n</table>\n",
+}
+
+// unsafe maps markdown with xss payloads to the html expected, raw html is escaped and urls checked.
+var unsafe = map[string]string{
+	"<script>alert(1)</script>":                "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n",
+	"<img src=x onerror=alert(1)>":             "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n",
+	"[x](javascript:alert(1))":                 "<p>x</p>\n",
+	"[x](JAVASCRIPT&#58;alert(1))":             "<p>x</p>\n",
+	"![x](data:image/svg+xml;base64,PHN2Zz4=)": "<p>x</p>\n",
+	"<javascript:alert(1)>":                    "<p>javascript:alert(1)</p>\n",
+	"[x](/a\" onmouseover=\"alert(1))":         "<p>[x](/a&quot; onmouseover=&quot;alert(1))</p>\n",
+	"[x](</a\" onmouseover=\"alert(1)>)":       "<p><a href=\"/a%22%20onmouseover=%22alert(1)\">x</a></p>\n",
+	"```\"><script>\nx\n```":                   "<pre><code class=\"language-&quot;&gt;&lt;script&gt;\">x\n</code></pre>\n",
+	"[x]: javascript:alert(1)\n\n[y][x]":       "<p>y</p>\n",
+}
+
+// TestRender tests markdown is rendered as html.
+func TestRender(t *testing.T) {
+	var err error
+	settings.Current, err = settings.New(settings.Test, nil)
+	if err != nil {
+		t.Fatalf("markdown: error loading settings %s", err)
+	}
+
+	for in, expected := range renders {
+		got := Render(in)
+		if got != expected {
+			t.Errorf("markdown: unexpected html for %q\nexpected:%q\ngot:     %q", in, expected, got)
+		}
+	}
+
+	for in, expected := range unsafe {
+		got := Render(in)
+		if got != expected {
+			t.Errorf("markdown: unexpected html for unsafe %q\nexpected:%q\ngot:     %q", in, expected, got)
+		}
+	}
+}
+
+// TestFootnotes tests footnotes are numbered in the order referred to, with ids
+// which differ between documents so that they may be shown on the same page.
+func TestFootnotes(t *testing.T) {
+	doc := "Note[^1].\n\n[^1]: The *note*."
+	expected := "<p>Note<sup class=\"footnote-ref\"><a href=\"#fn-%[1]s-1\" id=\"fnref-%[1]s-1\">1</a></sup>.</p>\n<section class=\"footnotes\">\n<ol>\n<li id=\"fn-%[1]s-1\">\n<p>The <em>note</em>. <a href=\"#fnref-%[1]s-1\" class=\"footnote-backref\">&#8617;</a></p>\n</li>\n</ol>\n</section>\n"
+
+	got := Render(doc)
+	m := footnoteIDRegexp.FindStringSubmatch(got)
+	if m == nil || got != fmt.Sprintf(expected, m[1]) {
+		t.Fatalf("markdown: unexpected html for footnotes\nexpected:%q\ngot:     %q", expected, got)
+	}
+
+	other := footnoteIDRegexp.FindStringSubmatch(Render("Another[^1].\n\n[^1]: Note."))
+	if other == nil || other[1] == m[1] {
+		t.Fatalf("markdown: footnote ids not unique to document %v %v", m, other)
+	}
+	if Render(doc) != got {
+		t.Fatalf("markdown: footnote ids differ when rendered again")
+	}
+}
+
+// footnoteIDRegexp matches the prefix of footnote ids.
+var footnoteIDRegexp = regexp.MustCompile(`id="fnref-([0-9a-f]+)-1"`)
+
+// conversions maps html to the markdown expected.
+var conversions = map[string]string{
+	"<h2>Title</h2><p>Some <b>bold</b> and <i>italic</i> text.</p>":                            "## Title\n\nSome **bold** and *italic* text.",
+	"<p>A <a href=\"/pages/1\" title=\"T\">link</a> and <img src=\"/a.png\" alt=\"A\"></p>":    "A [link](/pages/1 \"T\") and ![A](/a.png)",
+	"<ul>\n<li>one</li>\n<li>two<ul><li>three</li></ul></li>\n</ul>":                           "- one\n- two\n  - three",
+	"<ol start=\"2\"><li><p>a</p><p>b</p></li><li>c</li></ol>":                                 "2. a\n\n   b\n\n3. c",
+	"<blockquote><p>quote</p><p>more</p></blockquote>":                                         "> quote\n>\n> more",
+	"<pre><code class=\"language-go\">x := `a`\n</code></pre>":                                 "```go\nx := `a`\n```",
+	"<p>1. not a list, *not em* and line<br>break</p>":                                         "1\\. not a list, \\*not em\\* and line\\\nbreak",
+	"<table><tr><th>a</th><th>b</th></tr><tr><td>1|2</td><td><code>x</code></td></tr></table>": "| a | b |\n| --- | --- |\n| 1\\|2 | `x` |",
+	"<div>text</div><iframe src=\"https://example.com\"></iframe><script>x</script><hr>":       "text\n\n---",
+}
+
+// TestFromHTML tests html is converted to markdown, which renders back to similar html.
+func TestFromHTML(t *testing.T) {
+	for in, expected := range conversions {
+		got := FromHTML(in)
+		if got != expected {
+			t.Errorf("markdown: unexpected markdown for %q\nexpected:%q\ngot:     %q", in, expected, got)
+		}
+	}
+
+	html := Render(FromHTML("<p>Some <b>bold</b> and a <a href=\"/x\">link</a></p>"))
+	if !strings.Contains(html, "<strong>bold</strong>") || !strings.Contains(html, `<a href="/x">link</a>`) {
+		t.Fatalf("markdown: unexpected html after conversion got:%s", html)
+	}
+}
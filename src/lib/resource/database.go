@@ -0,0 +1,101 @@
+package resource
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fragmenta/query"
+)
+
+// This file contains helpers for working with the different database adapters
+// supported by the cms, so that resources may avoid adapter specific sql.
+
+// Database adapters supported by the cms.
+const (
+	AdapterPostgres = "postgres"
+	AdapterSQLite   = "sqlite3"
+)
+
+// Adapter is the name of the database adapter in use, set by OpenDatabase.
+var Adapter = AdapterPostgres
+
+// OpenDatabase opens the database with the options given (as for query.OpenDatabase)
+// and records the adapter in use, applying any adapter specific settings.
+func OpenDatabase(options map[string]string) error {
+
+	// Default to postgres if no adapter is specified
+	if options["adapter"] == "" {
+		options["adapter"] = AdapterPostgres
+	}
+
+	err := query.OpenDatabase(options)
+	if err != nil {
+		return err
+	}
+
+	Adapter = options["adapter"]
+
+	// Use write-ahead logging on sqlite so that reads don't block on writes
+	if SQLite() {
+		_, err = query.ExecSQL("PRAGMA journal_mode=WAL;")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SQLite returns true if the database in use is sqlite.
+func SQLite() bool {
+	return Adapter == AdapterSQLite
+}
+
+// ILike returns a case-insensitive LIKE condition on col for use in a where clause,
+// sqlite does not support ILIKE, but its LIKE is case-insensitive for ascii.
+func ILike(col string) string {
+	if SQLite() {
+		return fmt.Sprintf("%s LIKE ?", col)
+	}
+	return fmt.Sprintf("%s ILIKE ?", col)
+}
+
+// ResetSequence resets the sequence for ids on table, so that the next id inserted is next.
+func ResetSequence(table string, next int64) error {
+
+	if SQLite() {
+		// The sqlite_sequence table stores the last id used for autoincrement tables
+		_, err := query.ExecSQL("DELETE FROM sqlite_sequence WHERE name=?;", table)
+		if err != nil || next <= 1 {
+			return err
+		}
+		_, err = query.ExecSQL("INSERT INTO sqlite_sequence(name,seq) VALUES(?,?);", table, next-1)
+		return err
+	}
+
+	_, err := query.ExecSQL(fmt.Sprintf("ALTER SEQUENCE %s_id_seq RESTART WITH %d;", table, next))
+	return err
+}
+
+var (
+	// serialRegexp matches postgres serial column types
+	serialRegexp = regexp.MustCompile(`(?i)\bSERIAL\b`)
+
+	// ownerRegexp matches postgres statements setting table ownership
+	ownerRegexp = regexp.MustCompile(`(?im)^ALTER TABLE \S+ OWNER TO .*;[ \t]*\n?`)
+)
+
+// AdaptSQL vivifies the sql migration template given for the db user and adapter.
+// Templates are written for postgres, so for sqlite we replace serial columns
+// with autoincrement primary keys. Statements setting table ownership are removed
+// for sqlite or if no user is given.
+func AdaptSQL(sql, adapter, user string) string {
+	if adapter == AdapterSQLite {
+		sql = serialRegexp.ReplaceAllString(sql, "INTEGER PRIMARY KEY AUTOINCREMENT")
+	}
+	if adapter == AdapterSQLite || user == "" {
+		return ownerRegexp.ReplaceAllString(sql, "")
+	}
+	return strings.Replace(sql, "[[.fragmenta_db_user]]", user, -1)
+}
@@ -1,7 +1,12 @@
 package resource
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/fragmenta/query"
 )
 
 var r = Base{ID: 99, TableName: "images", KeyName: "id"}
@@ -104,3 +109,68 @@ func TestURLs(t *testing.T) {
 	}
 
 }
+
+// TestILike tests case insensitive conditions for each adapter.
+func TestILike(t *testing.T) {
+	defer func() { Adapter = AdapterPostgres }()
+
+	expected := "name ILIKE ?"
+	if ILike("name") != expected {
+		t.Fatalf("ILike does not match expected:%s got:%s", expected, ILike("name"))
+	}
+
+	Adapter = AdapterSQLite
+	expected = "name LIKE ?"
+	if ILike("name") != expected {
+		t.Fatalf("ILike does not match expected:%s got:%s", expected, ILike("name"))
+	}
+}
+
+// TestAdaptSQL tests table creation sql is adapted for each adapter.
+func TestAdaptSQL(t *testing.T) {
+	sql := "CREATE TABLE pages (\nid SERIAL NOT NULL,\nname text\n);\nALTER TABLE pages OWNER TO \"[[.fragmenta_db_user]]\";\n"
+
+	pg := AdaptSQL(sql, AdapterPostgres, "cms_server")
+	if !strings.Contains(pg, "id SERIAL NOT NULL") || !strings.Contains(pg, "OWNER TO \"cms_server\"") {
+		t.Fatalf("AdaptSQL failed for postgres got:%s", pg)
+	}
+
+	lite := AdaptSQL(sql, AdapterSQLite, "")
+	if !strings.Contains(lite, "id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL") || strings.Contains(lite, "OWNER") {
+		t.Fatalf("AdaptSQL failed for sqlite got:%s", lite)
+	}
+}
+
+// TestLoadTestSchema tests the Create-Tables template is loaded without the
+// migrations it includes.
+func TestLoadTestSchema(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "db", "migrate")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		t.Fatalf("resource: error creating migrations %s", err)
+	}
+
+	files := map[string]string{
+		"Create-Tables.sql.tmpl":             "CREATE TABLE notes (id SERIAL NOT NULL, name text);",
+		"2026-01-01-120000-Create-Notes.sql": "CREATE TABLE notes (id SERIAL NOT NULL);",
+		"2026-01-02-120000-Add-Name.sql":     "ALTER TABLE notes ADD COLUMN name text;",
+	}
+	for name, sql := range files {
+		err = os.WriteFile(filepath.Join(dir, name), []byte(sql), 0644)
+		if err != nil {
+			t.Fatalf("resource: error writing migration %s", err)
+		}
+	}
+
+	err = openTestSQLite()
+	if err != nil {
+		t.Fatalf("resource: error opening database %s", err)
+	}
+	defer query.CloseDatabase()
+
+	err = loadTestSchema(root)
+	if err != nil {
+		t.Fatalf("resource: error loading schema with template %s", err)
+	}
+}
@@ -6,7 +6,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/fragmenta/auth"
@@ -60,8 +64,8 @@ func AddUserSessionCookie(w *httptest.ResponseRecorder, r *http.Request, id int)
 	// Now from secret, generate a secure token for this request
 	token := auth.BytesToBase64(auth.AuthenticityTokenWithSecret(auth.Base64ToBytes(secret)))
 
-	// Write value of user id 1
-	session.Set(auth.SessionUserKey, "1")
+	// Write value of user id
+	session.Set(auth.SessionUserKey, strconv.Itoa(id))
 
 	// Set the cookie on the recorder
 	err = session.Save(w)
@@ -93,8 +97,33 @@ func SetupView(depth int) error {
 	return view.LoadTemplatesAtPaths([]string{filepath.Join(basePath(depth), "src")}, view.Helpers)
 }
 
-// SetupTestDatabase sets up the database for all tests from the test config.
+// SetupTestDatabase sets up an isolated database for the tests in the calling package
+// and loads the schema from the migrations, see SetupTestDatabaseAt.
 func SetupTestDatabase(depth int) error {
+	root, err := filepath.Abs(basePath(depth))
+	if err != nil {
+		return err
+	}
+
+	// Name the database after the package path relative to the root, e.g. src/pages/actions
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	name, err := filepath.Rel(root, wd)
+	if err != nil {
+		return err
+	}
+
+	return SetupTestDatabaseAt(root, name)
+}
+
+// SetupTestDatabaseAt sets up an isolated database called name for tests,
+// and loads the schema from the migrations in db/migrate under root.
+// By default this is a new sqlite database in a temporary directory, so no database
+// need be provisioned. If FRAG_TEST_ADAPTER=postgres is set, the postgres test database
+// from secrets/fragmenta.json is used instead, with a fresh schema for each name.
+func SetupTestDatabaseAt(root, name string) error {
 
 	// Set up a stderr logger with time prefix
 	logger, err := log.NewStdErr(log.PrefixDateTime)
@@ -103,8 +132,51 @@ func SetupTestDatabase(depth int) error {
 	}
 	log.Add(logger)
 
+	if os.Getenv(testAdapterEnv) == AdapterPostgres {
+		err = openTestPostgres(root, name)
+	} else {
+		err = openTestSQLite()
+	}
+	if err != nil {
+		return err
+	}
+
+	return loadTestSchema(root)
+}
+
+// testAdapterEnv is the environment variable used to choose the test database adapter.
+const testAdapterEnv = "FRAG_TEST_ADAPTER"
+
+// schemaRegexp removes everything but letters, digits and _ from schema names.
+var schemaRegexp = regexp.MustCompile("[^a-z0-9_]+")
+
+// openTestSQLite opens a new sqlite database in a temporary directory.
+func openTestSQLite() error {
+	dir, err := ioutil.TempDir("", "fragmenta-test")
+	if err != nil {
+		return err
+	}
+
+	options := map[string]string{
+		"adapter": AdapterSQLite,
+		"db":      filepath.Join(dir, "test.db"),
+	}
+	err = OpenDatabase(options)
+	if err != nil {
+		return err
+	}
+
+	// For speed
+	_, err = query.ExecSQL("PRAGMA synchronous=OFF;")
+	return err
+}
+
+// openTestPostgres opens the postgres test database from the config under root,
+// and replaces the schema for name with an empty one which is used for all queries.
+func openTestPostgres(root, name string) error {
+
 	// Read config json
-	path := filepath.Join(basePath(depth), "secrets", "fragmenta.json")
+	path := filepath.Join(root, "secrets", "fragmenta.json")
 	file, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
@@ -118,19 +190,84 @@ func SetupTestDatabase(depth int) error {
 
 	config := data["test"]
 	options := map[string]string{
-		"adapter":  config["db_adapter"],
+		"adapter":  AdapterPostgres,
 		"user":     config["db_user"],
 		"password": config["db_pass"],
 		"db":       config["db"],
+		"params":   "sslmode=disable",
+	}
+	if config["db_params"] != "" {
+		options["params"] = config["db_params"]
 	}
 
 	// Ask query to open the database
-	err = query.OpenDatabase(options)
+	err = OpenDatabase(options)
+	if err != nil {
+		return err
+	}
+
+	// Create an empty schema for these tests
+	schema := "test_" + schemaRegexp.ReplaceAllString(strings.ToLower(name), "_")
+	_, err = query.ExecSQL(fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE; CREATE SCHEMA %s;", schema, schema))
+	if err != nil {
+		return err
+	}
+	query.CloseDatabase()
+
+	// Reopen the database using the schema
+	options["params"] = fmt.Sprintf("%s search_path=%s", options["params"], schema)
+	err = OpenDatabase(options)
 	if err != nil {
 		return err
 	}
 
 	// For speed
-	query.Exec("set synchronous_commit=off;")
+	_, err = query.ExecSQL("set synchronous_commit=off;")
+	return err
+}
+
+// loadTestSchema loads the table schema from db/migrate under root into the open database.
+// The Create-Tables template is used if present, as on bootstrap it includes every
+// migration, otherwise the latest Create-Tables migration followed by any migrations added since.
+func loadTestSchema(root string) error {
+	dir := filepath.Join(root, "db", "migrate")
+
+	var migrations []string
+	template := filepath.Join(dir, "Create-Tables.sql.tmpl")
+	if _, err := os.Stat(template); err == nil {
+		migrations = []string{template}
+	} else {
+		files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+		if err != nil {
+			return err
+		}
+		sort.Strings(files)
+
+		// Skip migrations up to the Create-Tables migration, they are included in it
+		for _, f := range files {
+			if strings.HasSuffix(f, "Create-Tables.sql") {
+				migrations = nil
+			}
+			if !strings.HasSuffix(f, "Create-Database.sql") {
+				migrations = append(migrations, f)
+			}
+		}
+	}
+
+	if len(migrations) == 0 {
+		return fmt.Errorf("resource: no schema found in %s", dir)
+	}
+
+	for _, m := range migrations {
+		sql, err := ioutil.ReadFile(m)
+		if err != nil {
+			return err
+		}
+		_, err = query.ExecSQL(AdaptSQL(string(sql), Adapter, ""))
+		if err != nil {
+			return fmt.Errorf("resource: error loading schema %s %s", filepath.Base(m), err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,386 @@
+// Package sanitize cleans html from editors before it is stored, keeping only
+// the tags and attributes allowed for the user, and links with safe url schemes.
+package sanitize
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// User is the interface for users who edit html, admins may use the admin tags.
+type User interface {
+	Admin() bool
+}
+
+// Policy lists the tags and attributes allowed in html, and the schemes allowed in urls.
+type Policy struct {
+	// tags maps allowed tag names to their allowed attributes, * holds attributes allowed on any tag
+	tags    map[string]map[string]bool
+	schemes map[string]bool
+}
+
+// urlAttributes are the attributes which hold urls, checked for allowed schemes.
+var urlAttributes = map[string]bool{"href": true, "src": true, "cite": true, "action": true, "formaction": true, "poster": true}
+
+// voidTags are the tags which have no content or end tag.
+var voidTags = map[string]bool{"area": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true, "source": true, "track": true, "wbr": true}
+
+// dropTags are the tags whose content is removed with them if they are not allowed.
+var dropTags = map[string]bool{"script": true, "style": true, "iframe": true, "object": true, "applet": true, "embed": true, "template": true, "noscript": true, "noembed": true, "noframes": true, "xmp": true, "plaintext": true, "textarea": true, "title": true, "select": true, "svg": true, "math": true}
+
+// policyRegexp matches the tags in a policy such as p, a[href title], *[class].
+var policyRegexp = regexp.MustCompile(`([a-z0-9*]+)\s*(?:\[([^\]]*)\])?`)
+
+// NewPolicy returns a policy allowing the tags and attributes listed in tags,
+// for example "p, a[href title], img[src alt]", in which *[class] allows
+// the class attribute on all tags, and the url schemes separated by commas in schemes.
+func NewPolicy(tags, schemes string) *Policy {
+	p := &Policy{
+		tags:    make(map[string]map[string]bool),
+		schemes: make(map[string]bool),
+	}
+	p.Allow(tags)
+	for _, s := range strings.Split(schemes, ",") {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s != "" {
+			p.schemes[s] = true
+		}
+	}
+	return p
+}
+
+// Allow adds the tags and attributes listed in tags to the policy.
+func (p *Policy) Allow(tags string) *Policy {
+	for _, m := range policyRegexp.FindAllStringSubmatch(strings.ToLower(tags), -1) {
+		attributes := p.tags[m[1]]
+		if attributes == nil {
+			attributes = make(map[string]bool)
+			p.tags[m[1]] = attributes
+		}
+		for _, a := range strings.Fields(m[2]) {
+			attributes[a] = true
+		}
+	}
+	return p
+}
+
+// policies caches the policies read from settings, by their tags and schemes.
+var policies = struct {
+	sync.Mutex
+	m map[string]*Policy
+}{m: make(map[string]*Policy)}
+
+// PolicyFor returns the policy from settings for the user, admins may use the
+// admin tags as well as the tags allowed for everyone.
+func PolicyFor(u User) *Policy {
+	config := settings.Current.Sanitize
+	tags := config.Tags
+	if u != nil && u.Admin() {
+		tags += "," + config.AdminTags
+	}
+
+	key := tags + "|" + config.Schemes
+	policies.Lock()
+	defer policies.Unlock()
+	p := policies.m[key]
+	if p == nil {
+		p = NewPolicy(tags, config.Schemes)
+		policies.m[key] = p
+	}
+	return p
+}
+
+// HTML returns the html s cleaned for the user, with the policy from settings.
+func HTML(s string, u User) string {
+	return PolicyFor(u).HTML(s)
+}
+
+// Content returns the html s cleaned with the policy for admins, for rendering
+// text which was cleaned for its author when it was saved.
+func Content(s string) template.HTML {
+	return template.HTML(PolicyFor(adminUser{}).HTML(s))
+}
+
+// adminUser is used to select the admin policy in Content.
+type adminUser struct{}
+
+func (adminUser) Admin() bool { return true }
+
+// HTML returns the html s with tags, attributes and urls not allowed by the policy removed.
+// Tags are rebuilt with their attribute values escaped, comments are removed, the
+// content of tags such as script is removed with them, and < which does not start a
+// tag is escaped, so the result is safe to render whatever the input.
+func (p *Policy) HTML(s string) string {
+	var b strings.Builder
+	skip := ""
+	for _, token := range Tokenize(s) {
+		t := token.Tag
+
+		// Remove everything up to the end of a dropped tag
+		if skip != "" {
+			if t != nil && t.End && t.Name == skip {
+				skip = ""
+			}
+			continue
+		}
+
+		if t == nil {
+			b.WriteString(strings.Replace(token.Text, "<", "&lt;", -1))
+			continue
+		}
+
+		// Comments and doctypes have no name
+		if t.Name == "" {
+			continue
+		}
+
+		attributes, ok := p.tags[t.Name]
+		if !ok {
+			if dropTags[t.Name] && !t.End && !voidTags[t.Name] {
+				skip = t.Name
+			}
+			continue
+		}
+
+		if t.End {
+			if !voidTags[t.Name] {
+				b.WriteString("</" + t.Name + ">")
+			}
+			continue
+		}
+		b.WriteString(p.startTag(t, attributes))
+	}
+	return b.String()
+}
+
+// startTag returns the start tag for t with the attributes allowed.
+func (p *Policy) startTag(t *Tag, allowed map[string]bool) string {
+	s := "<" + t.Name
+	seen := make(map[string]bool)
+	for _, a := range t.Attributes {
+		if seen[a.Name] || strings.HasPrefix(a.Name, "on") || !(allowed[a.Name] || p.tags["*"][a.Name]) {
+			continue
+		}
+		seen[a.Name] = true
+
+		v := strings.TrimSpace(html.UnescapeString(a.Value))
+		if urlAttributes[a.Name] && !p.allowURL(v) {
+			continue
+		}
+		if a.Name == "srcset" && !p.allowSrcset(v) {
+			continue
+		}
+		if !a.HasValue {
+			s += " " + a.Name
+			continue
+		}
+		if t.Name == "a" && a.Name == "target" && !seen["rel"] {
+			s += ` rel="noopener noreferrer"`
+			seen["rel"] = true
+		}
+		s += " " + a.Name + `="` + html.EscapeString(v) + `"`
+	}
+	return s + ">"
+}
+
+// AllowURL returns true if the url u is relative or has a scheme allowed in settings.
+func AllowURL(u string) bool {
+	return PolicyFor(nil).allowURL(u)
+}
+
+// allowURL returns true if the url u is relative or has a scheme allowed by the policy.
+func (p *Policy) allowURL(u string) bool {
+	// Browsers ignore whitespace and control characters in urls, so remove them before checking the scheme
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+
+	colon := strings.IndexByte(u, ':')
+	if colon < 0 || strings.ContainsAny(u[:colon], "/?#") {
+		return true
+	}
+	return p.schemes[strings.ToLower(u[:colon])]
+}
+
+// allowSrcset returns true if every url in the srcset s is allowed by the policy.
+func (p *Policy) allowSrcset(s string) bool {
+	for _, candidate := range strings.Split(s, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && !p.allowURL(fields[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Token is text or a tag read from html by Tokenize.
+type Token struct {
+	// Text is the text of text tokens as written, with entities not decoded
+	Text string
+	// Tag is the tag of tag tokens, or nil for text
+	Tag *Tag
+}
+
+// Tag is a start or end tag read from html, comments and doctypes are read as tags without a name.
+type Tag struct {
+	Name       string
+	End        bool
+	Attributes []Attribute
+}
+
+// Attribute is an attribute of a start tag, with its value as written.
+type Attribute struct {
+	Name     string
+	Value    string
+	HasValue bool
+}
+
+// Attr returns the value of the attribute name with entities decoded, or an empty string.
+func (t *Tag) Attr(name string) string {
+	for _, a := range t.Attributes {
+		if a.Name == name {
+			return strings.TrimSpace(html.UnescapeString(a.Value))
+		}
+	}
+	return ""
+}
+
+// Tokenize splits the html s into text and tags, < which does not start a
+// complete tag is returned as text.
+func Tokenize(s string) []Token {
+	var tokens []Token
+	text := ""
+	for i := 0; i < len(s); {
+		lt := strings.IndexByte(s[i:], '<')
+		if lt < 0 {
+			text += s[i:]
+			break
+		}
+		text += s[i : i+lt]
+		i += lt
+
+		t, n := readTag(s[i:])
+		if n == 0 {
+			text += "<"
+			i++
+			continue
+		}
+		i += n
+
+		if text != "" {
+			tokens = append(tokens, Token{Text: text})
+			text = ""
+		}
+		tokens = append(tokens, Token{Tag: t})
+	}
+	if text != "" {
+		tokens = append(tokens, Token{Text: text})
+	}
+	return tokens
+}
+
+// readTag reads the tag at the start of s, which starts with <, returning the tag and its
+// length, or a length of 0 if s does not start with a complete tag. Comments, doctypes
+// and processing instructions are returned as tags without a name.
+func readTag(s string) (*Tag, int) {
+	t := &Tag{}
+	if len(s) < 2 {
+		return t, 0
+	}
+
+	if strings.HasPrefix(s, "<!--") {
+		end := strings.Index(s[4:], "-->")
+		if end < 0 {
+			return t, len(s)
+		}
+		return t, 4 + end + 3
+	}
+
+	if s[1] == '!' || s[1] == '?' {
+		end := strings.IndexByte(s, '>')
+		if end < 0 {
+			return t, len(s)
+		}
+		return t, end + 1
+	}
+
+	i := 1
+	if s[1] == '/' {
+		t.End = true
+		i++
+	}
+	if i >= len(s) || !isLetter(s[i]) {
+		return t, 0
+	}
+	start := i
+	for i < len(s) && (isLetter(s[i]) || (s[i] >= '0' && s[i] <= '9') || s[i] == '-') {
+		i++
+	}
+	t.Name = strings.ToLower(s[start:i])
+
+	for {
+		for i < len(s) && (isSpace(s[i]) || s[i] == '/') {
+			i++
+		}
+		if i >= len(s) {
+			return t, 0
+		}
+		if s[i] == '>' {
+			return t, i + 1
+		}
+
+		// Read the attribute name, which may start with =
+		start = i
+		i++
+		for i < len(s) && !isSpace(s[i]) && s[i] != '/' && s[i] != '>' && s[i] != '=' {
+			i++
+		}
+		a := Attribute{Name: strings.ToLower(s[start:i])}
+
+		j := i
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		if j < len(s) && s[j] == '=' {
+			i = j + 1
+			for i < len(s) && isSpace(s[i]) {
+				i++
+			}
+			if i >= len(s) {
+				return t, 0
+			}
+			a.HasValue = true
+			if q := s[i]; q == '"' || q == '\'' {
+				end := strings.IndexByte(s[i+1:], q)
+				if end < 0 {
+					return t, 0
+				}
+				a.Value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+					i++
+				}
+				a.Value = s[start:i]
+			}
+		}
+		t.Attributes = append(t.Attributes, a)
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,123 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// user is a mock user who may be an admin
+type user struct {
+	admin bool
+}
+
+func (u *user) Admin() bool { return u.admin }
+
+// payloads maps html containing common xss payloads to the html expected for editors.
+var payloads = map[string]string{
+	`<p>Hello <b>world</b></p>`:                                 `<p>Hello <b>world</b></p>`,
+	`<script>alert(1)</script><p>x</p>`:                         `<p>x</p>`,
+	`<SCRIPT SRC=//evil.com/x.js></SCRIPT>`:                     ``,
+	`<img src=x onerror=alert(1)>`:                              `<img src="x">`,
+	`<img src="x" ONERROR="alert(1)" alt="a">`:                  `<img src="x" alt="a">`,
+	`<a href="javascript:alert(1)">x</a>`:                       `<a>x</a>`,
+	`<a href="JaVaScRiPt:alert(1)">x</a>`:                       `<a>x</a>`,
+	`<a href=" java&#x09;script:alert(1)">x</a>`:                `<a>x</a>`,
+	`<a href="&#106;avascript:alert(1)">x</a>`:                  `<a>x</a>`,
+	`<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`:        `<a>x</a>`,
+	`<a href="vbscript:msgbox(1)">x</a>`:                        `<a>x</a>`,
+	`<a href="/pages/1?a=b&amp;c=d#top">x</a>`:                  `<a href="/pages/1?a=b&amp;c=d#top">x</a>`,
+	`<a href="https://example.com" target="_blank">x</a>`:       `<a href="https://example.com" rel="noopener noreferrer" target="_blank">x</a>`,
+	`<a href="mailto:a@example.com">x</a>`:                      `<a href="mailto:a@example.com">x</a>`,
+	`<svg onload=alert(1)><circle r="1"/></svg>ok`:              `ok`,
+	`<iframe src="javascript:alert(1)"></iframe>`:               ``,
+	`<p style="background:url(javascript:alert(1))">x</p>`:      `<p>x</p>`,
+	`<p title='a" onmouseover="alert(1)'>x</p>`:                 `<p title="a&#34; onmouseover=&#34;alert(1)">x</p>`,
+	`<div class="x"onclick="alert(1)">x</div>`:                  `<div class="x">x</div>`,
+	`<!-- <script>alert(1)</script> -->x`:                       `x`,
+	`<<script>alert(1)//<</script>x`:                            `&lt;x`,
+	`<scr<script>ipt>alert(1)</script>`:                         `ipt>alert(1)`,
+	`<img src=x onerror=alert(1)`:                               `&lt;img src=x onerror=alert(1)`,
+	`1 < 2 and 3 > 2`:                                           `1 &lt; 2 and 3 > 2`,
+	`<style>body{display:none}</style><p>x</p>`:                 `<p>x</p>`,
+	`<object data="x.swf"><embed src="x.swf"></object>x`:        `x`,
+	`<form action="/x"><input name="a"></form>`:                 ``,
+	`<math><mi xlink:href="javascript:alert(1)">x</mi></math>y`: `y`,
+	`<br/><hr /></br>`:                                          `<br><hr>`,
+}
+
+// TestHTML tests common xss payloads are removed from html for editors.
+func TestHTML(t *testing.T) {
+	var err error
+	settings.Current, err = settings.New(settings.Test, nil)
+	if err != nil {
+		t.Fatalf("sanitize: error loading settings %s", err)
+	}
+
+	editor := &user{}
+	for in, expected := range payloads {
+		got := HTML(in, editor)
+		if got != expected {
+			t.Errorf("sanitize: unexpected html for %s expected:%s got:%s", in, expected, got)
+		}
+	}
+}
+
+// TestAdmin tests admins may embed iframes but editors may not.
+func TestAdmin(t *testing.T) {
+	iframe := `<iframe src="https://www.youtube.com/embed/x" allowfullscreen onload="alert(1)"></iframe>`
+
+	got := HTML(iframe, &user{})
+	if got != "" {
+		t.Fatalf("sanitize: iframe allowed for editor got:%s", got)
+	}
+
+	expected := `<iframe src="https://www.youtube.com/embed/x" allowfullscreen></iframe>`
+	got = HTML(iframe, &user{admin: true})
+	if got != expected {
+		t.Fatalf("sanitize: unexpected iframe for admin expected:%s got:%s", expected, got)
+	}
+
+	got = string(Content(iframe))
+	if got != expected {
+		t.Fatalf("sanitize: unexpected iframe in content expected:%s got:%s", expected, got)
+	}
+
+	// Admin tags should still have their urls checked
+	got = HTML(`<iframe src="javascript:alert(1)"></iframe>`, &user{admin: true})
+	if strings.Contains(got, "javascript") {
+		t.Fatalf("sanitize: unsafe iframe src allowed for admin got:%s", got)
+	}
+}
+
+// TestPolicy tests policies may be configured.
+func TestPolicy(t *testing.T) {
+	p := NewPolicy("p, a[href], *[class]", "https")
+
+	got := p.HTML(`<p class="a" id="b"><a href="http://example.com" class="c">x</a><a href="https://example.com">y</a><b>z</b></p>`)
+	expected := `<p class="a"><a class="c">x</a><a href="https://example.com">y</a>z</p>`
+	if got != expected {
+		t.Fatalf("sanitize: unexpected html for policy expected:%s got:%s", expected, got)
+	}
+
+	p.Allow("b")
+	got = p.HTML(`<b>z</b>`)
+	if got != `<b>z</b>` {
+		t.Fatalf("sanitize: unexpected html after allow got:%s", got)
+	}
+}
+
+// TestSrcset tests responsive images are allowed, with the urls in srcset checked.
+func TestSrcset(t *testing.T) {
+	img := `<img src="/a.jpg" srcset="/a-480.jpg 480w, /a.jpg 960w" sizes="100vw" loading="lazy">`
+	got := HTML(img, &user{})
+	if got != img {
+		t.Fatalf("sanitize: unexpected html for srcset expected:%s got:%s", img, got)
+	}
+
+	got = HTML(`<img src="/a.jpg" srcset="/a-480.jpg 480w, javascript:alert(1) 960w">`, &user{})
+	if got != `<img src="/a.jpg">` {
+		t.Fatalf("sanitize: unsafe srcset allowed got:%s", got)
+	}
+}
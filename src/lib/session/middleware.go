@@ -12,10 +12,14 @@ import (
 
 // Middleware sets a token on every GET request so that it can be
 // inserted into the view. It currently ignores requests for files and assets.
+// Sessions signed with previous keys are re-signed with the current keys.
 func Middleware(h http.HandlerFunc) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
 
+		// Upgrade sessions signed with the previous keys after key rotation
+		upgradeSession(w, r)
+
 		// If a get method, we need to set the token for use in views
 		if shouldSetToken(r) {
 			// This sets the token on the encrypted session cookie
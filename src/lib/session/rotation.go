@@ -0,0 +1,75 @@
+package session
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/server/log"
+)
+
+// This file contains support for rotating the auth keys. Session cookies signed with
+// the previous keys are accepted and re-signed with the current keys until they expire.
+
+var (
+	// PreviousHMACKey is the hmac key in use before auth.HMACKey, if keys have been rotated.
+	PreviousHMACKey []byte
+
+	// PreviousSecretKey is the secret key in use before auth.SecretKey, if keys have been rotated.
+	PreviousSecretKey []byte
+
+	// PreviousExpires is the time after which the previous keys are no longer accepted.
+	PreviousExpires time.Time
+)
+
+// upgradeSession re-signs the session cookie with the current keys if it was signed
+// with the previous keys, so that the request and response use the current keys.
+func upgradeSession(w http.ResponseWriter, r *http.Request) {
+
+	if len(PreviousHMACKey) == 0 || time.Now().After(PreviousExpires) {
+		return
+	}
+
+	cookie, err := r.Cookie(auth.SessionName)
+	if err != nil {
+		return
+	}
+
+	// If the cookie can be read with the current keys, there is nothing to do
+	values := make(map[string]string)
+	store := &auth.CookieSessionStore{}
+	err = store.Decode(auth.SessionName, auth.HMACKey, auth.SecretKey, cookie.Value, &values)
+	if err == nil {
+		return
+	}
+
+	// Otherwise try the previous keys, ignoring cookies which are not valid with either
+	err = store.Decode(auth.SessionName, PreviousHMACKey, PreviousSecretKey, cookie.Value, &values)
+	if err != nil {
+		return
+	}
+
+	cookie.Value, err = store.Encode(auth.SessionName, values, auth.HMACKey, auth.SecretKey)
+	if err != nil {
+		log.Error(log.V{"msg": "session: problem re-signing session", "error": err})
+		return
+	}
+
+	// Replace the cookie on the request for handlers
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name == cookie.Name {
+			c = cookie
+		}
+		r.AddCookie(c)
+	}
+
+	// Save the session to send the cookie signed with the current keys to the client
+	session, err := auth.SessionGet(r)
+	if err != nil {
+		log.Error(log.V{"msg": "session: problem loading re-signed session", "error": err})
+		return
+	}
+	session.Save(w)
+}
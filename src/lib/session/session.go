@@ -1,13 +1,17 @@
 package session
 
 import (
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/fragmenta/auth"
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server/log"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -47,6 +51,70 @@ func CurrentUser(w http.ResponseWriter, r *http.Request) *users.User {
 	return user
 }
 
+// ClientIP returns the address of the client making the request. Requests from
+// the proxies in the trusted_proxies setting are from the last address in
+// X-Forwarded-For which is not a trusted proxy, or from X-Real-IP.
+func ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !trustedProxy(ip) {
+		return ip
+	}
+
+	// Read forwarded addresses from the nearest, as only the proxies can be trusted
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		f := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(f) == nil {
+			break
+		}
+		ip = f
+		if !trustedProxy(f) {
+			return ip
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return ip
+}
+
+// proxies caches the networks parsed from the trusted_proxies setting.
+var proxies struct {
+	sync.Mutex
+	setting  string
+	networks []*net.IPNet
+}
+
+// trustedProxy returns true if ip is in the trusted_proxies setting.
+func trustedProxy(ip string) bool {
+	if settings.Current.Proxies == "" {
+		return false
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	proxies.Lock()
+	if proxies.networks == nil || proxies.setting != settings.Current.Proxies {
+		proxies.setting = settings.Current.Proxies
+		proxies.networks = settings.ProxyNetworks(proxies.setting)
+	}
+	networks := proxies.networks
+	proxies.Unlock()
+
+	for _, n := range networks {
+		if n != nil && n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
 // clearSession clears the request session cookie entirely.
 // If an error is encountered in processing params, the session is cleared.
 func clearSession(w http.ResponseWriter, r *http.Request) error {
@@ -1,11 +1,15 @@
 package session
 
 import (
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fragmenta/auth"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 )
 
 var (
@@ -56,3 +60,100 @@ func TestAuthenticate(t *testing.T) {
 	}
 
 }
+
+// TestRotation tests sessions signed with previous keys are re-signed with the current keys.
+func TestRotation(t *testing.T) {
+	auth.HMACKey = auth.HexToBytes(testKey)
+	auth.SecretKey = auth.HexToBytes(testKey)
+	auth.SessionName = "test_session"
+
+	// Save a session with the test key
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	session, err := auth.Session(w, r)
+	if err != nil {
+		t.Fatalf("session: failed to build session")
+	}
+	session.Set(auth.SessionUserKey, "1")
+	err = session.Save(w)
+	if err != nil {
+		t.Fatalf("session: failed to save session")
+	}
+
+	// Rotate the keys, keeping the test key as the previous key
+	newKey := "a2353bce2bbc4efb90eff81c29dc982de9a0176b568db18a61b4f4732cadabbc"
+	auth.HMACKey = auth.HexToBytes(newKey)
+	auth.SecretKey = auth.HexToBytes(newKey)
+	PreviousHMACKey = auth.HexToBytes(testKey)
+	PreviousSecretKey = auth.HexToBytes(testKey)
+	PreviousExpires = time.Now().Add(time.Hour)
+	defer func() { PreviousHMACKey = nil }()
+
+	oldCookie := strings.Join(w.HeaderMap["Set-Cookie"], "")
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", oldCookie)
+	w = httptest.NewRecorder()
+
+	// The handler should see the session signed with the current keys
+	handler := Middleware(func(w http.ResponseWriter, r *http.Request) {
+		session, err = auth.SessionGet(r)
+		if err != nil || session.Get(auth.SessionUserKey) != "1" {
+			t.Fatalf("session: failed to read session after rotation %s", err)
+		}
+	})
+	handler(w, r)
+
+	if !strings.Contains(strings.Join(w.HeaderMap["Set-Cookie"], ""), auth.SessionName) {
+		t.Fatalf("session: re-signed session not saved")
+	}
+
+	// After the grace period the previous keys should not be accepted
+	PreviousExpires = time.Now().Add(-time.Hour)
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Cookie", oldCookie)
+	upgradeSession(httptest.NewRecorder(), r)
+	if r.Header.Get("Cookie") != oldCookie {
+		t.Fatalf("session: session re-signed after grace period")
+	}
+}
+
+// TestClientIP tests the address of the client is read from the request without the port.
+func TestClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+
+	r.RemoteAddr = "192.0.2.1:1234"
+	if ip := ClientIP(r); ip != "192.0.2.1" {
+		t.Errorf("session: unexpected client ip got:%s want:192.0.2.1", ip)
+	}
+
+	r.RemoteAddr = "[2001:db8::1]:1234"
+	if ip := ClientIP(r); ip != "2001:db8::1" {
+		t.Errorf("session: unexpected client ip got:%s want:2001:db8::1", ip)
+	}
+
+	r.RemoteAddr = "192.0.2.1"
+	if ip := ClientIP(r); ip != "192.0.2.1" {
+		t.Errorf("session: unexpected client ip got:%s want:192.0.2.1", ip)
+	}
+
+	// Forwarded addresses are ignored unless the request is from a trusted proxy
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9, 10.0.0.1")
+	if ip := ClientIP(r); ip != "10.0.0.2" {
+		t.Errorf("session: unexpected client ip from untrusted proxy got:%s want:10.0.0.2", ip)
+	}
+
+	settings.Current.Proxies = "10.0.0.0/8, 192.0.2.1"
+	defer func() { settings.Current.Proxies = "" }()
+
+	// The last address which is not a proxy is used, as earlier ones may be spoofed
+	if ip := ClientIP(r); ip != "203.0.113.9" {
+		t.Errorf("session: unexpected forwarded client ip got:%s want:203.0.113.9", ip)
+	}
+
+	r.Header.Del("X-Forwarded-For")
+	r.Header.Set("X-Real-IP", "203.0.113.10")
+	if ip := ClientIP(r); ip != "203.0.113.10" {
+		t.Errorf("session: unexpected real client ip got:%s want:203.0.113.10", ip)
+	}
+}
@@ -0,0 +1,225 @@
+package settings
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// This file contains support for secrets kept out of the config file in plain text,
+// either read from files (for example docker or kubernetes secret mounts), or kept
+// in an encrypted section of the config file which is unlocked by a master key.
+
+// SecretsKey is the config key for the encrypted section holding secrets,
+// which is merged over the plain config values when loaded.
+const SecretsKey = "secrets"
+
+// MasterKeyEnv is the environment variable holding the master key for the encrypted
+// section, as 32 bytes encoded in hex. Set MasterKeyEnv+"_FILE" to read it from a file.
+const MasterKeyEnv = EnvPrefix + "MASTER_KEY"
+
+// FilePermissions are the permissions used when writing the config file.
+const FilePermissions = 0600
+
+// ReadFile reads the json config file at path, which holds a map of config keys and values for each environment.
+func ReadFile(path string) (map[string]map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs map[string]map[string]string
+	err = json.Unmarshal(data, &configs)
+	if err != nil {
+		return nil, fmt.Errorf("config: error reading %s: %s", path, err)
+	}
+
+	return configs, nil
+}
+
+// WriteFile writes the configs to the json config file at path, readable only by the owner.
+func WriteFile(path string, configs map[string]map[string]string) error {
+	data, err := json.MarshalIndent(configs, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, data, FilePermissions)
+	if err != nil {
+		return err
+	}
+
+	// Make sure existing files are not left readable by others
+	return os.Chmod(path, FilePermissions)
+}
+
+// SecretKeys returns the config keys which hold secrets.
+func SecretKeys() []string {
+	var keys []string
+	for _, f := range (&Settings{}).fields() {
+		if f.secret {
+			keys = append(keys, f.key)
+		}
+	}
+	return keys
+}
+
+// MasterKey returns the master key set with FRAGMENTA_MASTER_KEY or FRAGMENTA_MASTER_KEY_FILE,
+// or nil if no master key is set.
+func MasterKey() ([]byte, error) {
+	k := os.Getenv(MasterKeyEnv)
+	if p := os.Getenv(MasterKeyEnv + "_FILE"); k == "" && p != "" {
+		var err error
+		k, err = readSecretFile(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if k == "" {
+		return nil, nil
+	}
+
+	key, err := hex.DecodeString(k)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("config: invalid %s, it should be 32 bytes encoded as hex", MasterKeyEnv)
+	}
+	return key, nil
+}
+
+// EncryptSecrets moves the values of secret keys into the encrypted secrets section of values,
+// merging them with any secrets already encrypted there.
+func EncryptSecrets(values map[string]string, key []byte) error {
+	secrets, err := decryptSection(values, key)
+	if err != nil {
+		return err
+	}
+
+	for _, k := range SecretKeys() {
+		if v, ok := values[k]; ok {
+			secrets[k] = v
+			delete(values, k)
+		}
+	}
+
+	if len(secrets) == 0 {
+		delete(values, SecretsKey)
+		return nil
+	}
+
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	values[SecretsKey], err = encrypt(data, key)
+	return err
+}
+
+// DecryptSecrets returns a copy of values with the encrypted secrets section (if any)
+// decrypted with key and merged over the plain values.
+func DecryptSecrets(values map[string]string, key []byte) (map[string]string, error) {
+	secrets, err := decryptSection(values, key)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]string)
+	for k, v := range values {
+		if k != SecretsKey {
+			merged[k] = v
+		}
+	}
+	for k, v := range secrets {
+		merged[k] = v
+	}
+
+	return merged, nil
+}
+
+// decryptSection returns the secrets in the encrypted section of values,
+// or an empty map if there is no encrypted section.
+func decryptSection(values map[string]string, key []byte) (map[string]string, error) {
+	secrets := make(map[string]string)
+	if values[SecretsKey] == "" {
+		return secrets, nil
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("config: secrets are encrypted, set %s to unlock them", MasterKeyEnv)
+	}
+
+	data, err := decrypt(values[SecretsKey], key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &secrets)
+	if err != nil {
+		return nil, fmt.Errorf("config: error reading secrets %s", err)
+	}
+
+	return secrets, nil
+}
+
+// encrypt encrypts data with key using AES-GCM, returning the nonce and ciphertext encoded as base64.
+func encrypt(data, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, data, nil)), nil
+}
+
+// decrypt decrypts the base64 encoded nonce and ciphertext given with key.
+func decrypt(s string, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return nil, errors.New("config: invalid encrypted secrets")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("config: unable to decrypt secrets, check the master key")
+	}
+
+	return plain, nil
+}
+
+// newGCM returns an AES-GCM cipher for key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readSecretFile reads a secret from the file at path, ignoring trailing whitespace.
+func readSecretFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("config: error reading secret file %s", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,494 @@
+// Package settings provides typed config for the cms, loaded for the current
+// environment from secrets/fragmenta.json and overridden by FRAGMENTA_* environment variables.
+package settings
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environments the server may run in.
+const (
+	Production  = "production"
+	Development = "development"
+	Test        = "test"
+)
+
+// EnvPrefix is the prefix for environment variables overriding config keys,
+// for example FRAGMENTA_DB_PASS overrides db_pass.
+const EnvPrefix = "FRAGMENTA_"
+
+// Redacted replaces the values of secrets in Dump.
+const Redacted = "[redacted]"
+
+// Current holds the settings for the running server, and should be set on startup.
+var Current = &Settings{Env: Development}
+
+// Settings holds the config for one environment. Fields are set from the config key
+// in their config tag, with a default if the key is missing. Secret fields are
+// redacted in Dump, and required fields are checked by Validate.
+type Settings struct {
+	Env        string
+	Port       int    `config:"port" required:"true"`
+	Log        string `config:"log" required:"true"`
+	Path       string `config:"path"`
+	RootURL    string `config:"root_url"`
+	Theme      string `config:"theme"`
+	Proxies    string `config:"trusted_proxies"`
+	Meta       Meta
+	DB         Database
+	Mail       Mail
+	Auth       Auth
+	Assets     Assets
+	Uploads    Uploads
+	Cache      Cache
+	Autocert   Autocert
+	Comments   Comments
+	Register   Register
+	Restricted Restricted
+	NotFound   NotFound
+	Sanitize   Sanitize
+}
+
+// Meta holds the default metadata for pages.
+type Meta struct {
+	Title    string `config:"meta_title"`
+	Desc     string `config:"meta_desc"`
+	Keywords string `config:"meta_keywords"`
+}
+
+// Database holds the database connection settings.
+type Database struct {
+	Adapter  string `config:"db_adapter" default:"postgres"`
+	Name     string `config:"db" required:"true"`
+	User     string `config:"db_user"`
+	Password string `config:"db_pass" secret:"true"`
+	Host     string `config:"db_host"`
+	Port     string `config:"db_port"`
+	Params   string `config:"db_params"`
+}
+
+// Options returns the options for opening the database with query,
+// the host, port and params are optional to support remote databases.
+func (d Database) Options() map[string]string {
+	options := map[string]string{
+		"adapter":  d.Adapter,
+		"user":     d.User,
+		"password": d.Password,
+		"db":       d.Name,
+	}
+	if d.Host != "" {
+		options["host"] = d.Host
+	}
+	if d.Port != "" {
+		options["port"] = d.Port
+	}
+	if d.Params != "" {
+		options["params"] = d.Params
+	}
+	return options
+}
+
+// Mail holds the settings for sending mail with the adapter chosen.
+type Mail struct {
+	Adapter  string `config:"mail_adapter"`
+	From     string `config:"mail_from"`
+	Secret   string `config:"mail_secret" secret:"true"`
+	Host     string `config:"mail_host"`
+	Port     string `config:"mail_port" default:"587"`
+	User     string `config:"mail_user"`
+	Password string `config:"mail_pass" secret:"true"`
+	Security string `config:"mail_security" default:"starttls"`
+	Path     string `config:"mail_path" default:"log/mail"`
+
+	// Queue mail in the database to be sent by a background worker, rather than during requests
+	Queue bool `config:"mail_queue" default:"yes"`
+}
+
+// Auth holds the keys used for sessions and authenticity tokens.
+type Auth struct {
+	HMACKey     string `config:"hmac_key" secret:"true" required:"true"`
+	SecretKey   string `config:"secret_key" secret:"true" required:"true"`
+	SessionName string `config:"session_name" required:"true"`
+
+	// Previous keys are accepted for sessions during the grace period (in hours) after rotation
+	PreviousHMACKey   string `config:"hmac_key_previous" secret:"true"`
+	PreviousSecretKey string `config:"secret_key_previous" secret:"true"`
+	RotatedAt         string `config:"keys_rotated_at"`
+	GracePeriod       int    `config:"keys_grace_period" default:"168"`
+}
+
+// PreviousExpires returns the time at which the previous keys expire,
+// or the zero time if the keys have not been rotated.
+func (a Auth) PreviousExpires() time.Time {
+	rotated, err := time.Parse(time.RFC3339, a.RotatedAt)
+	if err != nil || a.PreviousHMACKey == "" {
+		return time.Time{}
+	}
+	return rotated.Add(time.Duration(a.GracePeriod) * time.Hour)
+}
+
+// Assets holds the settings for serving assets.
+type Assets struct {
+	Compiled bool `config:"assets_compiled"`
+}
+
+// Uploads holds the settings for uploaded files.
+type Uploads struct {
+	Path    string `config:"uploads_path" default:"public/files"`
+	MaxSize int64  `config:"uploads_max_size" default:"20971520"`
+	// URL is the url at which files in Path are served
+	URL string `config:"uploads_url" default:"/files"`
+	// ImageWidths are the widths of the smaller copies of uploaded images made for srcset
+	ImageWidths string `config:"uploads_image_widths" default:"480,960,1600"`
+}
+
+// Cache holds the settings for caching by clients.
+type Cache struct {
+	MaxAge int `config:"cache_max_age" default:"3456000"`
+}
+
+// Autocert holds the settings for certificates from letsencrypt, used in production on port 443.
+type Autocert struct {
+	Email   string `config:"autocert_email"`
+	Domains string `config:"autocert_domains"`
+}
+
+// Comments holds the settings for comments on blog posts.
+type Comments struct {
+	// Allow visitors who are not logged in to comment, with their name and email
+	Anon bool `config:"comments_anon"`
+	// Hold comments by readers for approval by an editor before they are shown
+	Moderate bool `config:"comments_moderate" default:"yes"`
+}
+
+// Register holds the settings for readers signing up themselves.
+type Register struct {
+	// Allow visitors to register as readers at /users/register
+	Open bool `config:"registration"`
+	// Hold new readers for approval by an admin once they have verified their email
+	Approve bool `config:"registration_approve"`
+}
+
+// Restricted holds the settings for pages and posts visible only to some users.
+type Restricted struct {
+	// Show others the name and summary with a prompt to log in, rather than an error
+	Teaser  bool   `config:"restricted_teaser" default:"yes"`
+	Message string `config:"restricted_message" default:"This content is only available to members."`
+}
+
+// NotFound holds the settings for recording requests for urls which were not found.
+type NotFound struct {
+	// Paths which are not recorded, separated by commas, in which * matches any text
+	Ignore string `config:"notfound_ignore" default:"/wp-*,/wordpress*,/xmlrpc.php,/.env*,/.git*,/cgi-bin/*,*phpmyadmin*,/apple-touch-icon*"`
+}
+
+// Sanitize holds the tags, attributes and url schemes allowed in the html of pages and posts.
+type Sanitize struct {
+	// Tags allowed for all editors, as a list such as p, a[href title], in which *[class] allows attributes on any tag
+	Tags string `config:"sanitize_tags" default:"*[class title], a[href rel target], abbr, b, blockquote[cite], br, caption, cite, code, dd, del, div, dl, dt, em, figcaption, figure, h1, h2, h3, h4, h5, h6, hr, i, img[src alt width height srcset sizes loading], ins, li, ol[start], p, pre, q[cite], s, small, span, strong, sub, sup, table, tbody, td[colspan rowspan], tfoot, th[colspan rowspan scope], thead, tr, u, ul"`
+	// Tags allowed for admins in addition to the tags above
+	AdminTags string `config:"sanitize_admin_tags" default:"iframe[src width height allow allowfullscreen frameborder title]"`
+	// Schemes allowed in urls such as href and src, urls without a scheme are always allowed
+	Schemes string `config:"sanitize_schemes" default:"http,https,mailto,tel"`
+}
+
+// Env returns the environment to run in, set with FRAGMENTA_ENV (or FRAG_ENV),
+// which defaults to development.
+func Env() string {
+	env := os.Getenv(EnvPrefix + "ENV")
+	if env == "" {
+		env = os.Getenv("FRAG_ENV")
+	}
+	switch env {
+	case Production, Test:
+		return env
+	}
+	return Development
+}
+
+// Load reads the settings for env from the json config file at path,
+// which holds a map of config keys and values for each environment.
+func Load(path, env string) (*Settings, error) {
+	configs, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := configs[env]
+	if !ok {
+		return nil, fmt.Errorf("config: no %s config in %s", env, path)
+	}
+
+	return New(env, values)
+}
+
+// New returns the settings for env from the config values given, using the
+// default for missing keys. The value for a key such as db_pass is read from the
+// FRAGMENTA_DB_PASS environment variable, then the file named by FRAGMENTA_DB_PASS_FILE,
+// then the encrypted secrets section or db_pass in values, then the file named by db_pass_file.
+func New(env string, values map[string]string) (*Settings, error) {
+	s := &Settings{Env: env}
+
+	// Only require the master key if we have encrypted secrets
+	var key []byte
+	if values[SecretsKey] != "" {
+		var err error
+		key, err = MasterKey()
+		if err != nil {
+			return nil, err
+		}
+	}
+	values, err := DecryptSecrets(values, key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range s.fields() {
+		v, err := f.lookup(values)
+		if err != nil {
+			return nil, err
+		}
+		err = f.set(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// EnvKey returns the environment variable which overrides the config key.
+func EnvKey(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// Production returns true if these are settings for production.
+func (s *Settings) Production() bool {
+	return s.Env == Production
+}
+
+// ProxyNetworks returns the networks in proxies, a list of ip addresses and ranges
+// separated by commas. Entries which are not valid are returned as nil.
+func ProxyNetworks(proxies string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, p := range strings.Split(proxies, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !strings.Contains(p, "/") {
+			if ip := net.ParseIP(p); ip != nil && ip.To4() != nil {
+				p += "/32"
+			} else {
+				p += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(p)
+		if err != nil {
+			network = nil
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// Validate returns an error describing any problems with the settings.
+func (s *Settings) Validate() error {
+	problems := s.Problems()
+	if len(problems) > 0 {
+		return fmt.Errorf("config: invalid %s config:\n  %s", s.Env, strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Problems returns a description of each problem with the settings,
+// for example missing secrets or keys of the wrong length.
+func (s *Settings) Problems() []string {
+	var problems []string
+
+	for _, f := range s.fields() {
+		if f.required && f.String() == "" {
+			problems = append(problems, fmt.Sprintf("missing %s, set it in the config file or with %s", f.key, EnvKey(f.key)))
+		}
+	}
+
+	// Keys should be 32 bytes encoded as hex (as generated on bootstrap)
+	keys := map[string]string{
+		"hmac_key":            s.Auth.HMACKey,
+		"secret_key":          s.Auth.SecretKey,
+		"hmac_key_previous":   s.Auth.PreviousHMACKey,
+		"secret_key_previous": s.Auth.PreviousSecretKey,
+	}
+	for _, k := range []string{"hmac_key", "secret_key", "hmac_key_previous", "secret_key_previous"} {
+		if keys[k] == "" {
+			continue
+		}
+		b, err := hex.DecodeString(keys[k])
+		if err != nil || len(b) != 32 {
+			problems = append(problems, fmt.Sprintf("invalid %s, it should be 32 bytes encoded as hex", k))
+		}
+	}
+
+	if s.Auth.RotatedAt != "" {
+		_, err := time.Parse(time.RFC3339, s.Auth.RotatedAt)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("invalid keys_rotated_at %s, it should be a time in RFC3339 format", s.Auth.RotatedAt))
+		}
+	}
+
+	for _, p := range ProxyNetworks(s.Proxies) {
+		if p == nil {
+			problems = append(problems, fmt.Sprintf("invalid trusted_proxies %s, it should list ip addresses or ranges such as 10.0.0.0/8", s.Proxies))
+			break
+		}
+	}
+
+	switch s.Mail.Adapter {
+	case "", "sendgrid", "maildir", "memory":
+	case "smtp":
+		if s.Mail.Host == "" {
+			problems = append(problems, fmt.Sprintf("missing mail_host for smtp, set it in the config file or with %s", EnvKey("mail_host")))
+		}
+		switch s.Mail.Security {
+		case "starttls", "tls", "none":
+		default:
+			problems = append(problems, fmt.Sprintf("invalid mail_security %s, it should be starttls, tls or none", s.Mail.Security))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("invalid mail_adapter %s, it should be sendgrid, smtp, maildir or memory", s.Mail.Adapter))
+	}
+
+	switch s.DB.Adapter {
+	case "postgres":
+		if s.DB.User == "" {
+			problems = append(problems, fmt.Sprintf("missing db_user for postgres, set it in the config file or with %s", EnvKey("db_user")))
+		}
+	case "sqlite3":
+	default:
+		problems = append(problems, fmt.Sprintf("invalid db_adapter %s, it should be postgres or sqlite3", s.DB.Adapter))
+	}
+
+	return problems
+}
+
+// Dump returns the config keys and values of the settings one per line,
+// with the values of secrets redacted, for debugging.
+func (s *Settings) Dump() string {
+	dump := fmt.Sprintf("env = %s\n", s.Env)
+	for _, f := range s.fields() {
+		v := f.String()
+		if f.secret && v != "" {
+			v = Redacted
+		}
+		dump += fmt.Sprintf("%s = %s\n", f.key, v)
+	}
+	return dump
+}
+
+// field is a settings field set from a config key.
+type field struct {
+	key      string
+	def      string
+	secret   bool
+	required bool
+	value    reflect.Value
+}
+
+// fields returns the fields of s set from config keys, in the order they are declared.
+func (s *Settings) fields() []field {
+	return structFields(reflect.ValueOf(s).Elem())
+}
+
+// structFields returns the fields of the struct v with config tags, including those of nested structs.
+func structFields(v reflect.Value) []field {
+	var fields []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, structFields(v.Field(i))...)
+			continue
+		}
+
+		key := sf.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+
+		fields = append(fields, field{
+			key:      key,
+			def:      sf.Tag.Get("default"),
+			secret:   sf.Tag.Get("secret") == "true",
+			required: sf.Tag.Get("required") == "true",
+			value:    v.Field(i),
+		})
+	}
+	return fields
+}
+
+// lookup returns the value for the field from values, files or environment variables.
+func (f field) lookup(values map[string]string) (string, error) {
+	if v, ok := os.LookupEnv(EnvKey(f.key)); ok {
+		return v, nil
+	}
+	if p := os.Getenv(EnvKey(f.key) + "_FILE"); p != "" {
+		return readSecretFile(p)
+	}
+	if v, ok := values[f.key]; ok {
+		return v, nil
+	}
+	if p := values[f.key+"_file"]; p != "" {
+		return readSecretFile(p)
+	}
+	return f.def, nil
+}
+
+// set parses the string value given into the field.
+func (f field) set(s string) error {
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+	case reflect.Int, reflect.Int64:
+		if s == "" {
+			f.value.SetInt(0)
+			return nil
+		}
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("config: invalid %s, it should be a number: %s", f.key, s)
+		}
+		f.value.SetInt(i)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "yes", "true", "1", "on":
+			f.value.SetBool(true)
+		default:
+			f.value.SetBool(false)
+		}
+	}
+	return nil
+}
+
+// String returns the value of the field as a string, ints of zero are treated as empty.
+func (f field) String() string {
+	switch f.value.Kind() {
+	case reflect.Int, reflect.Int64:
+		if f.value.Int() == 0 {
+			return ""
+		}
+		return strconv.FormatInt(f.value.Int(), 10)
+	case reflect.Bool:
+		if f.value.Bool() {
+			return "yes"
+		}
+		return "no"
+	}
+	return f.value.String()
+}
@@ -0,0 +1,140 @@
+package settings
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testValues holds valid config values for testing.
+var testValues = map[string]string{
+	"port":            "3000",
+	"log":             "log/test.log",
+	"db":              "fragmenta_cms_test",
+	"db_user":         "fragmenta_cms_server",
+	"db_pass":         "password",
+	"hmac_key":        strings.Repeat("ab", 32),
+	"secret_key":      strings.Repeat("cd", 32),
+	"session_name":    "fragmenta_cms",
+	"assets_compiled": "yes",
+}
+
+// TestNew tests settings are set from values, defaults and environment variables.
+func TestNew(t *testing.T) {
+	os.Setenv("FRAGMENTA_DB_PASS", "override")
+	defer os.Unsetenv("FRAGMENTA_DB_PASS")
+
+	s, err := New(Test, testValues)
+	if err != nil {
+		t.Fatalf("settings: error creating settings %s", err)
+	}
+
+	if s.Port != 3000 || s.DB.Name != "fragmenta_cms_test" || !s.Assets.Compiled {
+		t.Fatalf("settings: unexpected values %v", s)
+	}
+
+	if s.DB.Adapter != "postgres" || s.Uploads.Path != "public/files" {
+		t.Fatalf("settings: defaults not set %v", s)
+	}
+
+	if s.DB.Password != "override" {
+		t.Fatalf("settings: env override not set expected:override got:%s", s.DB.Password)
+	}
+
+	_, err = New(Test, map[string]string{"port": "foo"})
+	if err == nil {
+		t.Fatalf("settings: no error for invalid port")
+	}
+}
+
+// TestValidate tests validation of required keys and secrets.
+func TestValidate(t *testing.T) {
+	s, err := New(Test, testValues)
+	if err != nil || s.Validate() != nil {
+		t.Fatalf("settings: unexpected error for valid settings %v %v", err, s.Validate())
+	}
+
+	s.Auth.HMACKey = ""
+	s.Auth.SecretKey = "abcd"
+	err = s.Validate()
+	if err == nil {
+		t.Fatalf("settings: no error for invalid settings")
+	}
+	if !strings.Contains(err.Error(), "missing hmac_key") || !strings.Contains(err.Error(), "invalid secret_key") {
+		t.Fatalf("settings: unexpected error for invalid settings %s", err)
+	}
+
+	s.Proxies = "10.0.0.0/8, 192.0.2.1, ::1"
+	networks := ProxyNetworks(s.Proxies)
+	if len(networks) != 3 || networks[1] == nil || networks[1].String() != "192.0.2.1/32" || networks[2].String() != "::1/128" {
+		t.Fatalf("settings: unexpected proxy networks %v", networks)
+	}
+
+	s.Proxies = "10.0.0.0/8, proxy"
+	if !strings.Contains(s.Validate().Error(), "invalid trusted_proxies") {
+		t.Fatalf("settings: no error for invalid trusted_proxies")
+	}
+}
+
+// TestDump tests secrets are redacted in Dump.
+func TestDump(t *testing.T) {
+	s, err := New(Test, testValues)
+	if err != nil {
+		t.Fatalf("settings: error creating settings %s", err)
+	}
+
+	dump := s.Dump()
+	if strings.Contains(dump, testValues["hmac_key"]) || strings.Contains(dump, testValues["db_pass"]) {
+		t.Fatalf("settings: secrets not redacted in dump %s", dump)
+	}
+	if !strings.Contains(dump, "hmac_key = "+Redacted) || !strings.Contains(dump, "db = fragmenta_cms_test") {
+		t.Fatalf("settings: unexpected dump %s", dump)
+	}
+}
+
+// TestSecrets tests secrets are read from files and the encrypted section.
+func TestSecrets(t *testing.T) {
+	values := make(map[string]string)
+	for k, v := range testValues {
+		values[k] = v
+	}
+
+	// Read the db password from a file
+	f, err := ioutil.TempFile("", "fragmenta-secret")
+	if err != nil {
+		t.Fatalf("settings: error creating secret file %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("filepass\n")
+	f.Close()
+	delete(values, "db_pass")
+	values["db_pass_file"] = f.Name()
+
+	// Encrypt the keys with a master key
+	masterKey := strings.Repeat("ef", 32)
+	err = EncryptSecrets(values, []byte(strings.Repeat("\xef", 32)))
+	if err != nil {
+		t.Fatalf("settings: error encrypting secrets %s", err)
+	}
+	if values["hmac_key"] != "" || values[SecretsKey] == "" {
+		t.Fatalf("settings: secrets not encrypted %v", values)
+	}
+
+	// Without the master key settings should not load
+	_, err = New(Test, values)
+	if err == nil {
+		t.Fatalf("settings: no error for encrypted secrets without master key")
+	}
+
+	os.Setenv(MasterKeyEnv, masterKey)
+	defer os.Unsetenv(MasterKeyEnv)
+
+	s, err := New(Test, values)
+	if err != nil {
+		t.Fatalf("settings: error loading encrypted secrets %s", err)
+	}
+	if s.Auth.HMACKey != testValues["hmac_key"] || s.DB.Password != "filepass" {
+		t.Fatalf("settings: unexpected secrets %s %s", s.Auth.HMACKey, s.DB.Password)
+	}
+}
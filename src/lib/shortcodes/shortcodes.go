@@ -0,0 +1,128 @@
+// Package shortcodes renders shortcodes such as [[youtube id="M7lc1UVf-VE"]]
+// in content when it is shown, using handlers registered by the app which
+// have access to the request and the current user.
+package shortcodes
+
+import (
+	"html"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/fragmenta/server/log"
+)
+
+// User is the interface for the user viewing content with shortcodes.
+type User interface {
+	Anon() bool
+	Admin() bool
+	RoleID() int64
+}
+
+// Shortcode is a shortcode found in content, passed to the handler for its name.
+type Shortcode struct {
+	Name       string
+	Attributes map[string]string
+	Request    *http.Request
+	User       User
+}
+
+// Attr returns the value of the attribute name, or an empty string if it is missing.
+func (s *Shortcode) Attr(name string) string {
+	return s.Attributes[name]
+}
+
+// AttrInt returns the value of the attribute name as an int, or fallback if it is missing or invalid.
+func (s *Shortcode) AttrInt(name string, fallback int64) int64 {
+	i, err := strconv.ParseInt(s.Attr(name), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return i
+}
+
+// Handler returns the html for a shortcode.
+type Handler func(s *Shortcode) (template.HTML, error)
+
+var (
+	mu       sync.RWMutex
+	handlers = make(map[string]Handler)
+)
+
+// Register sets the handler for shortcodes with name, replacing any handler already registered.
+func Register(name string, h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[name] = h
+}
+
+// handler returns the handler registered for name, or nil if there is none.
+func handler(name string) Handler {
+	mu.RLock()
+	defer mu.RUnlock()
+	return handlers[name]
+}
+
+// shortcodeRegexp matches shortcodes such as [[name key="value"]], along with a
+// paragraph wrapped around them by the editor or markdown so that it can be removed.
+var shortcodeRegexp = regexp.MustCompile(`(<p>\s*)?\[\[([a-z][a-z0-9_-]*)((?:\s[^\[\]]*)?)\]\](\s*</p>)?`)
+
+// attributeRegexp matches attributes within a shortcode, with values quoted or not.
+var attributeRegexp = regexp.MustCompile(`([a-z][a-z0-9_-]*)=(?:"([^"]*)"|'([^']*)'|([^\s"']+))`)
+
+// Parse returns the shortcode for the text of a shortcode such as [[name key="value"]],
+// or nil if it is not one. Quotes in the text may be escaped if it has been sanitized.
+func Parse(code string) *Shortcode {
+	m := shortcodeRegexp.FindStringSubmatch(code)
+	if m == nil {
+		return nil
+	}
+
+	s := &Shortcode{Name: m[2], Attributes: make(map[string]string)}
+	for _, a := range attributeRegexp.FindAllStringSubmatch(html.UnescapeString(m[3]), -1) {
+		s.Attributes[a[1]] = a[2] + a[3] + a[4]
+	}
+	return s
+}
+
+// Contains returns true if the content contains shortcodes with a handler registered.
+func Contains(content string) bool {
+	for _, m := range shortcodeRegexp.FindAllStringSubmatch(content, -1) {
+		if handler(m[2]) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Render replaces the shortcodes in content with the html returned by their handlers
+// for the request and user given. Shortcodes without a handler are left as they are,
+// those with handlers which fail are removed.
+func Render(content template.HTML, r *http.Request, u User) template.HTML {
+	out := shortcodeRegexp.ReplaceAllStringFunc(string(content), func(code string) string {
+		m := shortcodeRegexp.FindStringSubmatch(code)
+		h := handler(m[2])
+		if h == nil {
+			return code
+		}
+
+		s := Parse(code)
+		s.Request = r
+		s.User = u
+		result, err := h(s)
+		if err != nil {
+			log.Error(log.V{"msg": "unable to render shortcode", "shortcode": s.Name, "error": err})
+			result = ""
+		}
+
+		// Keep the paragraph around the shortcode unless it contained only the shortcode
+		if m[1] != "" && m[4] != "" {
+			return string(result)
+		}
+		return m[1] + string(result) + m[4]
+	})
+
+	return template.HTML(out)
+}
@@ -0,0 +1,74 @@
+package shortcodes
+
+import (
+	"errors"
+	"html/template"
+	"net/http/httptest"
+	"testing"
+)
+
+// testUser is a user viewing content in tests.
+type testUser struct {
+	admin bool
+}
+
+func (u testUser) Anon() bool    { return false }
+func (u testUser) Admin() bool   { return u.admin }
+func (u testUser) RoleID() int64 { return 0 }
+
+// renders maps content to the html expected with the test shortcodes registered.
+var renders = map[string]string{
+	`<p>[[greet name="Ann"]]</p>`:                 `<b>Hello Ann</b>`,
+	`<p>Say [[greet name=&#34;Bob&#34;]] now</p>`: `<p>Say <b>Hello Bob</b> now</p>`,
+	`[[greet name=&quot;&lt;i&gt;&quot;]]`:        `<b>Hello &lt;i&gt;</b>`,
+	`[[greet name='Cy' extra]] and [[greet]]`:     `<b>Hello Cy</b> and <b>Hello </b>`,
+	`[[admin]]`: `admin`,
+	`<p>[[broken]]</p><p>[[unknown id="1"]]</p>`:   `<p>[[unknown id="1"]]</p>`,
+	`[[Greet]] [[ greet]] [link] [[greet name=x]]`: `[[Greet]] [[ greet]] [link] <b>Hello x</b>`,
+}
+
+// TestRender tests shortcodes are replaced by the html from their handlers.
+func TestRender(t *testing.T) {
+	Register("greet", func(s *Shortcode) (template.HTML, error) {
+		return template.HTML("<b>Hello " + template.HTMLEscapeString(s.Attr("name")) + "</b>"), nil
+	})
+	Register("admin", func(s *Shortcode) (template.HTML, error) {
+		if s.Request == nil || !s.User.Admin() {
+			return "", nil
+		}
+		return "admin", nil
+	})
+	Register("broken", func(s *Shortcode) (template.HTML, error) {
+		return "", errors.New("broken")
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	for in, expected := range renders {
+		got := Render(template.HTML(in), r, testUser{admin: true})
+		if string(got) != expected {
+			t.Errorf("shortcodes: unexpected html for %q\nexpected:%q\ngot:     %q", in, expected, got)
+		}
+	}
+
+	if got := Render("[[admin]]", r, testUser{}); got != "" {
+		t.Errorf("shortcodes: unexpected html for user got:%q", got)
+	}
+
+	if !Contains(`<p>[[greet]]</p>`) || Contains(`<p>[[unknown]]</p>`) {
+		t.Errorf("shortcodes: Contains failed")
+	}
+}
+
+// TestYouTube tests videos are embedded only for valid ids.
+func TestYouTube(t *testing.T) {
+	html, err := YouTube(Parse(`[[youtube id="M7lc1UVf-VE" start="30" title="A <b>video</b>"]]`))
+	expected := `<div class="video"><iframe src="https://www.youtube-nocookie.com/embed/M7lc1UVf-VE?start=30" title="A &lt;b&gt;video&lt;/b&gt;" width="560" height="315" allow="accelerometer; encrypted-media; gyroscope; picture-in-picture" allowfullscreen loading="lazy"></iframe></div>`
+	if err != nil || string(html) != expected {
+		t.Fatalf("shortcodes: unexpected html for youtube\nexpected:%s\ngot:     %s", expected, html)
+	}
+
+	html, err = YouTube(Parse(`[[youtube id="x&quot; onload=&quot;alert(1)"]]`))
+	if err != nil || html != "" {
+		t.Fatalf("shortcodes: unexpected html for invalid youtube id got:%s", html)
+	}
+}
@@ -0,0 +1,33 @@
+package shortcodes
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+)
+
+// youtubeRegexp matches valid YouTube video ids.
+var youtubeRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// YouTube returns the html to embed a YouTube video for shortcodes such as
+// [[youtube id="M7lc1UVf-VE" title="Video" start="30"]], using the privacy enhanced
+// domain. Shortcodes with an invalid id are removed.
+func YouTube(s *Shortcode) (template.HTML, error) {
+	id := s.Attr("id")
+	if !youtubeRegexp.MatchString(id) {
+		return "", nil
+	}
+
+	src := "https://www.youtube-nocookie.com/embed/" + id
+	if start := s.AttrInt("start", 0); start > 0 {
+		src += fmt.Sprintf("?start=%d", start)
+	}
+
+	title := s.Attr("title")
+	if title == "" {
+		title = "YouTube video"
+	}
+
+	html := fmt.Sprintf(`<div class="video"><iframe src="%s" title="%s" width="560" height="315" allow="accelerometer; encrypted-media; gyroscope; picture-in-picture" allowfullscreen loading="lazy"></iframe></div>`, src, template.HTMLEscapeString(title))
+	return template.HTML(html), nil
+}
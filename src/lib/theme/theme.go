@@ -0,0 +1,224 @@
+// Package theme finds the templates available for showing resources such as pages
+// and posts, in src and in the active theme, along with their front-matter.
+package theme
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/fields"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// DefaultName is the file name of the default template in each directory.
+const DefaultName = "default.html.got"
+
+// Template describes a template found in a templates directory, with metadata
+// from its optional front-matter, a template comment at the start of the file:
+//
+//	{{/*
+//	name: Landing
+//	description: A wide page with a large image
+//	field: Hero Image | image | required
+//	field: Features | group | Title, Text: richtext
+//	*/}}
+//
+// Each field line declares a custom field, see lib/fields for the definition.
+type Template struct {
+	// Path is the path of the template used for rendering, for example pages/views/templates/default.html.got
+	Path string
+
+	Name        string
+	Description string
+
+	// Fields are the custom fields used by the template
+	Fields []fields.Field
+}
+
+// Path returns the path of the src directory of the active theme, or an empty string if no theme is set.
+func Path() string {
+	if settings.Current.Theme == "" {
+		return ""
+	}
+	return filepath.Join("themes", settings.Current.Theme, "src")
+}
+
+// roots returns the directories templates are loaded from, in order, templates
+// in the theme replace those with the same path in src.
+func roots() []string {
+	roots := []string{"src"}
+	if Path() != "" {
+		roots = append(roots, Path())
+	}
+	return roots
+}
+
+// index holds the templates found in each directory, so that they are
+// read once rather than each time a page or post is shown.
+var index = struct {
+	sync.RWMutex
+	templates map[string][]Template
+}{templates: make(map[string][]Template)}
+
+// Load finds the templates in each of dirs, replacing any found before. It is
+// called when the app loads its views, as templates only change on restart.
+// Other directories are loaded when first used.
+func Load(dirs ...string) error {
+	templates := make(map[string][]Template)
+	for _, dir := range dirs {
+		found, err := find(dir)
+		if err != nil {
+			return err
+		}
+		templates[dir] = found
+	}
+
+	index.Lock()
+	defer index.Unlock()
+	index.templates = templates
+	return nil
+}
+
+// Templates returns the templates found in dir, for example pages/views/templates,
+// with the default template first followed by the others sorted by name.
+func Templates(dir string) ([]Template, error) {
+	index.RLock()
+	templates, ok := index.templates[dir]
+	index.RUnlock()
+
+	if !ok {
+		var err error
+		templates, err = find(dir)
+		if err != nil {
+			return nil, err
+		}
+		index.Lock()
+		index.templates[dir] = templates
+		index.Unlock()
+	}
+
+	return append([]Template(nil), templates...), nil
+}
+
+// Find returns the template with path in dir, or an error if it is not found.
+func Find(dir, path string) (Template, error) {
+	templates, err := Templates(dir)
+	if err != nil {
+		return Template{}, err
+	}
+	for _, t := range templates {
+		if t.Path == path {
+			return t, nil
+		}
+	}
+	return Template{}, fmt.Errorf("theme: template %s not found in %s", path, dir)
+}
+
+// Exists returns true if the template with path exists in dir.
+func Exists(dir, path string) bool {
+	if filepath.Dir(path) != dir || !strings.HasSuffix(path, ".html.got") {
+		return false
+	}
+	_, err := Find(dir, path)
+	return err == nil
+}
+
+// find reads the templates in dir from src and the theme, sorted as for Templates.
+func find(dir string) ([]Template, error) {
+	found := make(map[string]Template)
+	for _, root := range roots() {
+		files, err := filepath.Glob(filepath.Join(root, dir, "*.html.got"))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			t, err := load(f)
+			if err != nil {
+				return nil, err
+			}
+			t.Path = filepath.ToSlash(filepath.Join(dir, filepath.Base(f)))
+			found[t.Path] = t
+		}
+	}
+
+	var templates []Template
+	for _, t := range found {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if isDefault(templates[i]) != isDefault(templates[j]) {
+			return isDefault(templates[i])
+		}
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates, nil
+}
+
+// isDefault returns true if t is the default template in its directory.
+func isDefault(t Template) bool {
+	return filepath.Base(t.Path) == DefaultName
+}
+
+// load reads the front-matter of the template file at path. The name defaults
+// to the file name, for example Landing Page for landing_page.html.got.
+func load(path string) (Template, error) {
+	t := Template{Name: nameFromFile(path)}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return t, err
+	}
+	defer file.Close()
+
+	var definitions []string
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "{{/*" {
+		return t, scanner.Err()
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "*/}}" {
+			break
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "name":
+			t.Name = value
+		case "description":
+			t.Description = value
+		case "field":
+			definitions = append(definitions, value)
+		}
+	}
+	if scanner.Err() != nil {
+		return t, scanner.Err()
+	}
+
+	t.Fields, err = fields.ParseAll(definitions)
+	if err != nil {
+		return t, fmt.Errorf("theme: invalid field in %s %s", path, err)
+	}
+
+	return t, nil
+}
+
+// nameFromFile returns a display name for the template file at path.
+func nameFromFile(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), ".html.got")
+	words := strings.Fields(strings.NewReplacer("_", " ", "-", " ").Replace(name))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,91 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+var testFiles = map[string]string{
+	"src/pages/views/templates/default.html.got":   "<p>Default</p>",
+	"src/pages/views/templates/wide_page.html.got": "<p>Wide</p>",
+	"themes/test/src/pages/views/templates/landing.html.got": `{{/*
+name: A Landing
+description: A page with a large image
+field: Hero Image | image
+field: Subtitle | text | default: Welcome
+*/}}
+<p>Landing</p>`,
+	"themes/test/src/pages/views/templates/wide_page.html.got": "{{/*\nname: Wider\n*/}}",
+}
+
+// TestTemplates tests templates are found in src and the theme.
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	for p, content := range testFiles {
+		path := filepath.Join(dir, p)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(content), 0644)
+		}
+		if err != nil {
+			t.Fatalf("theme: error writing test files %s", err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("theme: error getting working dir %s", err)
+	}
+	defer os.Chdir(wd)
+	os.Chdir(dir)
+
+	settings.Current.Theme = "test"
+	defer func() {
+		settings.Current.Theme = ""
+		Load()
+	}()
+
+	err = Load("pages/views/templates")
+	if err != nil {
+		t.Fatalf("theme: error loading templates %s", err)
+	}
+
+	templates, err := Templates("pages/views/templates")
+	if err != nil || len(templates) != 3 {
+		t.Fatalf("theme: unexpected templates %v %s", templates, err)
+	}
+
+	if templates[0].Name != "Default" || templates[1].Name != "A Landing" || templates[2].Name != "Wider" {
+		t.Fatalf("theme: unexpected template order %v", templates)
+	}
+
+	landing := templates[1]
+	if landing.Path != "pages/views/templates/landing.html.got" || landing.Description != "A page with a large image" || len(landing.Fields) != 2 || landing.Fields[1].Name != "subtitle" {
+		t.Fatalf("theme: unexpected front-matter %v", landing)
+	}
+
+	if !Exists("pages/views/templates", "pages/views/templates/wide_page.html.got") || Exists("pages/views/templates", "pages/views/templates/missing.html.got") {
+		t.Fatalf("theme: unexpected result for Exists")
+	}
+
+	// Templates are kept once loaded, rather than read each time they are used
+	os.Remove("src/pages/views/templates/default.html.got")
+	if !Exists("pages/views/templates", "pages/views/templates/default.html.got") {
+		t.Fatalf("theme: templates read again after loading")
+	}
+	os.WriteFile("src/pages/views/templates/default.html.got", []byte(testFiles["src/pages/views/templates/default.html.got"]), 0644)
+
+	// Templates in the theme are not found without it
+	settings.Current.Theme = ""
+	err = Load("pages/views/templates")
+	if err != nil {
+		t.Fatalf("theme: error loading templates %s", err)
+	}
+	templates, err = Templates("pages/views/templates")
+	if err != nil || len(templates) != 2 || templates[1].Name != "Wide Page" {
+		t.Fatalf("theme: unexpected templates without theme %v %s", templates, err)
+	}
+}
@@ -0,0 +1,31 @@
+package visibility
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+// RenderRestricted responds to a request for a resource the user may not see,
+// with a teaser showing the name and summary and a prompt to log in if
+// restricted_teaser is set, or with an error if not.
+func RenderRestricted(w http.ResponseWriter, r *http.Request, u User, name, summary string) error {
+	if !settings.Current.Restricted.Teaser {
+		return server.NotAuthorizedError(errors.New("visibility: resource is restricted"))
+	}
+
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", u)
+	view.AddKey("name", name)
+	view.AddKey("summary", summary)
+	view.AddKey("message", settings.Current.Restricted.Message)
+	view.AddKey("registration", settings.Current.Register.Open)
+	view.AddKey("meta_title", name)
+	view.AddKey("meta_desc", summary)
+	view.Template("lib/visibility/views/restricted.html.got")
+	return view.Render()
+}
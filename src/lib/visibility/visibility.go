@@ -0,0 +1,125 @@
+// Package visibility restricts who may see resources such as pages and posts,
+// to everyone, logged in users, or users with selected roles.
+package visibility
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/view/helpers"
+)
+
+// Visibility values valid in the visibility field added with visibility.ResourceVisibility.
+const (
+	Public  = 0
+	Members = 1
+	Roles   = 2
+)
+
+// User is the interface for users who may see restricted resources.
+type User interface {
+	Anon() bool
+	Admin() bool
+	RoleID() int64
+}
+
+// ResourceVisibility adds visibility and visible roles fields to resources.
+type ResourceVisibility struct {
+	Visibility int64
+	// VisibleRoles is a comma separated list of role ids, used if Visibility is Roles
+	VisibleRoles string
+}
+
+// Options returns an array of visibilities for a visibility select.
+func Options() []helpers.Option {
+	var options []helpers.Option
+
+	options = append(options, helpers.Option{Id: Public, Name: "Public"})
+	options = append(options, helpers.Option{Id: Members, Name: "Logged in users"})
+	options = append(options, helpers.Option{Id: Roles, Name: "Selected roles"})
+
+	return options
+}
+
+// VisibilityOptions returns an array of visibilities for a visibility select for this resource.
+func (r *ResourceVisibility) VisibilityOptions() []helpers.Option {
+	return Options()
+}
+
+// VisibilityDisplay returns a string representation of the resource visibility.
+func (r *ResourceVisibility) VisibilityDisplay() string {
+	for _, o := range r.VisibilityOptions() {
+		if o.Id == r.Visibility {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// IsPublic returns true if this resource is visible to everyone.
+func (r *ResourceVisibility) IsPublic() bool {
+	return r.Visibility == Public
+}
+
+// RoleIDs returns the ids of the roles this resource is visible to.
+func (r *ResourceVisibility) RoleIDs() []int64 {
+	var ids []int64
+	for _, s := range strings.Split(r.VisibleRoles, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// HasRole returns true if role is one of the roles this resource is visible to.
+func (r *ResourceVisibility) HasRole(role int64) bool {
+	for _, id := range r.RoleIDs() {
+		if id == role {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleTo returns true if the user may see this resource, admins may see all resources.
+func (r *ResourceVisibility) VisibleTo(u User) bool {
+	switch {
+	case r.Visibility == Public || u.Admin():
+		return true
+	case u.Anon():
+		return false
+	case r.Visibility == Members:
+		return true
+	default:
+		return r.HasRole(u.RoleID())
+	}
+}
+
+// RolesParam returns the value of the visible_roles param for the role ids selected in a form.
+func RolesParam(values []string) string {
+	var ids []string
+	for _, v := range values {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			ids = append(ids, fmt.Sprintf("%d", id))
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+// WhereVisibleTo modifies the given query to select resources the user may see.
+func WhereVisibleTo(q *query.Query, u User) *query.Query {
+	switch {
+	case u.Admin():
+		return q
+	case u.Anon():
+		return q.Where("visibility=?", Public)
+	default:
+		role := fmt.Sprintf("%%,%d,%%", u.RoleID())
+		return q.Where("(visibility<=? OR (visibility=? AND ','||visible_roles||',' LIKE ?))", Members, Roles, role)
+	}
+}
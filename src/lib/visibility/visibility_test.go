@@ -0,0 +1,44 @@
+package visibility
+
+import (
+	"testing"
+)
+
+// resource embeds ResourceVisibility
+type resource struct {
+	ResourceVisibility
+}
+
+// user is a mock user with a role
+type user struct {
+	role  int64
+	admin bool
+}
+
+func (u *user) Anon() bool    { return u.role == 0 }
+func (u *user) Admin() bool   { return u.admin }
+func (u *user) RoleID() int64 { return u.role }
+
+// TestVisibleTo tests resources are visible to the users allowed.
+func TestVisibleTo(t *testing.T) {
+	anon := &user{}
+	reader := &user{role: 20}
+	editor := &user{role: 10}
+	admin := &user{role: 100, admin: true}
+
+	r := &resource{}
+	if !r.VisibleTo(anon) || r.VisibilityDisplay() != "Public" {
+		t.Fatalf("visibility: public resource not visible to anon")
+	}
+
+	r.Visibility = Members
+	if r.VisibleTo(anon) || !r.VisibleTo(reader) {
+		t.Fatalf("visibility: unexpected visibility for members")
+	}
+
+	r.Visibility = Roles
+	r.VisibleRoles = RolesParam([]string{"10", "x"})
+	if r.VisibleRoles != "10" || r.VisibleTo(reader) || !r.VisibleTo(editor) || !r.VisibleTo(admin) {
+		t.Fatalf("visibility: unexpected visibility for roles %s", r.VisibleRoles)
+	}
+}
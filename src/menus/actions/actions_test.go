@@ -0,0 +1,165 @@
+package menuactions_test
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/menus"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("menuactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("menuactions: error creating admin %s", err)
+	}
+}
+
+// Test GET /menus/create
+func TestShowCreateMenu(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/menus/create", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("menuactions: error handling HandleCreateShow %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := "resource-update-form"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("menuactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test POST /menus/create
+func TestCreateMenu(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", "Main")
+	form.Add("status", "100")
+	form.Add("location", menus.Primary)
+	form.Add("items", "Home | /\nAbout | about")
+
+	// Test an item with an invalid url is rejected
+	w, err := apptest.Request(router, "POST", "/menus/create", form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("menuactions: unexpected response for HandleCreate with invalid items %v %d", err, w.Code)
+	}
+
+	// Test creating the menu as anon
+	form.Set("items", "Home | /\nAbout | /about\n- Our Team | /about/team")
+	w, err = apptest.Request(router, "POST", "/menus/create", form, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("menuactions: unexpected response for HandleCreate as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "POST", "/menus/create", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("menuactions: error handling HandleCreate %v %d", err, w.Code)
+	}
+
+	menu, err := menus.Find(1)
+	if err != nil || menu.Name != "Main" || len(menu.ItemList()) != 2 {
+		t.Fatalf("menuactions: error with created menu values: %v %s", menu, err)
+	}
+}
+
+// Test the primary menu is shown in the header
+func TestShowMenu(t *testing.T) {
+
+	_, err := apptest.CreatePage(map[string]string{"url": "/about"})
+	if err != nil {
+		t.Fatalf("menuactions: error creating page %s", err)
+	}
+
+	w, err := apptest.Request(router, "GET", "/about", nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("menuactions: error showing page %v %d", err, w.Code)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, `<a href="/about/team">Our Team</a>`) || strings.Contains(body, `<a href="/blog">Blog</a>`) {
+		t.Fatalf("menuactions: primary menu not shown got:%s", body)
+	}
+}
+
+// Test GET /menus
+func TestListMenus(t *testing.T) {
+
+	// Test listing menus as anon
+	w, err := apptest.Request(router, "GET", "/menus", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("menuactions: unexpected response for HandleIndex as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "GET", "/menus", nil, admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("menuactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+
+	pattern := "Main"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("menuactions: unexpected response for HandleIndex expected:%s got:%s", pattern, w.Body.String())
+	}
+}
+
+// Test POST /menus/1/update
+func TestUpdateMenu(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", "Footer")
+	form.Add("location", menus.Footer)
+	form.Add("items", "Privacy | /privacy")
+
+	w, err := apptest.Request(router, "POST", "/menus/1/update", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("menuactions: error handling HandleUpdate %v %d", err, w.Code)
+	}
+
+	menu, err := menus.Find(1)
+	if err != nil || menu.Location != menus.Footer || len(menu.ItemList()) != 1 {
+		t.Fatalf("menuactions: error with updated menu values: %v %s", menu, err)
+	}
+}
+
+// Test of POST /menus/1/destroy
+func TestDeleteMenu(t *testing.T) {
+
+	// Test deleting the menu as anon
+	w, err := apptest.Request(router, "POST", "/menus/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("menuactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "POST", "/menus/1/destroy", nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("menuactions: error handling HandleDestroy %v %d", err, w.Code)
+	}
+
+	_, err = menus.Find(1)
+	if err == nil {
+		t.Fatalf("menuactions: menu found after HandleDestroy")
+	}
+}
@@ -0,0 +1,79 @@
+package menuactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/menus"
+)
+
+// HandleCreateShow serves the create form via GET for menus.
+func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
+
+	menu := menus.New()
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err := can.Create(menu, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("menu", menu)
+	return view.Render()
+}
+
+// HandleCreate handles the POST of the create form for menus
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	menu := menus.New()
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err = can.Create(menu, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Setup context
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Check the location and item definitions
+	err = menus.ValidateItems(params.Map())
+	if err != nil {
+		return server.BadRequestError(err, "Invalid menu", err.Error())
+	}
+
+	// Validate the params, removing any we don't accept
+	menuParams := menu.ValidateParams(params.Map(), menus.AllowedParams())
+
+	id, err := menu.Create(menuParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the new menu
+	menu, err = menus.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, menu.IndexURL())
+}
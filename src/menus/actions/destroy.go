@@ -0,0 +1,48 @@
+package menuactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/menus"
+)
+
+// HandleDestroy responds to /menus/n/destroy by deleting the menu.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the menu
+	menu, err := menus.Find(params.GetInt(menus.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy menu
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(menu, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the menu
+	menu.Destroy()
+
+	// Redirect to menus root
+	return server.Redirect(w, r, menu.IndexURL())
+
+}
@@ -0,0 +1,88 @@
+package menuactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/menus"
+)
+
+// HandleUpdateShow renders the form to update a menu.
+func HandleUpdateShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the menu
+	menu, err := menus.Find(params.GetInt(menus.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise update menu
+	user := session.CurrentUser(w, r)
+	err = can.Update(menu, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("menu", menu)
+	return view.Render()
+}
+
+// HandleUpdate handles the POST of the form to update a menu
+func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the menu
+	menu, err := menus.Find(params.GetInt(menus.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update menu
+	user := session.CurrentUser(w, r)
+	err = can.Update(menu, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Check the location and item definitions
+	err = menus.ValidateItems(params.Map())
+	if err != nil {
+		return server.BadRequestError(err, "Invalid menu", err.Error())
+	}
+
+	// Validate the params, removing any we don't accept
+	menuParams := menu.ValidateParams(params.Map(), menus.AllowedParams())
+
+	err = menu.Update(menuParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to menus root
+	return server.Redirect(w, r, menu.IndexURL())
+}
@@ -0,0 +1,140 @@
+// Package menus represents navigation menus, which are managed by admins and
+// shown by themes in locations such as the header and footer.
+package menus
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fragmenta/view/helpers"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// Menu handles saving and retreiving menus from the database
+type Menu struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	// status.ResourceStatus defines a status field and associated behaviour
+	status.ResourceStatus
+
+	Name     string
+	Location string
+	Items    string
+}
+
+// Locations in which menus are shown by themes.
+const (
+	Primary = "primary"
+	Footer  = "footer"
+)
+
+// Item is a link in a menu, defined in Menu.Items with one item per line as:
+// Label | /url
+// Items starting with - are shown below the item before them.
+type Item struct {
+	Label    string
+	URL      string
+	Children []Item
+}
+
+// LocationOptions returns the locations a menu may be shown in.
+func (m *Menu) LocationOptions() []helpers.Selectable {
+	return []helpers.Selectable{
+		helpers.SelectableOption{Value: Primary, Name: "Primary"},
+		helpers.SelectableOption{Value: Footer, Name: "Footer"},
+	}
+}
+
+// ParseItems parses the item definitions given, one per line.
+func ParseItems(definition string) ([]Item, error) {
+	var items []Item
+
+	for i, line := range strings.Split(definition, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		child := strings.HasPrefix(line, "-")
+		line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+
+		parts := strings.Split(line, "|")
+		item := Item{Label: strings.TrimSpace(parts[0])}
+		if len(parts) > 1 {
+			item.URL = strings.TrimSpace(parts[1])
+		}
+		if item.Label == "" || len(parts) > 2 {
+			return nil, fmt.Errorf("menus: line %d should be Label | /url", i+1)
+		}
+		if !validURL(item.URL) {
+			return nil, fmt.Errorf("menus: line %d has invalid url %s, it should start with /, http://, https:// or mailto:", i+1, item.URL)
+		}
+
+		if child {
+			if len(items) == 0 {
+				return nil, fmt.Errorf("menus: line %d is a child item with no item before it", i+1)
+			}
+			parent := &items[len(items)-1]
+			parent.Children = append(parent.Children, item)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// validURL returns true if url is a relative or web url.
+func validURL(url string) bool {
+	for _, prefix := range []string{"/", "http://", "https://", "mailto:"} {
+		if strings.HasPrefix(url, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ItemList returns the items in this menu, invalid definitions are
+// rejected on save so any error here is ignored.
+func (m *Menu) ItemList() []Item {
+	items, _ := ParseItems(m.Items)
+	return items
+}
+
+// LocationDisplay returns the name of the location of this menu.
+func (m *Menu) LocationDisplay() string {
+	switch m.Location {
+	case Primary:
+		return "Primary"
+	case Footer:
+		return "Footer"
+	}
+	return m.Location
+}
+
+// ValidateItems returns an error if the location or item definitions
+// in the params given are invalid.
+func ValidateItems(params map[string]string) error {
+	switch params["location"] {
+	case Primary, Footer:
+	default:
+		return fmt.Errorf("menus: invalid location %s", params["location"])
+	}
+	_, err := ParseItems(params["items"])
+	return err
+}
+
+// ForLocation returns the items of the published menu for location, or nil if
+// there is none, for use in templates with the menu helper:
+//
+//	{{ range menu "primary" }}<a href="{{ .URL }}">{{ .Label }}</a>{{ end }}
+func ForLocation(location string) []Item {
+	menu, err := FindFirst("location=? AND status>=?", location, status.Published)
+	if err != nil {
+		return nil
+	}
+	return menu.ItemList()
+}
@@ -0,0 +1,94 @@
+// Tests for the menus package
+package menus
+
+import (
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+var testItems = `Home | /
+About | /about
+- Team | /about/team
+- History | /about/history
+Github | https://github.com/fragmenta`
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("menus: Setup db failed %s", err)
+	}
+}
+
+// Test Create method
+func TestCreateMenu(t *testing.T) {
+	params := map[string]string{
+		"name":     "Main",
+		"location": Primary,
+		"items":    testItems,
+		"status":   "100",
+	}
+
+	id, err := New().Create(params)
+	if err != nil {
+		t.Fatalf("menus: Create menu failed :%s", err)
+	}
+
+	menu, err := Find(id)
+	if err != nil {
+		t.Fatalf("menus: Create menu find failed")
+	}
+
+	if menu.Name != "Main" || menu.Location != Primary || len(menu.ItemList()) != 3 {
+		t.Fatalf("menus: Create menu failed got:%v", menu)
+	}
+}
+
+// TestForLocation tests the published menu is found for a location.
+func TestForLocation(t *testing.T) {
+	items := ForLocation(Primary)
+	if len(items) != 3 || items[1].Label != "About" {
+		t.Fatalf("menus: ForLocation unexpected items :%v", items)
+	}
+
+	// Draft menus are not shown
+	_, err := New().Create(map[string]string{"name": "Footer", "location": Footer, "items": testItems})
+	if err != nil {
+		t.Fatalf("menus: Create menu failed :%s", err)
+	}
+	if ForLocation(Footer) != nil {
+		t.Fatalf("menus: ForLocation returned draft menu")
+	}
+}
+
+func TestParseItems(t *testing.T) {
+	items, err := ParseItems(testItems)
+	if err != nil {
+		t.Fatalf("menus: ParseItems failed :%s", err)
+	}
+
+	about := items[1]
+	if about.URL != "/about" || len(about.Children) != 2 || about.Children[1].Label != "History" || about.Children[1].URL != "/about/history" {
+		t.Fatalf("menus: ParseItems unexpected item :%v", about)
+	}
+
+	invalid := []string{
+		"Home",
+		"Home | about",
+		" | /",
+		"- Team | /team",
+		"Home | / | extra",
+		"Home | javascript:alert(1)",
+	}
+	for _, d := range invalid {
+		_, err = ParseItems(d)
+		if err == nil {
+			t.Fatalf("menus: ParseItems accepted invalid definition :%s", d)
+		}
+	}
+
+	err = ValidateItems(map[string]string{"location": "sidebar", "items": testItems})
+	if err == nil {
+		t.Fatalf("menus: ValidateItems accepted invalid location")
+	}
+}
@@ -0,0 +1,104 @@
+package menus
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "menus"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "location asc, name asc, id desc"
+)
+
+// AllowedParams returns an array of allowed param keys for Update and Create.
+func AllowedParams() []string {
+	return []string{"status", "name", "location", "items"}
+}
+
+// NewWithColumns creates a new menu instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Menu {
+
+	menu := New()
+	menu.ID = resource.ValidateInt(cols["id"])
+	menu.CreatedAt = resource.ValidateTime(cols["created_at"])
+	menu.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	menu.Status = resource.ValidateInt(cols["status"])
+	menu.Name = resource.ValidateString(cols["name"])
+	menu.Location = resource.ValidateString(cols["location"])
+	menu.Items = resource.ValidateString(cols["items"])
+
+	return menu
+}
+
+// New creates and initialises a new menu instance.
+func New() *Menu {
+	menu := &Menu{}
+	menu.CreatedAt = time.Now()
+	menu.UpdatedAt = time.Now()
+	menu.TableName = TableName
+	menu.KeyName = KeyName
+	menu.Status = status.Draft
+	menu.Location = Primary
+	return menu
+}
+
+// FindFirst fetches a single menu record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Menu, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single menu record from the database by id.
+func Find(id int64) (*Menu, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all menu records matching this query from the database.
+func FindAll(q *query.Query) ([]*Menu, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of menus constructed from the results
+	var menus []*Menu
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		menus = append(menus, p)
+	}
+
+	return menus, nil
+}
+
+// Query returns a new query for menus with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for menus with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Published returns a query for all menus with status >= published.
+func Published() *query.Query {
+	return Query().Where("status>=?", status.Published)
+}
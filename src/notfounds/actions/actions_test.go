@@ -0,0 +1,121 @@
+package notfoundactions_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/notfounds"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("notfoundactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("notfoundactions: error creating admin %s", err)
+	}
+}
+
+// Test requests for missing pages are recorded and listed in the report
+func TestListNotFounds(t *testing.T) {
+
+	for i := 0; i < 2; i++ {
+		w, err := apptest.Request(router, "GET", "/missing-page", nil, nil)
+		if err != nil || w.Code != http.StatusNotFound {
+			t.Fatalf("notfoundactions: unexpected response for missing page %v %d", err, w.Code)
+		}
+	}
+
+	n, err := notfounds.FindFirst("path=?", "/missing-page")
+	if err != nil || n.Count != 2 {
+		t.Fatalf("notfoundactions: missing page not recorded %v %s", n, err)
+	}
+
+	w, err := apptest.Request(router, "GET", "/notfounds", nil, admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("notfoundactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+	pattern := "/redirects/create?old_url=%2Fmissing-page"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("notfoundactions: unexpected response for HandleIndex expected:%s got:%s", pattern, w.Body.String())
+	}
+
+	w, err = apptest.Request(router, "GET", "/notfounds", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("notfoundactions: unexpected response for HandleIndex as anon, expected failure")
+	}
+
+	// Test the redirect form is filled in with the path
+	w, err = apptest.Request(router, "GET", "/redirects/create?old_url=/missing-page", nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `value="/missing-page"`) {
+		t.Fatalf("notfoundactions: redirect form not filled in %v %d", err, w.Code)
+	}
+}
+
+// Test POST /notfounds/123/ignore hides the path from the report
+func TestIgnoreNotFound(t *testing.T) {
+
+	n, err := notfounds.FindFirst("path=?", "/missing-page")
+	if err != nil {
+		t.Fatalf("notfoundactions: error finding path %s", err)
+	}
+
+	w, err := apptest.Request(router, "POST", fmt.Sprintf("/notfounds/%d/ignore", n.ID), nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("notfoundactions: error handling HandleIgnore %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", "/notfounds", nil, admin)
+	if err != nil || w.Code != http.StatusOK || strings.Contains(w.Body.String(), "/missing-page") {
+		t.Fatalf("notfoundactions: ignored path listed %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", "/notfounds?status=ignored", nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/missing-page") {
+		t.Fatalf("notfoundactions: ignored path not listed %v %d", err, w.Code)
+	}
+}
+
+// Test of POST /notfounds/123/destroy
+func TestDeleteNotFound(t *testing.T) {
+
+	n, err := notfounds.FindFirst("path=?", "/missing-page")
+	if err != nil {
+		t.Fatalf("notfoundactions: error finding path %s", err)
+	}
+
+	w, err := apptest.Request(router, "POST", fmt.Sprintf("/notfounds/%d/destroy", n.ID), nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("notfoundactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "POST", fmt.Sprintf("/notfounds/%d/destroy", n.ID), nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("notfoundactions: error handling HandleDestroy %v %d", err, w.Code)
+	}
+
+	_, err = notfounds.Find(n.ID)
+	if err == nil {
+		t.Fatalf("notfoundactions: path found after HandleDestroy")
+	}
+}
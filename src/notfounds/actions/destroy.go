@@ -0,0 +1,48 @@
+package notfoundactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/notfounds"
+)
+
+// HandleDestroy responds to /notfounds/n/destroy by deleting the record of the path.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the notfound
+	notfound, err := notfounds.Find(params.GetInt(notfounds.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy notfound
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(notfound, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the notfound
+	notfound.Destroy()
+
+	// Redirect to notfounds root
+	return server.Redirect(w, r, notfound.IndexURL())
+
+}
@@ -0,0 +1,50 @@
+package notfoundactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/notfounds"
+)
+
+// HandleIgnore responds to POST /notfounds/n/ignore by hiding the path from the report,
+// requests for it are still counted.
+func HandleIgnore(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the notfound
+	notfound, err := notfounds.Find(params.GetInt(notfounds.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update notfound
+	user := session.CurrentUser(w, r)
+	err = can.Update(notfound, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	err = notfound.Update(map[string]string{"ignored": "1"})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the report
+	return server.Redirect(w, r, notfound.IndexURL())
+}
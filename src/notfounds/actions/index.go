@@ -0,0 +1,67 @@
+package notfoundactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/notfounds"
+)
+
+// HandleIndex displays the report of paths not found, most requested first,
+// by default excluding those ignored.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list notfound
+	user := session.CurrentUser(w, r)
+	err := can.List(notfounds.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Build a query
+	q := notfounds.Query()
+
+	// Filter by status, or show those not ignored
+	switch params.Get("status") {
+
+	case "ignored":
+		q.Where("ignored=1")
+
+	case "all":
+
+	default:
+		q.Where("ignored=0")
+	}
+
+	// Filter if requested
+	filter := params.Get("filter")
+	if len(filter) > 0 {
+		q.Where(resource.ILike("path"), filter)
+	}
+
+	// Fetch the notfounds
+	results, err := notfounds.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("status", params.Get("status"))
+	view.AddKey("filter", filter)
+	view.AddKey("notfounds", results)
+	return view.Render()
+}
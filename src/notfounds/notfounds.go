@@ -0,0 +1,127 @@
+// Package notfounds records requests for urls which were not found, so that
+// broken links can be found and fixed with redirects.
+package notfounds
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/redirects"
+)
+
+// MaxRecords is the maximum number of paths recorded, requests for other
+// paths are not recorded once it is reached.
+const MaxRecords = 10000
+
+// MaxPathLength is the maximum length of paths and referrers recorded.
+const MaxPathLength = 500
+
+// NotFound handles saving and retreiving paths not found from the database,
+// the first request for the path was made when it was created.
+type NotFound struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	Path       string
+	Referrer   string
+	Count      int64
+	LastSeenAt time.Time
+	Ignored    int64
+}
+
+// IsIgnored returns true if this path has been ignored by an admin.
+func (n *NotFound) IsIgnored() bool {
+	return n.Ignored == 1
+}
+
+// IsRedirected returns true if a redirect now matches this path.
+func (n *NotFound) IsRedirected() bool {
+	r, _ := redirects.Match(&url.URL{Path: n.Path})
+	return r != nil
+}
+
+// CreateRedirectURL returns the url of the form to create a redirect from this path.
+func (n *NotFound) CreateRedirectURL() string {
+	return "/redirects/create?old_url=" + url.QueryEscape(n.Path)
+}
+
+// Record records a request for path which was not found, with the referrer
+// if given. Paths matching the ignore rules in the notfound_ignore setting
+// are not recorded.
+func Record(path, referrer string) error {
+	if path == "" || len(path) > MaxPathLength || Ignore(path, settings.Current.NotFound.Ignore) {
+		return nil
+	}
+	if len(referrer) > MaxPathLength {
+		referrer = ""
+	}
+	now := query.TimeString(time.Now().UTC())
+
+	n, err := FindFirst("path=?", path)
+	if err == nil {
+		params := map[string]string{
+			"count":        strconv.FormatInt(n.Count+1, 10),
+			"last_seen_at": now,
+		}
+		if referrer != "" {
+			params["referrer"] = referrer
+		}
+		return n.Update(params)
+	}
+
+	// Stop recording new paths once the limit is reached
+	count, err := Query().Count()
+	if err != nil || count >= MaxRecords {
+		return err
+	}
+
+	_, err = New().Create(map[string]string{
+		"path":         path,
+		"referrer":     referrer,
+		"count":        "1",
+		"last_seen_at": now,
+		"ignored":      "0",
+	})
+	return err
+}
+
+// Ignore returns true if path matches one of the rules given, separated by
+// commas. In rules * matches any text, for example /wp-* or *.php.
+func Ignore(path, rules string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule != "" && match(rule, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns true if s matches the pattern, in which * matches any text.
+func match(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	// The first and last parts must match the start and end, others may be anywhere between
+	first, last := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(s, first) || len(s) < len(first)+len(last) {
+		return false
+	}
+	s = s[len(first):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
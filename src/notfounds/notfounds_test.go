@@ -0,0 +1,67 @@
+// Tests for the notfounds package
+package notfounds
+
+import (
+	"testing"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+)
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("notfounds: Setup db failed %s", err)
+	}
+}
+
+// Test requests for the same path are counted, and ignored paths are not recorded
+func TestRecord(t *testing.T) {
+	settings.Current.NotFound.Ignore = "/wp-*"
+
+	for _, referrer := range []string{"https://example.com/links", ""} {
+		err := Record("/old-page", referrer)
+		if err != nil {
+			t.Fatalf("notfounds: Record failed :%s", err)
+		}
+	}
+
+	n, err := FindFirst("path=?", "/old-page")
+	if err != nil {
+		t.Fatalf("notfounds: Record path not found :%s", err)
+	}
+	if n.Count != 2 || n.Referrer != "https://example.com/links" || n.LastSeenAt.IsZero() || n.IsIgnored() {
+		t.Fatalf("notfounds: Record unexpected values :%v", n)
+	}
+	if n.IsRedirected() || n.CreateRedirectURL() != "/redirects/create?old_url=%2Fold-page" {
+		t.Fatalf("notfounds: unexpected redirect for path :%s", n.CreateRedirectURL())
+	}
+
+	err = Record("/wp-login.php", "")
+	if err != nil {
+		t.Fatalf("notfounds: Record failed :%s", err)
+	}
+	count, err := Query().Count()
+	if err != nil || count != 1 {
+		t.Fatalf("notfounds: Record recorded ignored path :%d %v", count, err)
+	}
+}
+
+// Test ignore rules match paths with wildcards
+func TestIgnore(t *testing.T) {
+	rules := "/wp-*, *.php, /cgi-bin/*/run, /.env"
+
+	ignored := []string{"/wp-admin", "/wp-content/x.js", "/index.php", "/cgi-bin/a/b/run", "/.env"}
+	for _, path := range ignored {
+		if !Ignore(path, rules) {
+			t.Fatalf("notfounds: Ignore failed to ignore %s", path)
+		}
+	}
+
+	recorded := []string{"/about", "/wp", "/php", "/cgi-bin/run", "/.env.local", "/index.php/x"}
+	for _, path := range recorded {
+		if Ignore(path, rules) {
+			t.Fatalf("notfounds: Ignore unexpectedly ignored %s", path)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package notfounds
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "notfounds"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "count desc, last_seen_at desc, id desc"
+)
+
+// AllowedParams returns an array of allowed param keys for Update and Create.
+func AllowedParams() []string {
+	return []string{"ignored"}
+}
+
+// NewWithColumns creates a new notfound instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *NotFound {
+
+	notfound := New()
+	notfound.ID = resource.ValidateInt(cols["id"])
+	notfound.CreatedAt = resource.ValidateTime(cols["created_at"])
+	notfound.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	notfound.Path = resource.ValidateString(cols["path"])
+	notfound.Referrer = resource.ValidateString(cols["referrer"])
+	notfound.Count = resource.ValidateInt(cols["count"])
+	notfound.LastSeenAt = resource.ValidateTime(cols["last_seen_at"])
+	notfound.Ignored = resource.ValidateInt(cols["ignored"])
+
+	return notfound
+}
+
+// New creates and initialises a new notfound instance.
+func New() *NotFound {
+	notfound := &NotFound{}
+	notfound.CreatedAt = time.Now()
+	notfound.UpdatedAt = time.Now()
+	notfound.TableName = TableName
+	notfound.KeyName = KeyName
+	return notfound
+}
+
+// FindFirst fetches a single notfound record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*NotFound, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single notfound record from the database by id.
+func Find(id int64) (*NotFound, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all notfound records matching this query from the database.
+func FindAll(q *query.Query) ([]*NotFound, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of notfounds constructed from the results
+	var notfounds []*NotFound
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		notfounds = append(notfounds, p)
+	}
+
+	return notfounds, nil
+}
+
+// Query returns a new query for notfounds with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for notfounds with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
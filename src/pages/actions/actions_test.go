@@ -1,79 +1,56 @@
-package pageactions
+package pageactions_test
 
 import (
 	"fmt"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/fragmenta/mux"
-	"github.com/fragmenta/query"
 
-	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/lib/format"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/pages"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // names is used to test setting and getting the first string field of the page.
 var names = []string{"foo", "bar"}
 
-// testSetup performs setup for integration tests
-// using the test database, real views, and mock authorisation
-// If we can run this once for global tests it might be more efficient?
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
 func TestSetup(t *testing.T) {
-	err := resource.SetupTestDatabase(3)
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("pageactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
 	if err != nil {
-		fmt.Printf("pages: Setup db failed %s", err)
-	}
-
-	// Set up mock auth
-	resource.SetupAuthorisation()
-
-	// Load templates for rendering
-	resource.SetupView(3)
-
-	router := mux.New()
-	mux.SetDefault(router)
-
-	// FIXME - Need to write routes out here again, but without pkg prefix
-	// Any neat way to do this instead? We'd need a separate routes package under app...
-	router.Add("/pages", nil)
-	router.Add("/pages/create", nil)
-	router.Add("/pages/create", nil).Post()
-	router.Add("/pages/login", nil)
-	router.Add("/pages/login", nil).Post()
-	router.Add("/pages/login", nil).Post()
-	router.Add("/pages/logout", nil).Post()
-	router.Add("/pages/{id:\\d+}/update", nil)
-	router.Add("/pages/{id:\\d+}/update", nil).Post()
-	router.Add("/pages/{id:\\d+}/destroy", nil).Post()
-	router.Add("/pages/{id:\\d+}", nil)
-
-	// Delete all pages to ensure we get consistent results
-	query.ExecSQL("delete from pages;")
-	query.ExecSQL("ALTER SEQUENCE pages_id_seq RESTART WITH 1;")
+		t.Fatalf("pageactions: error creating admin %s", err)
+	}
 }
 
 // Test GET /pages/create
 func TestShowCreatePage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/pages/create", nil)
-	w := httptest.NewRecorder()
-
-	// Set up page session cookie for admin page above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleCreateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/pages/create", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("pageactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("pageactions: error handling HandleCreateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -90,20 +67,8 @@ func TestCreatePage(t *testing.T) {
 	form := url.Values{}
 	form.Add("name", names[0])
 	form.Add("url", "/foo")
-	body := strings.NewReader(form.Encode())
 
-	r := httptest.NewRequest("POST", "/pages/create", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up page session cookie for admin page
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
-	}
-
-	// Run the handler to update the page
-	err = HandleCreate(w, r)
+	w, err := apptest.Request(router, "POST", "/pages/create", form, admin)
 	if err != nil {
 		t.Fatalf("pageactions: error handling HandleCreate %s", err)
 	}
@@ -113,7 +78,7 @@ func TestCreatePage(t *testing.T) {
 		t.Fatalf("pageactions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
 	}
 
-	// Check the page name is in now value names[1]
+	// Check the page name is in now value names[0]
 	allPage, err := pages.FindAll(pages.Query().Order("id desc"))
 	if err != nil || len(allPage) == 0 {
 		t.Fatalf("pageactions: error finding created page %s", err)
@@ -127,22 +92,11 @@ func TestCreatePage(t *testing.T) {
 // Test GET /pages
 func TestListPage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/pages", nil)
-	w := httptest.NewRecorder()
-
-	// Set up page session cookie for admin page above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleIndex(w, r)
+	w, err := apptest.Request(router, "GET", "/pages", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("pageactions: error handling HandleIndex %s", err)
+		t.Fatalf("pageactions: error handling HandleIndex %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -156,22 +110,11 @@ func TestListPage(t *testing.T) {
 // Test of GET /pages/1
 func TestShowPage(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/pages/1", nil)
-	w := httptest.NewRecorder()
-
-	// Set up page session cookie for admin page above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleShow(w, r)
+	w, err := apptest.Request(router, "GET", "/pages/1", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("pageactions: error handling HandleShow %s", err)
+		t.Fatalf("pageactions: error handling HandleShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -181,31 +124,35 @@ func TestShowPage(t *testing.T) {
 	}
 }
 
-// Test GET /pages/123/update
-func TestShowUpdatePage(t *testing.T) {
+// Test of GET /foo served by the catch-all page route
+func TestShowPagePath(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/pages/1/update", nil)
-	w := httptest.NewRecorder()
+	// The page is a draft, so anon users should not see it
+	w, err := apptest.Request(router, "GET", "/foo", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("pageactions: unexpected response for HandleShowPath as anon %v %d", err, w.Code)
+	}
 
-	// Set up page session cookie for admin page above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
+	w, err = apptest.Request(router, "GET", "/foo", nil, admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("pageactions: error handling HandleShowPath %v %d", err, w.Code)
 	}
+}
+
+// Test GET /pages/123/update
+func TestShowUpdatePage(t *testing.T) {
 
-	// Run the handler
-	err = HandleUpdateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/pages/1/update", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("pageactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("pageactions: error handling HandleUpdateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
 	pattern := "resource-update-form"
 	if !strings.Contains(w.Body.String(), pattern) {
-		t.Fatalf("pageactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+		t.Fatalf("pageactions: unexpected response for HandleUpdateShow expected:%s got:%s", pattern, w.Body.String())
 	}
 
 }
@@ -216,20 +163,8 @@ func TestUpdatePage(t *testing.T) {
 	form := url.Values{}
 	form.Add("name", names[1])
 	form.Add("url", "/bar")
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/pages/1/update", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up page session cookie for admin page
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
-	}
 
-	// Run the handler to update the page
-	err = HandleUpdate(w, r)
+	w, err := apptest.Request(router, "POST", "/pages/1/update", form, admin)
 	if err != nil {
 		t.Fatalf("pageactions: error handling HandleUpdatePage %s", err)
 	}
@@ -250,26 +185,367 @@ func TestUpdatePage(t *testing.T) {
 
 }
 
-// Test of POST /pages/123/destroy
-func TestDeletePage(t *testing.T) {
+// Test the html of the text is cleaned on save, keeping the tags allowed for admins
+func TestPageText(t *testing.T) {
 
-	body := strings.NewReader(``)
+	form := url.Values{}
+	form.Add("text", `<p onclick="alert(1)">Hello</p><script>alert(1)</script><a href="javascript:alert(1)">x</a><iframe src="https://example.com/embed"></iframe>`)
+	w, err := apptest.Request(router, "POST", "/pages/1/update", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error updating page text %v %d", err, w.Code)
+	}
 
-	// Now test deleting the page created above as admin
-	r := httptest.NewRequest("POST", "/pages/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
+	page, err := pages.Find(1)
+	if err != nil {
+		t.Fatalf("pageactions: error finding updated page %s", err)
+	}
+	expected := `<p>Hello</p><a>x</a><iframe src="https://example.com/embed"></iframe>`
+	if page.Text != expected {
+		t.Fatalf("pageactions: unexpected page text expected:%s got:%s", expected, page.Text)
+	}
+
+}
 
-	// Set up page session cookie for admin page
-	err := resource.AddUserSessionCookie(w, r, 1)
+// Test the text is converted when the format changes, and markdown is previewed
+func TestPageFormat(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("format", fmt.Sprintf("%d", format.Markdown))
+	form.Add("text", `<h2>Title</h2><p>Some <b>bold</b> text</p>`)
+	w, err := apptest.Request(router, "POST", "/pages/1/update", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error updating page format %v %d", err, w.Code)
+	}
+
+	page, err := pages.Find(1)
 	if err != nil {
-		t.Fatalf("pageactions: error setting session %s", err)
+		t.Fatalf("pageactions: error finding updated page %s", err)
+	}
+	expected := "## Title\n\nSome **bold** text"
+	if !page.IsMarkdown() || page.Text != expected {
+		t.Fatalf("pageactions: unexpected page text after conversion expected:%s got:%s", expected, page.Text)
+	}
+	if !strings.Contains(string(page.Content()), "<strong>bold</strong>") {
+		t.Fatalf("pageactions: unexpected page content got:%s", page.Content())
+	}
+
+	form = url.Values{}
+	form.Add("text", "*preview* <script>")
+	w, err = apptest.Request(router, "POST", "/pages/preview", form, admin)
+	if err != nil || w.Code != http.StatusOK || w.Body.String() != "<p><em>preview</em> &lt;script&gt;</p>\n" {
+		t.Fatalf("pageactions: unexpected response for HandlePreview %v %d %s", err, w.Code, w.Body.String())
+	}
+
+	// Convert the page back to html for the tests which follow
+	form = url.Values{}
+	form.Add("format", fmt.Sprintf("%d", format.HTML))
+	w, err = apptest.Request(router, "POST", "/pages/1/update", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error updating page format %v %d", err, w.Code)
+	}
+	page, err = pages.Find(1)
+	if err != nil || page.IsMarkdown() || page.Text != "<h2>Title</h2>\n<p>Some <strong>bold</strong> text</p>\n" {
+		t.Fatalf("pageactions: unexpected page text after conversion to html %v %q", err, page.Text)
+	}
+
+}
+
+// Test templates are found and validated on save
+func TestPageTemplates(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/pages/1/update", nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), pages.DefaultTemplate) {
+		t.Fatalf("pageactions: default template not offered %v %d", err, w.Code)
 	}
 
-	// Run the handler
-	err = HandleDestroy(w, r)
+	form := url.Values{}
+	form.Add("template", "pages/views/templates/missing.html.got")
+	w, err = apptest.Request(router, "POST", "/pages/1/update", form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("pageactions: unexpected response for missing template %v %d", err, w.Code)
+	}
 
-	// Test the error response is 302 StatusFound
+	form.Set("template", pages.DefaultTemplate)
+	w, err = apptest.Request(router, "POST", "/pages/1/update", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: unexpected response for default template %v %d", err, w.Code)
+	}
+}
+
+// Test custom fields declared by the landing template are edited and shown
+func TestPageFields(t *testing.T) {
+
+	page, err := apptest.CreatePage(map[string]string{
+		"url":      "/landing",
+		"template": "pages/views/templates/landing.html.got",
+	})
+	if err != nil {
+		t.Fatalf("pageactions: error creating page %s", err)
+	}
+	image, err := apptest.CreateImage(nil)
+	if err != nil {
+		t.Fatalf("pageactions: error creating image %s", err)
+	}
+
+	// Test the form has inputs for the fields
+	updateURL := fmt.Sprintf("/pages/%d/update", page.ID)
+	w, err := apptest.Request(router, "GET", updateURL, nil, admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("pageactions: error handling HandleUpdateShow %v %d", err, w.Code)
+	}
+	for _, pattern := range []string{`name="fields.headline"`, `name="fields.call_to_action.url"`, `name="fields.features.0.title"`, image.Name} {
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("pageactions: field input missing expected:%s got:%s", pattern, w.Body.String())
+		}
+	}
+
+	// Test the required headline is checked
+	form := url.Values{}
+	form.Add("fields.features.0.title", "Fast")
+	w, err = apptest.Request(router, "POST", updateURL, form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("pageactions: unexpected response for missing field %v %d", err, w.Code)
+	}
+
+	form.Add("fields.headline", "Welcome aboard")
+	form.Add("fields.hero_image", fmt.Sprintf("%d", image.ID))
+	form.Add("fields.features.0.text", `<p>Very fast</p><script>alert(1)</script>`)
+	form.Add("fields.features.1.title", "Simple")
+	w, err = apptest.Request(router, "POST", updateURL, form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error handling HandleUpdatePage %v %d", err, w.Code)
+	}
+
+	// Test the fields are shown, with the default call to action
+	w, err = apptest.Request(router, "GET", page.URL, nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("pageactions: error showing page %v %d", err, w.Code)
+	}
+	body := w.Body.String()
+	for _, pattern := range []string{"Welcome aboard", image.Path, "<h3>Fast</h3>", "<p>Very fast</p>", "<h3>Simple</h3>", `href="/contact"`} {
+		if !strings.Contains(body, pattern) {
+			t.Fatalf("pageactions: field not shown expected:%s got:%s", pattern, body)
+		}
+	}
+	if strings.Contains(body, "<script>alert") {
+		t.Fatalf("pageactions: rich text field not sanitized got:%s", body)
+	}
+
+	// Test rich text is cleaned when saved, as the page text is
+	page, err = pages.Find(page.ID)
+	if err != nil || strings.Contains(page.Fields, "script") || !strings.Contains(page.Fields, "Very fast") {
+		t.Fatalf("pageactions: rich text field not cleaned on save %s %v", page.Fields, err)
+	}
+}
+
+// Test pages placed below a parent have urls, breadcrumbs and order from the tree
+func TestPageHierarchy(t *testing.T) {
+
+	parent, err := apptest.CreatePage(map[string]string{"url": "/company", "name": "Company"})
+	if err != nil {
+		t.Fatalf("pageactions: error creating page %s", err)
+	}
+
+	// Test the url of a child page is prefixed with the parent url
+	form := url.Values{}
+	form.Add("name", "Our Team")
+	form.Add("status", "100")
+	form.Add("parent_id", fmt.Sprintf("%d", parent.ID))
+	w, err := apptest.Request(router, "POST", "/pages/create", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error handling HandleCreate with parent %v %d", err, w.Code)
+	}
+	child, err := pages.FindFirst("parent_id=?", parent.ID)
+	if err != nil || child.URL != "/company/our-team" {
+		t.Fatalf("pageactions: unexpected child page %v %s", child, err)
+	}
+
+	// Test breadcrumbs are shown on the child page
+	w, err = apptest.Request(router, "GET", child.URL, nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("pageactions: error showing child page %v %d", err, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `<a href="/company">Company</a>`) {
+		t.Fatalf("pageactions: breadcrumbs not shown got:%s", w.Body.String())
+	}
+
+	// Test a page cannot be placed below its child
+	form = url.Values{}
+	form.Add("parent_id", fmt.Sprintf("%d", child.ID))
+	w, err = apptest.Request(router, "POST", fmt.Sprintf("/pages/%d/update", parent.ID), form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("pageactions: unexpected response for parent loop %v %d", err, w.Code)
+	}
+
+	// Test changing the parent url changes the child url
+	form = url.Values{}
+	form.Add("url", "/about-us")
+	w, err = apptest.Request(router, "POST", fmt.Sprintf("/pages/%d/update", parent.ID), form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error handling HandleUpdatePage %v %d", err, w.Code)
+	}
+	child, err = pages.Find(child.ID)
+	if err != nil || child.URL != "/about-us/our-team" {
+		t.Fatalf("pageactions: child url not updated %v %s", child, err)
+	}
+
+	// Test the old urls redirect to the new ones
+	for old, new := range map[string]string{"/company": "/about-us", "/company/our-team": "/about-us/our-team"} {
+		w, err = apptest.Request(router, "GET", old, nil, nil)
+		if err != nil || w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != new {
+			t.Fatalf("pageactions: old url %s not redirected %v %d %s", old, err, w.Code, w.Header().Get("Location"))
+		}
+	}
+
+	// Test reordering pages, the new page should be listed first
+	form = url.Values{}
+	form.Add("ids", fmt.Sprintf("%d,1", parent.ID))
+	w, err = apptest.Request(router, "POST", "/pages/reorder", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error handling HandleReorder %v %d", err, w.Code)
+	}
+	list, err := pages.FindAll(pages.Query().Where("parent_id=0"))
+	if err != nil || len(list) < 2 || list[0].ID != parent.ID {
+		t.Fatalf("pageactions: unexpected order after HandleReorder %v", err)
+	}
+
+	w, err = apptest.Request(router, "POST", "/pages/reorder", form, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("pageactions: unexpected response for HandleReorder as anon, expected failure")
+	}
+
+	// Test a new page at an old url replaces the redirect from it
+	form = url.Values{}
+	form.Add("name", "Company")
+	form.Add("url", "/company")
+	form.Add("status", "100")
+	w, err = apptest.Request(router, "POST", "/pages/create", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error handling HandleCreate at old url %v %d", err, w.Code)
+	}
+	w, err = apptest.Request(router, "GET", "/company", nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("pageactions: new page at old url not shown %v %d %s", err, w.Code, w.Header().Get("Location"))
+	}
+}
+
+// Test pages restricted to selected roles show a teaser to others
+func TestRestrictedPage(t *testing.T) {
+
+	page, err := apptest.CreatePage(map[string]string{
+		"url":     "/members",
+		"summary": "For editors",
+		"text":    "<p>Secret</p>",
+	})
+	if err != nil {
+		t.Fatalf("pageactions: error creating page %s", err)
+	}
+
+	// Restrict the page to editors
+	form := url.Values{}
+	form.Add("visibility", fmt.Sprintf("%d", visibility.Roles))
+	form.Add("visible_roles", fmt.Sprintf("%d", users.Editor))
+	w, err := apptest.Request(router, "POST", fmt.Sprintf("/pages/%d/update", page.ID), form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("pageactions: error handling HandleUpdatePage %v %d", err, w.Code)
+	}
+
+	reader, err := apptest.CreateUser(nil)
+	if err != nil {
+		t.Fatalf("pageactions: error creating reader %s", err)
+	}
+	editor, err := apptest.CreateUser(map[string]string{"role": fmt.Sprintf("%d", users.Editor)})
+	if err != nil {
+		t.Fatalf("pageactions: error creating editor %s", err)
+	}
+
+	for _, u := range []*users.User{nil, reader} {
+		w, err = apptest.Request(router, "GET", page.URL, nil, u)
+		if err != nil || w.Code != http.StatusOK {
+			t.Fatalf("pageactions: error showing restricted page %v %d", err, w.Code)
+		}
+		body := w.Body.String()
+		if strings.Contains(body, "Secret") || !strings.Contains(body, page.Summary) || !strings.Contains(body, settings.Current.Restricted.Message) {
+			t.Fatalf("pageactions: unexpected teaser for restricted page got:%s", body)
+		}
+	}
+
+	for _, u := range []*users.User{editor, admin} {
+		w, err = apptest.Request(router, "GET", page.URL, nil, u)
+		if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Secret") {
+			t.Fatalf("pageactions: restricted page not shown to %s %v %d", u.Name, err, w.Code)
+		}
+	}
+
+	// Test an error is shown instead of the teaser if disabled
+	settings.Current.Restricted.Teaser = false
+	defer func() { settings.Current.Restricted.Teaser = true }()
+	w, err = apptest.Request(router, "GET", page.URL, nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("pageactions: unexpected response for restricted page without teaser %v %d", err, w.Code)
+	}
+}
+
+// Test shortcodes in pages are rendered for the user viewing the page
+func TestPageShortcodes(t *testing.T) {
+
+	image, err := apptest.CreateImage(nil)
+	if err != nil {
+		t.Fatalf("pageactions: error creating image %s", err)
+	}
+	public, err := apptest.CreatePost(map[string]string{"name": "Public news"})
+	if err != nil {
+		t.Fatalf("pageactions: error creating post %s", err)
+	}
+	members, err := apptest.CreatePost(map[string]string{"name": "Members news", "visibility": fmt.Sprintf("%d", visibility.Members)})
+	if err != nil {
+		t.Fatalf("pageactions: error creating post %s", err)
+	}
+
+	text := fmt.Sprintf("Intro\n\n[[youtube id=\"M7lc1UVf-VE\"]]\n\n[[image id=\"%d\" caption=\"A caption\"]]\n\n[[posts limit=\"10\"]]\n\n[[unknown]]", image.ID)
+	page, err := apptest.CreatePage(map[string]string{"format": fmt.Sprintf("%d", format.Markdown), "text": text})
+	if err != nil {
+		t.Fatalf("pageactions: error creating page %s", err)
+	}
+
+	w, err := apptest.Request(router, "GET", page.URL, nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("pageactions: error showing page %v %d", err, w.Code)
+	}
+	body := w.Body.String()
+	for _, pattern := range []string{
+		`<iframe src="https://www.youtube-nocookie.com/embed/M7lc1UVf-VE"`,
+		fmt.Sprintf(`<img src="%s" alt="%s" loading="lazy">`, image.Path, image.Name),
+		"<figcaption>A caption</figcaption>",
+		fmt.Sprintf(`<a href="%s">%s</a>`, public.ShowURL(), public.Name),
+		"[[unknown]]",
+	} {
+		if !strings.Contains(body, pattern) {
+			t.Fatalf("pageactions: shortcode not rendered expected:%s got:%s", pattern, body)
+		}
+	}
+	if strings.Contains(body, members.Name) || strings.Contains(body, "<p><div") {
+		t.Fatalf("pageactions: unexpected shortcode html for anon got:%s", body)
+	}
+
+	// Members see the posts restricted to members
+	w, err = apptest.Request(router, "GET", page.URL, nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), members.Name) {
+		t.Fatalf("pageactions: restricted post not listed for admin %v %d", err, w.Code)
+	}
+}
+
+// Test of POST /pages/123/destroy
+func TestDeletePage(t *testing.T) {
+
+	// Test deleting the page created above as anon
+	w, err := apptest.Request(router, "POST", "/pages/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("pageactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	// Now test deleting the page as admin
+	w, err = apptest.Request(router, "POST", "/pages/1/destroy", nil, admin)
 	if err != nil {
 		t.Fatalf("pageactions: error handling HandleDestroy %s", err)
 	}
@@ -278,15 +554,10 @@ func TestDeletePage(t *testing.T) {
 	if w.Code != http.StatusFound {
 		t.Fatalf("pageactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
 	}
-	// Now test as anon
-	r = httptest.NewRequest("POST", "/pages/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w = httptest.NewRecorder()
 
-	// Run the handler to test failure as anon
-	err = HandleDestroy(w, r)
-	if err == nil { // failure expected
-		t.Fatalf("pageactions: unexpected response for HandleDestroy as anon, expected failure")
+	_, err = pages.Find(1)
+	if err == nil {
+		t.Fatalf("pageactions: page found after HandleDestroy")
 	}
 
 }
@@ -8,8 +8,11 @@ import (
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/format"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/pages"
+	"github.com/fragmenta/fragmenta-cms/src/redirects"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -63,6 +66,45 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Check the template chosen exists
+	err = pages.ValidateTemplate(params.Get("template"))
+	if err != nil {
+		return server.BadRequestError(err, "Invalid template", "Please choose a template which exists.")
+	}
+
+	// Place the page below its parent, prefixing the url with the parent url
+	if params.GetInt("parent_id") > 0 {
+		parent, err := page.ValidateParent(params.GetInt("parent_id"))
+		if err != nil {
+			return server.BadRequestError(err, "Invalid parent", "Please choose a parent page which exists.")
+		}
+		params.SetString("url", pages.ChildURL(parent, params.Get("url"), params.Get("name")))
+	}
+
+	// Read the custom fields declared by the page template
+	pageFields, err := page.ReadFields(params.Get, user)
+	if err != nil {
+		return server.BadRequestError(err, "Please check the page fields", err.Error())
+	}
+	params.SetString("fields", pageFields)
+
+	// Clean the text for the user in the format chosen, converting it if the format has changed
+	to := page.Format
+	if _, ok := params.Values["format"]; ok {
+		to = params.GetInt("format")
+	}
+	_, hasText := params.Values["text"]
+	if hasText || to != page.Format {
+		text := page.Text
+		if hasText {
+			text = params.Get("text")
+		}
+		params.SetString("text", format.Text(text, page.Format, to, user))
+	}
+
+	// Store the roles selected for visibility as a list of role ids
+	params.SetString("visible_roles", visibility.RolesParam(params.Values["visible_roles"]))
+
 	// Validate the params, removing any we don't accept
 	pageParams := page.ValidateParams(params.Map(), pages.AllowedParams())
 
@@ -77,5 +119,11 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Remove any redirects from the url of the page, so that it can be reached
+	err = redirects.Remove(page.URL)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
 	return server.Redirect(w, r, page.IndexURL())
 }
@@ -4,11 +4,12 @@ import (
 	"net/http"
 
 	"github.com/fragmenta/server"
-	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/pages"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
@@ -32,13 +33,19 @@ func HandleShowHome(w http.ResponseWriter, r *http.Request) error {
 
 	currentUser := session.CurrentUser(w, r)
 
+	// Show a teaser to users who may not see the page
+	if !page.VisibleTo(currentUser) {
+		return visibility.RenderRestricted(w, r, currentUser, page.Name, page.Summary)
+	}
+
 	view := view.NewWithPath(r.URL.Path, w)
 	view.AddKey("title", "Fragmenta app")
 	view.AddKey("page", page)
+	view.AddKey("content", pageContent(page, r, currentUser))
 	view.AddKey("currentUser", currentUser)
-	view.AddKey("meta_title", config.Get("meta_title"))
-	view.AddKey("meta_desc", config.Get("meta_desc"))
-	view.AddKey("meta_keywords", config.Get("meta_keywords"))
-	view.Template("pages/views/templates/default.html.got")
+	view.AddKey("meta_title", settings.Current.Meta.Title)
+	view.AddKey("meta_desc", settings.Current.Meta.Desc)
+	view.AddKey("meta_keywords", settings.Current.Meta.Keywords)
+	view.Template(page.ShowTemplate())
 	return view.Render()
 }
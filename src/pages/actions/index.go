@@ -8,6 +8,7 @@ import (
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
 	"github.com/fragmenta/fragmenta-cms/src/pages"
 )
@@ -31,7 +32,7 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	// Build a query
 	q := pages.Query()
 
-	// Order by required order, or default to id asc
+	// Order by required order, or default to the sort order
 	switch params.Get("order") {
 
 	case "1":
@@ -41,13 +42,13 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 		q.Order("updated_at desc")
 
 	default:
-		q.Order("id asc")
+		q.Order(pages.Order)
 	}
 
 	// Filter if requested
 	filter := params.Get("filter")
 	if len(filter) > 0 {
-		q.Where("name ILIKE ?", filter)
+		q.Where(resource.ILike("name"), filter)
 	}
 
 	// Fetch the pages
@@ -56,9 +57,16 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Show the pages as a tree which may be reordered, unless filtered or ordered
+	tree := params.Get("order") == "" && filter == ""
+	if tree {
+		results = pages.Tree(results)
+	}
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("filter", filter)
+	view.AddKey("tree", tree)
 	view.AddKey("pages", results)
 	view.AddKey("currentUser", user)
 	return view.Render()
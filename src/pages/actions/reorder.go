@@ -0,0 +1,56 @@
+package pageactions
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/pages"
+)
+
+// HandleReorder handles the POST of a new order for pages, as a list of
+// page ids separated by commas, from drag and drop in the pages index.
+func HandleReorder(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update pages
+	user := session.CurrentUser(w, r)
+	err = can.Update(pages.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Fetch the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	var ids []int64
+	for _, s := range strings.Split(params.Get("ids"), ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return server.BadRequestError(err, "Invalid order", "Please send a list of page ids.")
+		}
+		ids = append(ids, id)
+	}
+
+	// Update the sort order of the pages
+	err = pages.Reorder(ids)
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Redirect to the pages index
+	return server.Redirect(w, r, "/pages")
+}
@@ -1,6 +1,7 @@
 package pageactions
 
 import (
+	"html/template"
 	"net/http"
 
 	"github.com/fragmenta/auth/can"
@@ -9,8 +10,10 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/pages"
-	"github.com/fragmenta/fragmenta-cms/src/redirects"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // HandleShow displays a single page.
@@ -38,10 +41,18 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	// Render the template
+	// Show a teaser to users who may not see the page
+	if !page.VisibleTo(user) {
+		return visibility.RenderRestricted(w, r, user, page.Name, page.Summary)
+	}
+
+	// Render the template, caching only public pages without shortcodes
 	view := view.NewRenderer(w, r)
-	view.CacheKey(page.CacheKey())
+	if page.IsPublic() && !shortcodes.Contains(page.Text) {
+		view.CacheKey(page.CacheKey())
+	}
 	view.AddKey("page", page)
+	view.AddKey("content", pageContent(page, r, user))
 	view.AddKey("currentUser", user)
 	view.AddKey("meta_title", page.Name)
 	view.AddKey("meta_keywords", page.Keywords)
@@ -59,15 +70,11 @@ func HandleShowPath(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
-	// Find the page
+	// Find the page, redirects are handled before routing
 	path := "/" + params.Get("path")
 	page, err := pages.FindFirst("url=?", path)
 	if err != nil {
-		redirect, err := redirects.FindFirst("old_url=?", path)
-		if err != nil {
-			return server.NotFoundError(err)
-		}
-		return server.Redirect(w, r, redirect.NewURL)
+		return server.NotFoundError(err)
 	}
 
 	// Authorise access IF the page is not published
@@ -79,10 +86,18 @@ func HandleShowPath(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	// Render the template
+	// Show a teaser to users who may not see the page
+	if !page.VisibleTo(user) {
+		return visibility.RenderRestricted(w, r, user, page.Name, page.Summary)
+	}
+
+	// Render the template, caching only public pages without shortcodes
 	view := view.NewRenderer(w, r)
-	view.CacheKey(page.CacheKey())
+	if page.IsPublic() && !shortcodes.Contains(page.Text) {
+		view.CacheKey(page.CacheKey())
+	}
 	view.AddKey("page", page)
+	view.AddKey("content", pageContent(page, r, user))
 	view.AddKey("currentUser", user)
 	view.AddKey("meta_title", page.Name)
 	view.AddKey("meta_keywords", page.Keywords)
@@ -90,3 +105,9 @@ func HandleShowPath(w http.ResponseWriter, r *http.Request) error {
 	view.Template(page.ShowTemplate())
 	return view.Render()
 }
+
+// pageContent returns the html for the text of the page, with shortcodes such as
+// [[form id="1"]] rendered for the request and user.
+func pageContent(page *pages.Page, r *http.Request, user *users.User) template.HTML {
+	return shortcodes.Render(page.Content(), r, user)
+}
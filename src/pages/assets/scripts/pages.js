@@ -1 +1,78 @@
-/* JS for pages */
\ No newline at end of file
+/* JS for pages */
+DOM.Ready(function() {
+    // Reorder pages by drag and drop in the pages index
+    ActivatePageReorder();
+});
+
+// Allow rows in the pages tree to be dragged before other pages with the same
+// parent, moving their children with them, then post the new order.
+function ActivatePageReorder() {
+    var dragged = null;
+
+    DOM.On(".pages-tree tr[draggable]", "dragstart", function(e) {
+        dragged = this;
+        DOM.AddClass(this, "dragging");
+        e.dataTransfer.effectAllowed = "move";
+        e.dataTransfer.setData("text/plain", this.getAttribute("data-id"));
+    });
+
+    DOM.On(".pages-tree tr[draggable]", "dragend", function(e) {
+        DOM.RemoveClass(this, "dragging");
+        DOM.RemoveClass(".pages-tree tr", "drop-target");
+        dragged = null;
+    });
+
+    DOM.On(".pages-tree tr[draggable]", "dragover", function(e) {
+        if (dragged === null || dragged === this || !samePageParent(dragged, this)) {
+            return;
+        }
+        e.preventDefault();
+        DOM.RemoveClass(".pages-tree tr", "drop-target");
+        DOM.AddClass(this, "drop-target");
+    });
+
+    DOM.On(".pages-tree tr[draggable]", "drop", function(e) {
+        e.preventDefault();
+        if (dragged === null || dragged === this || !samePageParent(dragged, this)) {
+            return;
+        }
+
+        // Move the dragged row and its children before the target
+        var rows = pageSubtree(dragged);
+        for (var i = 0; i < rows.length; i++) {
+            this.parentNode.insertBefore(rows[i], this);
+        }
+
+        // Post the ids of the pages with this parent in their new order
+        var ids = [];
+        var parent = dragged.getAttribute("data-parent");
+        DOM.Each(".pages-tree tr[draggable]", function(el) {
+            if (el.getAttribute("data-parent") == parent) {
+                ids.push(el.getAttribute("data-id"));
+            }
+        });
+        var data = "authenticity_token=" + authenticityToken() + "&ids=" + ids.join(",");
+        DOM.Post("/pages/reorder", data, function(request) {}, function(request) {
+            console.log("error reordering pages:", request);
+            window.location.reload();
+        });
+    });
+}
+
+// samePageParent returns true if the rows a and b have the same parent page.
+function samePageParent(a, b) {
+    return a.getAttribute("data-parent") == b.getAttribute("data-parent");
+}
+
+// pageSubtree returns the row given followed by the rows of its descendants.
+function pageSubtree(row) {
+    var rows = [row];
+    var depth = parseInt(row.getAttribute("data-depth"), 10);
+    for (var next = row.nextElementSibling; next !== null; next = next.nextElementSibling) {
+        if (parseInt(next.getAttribute("data-depth"), 10) <= depth) {
+            break;
+        }
+        rows.push(next);
+    }
+    return rows;
+}
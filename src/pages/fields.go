@@ -0,0 +1,135 @@
+package pages
+
+import (
+	"github.com/fragmenta/view/helpers"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/fields"
+	"github.com/fragmenta/fragmenta-cms/src/lib/sanitize"
+	"github.com/fragmenta/fragmenta-cms/src/lib/theme"
+)
+
+// FieldDefinitions returns the custom fields declared by the page template.
+func (p *Page) FieldDefinitions() []fields.Field {
+	if p.fieldDefinitions == nil {
+		t, err := theme.Find(TemplatesDir, p.ShowTemplate())
+		if err != nil {
+			return nil
+		}
+		p.fieldDefinitions = t.Fields
+	}
+	return p.fieldDefinitions
+}
+
+// FieldValues returns the values stored for custom fields.
+func (p *Page) FieldValues() fields.Values {
+	return fields.Decode(p.Fields)
+}
+
+// Field returns the value of the custom field with name for use in templates,
+// or the default if no value is set. Rich text is sanitized, images are an
+// *images.Image or nil, links are a fields.LinkValue and groups are a list
+// of rows, for example:
+//
+//	{{ with .page.Field "hero_image" }}<img src="{{ .Path }}">{{ end }}
+//	{{ range .page.Field "features" }}<h3>{{ .title }}</h3>{{ .text }}{{ end }}
+//
+// Values for fields no longer declared by the template are returned as stored.
+func (p *Page) Field(name string) interface{} {
+	values := p.FieldValues()
+	f, ok := fields.Find(p.FieldDefinitions(), name)
+	if !ok {
+		if v, ok := values[name]; ok {
+			return v
+		}
+		return ""
+	}
+	return resolveField(f, f.Value(values))
+}
+
+// resolveField returns the value v of field f ready for use in templates.
+func resolveField(f fields.Field, v interface{}) interface{} {
+	switch f.Type {
+	case fields.RichText:
+		return sanitize.Content(v.(string))
+	case fields.Image:
+		id := v.(int64)
+		if id == 0 {
+			return nil
+		}
+		image, err := images.Find(id)
+		if err != nil {
+			return nil
+		}
+		return image
+	case fields.Group:
+		rows := v.([]map[string]interface{})
+		for _, row := range rows {
+			for _, rf := range f.Fields {
+				row[rf.Name] = resolveField(rf, row[rf.Name])
+			}
+		}
+		return rows
+	}
+	return v
+}
+
+// FieldInputs returns inputs for editing the custom fields of the page template.
+func (p *Page) FieldInputs() []fields.Input {
+	inputs := fields.Inputs(p.FieldDefinitions(), p.FieldValues())
+
+	var options []helpers.Option
+	imageList, err := images.FindAll(images.Query().Order("name asc"))
+	if err == nil {
+		for _, i := range imageList {
+			options = append(options, helpers.Option{Id: i.ID, Name: i.Name})
+		}
+	}
+	setImageOptions(inputs, options)
+
+	return inputs
+}
+
+// setImageOptions sets the options for image inputs, including those in groups.
+func setImageOptions(inputs []fields.Input, options []helpers.Option) {
+	for i := range inputs {
+		if inputs[i].Type == fields.Image {
+			inputs[i].Options = options
+		}
+		for _, row := range inputs[i].Rows {
+			setImageOptions(row, options)
+		}
+	}
+}
+
+// ReadFields returns the custom field values for the page template read
+// with param, encoded for storage, or an error if a value is invalid.
+// Rich text is cleaned for the user, as the text of the page is.
+func (p *Page) ReadFields(param func(string) string, u sanitize.User) (string, error) {
+	definitions := p.FieldDefinitions()
+	values, err := fields.Read(definitions, param, p.FieldValues())
+	if err != nil {
+		return "", err
+	}
+	cleanFields(definitions, values, u)
+	return values.Encode()
+}
+
+// cleanFields cleans the html of rich text fields in values for the user,
+// including those in the rows of groups.
+func cleanFields(definitions []fields.Field, values map[string]interface{}, u sanitize.User) {
+	for _, f := range definitions {
+		switch v := values[f.Name].(type) {
+		case string:
+			if f.Type == fields.RichText {
+				values[f.Name] = sanitize.HTML(v, u)
+			}
+		case []interface{}:
+			for _, row := range v {
+				if r, ok := row.(map[string]interface{}); ok {
+					cleanFields(f.Fields, r, u)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package pages
+
+import (
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fragmenta/view/helpers"
+)
+
+// MaxDepth is the maximum depth of pages below the top level.
+const MaxDepth = 8
+
+// Tree arranges the pages given with each page followed by its children,
+// ordered by sort order then name, and sets their depth. Pages whose
+// parent is not included are shown at the top level.
+func Tree(list []*Page) []*Page {
+	byID := make(map[int64]bool)
+	for _, p := range list {
+		byID[p.ID] = true
+	}
+
+	children := make(map[int64][]*Page)
+	for _, p := range list {
+		parent := p.ParentID
+		if !byID[parent] || parent == p.ID {
+			parent = 0
+		}
+		children[parent] = append(children[parent], p)
+	}
+	for _, c := range children {
+		sort.SliceStable(c, func(i, j int) bool {
+			if c[i].Sort != c[j].Sort {
+				return c[i].Sort < c[j].Sort
+			}
+			return c[i].Name < c[j].Name
+		})
+	}
+
+	var tree []*Page
+	seen := make(map[int64]bool)
+	var add func(parent int64, depth int)
+	add = func(parent int64, depth int) {
+		for _, p := range children[parent] {
+			if seen[p.ID] {
+				continue
+			}
+			seen[p.ID] = true
+			p.Depth = depth
+			tree = append(tree, p)
+			add(p.ID, depth+1)
+		}
+	}
+	add(0, 0)
+
+	// Add any pages in a loop of parents at the top level
+	for _, p := range list {
+		if !seen[p.ID] {
+			seen[p.ID] = true
+			p.Depth = 0
+			tree = append(tree, p)
+		}
+	}
+
+	return tree
+}
+
+// Parent returns the parent of this page, or nil if it has none.
+func (p *Page) Parent() *Page {
+	if p.ParentID == 0 {
+		return nil
+	}
+	parent, err := Find(p.ParentID)
+	if err != nil {
+		return nil
+	}
+	return parent
+}
+
+// Ancestors returns the parents of this page, starting at the top level,
+// for use in breadcrumbs.
+func (p *Page) Ancestors() []*Page {
+	var ancestors []*Page
+	seen := map[int64]bool{p.ID: true}
+	for parent := p.Parent(); parent != nil && !seen[parent.ID]; parent = parent.Parent() {
+		seen[parent.ID] = true
+		ancestors = append([]*Page{parent}, ancestors...)
+	}
+	return ancestors
+}
+
+// Children returns the published children of this page, in sort order.
+func (p *Page) Children() []*Page {
+	children, err := FindAll(Published().Where("parent_id=?", p.ID))
+	if err != nil {
+		return nil
+	}
+	return children
+}
+
+// descendantIDs returns the ids of all pages below this page.
+func (p *Page) descendantIDs() (map[int64]bool, error) {
+	list, err := FindAll(Query())
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[int64]bool{p.ID: true}
+	for changed := true; changed; {
+		changed = false
+		for _, c := range list {
+			if ids[c.ParentID] && !ids[c.ID] {
+				ids[c.ID] = true
+				changed = true
+			}
+		}
+	}
+	delete(ids, p.ID)
+	return ids, nil
+}
+
+// ParentOptions returns the pages which may be chosen as the parent of this
+// page, excluding the page itself and its descendants, indented to show the tree.
+func (p *Page) ParentOptions() []helpers.Option {
+	options := []helpers.Option{{Id: 0, Name: "None"}}
+
+	list, err := FindAll(Query())
+	if err != nil {
+		return options
+	}
+	excluded, err := p.descendantIDs()
+	if err != nil {
+		return options
+	}
+
+	for _, o := range Tree(list) {
+		if o.ID == p.ID || excluded[o.ID] {
+			continue
+		}
+		options = append(options, helpers.Option{Id: o.ID, Name: strings.Repeat("— ", o.Depth) + o.Name})
+	}
+	return options
+}
+
+// ValidateParent returns the page with parentID if it may be chosen as the
+// parent of this page, or an error if it is not found, is this page or one
+// of its descendants, or would place this page too deep in the tree.
+func (p *Page) ValidateParent(parentID int64) (*Page, error) {
+	parent, err := Find(parentID)
+	if err != nil {
+		return nil, errors.New("pages: parent page not found")
+	}
+	if parent.ID == p.ID {
+		return nil, errors.New("pages: a page cannot be its own parent")
+	}
+	if p.ID != 0 {
+		excluded, err := p.descendantIDs()
+		if err != nil {
+			return nil, err
+		}
+		if excluded[parent.ID] {
+			return nil, errors.New("pages: a page cannot be placed below its own children")
+		}
+	}
+	if len(parent.Ancestors()) >= MaxDepth {
+		return nil, errors.New("pages: the parent page is too deep")
+	}
+	return parent, nil
+}
+
+// ChildURL returns the url for a page below parent, prefixing the last
+// segment of url with the parent url, or using a slug of name if url is empty.
+func ChildURL(parent *Page, url, name string) string {
+	segment := path.Base("/" + strings.Trim(url, "/"))
+	if segment == "/" {
+		segment = parent.ToSlug(name)
+	}
+	return strings.TrimSuffix(parent.URL, "/") + "/" + segment
+}
+
+// UpdateChildURLs updates the urls of the children of this page to start with
+// its url, and their children in turn, it should be called after the url
+// changes. The urls changed are returned, mapping old urls to new.
+func (p *Page) UpdateChildURLs() (map[string]string, error) {
+	changed := make(map[string]string)
+	err := p.updateChildURLs(0, changed)
+	return changed, err
+}
+
+// updateChildURLs updates the urls of children of this page at depth below the
+// page first updated, stopping at MaxDepth in case of a loop of parents.
+func (p *Page) updateChildURLs(depth int, changed map[string]string) error {
+	if depth > MaxDepth {
+		return nil
+	}
+	children, err := FindAll(Where("parent_id=?", p.ID))
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		url := ChildURL(p, c.URL, c.Name)
+		if url == c.URL {
+			continue
+		}
+		err = c.Update(map[string]string{"url": url})
+		if err != nil {
+			return err
+		}
+		changed[c.URL] = url
+		c.URL = url
+		err = c.updateChildURLs(depth+1, changed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reorder sets the sort order of the pages with ids to their position in the list.
+func Reorder(ids []int64) error {
+	for i, id := range ids {
+		page, err := Find(id)
+		if err != nil {
+			return err
+		}
+		err = page.Update(map[string]string{"sort": strconv.Itoa(i)})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
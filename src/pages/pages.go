@@ -2,10 +2,16 @@
 package pages
 
 import (
+	"html/template"
+
 	"github.com/fragmenta/view/helpers"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/fields"
+	"github.com/fragmenta/fragmenta-cms/src/lib/format"
 	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+	"github.com/fragmenta/fragmenta-cms/src/lib/theme"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 )
 
 // Page handles saving and retreiving pages from the database
@@ -16,13 +22,33 @@ type Page struct {
 	// status.ResourceStatus defines a status field and associated behaviour
 	status.ResourceStatus
 
+	// visibility.ResourceVisibility defines who may see the resource
+	visibility.ResourceVisibility
+
+	// format.ResourceFormat defines the format of the text, html or markdown
+	format.ResourceFormat
+
 	AuthorID int64
+	Fields   string
 	Keywords string
 	Name     string
+	ParentID int64
+	Sort     int64
 	Summary  string
 	Template string
 	Text     string
 	URL      string
+
+	// Depth is set when pages are arranged in a tree
+	Depth int
+
+	// fieldDefinitions caches the custom fields declared by the template
+	fieldDefinitions []fields.Field
+}
+
+// Content returns the html for the text of the page in its format.
+func (p *Page) Content() template.HTML {
+	return format.Render(p.Text, p.Format)
 }
 
 // ShowURL returns our canonical url for showing the page
@@ -30,20 +56,47 @@ func (p *Page) ShowURL() string {
 	return p.URL
 }
 
-// ShowTemplate returns the default template if none is set, or the template selected
+// TemplatesDir is the directory searched for templates for showing pages, in src and the theme.
+const TemplatesDir = "pages/views/templates"
+
+// DefaultTemplate is the template used to show pages if none is set.
+const DefaultTemplate = TemplatesDir + "/" + theme.DefaultName
+
+// ShowTemplate returns the template selected, or the default template
+// if none is set or the template selected no longer exists
 func (p *Page) ShowTemplate() string {
-	if p.Template == "" {
-		return "pages/views/templates/default.html.got"
+	if p.Template == "" || !theme.Exists(TemplatesDir, p.Template) {
+		return DefaultTemplate
 	}
 	return p.Template
 }
 
-// TemplateOptions provides a set of options for the templates menu
-// ids are indexes into the templates array above
+// Templates returns the templates available for showing pages.
+func (p *Page) Templates() []theme.Template {
+	templates, err := theme.Templates(TemplatesDir)
+	if err != nil {
+		return nil
+	}
+	return templates
+}
+
+// TemplateOptions provides a set of options for the templates menu,
+// from the templates found in src and the theme
 func (p *Page) TemplateOptions() []helpers.Selectable {
 	var options []helpers.Selectable
 
-	options = append(options, helpers.SelectableOption{Value: "pages/views/templates/default.html.got", Name: "Default"})
+	for _, t := range p.Templates() {
+		options = append(options, helpers.SelectableOption{Value: t.Path, Name: t.Name})
+	}
 
 	return options
 }
+
+// ValidateTemplate returns an error if the template given is not empty and does not exist.
+func ValidateTemplate(path string) error {
+	if path == "" {
+		return nil
+	}
+	_, err := theme.Find(TemplatesDir, path)
+	return err
+}
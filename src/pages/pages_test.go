@@ -137,3 +137,60 @@ func TestAllowedParams(t *testing.T) {
 		t.Fatalf("pages: no allowed params")
 	}
 }
+
+// TestTree tests pages are arranged with children below their parents.
+func TestTree(t *testing.T) {
+	page := func(id, parentID, sort int64, name string) *Page {
+		p := New()
+		p.ID, p.ParentID, p.Sort, p.Name = id, parentID, sort, name
+		return p
+	}
+
+	list := []*Page{
+		page(1, 0, 1, "About"),
+		page(2, 1, 0, "Team"),
+		page(3, 0, 0, "Home"),
+		page(4, 2, 0, "Jobs"),
+		page(5, 1, 0, "History"),
+		page(6, 99, 0, "Orphan"),
+	}
+
+	tree := Tree(list)
+	var got []int64
+	for _, p := range tree {
+		got = append(got, p.ID)
+	}
+
+	want := []int64{3, 6, 1, 5, 2, 4}
+	for i := range want {
+		if len(got) != len(want) || got[i] != want[i] {
+			t.Fatalf("pages: Tree unexpected order expected:%v got:%v", want, got)
+		}
+	}
+	if tree[1].Depth != 0 || tree[4].Depth != 1 || tree[5].Depth != 2 {
+		t.Fatalf("pages: Tree unexpected depth")
+	}
+}
+
+// TestChildURL tests urls of child pages are prefixed with the parent url.
+func TestChildURL(t *testing.T) {
+	parent := New()
+	parent.URL = "/about"
+
+	tests := map[string]string{
+		"/team":          "/about/team",
+		"/company/team/": "/about/team",
+		"":               "/about/our-team",
+	}
+	for url, want := range tests {
+		got := ChildURL(parent, url, "Our Team")
+		if got != want {
+			t.Fatalf("pages: ChildURL for %s expected:%s got:%s", url, want, got)
+		}
+	}
+
+	parent.URL = "/"
+	if got := ChildURL(parent, "/team", ""); got != "/team" {
+		t.Fatalf("pages: ChildURL below home expected:/team got:%s", got)
+	}
+}
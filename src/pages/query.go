@@ -15,12 +15,12 @@ const (
 	// KeyName is the primary key value for this resource
 	KeyName = "id"
 	// Order defines the default sort order in sql for this resource
-	Order = "name asc, id desc"
+	Order = "sort asc, name asc, id desc"
 )
 
 // AllowedParams returns an array of allowed param keys for Update and Create.
 func AllowedParams() []string {
-	return []string{"status", "author_id", "keywords", "name", "status", "summary", "template", "text", "url"}
+	return []string{"status", "author_id", "format", "fields", "keywords", "name", "parent_id", "sort", "status", "summary", "template", "text", "url", "visibility", "visible_roles"}
 }
 
 // NewWithColumns creates a new page instance and fills it with data from the database cols provided.
@@ -32,12 +32,18 @@ func NewWithColumns(cols map[string]interface{}) *Page {
 	page.UpdatedAt = resource.ValidateTime(cols["updated_at"])
 	page.Status = resource.ValidateInt(cols["status"])
 	page.AuthorID = resource.ValidateInt(cols["author_id"])
+	page.Fields = resource.ValidateString(cols["fields"])
+	page.Format = resource.ValidateInt(cols["format"])
 	page.Keywords = resource.ValidateString(cols["keywords"])
 	page.Name = resource.ValidateString(cols["name"])
+	page.ParentID = resource.ValidateInt(cols["parent_id"])
+	page.Sort = resource.ValidateInt(cols["sort"])
 	page.Status = resource.ValidateInt(cols["status"])
 	page.Summary = resource.ValidateString(cols["summary"])
 	page.Template = resource.ValidateString(cols["template"])
 	page.Text = resource.ValidateString(cols["text"])
+	page.Visibility = resource.ValidateInt(cols["visibility"])
+	page.VisibleRoles = resource.ValidateString(cols["visible_roles"])
 	page.URL = resource.ValidateString(cols["url"])
 
 	return page
@@ -51,7 +57,7 @@ func New() *Page {
 	page.TableName = TableName
 	page.KeyName = KeyName
 	page.Status = status.Draft
-	page.Template = "pages/views/templates/default.html.got"
+	page.Template = DefaultTemplate
 	return page
 }
 
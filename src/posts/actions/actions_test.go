@@ -1,79 +1,54 @@
-package postactions
+package postactions_test
 
 import (
 	"fmt"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/fragmenta/mux"
-	"github.com/fragmenta/query"
 
-	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // names is used to test setting and getting the first string field of the post.
 var names = []string{"foo", "bar"}
 
-// testSetup performs setup for integration tests
-// using the test database, real views, and mock authorisation
-// If we can run this once for global tests it might be more efficient?
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
 func TestSetup(t *testing.T) {
-	err := resource.SetupTestDatabase(3)
+	var err error
+	router, err = apptest.Setup()
 	if err != nil {
-		fmt.Printf("posts: Setup db failed %s", err)
-	}
-
-	// Set up mock auth
-	resource.SetupAuthorisation()
-
-	// Load templates for rendering
-	resource.SetupView(3)
-
-	router := mux.New()
-	mux.SetDefault(router)
-
-	// FIXME - Need to write routes out here again, but without pkg prefix
-	// Any neat way to do this instead? We'd need a separate routes package under app...
-	router.Add("/posts", nil)
-	router.Add("/posts/create", nil)
-	router.Add("/posts/create", nil).Post()
-	router.Add("/posts/login", nil)
-	router.Add("/posts/login", nil).Post()
-	router.Add("/posts/login", nil).Post()
-	router.Add("/posts/logout", nil).Post()
-	router.Add("/posts/{id:\\d+}/update", nil)
-	router.Add("/posts/{id:\\d+}/update", nil).Post()
-	router.Add("/posts/{id:\\d+}/destroy", nil).Post()
-	router.Add("/posts/{id:\\d+}", nil)
-
-	// Delete all posts to ensure we get consistent results
-	query.ExecSQL("delete from posts;")
-	query.ExecSQL("ALTER SEQUENCE posts_id_seq RESTART WITH 1;")
+		t.Fatalf("postactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("postactions: error creating admin %s", err)
+	}
 }
 
 // Test GET /posts/create
 func TestShowCreatePost(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/posts/create", nil)
-	w := httptest.NewRecorder()
-
-	// Set up post session cookie for admin post above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleCreateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/posts/create", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("postactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("postactions: error handling HandleCreateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -89,20 +64,8 @@ func TestCreatePost(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("name", names[0])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/posts/create", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
 
-	// Set up post session cookie for admin post
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
-	}
-
-	// Run the handler to update the post
-	err = HandleCreate(w, r)
+	w, err := apptest.Request(router, "POST", "/posts/create", form, admin)
 	if err != nil {
 		t.Fatalf("postactions: error handling HandleCreate %s", err)
 	}
@@ -112,7 +75,7 @@ func TestCreatePost(t *testing.T) {
 		t.Fatalf("postactions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
 	}
 
-	// Check the post name is in now value names[1]
+	// Check the post name is in now value names[0]
 	allPost, err := posts.FindAll(posts.Query().Order("id desc"))
 	if err != nil || len(allPost) == 0 {
 		t.Fatalf("postactions: error finding created post %s", err)
@@ -126,22 +89,11 @@ func TestCreatePost(t *testing.T) {
 // Test GET /posts
 func TestListPost(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/posts", nil)
-	w := httptest.NewRecorder()
-
-	// Set up post session cookie for admin post above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleIndex(w, r)
+	w, err := apptest.Request(router, "GET", "/posts", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("postactions: error handling HandleIndex %s", err)
+		t.Fatalf("postactions: error handling HandleIndex %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -155,22 +107,11 @@ func TestListPost(t *testing.T) {
 // Test of GET /posts/1
 func TestShowPost(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/posts/1", nil)
-	w := httptest.NewRecorder()
-
-	// Set up post session cookie for admin post above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleShow(w, r)
+	w, err := apptest.Request(router, "GET", "/posts/1", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("postactions: error handling HandleShow %s", err)
+		t.Fatalf("postactions: error handling HandleShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -180,31 +121,58 @@ func TestShowPost(t *testing.T) {
 	}
 }
 
-// Test GET /posts/123/update
-func TestShowUpdatePost(t *testing.T) {
+// Test posts for logged in users are hidden from anon on the blog, and show a teaser
+func TestRestrictedPost(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/posts/1/update", nil)
-	w := httptest.NewRecorder()
+	post, err := apptest.CreatePost(map[string]string{
+		"name":       "Members news",
+		"text":       "<p>Secret</p>",
+		"visibility": fmt.Sprintf("%d", visibility.Members),
+	})
+	if err != nil {
+		t.Fatalf("postactions: error creating post %s", err)
+	}
 
-	// Set up post session cookie for admin post above
-	err := resource.AddUserSessionCookie(w, r, 1)
+	reader, err := apptest.CreateUser(nil)
 	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
+		t.Fatalf("postactions: error creating reader %s", err)
+	}
+
+	w, err := apptest.Request(router, "GET", "/blog", nil, nil)
+	if err != nil || w.Code != http.StatusOK || strings.Contains(w.Body.String(), post.Name) {
+		t.Fatalf("postactions: restricted post listed on blog for anon %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", "/blog", nil, reader)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), post.Name) {
+		t.Fatalf("postactions: restricted post not listed on blog for reader %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", post.ShowURL(), nil, nil)
+	if err != nil || w.Code != http.StatusOK || strings.Contains(w.Body.String(), "Secret") || !strings.Contains(w.Body.String(), "/users/login") {
+		t.Fatalf("postactions: unexpected teaser for restricted post %v %d", err, w.Code)
+	}
+
+	w, err = apptest.Request(router, "GET", post.ShowURL(), nil, reader)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Secret") {
+		t.Fatalf("postactions: restricted post not shown to reader %v %d", err, w.Code)
 	}
+}
+
+// Test GET /posts/123/update
+func TestShowUpdatePost(t *testing.T) {
 
-	// Run the handler
-	err = HandleUpdateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/posts/1/update", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("postactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("postactions: error handling HandleUpdateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
 	pattern := "resource-update-form"
 	if !strings.Contains(w.Body.String(), pattern) {
-		t.Fatalf("postactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+		t.Fatalf("postactions: unexpected response for HandleUpdateShow expected:%s got:%s", pattern, w.Body.String())
 	}
 
 }
@@ -214,20 +182,8 @@ func TestUpdatePost(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("name", names[1])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/posts/1/update", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up post session cookie for admin post
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
-	}
 
-	// Run the handler to update the post
-	err = HandleUpdate(w, r)
+	w, err := apptest.Request(router, "POST", "/posts/1/update", form, admin)
 	if err != nil {
 		t.Fatalf("postactions: error handling HandleUpdatePost %s", err)
 	}
@@ -246,28 +202,27 @@ func TestUpdatePost(t *testing.T) {
 		t.Fatalf("postactions: error with updated post values: %v", post)
 	}
 
+	// Test the old url and urls without the slug redirect to the new url
+	for _, old := range []string{"/blog/1-" + names[0], "/blog/1"} {
+		w, err = apptest.Request(router, "GET", old, nil, admin)
+		if err != nil || w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != post.ShowURL() {
+			t.Fatalf("postactions: old url %s not redirected %v %d", old, err, w.Code)
+		}
+	}
+
 }
 
 // Test of POST /posts/123/destroy
 func TestDeletePost(t *testing.T) {
 
-	body := strings.NewReader(``)
-
-	// Now test deleting the post created above as admin
-	r := httptest.NewRequest("POST", "/posts/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up post session cookie for admin post
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("postactions: error setting session %s", err)
+	// Test deleting the post created above as anon
+	w, err := apptest.Request(router, "POST", "/posts/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("postactions: unexpected response for HandleDestroy as anon, expected failure")
 	}
 
-	// Run the handler
-	err = HandleDestroy(w, r)
-
-	// Test the error response is 302 StatusFound
+	// Now test deleting the post as admin
+	w, err = apptest.Request(router, "POST", "/posts/1/destroy", nil, admin)
 	if err != nil {
 		t.Fatalf("postactions: error handling HandleDestroy %s", err)
 	}
@@ -276,15 +231,55 @@ func TestDeletePost(t *testing.T) {
 	if w.Code != http.StatusFound {
 		t.Fatalf("postactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
 	}
-	// Now test as anon
-	r = httptest.NewRequest("POST", "/posts/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w = httptest.NewRecorder()
 
-	// Run the handler to test failure as anon
-	err = HandleDestroy(w, r)
-	if err == nil { // failure expected
-		t.Fatalf("postactions: unexpected response for HandleDestroy as anon, expected failure")
+	_, err = posts.Find(1)
+	if err == nil {
+		t.Fatalf("postactions: post found after HandleDestroy")
 	}
 
 }
+
+// Test posts show a byline for their author
+func TestPostByline(t *testing.T) {
+
+	author, err := apptest.CreateUser(map[string]string{
+		"name":    "Ada Lovelace",
+		"title":   "Analyst",
+		"summary": "Writes about engines & numbers",
+	})
+	if err != nil {
+		t.Fatalf("postactions: error creating author %s", err)
+	}
+
+	post, err := apptest.CreatePost(map[string]string{"author_id": fmt.Sprintf("%d", author.ID)})
+	if err != nil {
+		t.Fatalf("postactions: error creating post %s", err)
+	}
+
+	w, err := apptest.Request(router, "GET", post.ShowURL(), nil, nil)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("postactions: error handling HandleShow for byline %v %d", err, w.Code)
+	}
+
+	for _, pattern := range []string{
+		`<span class="byline-name">Ada Lovelace</span>`,
+		`<span class="byline-title">Analyst</span>`,
+		`<p class="byline-summary">Writes about engines &amp; numbers</p>`,
+		`avatar-initials`,
+		`>AL</span>`,
+	} {
+		if !strings.Contains(w.Body.String(), pattern) {
+			t.Fatalf("postactions: unexpected byline expected:%s got:%s", pattern, w.Body.String())
+		}
+	}
+
+	// Posts by users since deleted have no byline
+	post, err = apptest.CreatePost(map[string]string{"author_id": "9999"})
+	if err != nil {
+		t.Fatalf("postactions: error creating post %s", err)
+	}
+	w, err = apptest.Request(router, "GET", post.ShowURL(), nil, nil)
+	if err != nil || w.Code != http.StatusOK || strings.Contains(w.Body.String(), `class="byline"`) {
+		t.Fatalf("postactions: unexpected response for post without author %v %d", err, w.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package postactions
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/format"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/posts"
+)
+
+// HandlePreview responds to POST /posts/preview with the html for the markdown
+// text posted, for the preview shown beside the text in the post form.
+func HandlePreview(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise create post
+	user := session.CurrentUser(w, r)
+	err = can.Create(posts.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Fetch the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err = io.WriteString(w, string(format.Render(params.Get("text"), format.Markdown)))
+	return err
+}
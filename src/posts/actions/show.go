@@ -2,14 +2,20 @@ package postactions
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/fragmenta/auth/can"
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/comments"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // HandleShow displays a single post.
@@ -37,14 +43,64 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
-	// Render the template
+	// Redirect blog urls with a stale or missing slug to the canonical url
+	if strings.HasPrefix(r.URL.Path, "/blog/") && r.URL.Path != post.ShowURL() {
+		url := post.ShowURL()
+		if r.URL.RawQuery != "" {
+			url += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, url, http.StatusMovedPermanently)
+		return nil
+	}
+
+	// Show a teaser to users who may not see the post
+	if !post.VisibleTo(user) {
+		return visibility.RenderRestricted(w, r, user, post.Name, post.Summary)
+	}
+
+	// Fetch the approved comments on the post, arranged in threads
+	list, err := comments.FindAll(comments.ApprovedFor(post.ID))
+	if err != nil {
+		return server.InternalError(err)
+	}
+	threads := comments.Thread(list)
+
+	// Find the comment being replied to, if any
+	var replyTo *comments.Comment
+	for _, c := range list {
+		if c.ID == params.GetInt("reply") && c.CanReply() {
+			replyTo = c
+		}
+	}
+
+	// Find the author for the byline, posts by users since deleted have none
+	var author *users.User
+	if post.AuthorID > 0 {
+		author, err = users.Find(post.AuthorID)
+		if err != nil {
+			author = nil
+		}
+	}
+
+	// Readers may comment, and visitors if anonymous comments are enabled
+	commentsOpen := can.Create(comments.New(), user) == nil
+	if user.Anon() {
+		commentsOpen = settings.Current.Comments.Anon
+	}
+
+	// Render the template, which is not cached as comments depend on the user
 	view := view.NewRenderer(w, r)
-	view.CacheKey(post.CacheKey())
 	view.AddKey("currentUser", user)
 	view.AddKey("post", post)
+	view.AddKey("content", shortcodes.Render(post.Content(), r, user))
+	view.AddKey("author", author)
+	view.AddKey("comments", threads)
+	view.AddKey("commentsOpen", commentsOpen)
+	view.AddKey("replyTo", replyTo)
+	view.AddKey("honeypot", comments.Honeypot)
 	view.AddKey("meta_title", post.Name)
 	view.AddKey("meta_keywords", post.Keywords)
 	view.AddKey("meta_desc", post.Summary)
-	view.Template("posts/views/templates/default.html.got")
+	view.Template(post.ShowTemplate())
 	return view.Render()
 }
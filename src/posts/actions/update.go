@@ -8,8 +8,11 @@ import (
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/format"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 	"github.com/fragmenta/fragmenta-cms/src/posts"
+	"github.com/fragmenta/fragmenta-cms/src/redirects"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -77,6 +80,29 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
+	// Check the template chosen exists
+	err = posts.ValidateTemplate(params.Get("template"))
+	if err != nil {
+		return server.BadRequestError(err, "Invalid template", "Please choose a template which exists.")
+	}
+
+	// Clean the text for the user in the format chosen, converting it if the format has changed
+	to := post.Format
+	if _, ok := params.Values["format"]; ok {
+		to = params.GetInt("format")
+	}
+	_, hasText := params.Values["text"]
+	if hasText || to != post.Format {
+		text := post.Text
+		if hasText {
+			text = params.Get("text")
+		}
+		params.SetString("text", format.Text(text, post.Format, to, user))
+	}
+
+	// Store the roles selected for visibility as a list of role ids
+	params.SetString("visible_roles", visibility.RolesParam(params.Values["visible_roles"]))
+
 	// Validate the params, removing any we don't accept
 	postParams := post.ValidateParams(params.Map(), posts.AllowedParams())
 
@@ -85,6 +111,16 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Remove any redirects from the new url, old urls are redirected by id on show
+	post, err = posts.Find(post.ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	err = redirects.Remove(post.ShowURL())
+	if err != nil {
+		return server.InternalError(err)
+	}
+
 	// Redirect to post
 	return server.Redirect(w, r, post.ShowURL())
 }
@@ -0,0 +1,35 @@
+package posts
+
+import (
+	"html/template"
+
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/shortcodes"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
+)
+
+// Shortcode returns the html for shortcodes which list recent posts in content
+// such as [[posts limit="5"]], including only the posts the user may see.
+func Shortcode(s *shortcodes.Shortcode) (template.HTML, error) {
+	limit := s.AttrInt("limit", 5)
+	if limit < 1 || limit > 50 {
+		limit = 5
+	}
+
+	q := Published().Order("created_at desc").Limit(int(limit))
+	visibility.WhereVisibleTo(q, s.User)
+	list, err := FindAll(q)
+	if err != nil {
+		return "", err
+	}
+
+	view := view.NewWithPath("", nil)
+	view.Template("posts/views/embed.html.got")
+	view.AddKey("posts", list)
+	html, err := view.RenderToString()
+	if err != nil {
+		return "", err
+	}
+	return template.HTML(html), nil
+}
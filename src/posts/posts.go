@@ -3,10 +3,15 @@ package posts
 
 import (
 	"fmt"
+	"html/template"
+
 	"github.com/fragmenta/view/helpers"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/format"
 	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+	"github.com/fragmenta/fragmenta-cms/src/lib/theme"
+	"github.com/fragmenta/fragmenta-cms/src/lib/visibility"
 )
 
 // Post handles saving and retreiving posts from the database
@@ -17,12 +22,21 @@ type Post struct {
 	// status.ResourceStatus defines a status field and associated behaviour
 	status.ResourceStatus
 
+	// visibility.ResourceVisibility defines who may see the resource
+	visibility.ResourceVisibility
+
+	// format.ResourceFormat defines the format of the text, html or markdown
+	format.ResourceFormat
+
 	AuthorID int64
 	Keywords string
 	Name     string
 	Summary  string
 	Template string
 	Text     string
+
+	// CommentCount is the number of approved comments, kept up to date by the comments pkg
+	CommentCount int64
 }
 
 // ShowURL returns our canonical url for showing the post, including slug
@@ -30,20 +44,60 @@ func (p *Post) ShowURL() string {
 	return fmt.Sprintf("/blog/%d-%s", p.ID, p.ToSlug(p.Name))
 }
 
-// ShowTemplate returns the default template if none is set, or the template selected
+// Content returns the html for the text of the post in its format.
+func (p *Post) Content() template.HTML {
+	return format.Render(p.Text, p.Format)
+}
+
+// CommentCountDisplay returns the number of approved comments on the post for display.
+func (p *Post) CommentCountDisplay() string {
+	if p.CommentCount == 1 {
+		return "1 comment"
+	}
+	return fmt.Sprintf("%d comments", p.CommentCount)
+}
+
+// TemplatesDir is the directory searched for templates for showing posts, in src and the theme.
+const TemplatesDir = "posts/views/templates"
+
+// DefaultTemplate is the template used to show posts if none is set.
+const DefaultTemplate = TemplatesDir + "/" + theme.DefaultName
+
+// ShowTemplate returns the template selected, or the default template
+// if none is set or the template selected no longer exists
 func (p *Post) ShowTemplate() string {
-	if p.Template == "" {
-		return "posts/views/templates/default.html.got"
+	if p.Template == "" || !theme.Exists(TemplatesDir, p.Template) {
+		return DefaultTemplate
 	}
 	return p.Template
 }
 
-// TemplateOptions provides a set of options for the templates menu
-// ids are indexes into the templates array above
+// Templates returns the templates available for showing posts.
+func (p *Post) Templates() []theme.Template {
+	templates, err := theme.Templates(TemplatesDir)
+	if err != nil {
+		return nil
+	}
+	return templates
+}
+
+// TemplateOptions provides a set of options for the templates menu,
+// from the templates found in src and the theme
 func (p *Post) TemplateOptions() []helpers.Selectable {
 	var options []helpers.Selectable
 
-	options = append(options, helpers.SelectableOption{Value: "posts/views/templates/default.html.got", Name: "Default"})
+	for _, t := range p.Templates() {
+		options = append(options, helpers.SelectableOption{Value: t.Path, Name: t.Name})
+	}
 
 	return options
 }
+
+// ValidateTemplate returns an error if the template given is not empty and does not exist.
+func ValidateTemplate(path string) error {
+	if path == "" {
+		return nil
+	}
+	_, err := theme.Find(TemplatesDir, path)
+	return err
+}
@@ -20,7 +20,7 @@ const (
 
 // AllowedParams returns an array of allowed param keys for Update and Create.
 func AllowedParams() []string {
-	return []string{"status", "author_id", "keywords", "name", "status", "summary", "template", "text"}
+	return []string{"status", "author_id", "format", "keywords", "name", "status", "summary", "template", "text", "visibility", "visible_roles"}
 }
 
 // NewWithColumns creates a new post instance and fills it with data from the database cols provided.
@@ -32,12 +32,16 @@ func NewWithColumns(cols map[string]interface{}) *Post {
 	post.UpdatedAt = resource.ValidateTime(cols["updated_at"])
 	post.Status = resource.ValidateInt(cols["status"])
 	post.AuthorID = resource.ValidateInt(cols["author_id"])
+	post.CommentCount = resource.ValidateInt(cols["comment_count"])
+	post.Format = resource.ValidateInt(cols["format"])
 	post.Keywords = resource.ValidateString(cols["keywords"])
 	post.Name = resource.ValidateString(cols["name"])
 	post.Status = resource.ValidateInt(cols["status"])
 	post.Summary = resource.ValidateString(cols["summary"])
 	post.Template = resource.ValidateString(cols["template"])
 	post.Text = resource.ValidateString(cols["text"])
+	post.Visibility = resource.ValidateInt(cols["visibility"])
+	post.VisibleRoles = resource.ValidateString(cols["visible_roles"])
 
 	return post
 }
@@ -50,7 +54,7 @@ func New() *Post {
 	post.TableName = TableName
 	post.KeyName = KeyName
 	post.Status = status.Draft
-	post.Template = "posts/views/templates/default.html.got"
+	post.Template = DefaultTemplate
 	return post
 }
 
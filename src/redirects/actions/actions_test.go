@@ -1,78 +1,51 @@
-package redirectactions
+package redirectactions_test
 
 import (
-	"fmt"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/fragmenta/mux"
-	"github.com/fragmenta/query"
 
-	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
 	"github.com/fragmenta/fragmenta-cms/src/redirects"
+	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 var testURLs = []string{"/foo", "/bar"}
 
-// testSetup performs setup for integration tests
-// using the test database, real views, and mock authorisation
-// If we can run this once for global tests it might be more efficient?
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and an admin user.
 func TestSetup(t *testing.T) {
-	err := resource.SetupTestDatabase(3)
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("redirectactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
 	if err != nil {
-		fmt.Printf("redirects: Setup db failed %s", err)
-	}
-
-	// Set up mock auth
-	resource.SetupAuthorisation()
-
-	// Load templates for rendering
-	resource.SetupView(3)
-
-	router := mux.New()
-	mux.SetDefault(router)
-
-	// FIXME - Need to write routes out here again, but without pkg prefix
-	// Any neat way to do this instead? We'd need a separate routes package under app...
-	router.Add("/redirects", nil)
-	router.Add("/redirects/create", nil)
-	router.Add("/redirects/create", nil).Post()
-	router.Add("/redirects/login", nil)
-	router.Add("/redirects/login", nil).Post()
-	router.Add("/redirects/login", nil).Post()
-	router.Add("/redirects/logout", nil).Post()
-	router.Add("/redirects/{id:\\d+}/update", nil)
-	router.Add("/redirects/{id:\\d+}/update", nil).Post()
-	router.Add("/redirects/{id:\\d+}/destroy", nil).Post()
-	router.Add("/redirects/{id:\\d+}", nil)
-
-	// Delete all redirects to ensure we get consistent results
-	query.ExecSQL("delete from redirects;")
-	query.ExecSQL("ALTER SEQUENCE redirects_id_seq RESTART WITH 1;")
+		t.Fatalf("redirectactions: error creating admin %s", err)
+	}
 }
 
 // Test GET /redirects/create
 func TestShowCreateRedirect(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/redirects/create", nil)
-	w := httptest.NewRecorder()
-
-	// Set up redirect session cookie for admin redirect above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleCreateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/redirects/create", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("redirectactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("redirectactions: error handling HandleCreateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -88,20 +61,8 @@ func TestCreateRedirect(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("new_url", testURLs[0])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/redirects/create", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
 
-	// Set up redirect session cookie for admin redirect
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
-	}
-
-	// Run the handler to update the redirect
-	err = HandleCreate(w, r)
+	w, err := apptest.Request(router, "POST", "/redirects/create", form, admin)
 	if err != nil {
 		t.Fatalf("redirectactions: error handling HandleCreate %s", err)
 	}
@@ -111,12 +72,12 @@ func TestCreateRedirect(t *testing.T) {
 		t.Fatalf("redirectactions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
 	}
 
-	// Check the redirect name is in now value names[1]
-	allRedirects, err := redirects.FindAll(redirects.Query().Order("id desc"))
-	if err != nil || len(allRedirects) == 0 {
+	// Check the redirect url is in now value testURLs[0]
+	allRedirect, err := redirects.FindAll(redirects.Query().Order("id desc"))
+	if err != nil || len(allRedirect) == 0 {
 		t.Fatalf("redirectactions: error finding created redirect %s", err)
 	}
-	newRedirect := allRedirects[0]
+	newRedirect := allRedirect[0]
 	if newRedirect.ID != 1 || newRedirect.NewURL != testURLs[0] {
 		t.Fatalf("redirectactions: error with created redirect values: %v %s", newRedirect.ID, newRedirect.NewURL)
 	}
@@ -125,22 +86,11 @@ func TestCreateRedirect(t *testing.T) {
 // Test GET /redirects
 func TestListRedirects(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/redirects", nil)
-	w := httptest.NewRecorder()
-
-	// Set up redirect session cookie for admin redirect above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleIndex(w, r)
+	w, err := apptest.Request(router, "GET", "/redirects", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("redirectactions: error handling HandleIndex %s", err)
+		t.Fatalf("redirectactions: error handling HandleIndex %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -154,22 +104,11 @@ func TestListRedirects(t *testing.T) {
 // Test of GET /redirects/1
 func TestShowRedirect(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/redirects/1", nil)
-	w := httptest.NewRecorder()
-
-	// Set up redirect session cookie for admin redirect above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleShow(w, r)
+	w, err := apptest.Request(router, "GET", "/redirects/1", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("redirectactions: error handling HandleShow %s", err)
+		t.Fatalf("redirectactions: error handling HandleShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -182,28 +121,17 @@ func TestShowRedirect(t *testing.T) {
 // Test GET /redirects/123/update
 func TestShowUpdateRedirect(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/redirects/1/update", nil)
-	w := httptest.NewRecorder()
-
-	// Set up redirect session cookie for admin redirect above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleUpdateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/redirects/1/update", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Fatalf("redirectactions: error handling HandleCreateShow %s", err)
+		t.Fatalf("redirectactions: error handling HandleUpdateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
 	pattern := "resource-update-form"
 	if !strings.Contains(w.Body.String(), pattern) {
-		t.Fatalf("redirectactions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+		t.Fatalf("redirectactions: unexpected response for HandleUpdateShow expected:%s got:%s", pattern, w.Body.String())
 	}
 
 }
@@ -213,20 +141,8 @@ func TestUpdateRedirect(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("new_url", testURLs[1])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/redirects/1/update", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up redirect session cookie for admin redirect
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
-	}
 
-	// Run the handler to update the redirect
-	err = HandleUpdate(w, r)
+	w, err := apptest.Request(router, "POST", "/redirects/1/update", form, admin)
 	if err != nil {
 		t.Fatalf("redirectactions: error handling HandleUpdateRedirect %s", err)
 	}
@@ -236,7 +152,7 @@ func TestUpdateRedirect(t *testing.T) {
 		t.Fatalf("redirectactions: unexpected response code for HandleUpdateRedirect expected:%d got:%d", http.StatusFound, w.Code)
 	}
 
-	// Check the redirect name is in now value testURLs[1]
+	// Check the redirect url is in now value testURLs[1]
 	redirect, err := redirects.Find(1)
 	if err != nil {
 		t.Fatalf("redirectactions: error finding updated redirect %s", err)
@@ -250,23 +166,14 @@ func TestUpdateRedirect(t *testing.T) {
 // Test of POST /redirects/123/destroy
 func TestDeleteRedirect(t *testing.T) {
 
-	body := strings.NewReader(``)
-
-	// Now test deleting the redirect created above as admin
-	r := httptest.NewRequest("POST", "/redirects/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up redirect session cookie for admin redirect
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Fatalf("redirectactions: error setting session %s", err)
+	// Test deleting the redirect created above as anon
+	w, err := apptest.Request(router, "POST", "/redirects/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("redirectactions: unexpected response for HandleDestroy as anon, expected failure")
 	}
 
-	// Run the handler
-	err = HandleDestroy(w, r)
-
-	// Test the error response is 302 StatusFound
+	// Now test deleting the redirect as admin
+	w, err = apptest.Request(router, "POST", "/redirects/1/destroy", nil, admin)
 	if err != nil {
 		t.Fatalf("redirectactions: error handling HandleDestroy %s", err)
 	}
@@ -275,15 +182,85 @@ func TestDeleteRedirect(t *testing.T) {
 	if w.Code != http.StatusFound {
 		t.Fatalf("redirectactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
 	}
-	// Now test as anon
-	r = httptest.NewRequest("POST", "/redirects/1/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w = httptest.NewRecorder()
 
-	// Run the handler to test failure as anon
-	err = HandleDestroy(w, r)
-	if err == nil { // failure expected
-		t.Fatalf("redirectactions: unexpected response for HandleDestroy as anon, expected failure")
+	_, err = redirects.Find(1)
+	if err == nil {
+		t.Fatalf("redirectactions: redirect found after HandleDestroy")
+	}
+
+}
+
+// Test requests are redirected by the rules added, with their status codes
+func TestRedirectRules(t *testing.T) {
+
+	rules := []url.Values{
+		{"old_url": {"/archive/"}, "new_url": {"/blog/"}, "match_type": {"1"}, "status_code": {"308"}},
+		{"old_url": {"/retired"}, "new_url": {""}, "status_code": {"410"}},
+	}
+	for _, form := range rules {
+		w, err := apptest.Request(router, "POST", "/redirects/create", form, admin)
+		if err != nil || w.Code != http.StatusFound {
+			t.Fatalf("redirectactions: error handling HandleCreate %v %d", err, w.Code)
+		}
+	}
+
+	w, err := apptest.Request(router, "GET", "/archive/2016?page=2", nil, nil)
+	if err != nil || w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/blog/2016?page=2" {
+		t.Fatalf("redirectactions: unexpected response for prefix redirect %v %d %s", err, w.Code, w.Header().Get("Location"))
+	}
+
+	w, err = apptest.Request(router, "GET", "/retired", nil, nil)
+	if err != nil || w.Code != http.StatusGone {
+		t.Fatalf("redirectactions: unexpected response for gone redirect %v %d", err, w.Code)
 	}
 
+	// Test hits are recorded
+	redirect, err := redirects.FindFirst("old_url=?", "/archive/")
+	if err != nil || redirect.Hits != 1 {
+		t.Fatalf("redirectactions: hit not recorded %v %s", redirect, err)
+	}
+
+	// Test invalid rules are rejected
+	form := url.Values{"old_url": {"/(unclosed"}, "new_url": {"/x"}, "match_type": {"2"}}
+	w, err = apptest.Request(router, "POST", "/redirects/create", form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("redirectactions: unexpected response for invalid pattern %v %d", err, w.Code)
+	}
+}
+
+// Test GET /redirects/export and POST /redirects/import
+func TestImportExportRedirects(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/redirects/export", nil, admin)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "/archive/,/blog/,308,prefix,yes") {
+		t.Fatalf("redirectactions: unexpected response for HandleExport %v %d %s", err, w.Code, w.Body.String())
+	}
+
+	w, err = apptest.Request(router, "GET", "/redirects/export", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("redirectactions: unexpected response for HandleExport as anon, expected failure")
+	}
+
+	form := url.Values{"csv": {"old_url,new_url\n/old-about,/about\n/archive/,/posts/\n"}}
+	w, err = apptest.Request(router, "POST", "/redirects/import", form, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("redirectactions: error handling HandleImport %v %d", err, w.Code)
+	}
+
+	// The exact redirect is added, and the existing redirect from /archive/ is not
+	// replaced as it has a different match type
+	w, err = apptest.Request(router, "GET", "/old-about", nil, nil)
+	if err != nil || w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/about" {
+		t.Fatalf("redirectactions: imported redirect not used %v %d", err, w.Code)
+	}
+	list, err := redirects.FindAll(redirects.Where("old_url=?", "/archive/"))
+	if err != nil || len(list) != 2 {
+		t.Fatalf("redirectactions: unexpected redirects after import %d %v", len(list), err)
+	}
+
+	form = url.Values{"csv": {"/x,/x\n"}}
+	w, err = apptest.Request(router, "POST", "/redirects/import", form, admin)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("redirectactions: unexpected response for invalid import %v %d", err, w.Code)
+	}
 }
@@ -24,6 +24,13 @@ func HandleCreateShow(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
+	// Fill in the old url if given, for redirecting paths not found
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	redirect.OldURL = params.Get("old_url")
+
 	// Render the template
 	view := view.NewRenderer(w, r)
 	view.AddKey("currentUser", user)
@@ -55,6 +62,12 @@ func HandleCreate(w http.ResponseWriter, r *http.Request) error {
 		return server.InternalError(err)
 	}
 
+	// Check the redirect is valid and does not form a loop
+	err = redirect.ValidateRule(params.Map())
+	if err != nil {
+		return server.BadRequestError(err, "Invalid redirect", err.Error())
+	}
+
 	// Validate the params, removing any we don't accept
 	redirectParams := redirect.ValidateParams(params.Map(), redirects.AllowedParams())
 
@@ -0,0 +1,32 @@
+package redirectactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/redirects"
+)
+
+// HandleExport responds to GET /redirects/export with the redirects as csv.
+func HandleExport(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list redirect
+	user := session.CurrentUser(w, r)
+	err := can.List(redirects.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Fetch the redirects, oldest first
+	results, err := redirects.FindAll(redirects.Query().Order("id asc"))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"redirects.csv\"")
+	return redirects.WriteCSV(w, results)
+}
@@ -0,0 +1,71 @@
+package redirectactions
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/redirects"
+)
+
+// HandleImportShow serves the form to import redirects from csv.
+func HandleImportShow(w http.ResponseWriter, r *http.Request) error {
+
+	redirect := redirects.New()
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err := can.Create(redirect, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("columns", strings.Join(redirects.Columns, ","))
+	return view.Render()
+}
+
+// HandleImport handles the POST of csv to import redirects, replacing those
+// with the same old url and match type.
+func HandleImport(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise
+	user := session.CurrentUser(w, r)
+	err = can.Create(redirects.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Setup context
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Read all the redirects before importing any
+	list, err := redirects.ReadCSV(strings.NewReader(params.Get("csv")))
+	if err != nil {
+		return server.BadRequestError(err, "Invalid redirects", err.Error())
+	}
+
+	_, err = redirects.Import(list)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Redirect to the redirects, showing the most recently changed first
+	return server.Redirect(w, r, redirects.New().IndexURL()+"?order=2")
+}
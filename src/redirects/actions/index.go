@@ -8,6 +8,7 @@ import (
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
 	"github.com/fragmenta/fragmenta-cms/src/redirects"
 )
@@ -40,6 +41,9 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	case "2":
 		q.Order("updated_at desc")
 
+	case "3":
+		q.Order("hits desc, id asc")
+
 	default:
 		q.Order("id asc")
 	}
@@ -47,7 +51,7 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	// Filter if requested
 	filter := params.Get("filter")
 	if len(filter) > 0 {
-		q.Where("name ILIKE ?", filter)
+		q.Where("("+resource.ILike("old_url")+" OR "+resource.ILike("new_url")+")", filter, filter)
 	}
 
 	// Fetch the redirects
@@ -60,6 +64,7 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	view := view.NewRenderer(w, r)
 	view.AddKey("currentUser", user)
 	view.AddKey("filter", filter)
+	view.AddKey("order", params.Get("order"))
 	view.AddKey("redirects", results)
 	return view.Render()
 }
@@ -69,6 +69,12 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
+	// Check the redirect is valid and does not form a loop
+	err = redirect.ValidateRule(params.Map())
+	if err != nil {
+		return server.BadRequestError(err, "Invalid redirect", err.Error())
+	}
+
 	// Validate the params, removing any we don't accept
 	redirectParams := redirect.ValidateParams(params.Map(), redirects.AllowedParams())
 
@@ -1 +1,21 @@
-/* JS for redirects */
\ No newline at end of file
+/* JS for redirects */
+DOM.Ready(function() {
+    // Read csv files chosen for import into the form
+    ActivateRedirectImport();
+});
+
+// Read the csv file chosen on the import form into the csv field, so that
+// it is posted with the form.
+function ActivateRedirectImport() {
+    DOM.On(".redirects-csv-file", "change", function(e) {
+        var file = this.files[0];
+        if (!file) {
+            return;
+        }
+        var reader = new FileReader();
+        reader.onload = function() {
+            DOM.First(".redirects-import-form textarea").value = reader.result;
+        };
+        reader.readAsText(file);
+    });
+}
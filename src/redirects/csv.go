@@ -0,0 +1,150 @@
+package redirects
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns are the columns written by WriteCSV and read by ReadCSV. Only the
+// old and new urls are required on import, columns may be given in any
+// order with a header row, or in this order without one.
+var Columns = []string{"old_url", "new_url", "status_code", "match_type", "keep_query", "hits", "last_hit_at"}
+
+// WriteCSV writes the redirects to w as csv, with a header row.
+func WriteCSV(w io.Writer, redirects []*Redirect) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(Columns)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range redirects {
+		keep := "no"
+		if r.KeepQuery == 1 {
+			keep = "yes"
+		}
+		lastHit := ""
+		if !r.LastHitAt.IsZero() {
+			lastHit = r.LastHitAt.UTC().Format(time.RFC3339)
+		}
+		row := []string{
+			r.OldURL,
+			r.NewURL,
+			strconv.FormatInt(r.StatusCode, 10),
+			strings.ToLower(r.MatchTypeDisplay()),
+			keep,
+			strconv.FormatInt(r.Hits, 10),
+			lastHit,
+		}
+		err = cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads redirects from csv, as written by WriteCSV, returning the
+// params for each or an error describing the first invalid row. Match types
+// may be given as exact, prefix or pattern, and the query kept as yes or no.
+func ReadCSV(r io.Reader) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("redirects: invalid csv %s", err)
+	}
+
+	columns := Columns
+	var list []map[string]string
+	for i, row := range rows {
+		if i == 0 && len(row) > 0 && strings.ToLower(strings.TrimSpace(row[0])) == "old_url" {
+			columns = row
+			continue
+		}
+
+		values := make(map[string]string)
+		for j, v := range row {
+			if j < len(columns) {
+				values[strings.ToLower(strings.TrimSpace(columns[j]))] = strings.TrimSpace(v)
+			}
+		}
+		if values["old_url"] == "" && values["new_url"] == "" {
+			continue
+		}
+
+		params, err := csvParams(values)
+		if err == nil {
+			err = New().ValidateRule(params)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("redirects: row %d is invalid, %s", i+1, strings.TrimPrefix(err.Error(), "redirects: "))
+		}
+		list = append(list, params)
+	}
+
+	return list, nil
+}
+
+// csvParams returns the params for a redirect from the values of a csv row.
+func csvParams(values map[string]string) (map[string]string, error) {
+	if values["old_url"] == "" {
+		return nil, fmt.Errorf("redirects: an old url is required")
+	}
+
+	params := map[string]string{
+		"old_url":     values["old_url"],
+		"new_url":     values["new_url"],
+		"status_code": values["status_code"],
+		"match_type":  strconv.Itoa(Exact),
+		"keep_query":  "1",
+	}
+	if params["status_code"] == "" {
+		params["status_code"] = strconv.FormatInt(New().StatusCode, 10)
+	}
+
+	switch strings.ToLower(values["match_type"]) {
+	case "", "exact", "0":
+	case "prefix", "1":
+		params["match_type"] = strconv.Itoa(Prefix)
+	case "pattern", "2":
+		params["match_type"] = strconv.Itoa(Pattern)
+	default:
+		return nil, fmt.Errorf("redirects: the match type %s should be exact, prefix or pattern", values["match_type"])
+	}
+
+	switch strings.ToLower(values["keep_query"]) {
+	case "", "yes", "1", "true":
+	case "no", "0", "false":
+		params["keep_query"] = "0"
+	default:
+		return nil, fmt.Errorf("redirects: keep query %s should be yes or no", values["keep_query"])
+	}
+
+	return params, nil
+}
+
+// Import creates or updates redirects with the params given, as returned by
+// ReadCSV, replacing redirects with the same old url and match type.
+// The number of redirects imported is returned.
+func Import(list []map[string]string) (int, error) {
+	for i, params := range list {
+		r, err := FindFirst("old_url=? AND match_type=?", params["old_url"], params["match_type"])
+		if err != nil {
+			_, err = New().Create(params)
+		} else {
+			err = r.Update(params)
+		}
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(list), nil
+}
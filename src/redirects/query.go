@@ -1,6 +1,7 @@
 package redirects
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/fragmenta/query"
@@ -19,7 +20,7 @@ const (
 
 // AllowedParams returns an array of allowed param keys for Update and Create.
 func AllowedParams() []string {
-	return []string{"new_url", "old_url"}
+	return []string{"new_url", "old_url", "status_code", "match_type", "keep_query"}
 }
 
 // NewWithColumns creates a new redirect instance and fills it with data from the database cols provided.
@@ -31,6 +32,11 @@ func NewWithColumns(cols map[string]interface{}) *Redirect {
 	redirect.UpdatedAt = resource.ValidateTime(cols["updated_at"])
 	redirect.NewURL = resource.ValidateString(cols["new_url"])
 	redirect.OldURL = resource.ValidateString(cols["old_url"])
+	redirect.StatusCode = resource.ValidateInt(cols["status_code"])
+	redirect.MatchType = resource.ValidateInt(cols["match_type"])
+	redirect.KeepQuery = resource.ValidateInt(cols["keep_query"])
+	redirect.Hits = resource.ValidateInt(cols["hits"])
+	redirect.LastHitAt = resource.ValidateTime(cols["last_hit_at"])
 
 	return redirect
 }
@@ -42,6 +48,8 @@ func New() *Redirect {
 	redirect.UpdatedAt = time.Now()
 	redirect.TableName = TableName
 	redirect.KeyName = KeyName
+	redirect.StatusCode = http.StatusMovedPermanently
+	redirect.KeepQuery = 1
 	return redirect
 }
 
@@ -2,14 +2,244 @@
 package redirects
 
 import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/view/helpers"
+
 	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 )
 
+// Match types for redirects. Exact redirects match the path, or the path and
+// query if the old url includes a query, prefix redirects match paths starting
+// with the old url and keep the rest of the path, and pattern redirects match
+// the path with a regular expression, replacing $1 etc in the new url with
+// the text captured.
+const (
+	Exact   = 0
+	Prefix  = 1
+	Pattern = 2
+)
+
 // Redirect handles saving and retreiving redirects from the database
 type Redirect struct {
 	// resource.Base defines behaviour and fields shared between all resources
 	resource.Base
 
-	NewURL string
-	OldURL string
+	NewURL     string
+	OldURL     string
+	StatusCode int64
+	MatchType  int64
+	KeepQuery  int64
+	Hits       int64
+	LastHitAt  time.Time
+
+	// pattern is the compiled old url of pattern redirects
+	pattern *regexp.Regexp
+}
+
+// MaxChain is the maximum number of redirects followed when checking for loops.
+const MaxChain = 10
+
+// StatusCodeOptions returns the http status codes redirects may respond with.
+func (r *Redirect) StatusCodeOptions() []helpers.Option {
+	var options []helpers.Option
+
+	options = append(options, helpers.Option{Id: http.StatusMovedPermanently, Name: "301 Moved permanently"})
+	options = append(options, helpers.Option{Id: http.StatusFound, Name: "302 Found"})
+	options = append(options, helpers.Option{Id: http.StatusTemporaryRedirect, Name: "307 Temporary redirect"})
+	options = append(options, helpers.Option{Id: http.StatusPermanentRedirect, Name: "308 Permanent redirect"})
+	options = append(options, helpers.Option{Id: http.StatusGone, Name: "410 Gone"})
+
+	return options
+}
+
+// StatusCodeDisplay returns a string representation of the redirect status code.
+func (r *Redirect) StatusCodeDisplay() string {
+	for _, o := range r.StatusCodeOptions() {
+		if o.Id == r.StatusCode {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// MatchTypeOptions returns the ways redirects may match urls.
+func (r *Redirect) MatchTypeOptions() []helpers.Option {
+	var options []helpers.Option
+
+	options = append(options, helpers.Option{Id: Exact, Name: "Exact"})
+	options = append(options, helpers.Option{Id: Prefix, Name: "Prefix"})
+	options = append(options, helpers.Option{Id: Pattern, Name: "Pattern"})
+
+	return options
+}
+
+// MatchTypeDisplay returns a string representation of the redirect match type.
+func (r *Redirect) MatchTypeDisplay() string {
+	for _, o := range r.MatchTypeOptions() {
+		if o.Id == r.MatchType {
+			return o.Name
+		}
+	}
+	return ""
+}
+
+// KeepQueryOptions returns the choices for the query of redirected requests.
+func (r *Redirect) KeepQueryOptions() []helpers.Option {
+	var options []helpers.Option
+
+	options = append(options, helpers.Option{Id: 1, Name: "Keep"})
+	options = append(options, helpers.Option{Id: 0, Name: "Drop"})
+
+	return options
+}
+
+// IsGone returns true if this redirect responds that the content has been removed.
+func (r *Redirect) IsGone() bool {
+	return r.StatusCode == http.StatusGone
+}
+
+// ValidateRule returns an error if the redirect described by params, with the
+// values of this redirect for any not given, is invalid or would form a loop.
+func (r *Redirect) ValidateRule(params map[string]string) error {
+	oldURL, newURL := r.OldURL, r.NewURL
+	if v, ok := params["old_url"]; ok {
+		oldURL = strings.TrimSpace(v)
+	}
+	if v, ok := params["new_url"]; ok {
+		newURL = strings.TrimSpace(v)
+	}
+	statusCode, matchType := r.StatusCode, r.MatchType
+	if v, ok := params["status_code"]; ok {
+		statusCode, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := params["match_type"]; ok {
+		matchType, _ = strconv.ParseInt(v, 10, 64)
+	}
+
+	check := &Redirect{StatusCode: statusCode, MatchType: matchType}
+	if check.StatusCodeDisplay() == "" {
+		return fmt.Errorf("redirects: %d is not a status code redirects may use", statusCode)
+	}
+	if check.MatchTypeDisplay() == "" {
+		return fmt.Errorf("redirects: %d is not a valid match type", matchType)
+	}
+	if newURL == "" && !check.IsGone() && oldURL != "" {
+		return fmt.Errorf("redirects: a url to redirect to is required unless the status is 410 Gone")
+	}
+
+	switch matchType {
+	case Prefix:
+		if oldURL == "" || !strings.HasPrefix(oldURL, "/") {
+			return fmt.Errorf("redirects: the prefix %s should start with /", oldURL)
+		}
+		if strings.HasPrefix(newURL, oldURL) {
+			return fmt.Errorf("redirects: redirecting the prefix %s to %s would create a loop", oldURL, newURL)
+		}
+	case Pattern:
+		_, err := compilePattern(oldURL)
+		if err != nil {
+			return fmt.Errorf("redirects: the pattern %s is not a valid regular expression", oldURL)
+		}
+	default:
+		return Validate(oldURL, newURL)
+	}
+
+	return nil
+}
+
+// compilePattern compiles the regular expression for a pattern redirect,
+// which must match the whole path.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("redirects: empty pattern")
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// Validate returns an error if a redirect from oldURL to newURL would form a
+// loop, because they are the same or following the redirects from newURL
+// would lead back to oldURL.
+func Validate(oldURL, newURL string) error {
+	if oldURL == "" || newURL == "" {
+		return nil
+	}
+
+	url := newURL
+	for i := 0; i < MaxChain; i++ {
+		if url == oldURL {
+			return fmt.Errorf("redirects: redirecting %s to %s would create a loop", oldURL, newURL)
+		}
+		r, err := FindFirst("old_url=? AND match_type=?", url, Exact)
+		if err != nil || r.IsGone() {
+			return nil
+		}
+		url = r.NewURL
+	}
+
+	return nil
+}
+
+// Remove deletes exact redirects from url, after a page or post is given
+// the url, so that requests for it reach the content rather than being redirected.
+func Remove(url string) error {
+	list, err := FindAll(Where("old_url=? AND match_type=?", url, Exact))
+	if err != nil {
+		return err
+	}
+	for _, r := range list {
+		err = r.Destroy()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add records that the content at oldURL has moved to newURL, after the url
+// of a page or post changes. Redirects from newURL are removed as it now
+// has content, and redirects which led to oldURL are changed to lead
+// straight to newURL, so that redirects don't form chains or loops.
+func Add(oldURL, newURL string) error {
+	if oldURL == "" || newURL == "" || oldURL == newURL {
+		return nil
+	}
+
+	// Remove redirects from the new url
+	err := Remove(newURL)
+	if err != nil {
+		return err
+	}
+
+	// Collapse chains of redirects which led to the old url
+	list, err := FindAll(Where("new_url=? AND match_type=?", oldURL, Exact))
+	if err != nil {
+		return err
+	}
+	for _, r := range list {
+		err = r.Update(map[string]string{"new_url": newURL})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add or update the redirect from the old url
+	params := map[string]string{
+		"new_url":     newURL,
+		"status_code": strconv.Itoa(http.StatusMovedPermanently),
+	}
+	r, err := FindFirst("old_url=? AND match_type=?", oldURL, Exact)
+	if err != nil {
+		params["old_url"] = oldURL
+		params["match_type"] = strconv.Itoa(Exact)
+		params["keep_query"] = "1"
+		_, err = New().Create(params)
+		return err
+	}
+	return r.Update(params)
 }
@@ -2,6 +2,9 @@
 package redirects
 
 import (
+	"bytes"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
@@ -136,3 +139,168 @@ func TestAllowedParams(t *testing.T) {
 		t.Fatalf("redirects: no allowed params")
 	}
 }
+
+// TestAdd tests redirects added when urls change don't form chains or loops.
+func TestAdd(t *testing.T) {
+
+	// Move /a to /b, then /b to /c
+	for _, urls := range [][2]string{{"/a", "/b"}, {"/b", "/c"}} {
+		err := Add(urls[0], urls[1])
+		if err != nil {
+			t.Fatalf("redirects: Add failed :%s", err)
+		}
+	}
+
+	// The redirect from /a should be collapsed to lead straight to /c
+	r, err := FindFirst("old_url=?", "/a")
+	if err != nil || r.NewURL != "/c" {
+		t.Fatalf("redirects: Add did not collapse chain :%v %s", r, err)
+	}
+
+	// Move /c back to /a, which removes the redirect from /a
+	err = Add("/c", "/a")
+	if err != nil {
+		t.Fatalf("redirects: Add failed :%s", err)
+	}
+	_, err = FindFirst("old_url=?", "/a")
+	if err == nil {
+		t.Fatalf("redirects: Add kept redirect from new url")
+	}
+	r, err = FindFirst("old_url=?", "/b")
+	if err != nil || r.NewURL != "/a" {
+		t.Fatalf("redirects: Add unexpected redirect from /b :%v %s", r, err)
+	}
+
+	// Test loops are rejected
+	if Validate("/a", "/b") == nil || Validate("/x", "/x") == nil || Validate("/x", "/b") != nil {
+		t.Fatalf("redirects: Validate unexpected result")
+	}
+}
+
+// TestMatch tests requests are matched by exact, prefix and pattern redirects.
+func TestMatch(t *testing.T) {
+
+	rules := []map[string]string{
+		{"old_url": "/old/", "new_url": "/new/", "match_type": "1"},
+		{"old_url": "/old/deep/", "new_url": "/deep/", "match_type": "1", "keep_query": "0"},
+		{"old_url": `/([0-9]{4})/(.+)\.html`, "new_url": "/blog/$2", "match_type": "2"},
+		{"old_url": "/index.php?p=7", "new_url": "/seven"},
+		{"old_url": "/removed", "new_url": "", "status_code": "410"},
+		{"old_url": "/gone/", "new_url": "/", "match_type": "1"},
+		{"old_url": "/go/(.*)", "new_url": "/$1", "match_type": "2"},
+		{"old_url": "/archive", "new_url": "/history", "match_type": "1"},
+	}
+	for _, params := range rules {
+		err := New().ValidateRule(params)
+		if err != nil {
+			t.Fatalf("redirects: ValidateRule failed :%s", err)
+		}
+		_, err = New().Create(params)
+		if err != nil {
+			t.Fatalf("redirects: Create redirect failed :%s", err)
+		}
+	}
+
+	tests := map[string]string{
+		"/b?x=1":           "/a?x=1",
+		"/old/about":       "/new/about",
+		"/old/deep/x?y=1":  "/deep/x",
+		"/2016/hello.html": "/blog/hello",
+		"/index.php?p=7":   "/seven",
+		"/removed":         "",
+		"/gone/about":      "/about",
+		"/gone//evil.com":  "/evil.com",
+		`/gone/\evil.com`:  "/evil.com",
+		"/go///evil.com":   "/evil.com",
+		"/archive":         "/history",
+		"/archive/2019":    "/history/2019",
+	}
+	for path, expected := range tests {
+		u, _ := url.Parse(path)
+		r, target := Match(u)
+		if r == nil || target != expected {
+			t.Fatalf("redirects: Match unexpected result for %s expected:%s got:%s", path, expected, target)
+		}
+	}
+
+	for _, path := range []string{"/missing", "/archived"} {
+		u, _ := url.Parse(path)
+		r, _ := Match(u)
+		if r != nil {
+			t.Fatalf("redirects: Match unexpected redirect for %s :%v", path, r)
+		}
+	}
+
+	// Test hits are recorded
+	u, _ := url.Parse("/removed")
+	r, _ := Match(u)
+	if r == nil || !r.IsGone() || r.Hit() != nil {
+		t.Fatalf("redirects: Hit failed :%v", r)
+	}
+	found, err := Find(r.ID)
+	if err != nil || found.Hits != 1 || found.LastHitAt.IsZero() {
+		t.Fatalf("redirects: Hit not recorded :%v %s", found, err)
+	}
+
+	// Test hits from a redirect loaded before others were recorded are not lost
+	if found.Hit() != nil || r.Hit() != nil {
+		t.Fatalf("redirects: Hit failed :%v", r)
+	}
+	found, err = Find(r.ID)
+	if err != nil || found.Hits != 3 {
+		t.Fatalf("redirects: Hit lost hits :%v %s", found, err)
+	}
+
+	invalid := []map[string]string{
+		{"old_url": "/x", "new_url": ""},
+		{"old_url": "/x", "new_url": "/y", "status_code": "200"},
+		{"old_url": "(", "new_url": "/y", "match_type": "2"},
+		{"old_url": "/a", "new_url": "/a/b", "match_type": "1"},
+		{"old_url": "/a", "new_url": "/b"},
+	}
+	for _, params := range invalid {
+		err = New().ValidateRule(params)
+		if err == nil {
+			t.Fatalf("redirects: ValidateRule accepted invalid redirect :%v", params)
+		}
+	}
+}
+
+// TestCSV tests redirects are exported and imported as csv.
+func TestCSV(t *testing.T) {
+
+	list, err := FindAll(Query().Order("id asc"))
+	if err != nil {
+		t.Fatalf("redirects: FindAll failed :%s", err)
+	}
+
+	var b bytes.Buffer
+	err = WriteCSV(&b, list)
+	if err != nil {
+		t.Fatalf("redirects: WriteCSV failed :%s", err)
+	}
+	imported, err := ReadCSV(&b)
+	if err != nil || len(imported) != len(list) {
+		t.Fatalf("redirects: ReadCSV failed to read export :%d %s", len(imported), err)
+	}
+
+	// Rows without a header use the default column order
+	imported, err = ReadCSV(strings.NewReader("/legacy,/new,302,exact,no\n"))
+	if err != nil || len(imported) != 1 {
+		t.Fatalf("redirects: ReadCSV failed :%s", err)
+	}
+	n, err := Import(imported)
+	if err != nil || n != 1 {
+		t.Fatalf("redirects: Import failed :%s", err)
+	}
+	u, _ := url.Parse("/legacy?x=1")
+	r, target := Match(u)
+	if r == nil || r.StatusCode != 302 || target != "/new" {
+		t.Fatalf("redirects: imported redirect not matched :%v %s", r, target)
+	}
+
+	_, err = ReadCSV(strings.NewReader("old_url,new_url\n/x,/y\n/z,/z\n"))
+	if err == nil || !strings.Contains(err.Error(), "row 3") {
+		t.Fatalf("redirects: ReadCSV accepted invalid csv :%s", err)
+	}
+}
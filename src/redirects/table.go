@@ -0,0 +1,186 @@
+package redirects
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fragmenta/query"
+)
+
+// table holds the redirects compiled for matching requests, it is loaded
+// from the database when first used and again after redirects change.
+var table struct {
+	sync.RWMutex
+	loaded   bool
+	resets   int
+	exact    map[string]*Redirect
+	prefixes []*Redirect
+	patterns []*Redirect
+}
+
+// Load loads the redirects from the database into the table used to match requests.
+// Pattern redirects which fail to compile are ignored.
+func Load() error {
+	table.RLock()
+	resets := table.resets
+	table.RUnlock()
+
+	list, err := FindAll(Query().Order("id asc"))
+	if err != nil {
+		return err
+	}
+
+	exact := make(map[string]*Redirect)
+	var prefixes, patterns []*Redirect
+	for _, r := range list {
+		switch r.MatchType {
+		case Prefix:
+			prefixes = append(prefixes, r)
+		case Pattern:
+			r.pattern, err = compilePattern(r.OldURL)
+			if err == nil {
+				patterns = append(patterns, r)
+			}
+		default:
+			if exact[r.OldURL] == nil {
+				exact[r.OldURL] = r
+			}
+		}
+	}
+
+	// Match the longest prefix first
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return len(prefixes[i].OldURL) > len(prefixes[j].OldURL)
+	})
+
+	// If the table was reset while loading, load it again when next used
+	table.Lock()
+	defer table.Unlock()
+	table.loaded = resets == table.resets
+	table.exact = exact
+	table.prefixes = prefixes
+	table.patterns = patterns
+	return nil
+}
+
+// Reset clears the table of redirects, so that it is loaded again when next used.
+func Reset() {
+	table.Lock()
+	defer table.Unlock()
+	table.loaded = false
+	table.resets++
+}
+
+// Match returns the redirect for the url u and the url to redirect to, or nil if
+// no redirect matches. Exact redirects are checked first, then prefixes from
+// longest to shortest, then patterns in the order they were added.
+// The url is empty for redirects which respond that the content has gone.
+func Match(u *url.URL) (*Redirect, string) {
+	table.RLock()
+	loaded := table.loaded
+	table.RUnlock()
+	if !loaded {
+		err := Load()
+		if err != nil {
+			return nil, ""
+		}
+	}
+
+	table.RLock()
+	defer table.RUnlock()
+
+	if u.RawQuery != "" {
+		r := table.exact[u.Path+"?"+u.RawQuery]
+		if r != nil {
+			return r, r.target(r.NewURL, "")
+		}
+	}
+
+	r := table.exact[u.Path]
+	if r != nil {
+		return r, r.target(r.NewURL, u.RawQuery)
+	}
+
+	for _, r := range table.prefixes {
+		if matchPrefix(u.Path, r.OldURL) {
+			return r, r.target(r.NewURL+strings.TrimPrefix(u.Path, r.OldURL), u.RawQuery)
+		}
+	}
+
+	for _, r := range table.patterns {
+		if r.pattern.MatchString(u.Path) {
+			return r, r.target(r.pattern.ReplaceAllString(u.Path, r.NewURL), u.RawQuery)
+		}
+	}
+
+	return nil, ""
+}
+
+// matchPrefix returns true if path is prefix or a path below it, so that
+// a prefix of /old matches /old and /old/page but not /older.
+func matchPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// target returns the url to redirect to, adding rawQuery if this redirect keeps
+// the query of requests.
+func (r *Redirect) target(u, rawQuery string) string {
+	if r.IsGone() {
+		return ""
+	}
+
+	// Joining a url with the request path may leave several leading slashes, which
+	// browsers read as a url on another host, so relative urls keep only one
+	if strings.HasPrefix(u, "/") || strings.HasPrefix(u, `\`) {
+		u = "/" + strings.TrimLeft(u, `/\`)
+	}
+
+	if r.KeepQuery == 1 && rawQuery != "" {
+		if strings.Contains(u, "?") {
+			return u + "&" + rawQuery
+		}
+		return u + "?" + rawQuery
+	}
+	return u
+}
+
+// Hit records a request matched by this redirect, counting the hits and
+// setting the time of the last hit. The count is incremented in the database,
+// so that hits recorded by other processes or before the table was loaded are kept.
+func (r *Redirect) Hit() error {
+	now := time.Now().UTC()
+	table.Lock()
+	r.Hits++
+	r.LastHitAt = now
+	table.Unlock()
+
+	// Update the record directly, as hits don't change the redirects in the table
+	sql := fmt.Sprintf("UPDATE %s SET hits=hits+1, last_hit_at=$1 WHERE id=$2", TableName)
+	_, err := query.ExecSQL(sql, query.TimeString(now), r.ID)
+	return err
+}
+
+// Create inserts a new redirect and resets the table of redirects.
+func (r *Redirect) Create(params map[string]string) (int64, error) {
+	defer Reset()
+	return r.Base.Create(params)
+}
+
+// Update updates the redirect and resets the table of redirects.
+func (r *Redirect) Update(params map[string]string) error {
+	defer Reset()
+	return r.Base.Update(params)
+}
+
+// Destroy deletes the redirect and resets the table of redirects.
+func (r *Redirect) Destroy() error {
+	defer Reset()
+	return r.Base.Destroy()
+}
@@ -0,0 +1,228 @@
+package submissionactions_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fragmenta/mux"
+
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
+
+	// admin is used for requests which require authorisation.
+	admin *users.User
+
+	// form is the form submitted in tests.
+	form *forms.Form
+)
+
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, an admin user and a form.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
+	if err != nil {
+		t.Fatalf("submissionactions: setup failed %s", err)
+	}
+
+	admin, err = apptest.CreateAdmin()
+	if err != nil {
+		t.Fatalf("submissionactions: error creating admin %s", err)
+	}
+
+	form, err = apptest.CreateForm(nil)
+	if err != nil {
+		t.Fatalf("submissionactions: error creating form %s", err)
+	}
+}
+
+// submit posts values to the test form as anon.
+func submit(values url.Values) (int, string, error) {
+	w, err := apptest.Request(router, "POST", fmt.Sprintf("/forms/%d/submit", form.ID), values, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	return w.Code, w.Body.String(), nil
+}
+
+// Test POST /forms/1/submit
+func TestCreateSubmission(t *testing.T) {
+
+	values := url.Values{}
+	values.Add("field_name", "Alice")
+	values.Add("field_email", "alice@example.com")
+	values.Add("field_message", "Hello")
+
+	code, body, err := submit(values)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("submissionactions: error handling HandleCreate %v %d", err, code)
+	}
+	if !strings.Contains(body, form.Message) {
+		t.Fatalf("submissionactions: unexpected response for HandleCreate expected:%s got:%s", form.Message, body)
+	}
+
+	submission, err := submissions.FindFirst("form_id=?", form.ID)
+	if err != nil {
+		t.Fatalf("submissionactions: submission not saved %s", err)
+	}
+	if !strings.Contains(submission.Data, "alice@example.com") || submission.IsRead() {
+		t.Fatalf("submissionactions: unexpected submission %v", submission)
+	}
+
+	// Test the notification sent to the form recipients
+	m := apptest.Mail.Last()
+	if m == nil || m.Recipients[0] != "admin@example.com" || m.ReplyTo != "alice@example.com" || !strings.Contains(m.Body, "Hello") {
+		t.Fatalf("submissionactions: unexpected notification %v", m)
+	}
+}
+
+// Test invalid and spam submissions are not saved
+func TestRejectSubmission(t *testing.T) {
+	apptest.Mail.Reset()
+
+	// Test a missing required field
+	values := url.Values{}
+	values.Add("field_email", "bob@example.com")
+	code, _, err := submit(values)
+	if err != nil || code != http.StatusBadRequest {
+		t.Fatalf("submissionactions: unexpected response for invalid submission %v %d", err, code)
+	}
+
+	// Test a submission with the honeypot filled in
+	values.Add("field_name", "Bob")
+	values.Add(forms.Honeypot, "http://example.com")
+	code, _, err = submit(values)
+	if err != nil || code != http.StatusOK {
+		t.Fatalf("submissionactions: unexpected response for spam submission %v %d", err, code)
+	}
+
+	count, err := submissions.Query().Count()
+	if err != nil || count != 1 {
+		t.Fatalf("submissionactions: unexpected submission count %d %v", count, err)
+	}
+	if apptest.Mail.Last() != nil {
+		t.Fatalf("submissionactions: notification sent for rejected submission")
+	}
+}
+
+// Test visitors are limited to submissions.RateLimit submissions
+func TestRateLimit(t *testing.T) {
+	values := url.Values{}
+	values.Add("field_name", "Carol")
+	values.Add("field_email", "carol@example.com")
+
+	// One submission has been made above
+	for i := 1; i < submissions.RateLimit; i++ {
+		code, _, err := submit(values)
+		if err != nil || code != http.StatusOK {
+			t.Fatalf("submissionactions: unexpected response for submission %d %v %d", i, err, code)
+		}
+	}
+
+	code, _, err := submit(values)
+	if err != nil || code != http.StatusTooManyRequests {
+		t.Fatalf("submissionactions: unexpected response for submission over limit %v %d", err, code)
+	}
+}
+
+// Test GET /submissions
+func TestListSubmissions(t *testing.T) {
+
+	// Test listing submissions as anon
+	w, err := apptest.Request(router, "GET", "/submissions", nil, nil)
+	if err != nil || w.Code == http.StatusOK {
+		t.Fatalf("submissionactions: unexpected response for HandleIndex as anon, expected failure")
+	}
+
+	w, err = apptest.Request(router, "GET", fmt.Sprintf("/submissions?form_id=%d", form.ID), nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("submissionactions: error handling HandleIndex %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := "/submissions/1"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("submissionactions: unexpected response for HandleIndex expected:%s got:%s", pattern, w.Body.String())
+	}
+
+}
+
+// Test of GET /submissions/1
+func TestShowSubmission(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", "/submissions/1", nil, admin)
+
+	// Test the error response
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("submissionactions: error handling HandleShow %v %d", err, w.Code)
+	}
+
+	// Test the body for a known pattern
+	pattern := "alice@example.com"
+	if !strings.Contains(w.Body.String(), pattern) {
+		t.Fatalf("submissionactions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
+	}
+
+	// Test the submission is marked read
+	submission, err := submissions.Find(1)
+	if err != nil || !submission.IsRead() {
+		t.Fatalf("submissionactions: submission not marked read %v", err)
+	}
+}
+
+// Test of GET /submissions/export
+func TestExportSubmissions(t *testing.T) {
+
+	w, err := apptest.Request(router, "GET", fmt.Sprintf("/submissions/export?form_id=%d", form.ID), nil, admin)
+	if err != nil || w.Code != http.StatusOK {
+		t.Fatalf("submissionactions: error handling HandleExport %v %d", err, w.Code)
+	}
+
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("submissionactions: unexpected content type for HandleExport %s", w.Header().Get("Content-Type"))
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != submissions.RateLimit+1 || lines[0] != "Submitted,Name,Email,Message,IP" || !strings.Contains(lines[1], "Alice,alice@example.com,Hello") {
+		t.Fatalf("submissionactions: unexpected csv for HandleExport %s", w.Body.String())
+	}
+}
+
+// Test of POST /submissions/1/destroy
+func TestDeleteSubmission(t *testing.T) {
+
+	// Test deleting the submission as anon
+	w, err := apptest.Request(router, "POST", "/submissions/1/destroy", nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("submissionactions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	// Now test deleting the submission as admin
+	w, err = apptest.Request(router, "POST", "/submissions/1/destroy", nil, admin)
+	if err != nil {
+		t.Fatalf("submissionactions: error handling HandleDestroy %s", err)
+	}
+
+	// Test we get a redirect after delete
+	if w.Code != http.StatusFound {
+		t.Fatalf("submissionactions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
+	}
+
+	_, err = submissions.Find(1)
+	if err == nil {
+		t.Fatalf("submissionactions: submission found after HandleDestroy")
+	}
+
+}
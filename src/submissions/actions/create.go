@@ -0,0 +1,140 @@
+package submissionactions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+)
+
+// HandleCreate handles the POST of a form embedded in a page by visitors,
+// storing the submission and notifying the form recipients by email.
+func HandleCreate(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the form
+	form, err := forms.Find(params.GetInt(forms.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access IF the form is not published, so admins may try it out
+	user := session.CurrentUser(w, r)
+	if !form.IsPublished() {
+		err = can.Show(form, user)
+		if err != nil {
+			return server.NotAuthorizedError(err)
+		}
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Spam bots fill in the hidden honeypot field, pretend to accept their submission
+	if params.Get(forms.Honeypot) != "" {
+		log.Info(log.V{"msg": "discarded spam submission", "form_id": form.ID, "ip": session.ClientIP(r)})
+		return renderSubmitted(w, r, form)
+	}
+
+	// Limit the submissions from each address
+	ip := session.ClientIP(r)
+	limited, err := submissions.Limited(ip)
+	if err != nil {
+		return server.InternalError(err)
+	}
+	if limited {
+		return &server.StatusError{
+			Err:     errors.New("submissions: rate limit exceeded"),
+			Status:  http.StatusTooManyRequests,
+			Title:   "Too many submissions",
+			Message: "Sorry, you have sent too many submissions recently, please try again later.",
+		}
+	}
+
+	// Validate the values submitted for the form fields
+	values, err := form.Values(params.Get)
+	if err != nil {
+		return server.BadRequestError(err, "Please check your submission", err.Error())
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	submission := submissions.New()
+	submissionParams := map[string]string{
+		"status":  fmt.Sprintf("%d", submissions.Unread),
+		"form_id": fmt.Sprintf("%d", form.ID),
+		"data":    string(data),
+		"ip":      ip,
+	}
+
+	id, err := submission.Create(submissionParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	submission, err = submissions.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// The submission is saved, so log any failure to notify rather than reporting it
+	err = notify(form, submission, values)
+	if err != nil {
+		log.Error(log.V{"msg": "unable to send submission notification", "form_id": form.ID, "submission_id": submission.ID, "error": err})
+	}
+
+	return renderSubmitted(w, r, form)
+}
+
+// renderSubmitted thanks the visitor with the form message.
+func renderSubmitted(w http.ResponseWriter, r *http.Request, form *forms.Form) error {
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", session.CurrentUser(w, r))
+	view.AddKey("form", form)
+	view.Template("submissions/views/submitted.html.got")
+	return view.Render()
+}
+
+// notify emails the submission to the addresses set on the form.
+func notify(form *forms.Form, submission *submissions.Submission, values map[string]string) error {
+	addresses := form.NotifyAddresses()
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	emailContext := map[string]interface{}{
+		"form":   form,
+		"values": submission.Values(form),
+		"url":    fmt.Sprintf("%s%s", settings.Current.RootURL, submission.ShowURL()),
+	}
+
+	e := mail.New(addresses[0])
+	e.Recipients = addresses
+	e.ReplyTo = form.ReplyTo(values)
+	e.Subject = fmt.Sprintf("New submission to %s", form.Name)
+	e.Template = "submissions/views/notification_mail.html.got"
+	return mail.Send(e, emailContext)
+}
@@ -0,0 +1,49 @@
+package submissionactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+)
+
+// HandleDestroy responds to /submissions/n/destroy by deleting the submission.
+func HandleDestroy(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the submission
+	submission, err := submissions.Find(params.GetInt(submissions.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise destroy submission
+	user := session.CurrentUser(w, r)
+	err = can.Destroy(submission, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Destroy the submission
+	submission.Destroy()
+
+	// Redirect to the inbox for the form
+	return server.Redirect(w, r, fmt.Sprintf("%s?form_id=%d", submission.IndexURL(), submission.FormID))
+
+}
@@ -0,0 +1,47 @@
+package submissionactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+)
+
+// HandleExport responds to GET /submissions/export?form_id=n with the submissions for the form as csv.
+func HandleExport(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list submission
+	user := session.CurrentUser(w, r)
+	err := can.List(submissions.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the form, as columns are taken from its fields
+	form, err := forms.Find(params.GetInt("form_id"))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Fetch the submissions, oldest first
+	results, err := submissions.FindAll(submissions.Where("form_id=?", form.ID).Order("created_at asc, id asc"))
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-submissions.csv\"", form.ToSlug(form.Name)))
+	return submissions.WriteCSV(w, form, results)
+}
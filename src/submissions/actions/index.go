@@ -0,0 +1,71 @@
+package submissionactions
+
+import (
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+)
+
+// HandleIndex displays the inbox of submissions, optionally for one form.
+func HandleIndex(w http.ResponseWriter, r *http.Request) error {
+
+	// Authorise list submission
+	user := session.CurrentUser(w, r)
+	err := can.List(submissions.New(), user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Get the params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Build a query
+	q := submissions.Query()
+
+	// Filter by form if requested
+	formID := params.GetInt("form_id")
+	if formID > 0 {
+		q.Where("form_id=?", formID)
+	}
+
+	// Filter by status if requested
+	if params.Get("status") == "unread" {
+		q.Where("status=?", submissions.Unread)
+	}
+
+	// Fetch the submissions
+	results, err := submissions.FindAll(q)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Fetch the forms for the filter menu and form names
+	allForms, err := forms.FindAll(forms.Query())
+	if err != nil {
+		return server.InternalError(err)
+	}
+	formNames := make(map[int64]string)
+	for _, f := range allForms {
+		formNames[f.ID] = f.Name
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("formID", formID)
+	view.AddKey("status", params.Get("status"))
+	view.AddKey("forms", allForms)
+	view.AddKey("formNames", formNames)
+	view.AddKey("submissions", results)
+	return view.Render()
+}
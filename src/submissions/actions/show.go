@@ -0,0 +1,60 @@
+package submissionactions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/submissions"
+)
+
+// HandleShow displays a single submission, marking it as read.
+func HandleShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the submission
+	submission, err := submissions.Find(params.GetInt(submissions.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Authorise access
+	user := session.CurrentUser(w, r)
+	err = can.Show(submission, user)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Find the form submitted
+	form, err := forms.Find(submission.FormID)
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Mark the submission as read
+	if !submission.IsRead() {
+		err = submission.Update(map[string]string{"status": fmt.Sprintf("%d", submissions.Read)})
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
+	// Render the template
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", user)
+	view.AddKey("submission", submission)
+	view.AddKey("form", form)
+	view.AddKey("values", submission.Values(form))
+	return view.Render()
+}
@@ -0,0 +1,102 @@
+package submissions
+
+import (
+	"time"
+
+	"github.com/fragmenta/query"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+const (
+	// TableName is the database table for this resource
+	TableName = "submissions"
+	// KeyName is the primary key value for this resource
+	KeyName = "id"
+	// Order defines the default sort order in sql for this resource
+	Order = "created_at desc, id desc"
+)
+
+// NewWithColumns creates a new submission instance and fills it with data from the database cols provided.
+func NewWithColumns(cols map[string]interface{}) *Submission {
+
+	submission := New()
+	submission.ID = resource.ValidateInt(cols["id"])
+	submission.CreatedAt = resource.ValidateTime(cols["created_at"])
+	submission.UpdatedAt = resource.ValidateTime(cols["updated_at"])
+	submission.Status = resource.ValidateInt(cols["status"])
+	submission.FormID = resource.ValidateInt(cols["form_id"])
+	submission.Data = resource.ValidateString(cols["data"])
+	submission.IP = resource.ValidateString(cols["ip"])
+
+	return submission
+}
+
+// New creates and initialises a new submission instance.
+func New() *Submission {
+	submission := &Submission{}
+	submission.CreatedAt = time.Now()
+	submission.UpdatedAt = time.Now()
+	submission.TableName = TableName
+	submission.KeyName = KeyName
+	submission.Status = Unread
+	return submission
+}
+
+// FindFirst fetches a single submission record from the database using
+// a where query with the format and args provided.
+func FindFirst(format string, args ...interface{}) (*Submission, error) {
+	result, err := Query().Where(format, args...).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// Find fetches a single submission record from the database by id.
+func Find(id int64) (*Submission, error) {
+	result, err := Query().Where("id=?", id).FirstResult()
+	if err != nil {
+		return nil, err
+	}
+	return NewWithColumns(result), nil
+}
+
+// FindAll fetches all submission records matching this query from the database.
+func FindAll(q *query.Query) ([]*Submission, error) {
+
+	// Fetch query.Results from query
+	results, err := q.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return an array of submissions constructed from the results
+	var submissions []*Submission
+	for _, cols := range results {
+		p := NewWithColumns(cols)
+		submissions = append(submissions, p)
+	}
+
+	return submissions, nil
+}
+
+// Query returns a new query for submissions with a default order.
+func Query() *query.Query {
+	return query.New(TableName, KeyName).Order(Order)
+}
+
+// Where returns a new query for submissions with the format and arguments supplied.
+func Where(format string, args ...interface{}) *query.Query {
+	return Query().Where(format, args...)
+}
+
+// Limited returns true if the address given has made RateLimit submissions within RatePeriod.
+func Limited(ip string) (bool, error) {
+	since := query.TimeString(time.Now().Add(-RatePeriod).UTC())
+	count, err := Where("ip=? AND created_at>?", ip, since).Count()
+	if err != nil {
+		return false, err
+	}
+	return count >= RateLimit, nil
+}
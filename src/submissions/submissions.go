@@ -0,0 +1,109 @@
+// Package submissions represents the submissions made by visitors to forms.
+package submissions
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+// Status values for submissions, which are unread until shown to an admin.
+const (
+	Unread = 1
+	Read   = 100
+)
+
+// Visitors may make RateLimit submissions within RatePeriod from one address.
+const (
+	RateLimit  = 5
+	RatePeriod = time.Hour
+)
+
+// Submission handles saving and retreiving submissions from the database
+type Submission struct {
+	// resource.Base defines behaviour and fields shared between all resources
+	resource.Base
+
+	Status int64
+	FormID int64
+	Data   string
+	IP     string
+}
+
+// Value is the value submitted for a field, with the field label.
+type Value struct {
+	Label string
+	Value string
+}
+
+// Values returns the values submitted for the fields of form in order,
+// followed by any values for fields which have since been removed.
+func (s *Submission) Values(form *forms.Form) []Value {
+	data := make(map[string]string)
+	json.Unmarshal([]byte(s.Data), &data)
+
+	var values []Value
+	for _, f := range form.FieldList() {
+		if v, ok := data[f.Name]; ok {
+			values = append(values, Value{Label: f.Label, Value: v})
+			delete(data, f.Name)
+		}
+	}
+	for k, v := range data {
+		values = append(values, Value{Label: k, Value: v})
+	}
+
+	return values
+}
+
+// IsRead returns true if this submission has been read.
+func (s *Submission) IsRead() bool {
+	return s.Status == Read
+}
+
+// StatusDisplay returns a string representation of the submission status.
+func (s *Submission) StatusDisplay() string {
+	if s.IsRead() {
+		return "Read"
+	}
+	return "Unread"
+}
+
+// WriteCSV writes the submissions for form to w as csv,
+// with a column for each field of the form.
+func WriteCSV(w io.Writer, form *forms.Form, submissions []*Submission) error {
+	fields := form.FieldList()
+
+	cw := csv.NewWriter(w)
+	header := []string{"Submitted"}
+	for _, f := range fields {
+		header = append(header, f.Label)
+	}
+	header = append(header, "IP")
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range submissions {
+		data := make(map[string]string)
+		json.Unmarshal([]byte(s.Data), &data)
+
+		row := []string{s.CreatedAt.UTC().Format(time.RFC3339)}
+		for _, f := range fields {
+			row = append(row, data[f.Name])
+		}
+		row = append(row, s.IP)
+		err = cw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,105 @@
+// Tests for the submissions package
+package submissions
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/fragmenta/fragmenta-cms/src/forms"
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+)
+
+var testIP = "192.0.2.10"
+
+func TestSetup(t *testing.T) {
+	err := resource.SetupTestDatabase(2)
+	if err != nil {
+		t.Fatalf("submissions: Setup db failed %s", err)
+	}
+}
+
+// Test Create method
+func TestCreateSubmission(t *testing.T) {
+	params := map[string]string{
+		"status":  fmt.Sprintf("%d", Unread),
+		"form_id": "1",
+		"data":    `{"name":"Alice","email":"alice@example.com"}`,
+		"ip":      testIP,
+	}
+
+	id, err := New().Create(params)
+	if err != nil {
+		t.Fatalf("submissions: Create submission failed :%s", err)
+	}
+
+	submission, err := Find(id)
+	if err != nil {
+		t.Fatalf("submissions: Create submission find failed")
+	}
+
+	if submission.FormID != 1 || submission.IP != testIP || submission.IsRead() {
+		t.Fatalf("submissions: Create submission failed got:%v", submission)
+	}
+}
+
+// TestLimited tests submissions from an address are limited.
+func TestLimited(t *testing.T) {
+	limited, err := Limited(testIP)
+	if err != nil || limited {
+		t.Fatalf("submissions: Limited after one submission :%v %s", limited, err)
+	}
+
+	for i := 1; i < RateLimit; i++ {
+		_, err = New().Create(map[string]string{"form_id": "1", "ip": testIP})
+		if err != nil {
+			t.Fatalf("submissions: Create submission failed :%s", err)
+		}
+	}
+
+	limited, err = Limited(testIP)
+	if err != nil || !limited {
+		t.Fatalf("submissions: not Limited after %d submissions :%s", RateLimit, err)
+	}
+
+	// Other addresses are not limited
+	limited, err = Limited("192.0.2.11")
+	if err != nil || limited {
+		t.Fatalf("submissions: Limited for other address :%v %s", limited, err)
+	}
+}
+
+func TestValues(t *testing.T) {
+	form := forms.New()
+	form.Fields = "Name\nEmail | email"
+
+	s := New()
+	s.Data = `{"email":"alice@example.com","name":"Alice","removed":"old"}`
+
+	values := s.Values(form)
+	if len(values) != 3 || values[0].Label != "Name" || values[1].Value != "alice@example.com" || values[2].Label != "removed" {
+		t.Fatalf("submissions: Values unexpected values :%v", values)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	form := forms.New()
+	form.Fields = "Name\nMessage | textarea"
+
+	s := New()
+	s.CreatedAt = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s.Data = `{"name":"Alice","message":"Hello, \"world\""}`
+	s.IP = testIP
+
+	var b bytes.Buffer
+	err := WriteCSV(&b, form, []*Submission{s})
+	if err != nil {
+		t.Fatalf("submissions: WriteCSV failed :%s", err)
+	}
+
+	want := "Submitted,Name,Message,IP\n2026-01-02T03:04:05Z,Alice,\"Hello, \"\"world\"\"\",192.0.2.10\n"
+	if b.String() != want {
+		t.Fatalf("submissions: WriteCSV expected:%q got:%q", want, b.String())
+	}
+}
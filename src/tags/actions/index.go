@@ -8,6 +8,7 @@ import (
 	"github.com/fragmenta/server"
 	"github.com/fragmenta/view"
 
+	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
 	"github.com/fragmenta/fragmenta-cms/src/tags"
 )
@@ -47,7 +48,7 @@ func HandleIndex(w http.ResponseWriter, r *http.Request) error {
 	// Filter if requested
 	filter := params.Get("filter")
 	if len(filter) > 0 {
-		q.Where("name ILIKE ?", filter)
+		q.Where(resource.ILike("name"), filter)
 	}
 
 	// Fetch the tags
@@ -1,96 +1,70 @@
-package useractions
+package useractions_test
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	"image/jpeg"
 	"net/http"
-	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/fragmenta/auth"
 	"github.com/fragmenta/mux"
-	"github.com/fragmenta/query"
 
-	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
+	"github.com/fragmenta/fragmenta-cms/src/app/apptest"
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
 // names is used to test setting and getting the first string field of the user.
 var names = []string{"foo", "bar"}
 
-// testSetup performs setup for integration tests
-// using the test database, real views, and mock authorisation
-// If we can run this once for global tests it might be more efficient?
-func TestSetup(t *testing.T) {
-	err := resource.SetupTestDatabase(3)
-	if err != nil {
-		fmt.Printf("users: Setup db failed %s", err)
-	}
+var (
+	// router is the app router, used to serve requests in tests.
+	router *mux.Mux
 
-	// Set up mock auth
-	resource.SetupAuthorisation()
+	// admin is used for requests which require authorisation.
+	admin *users.User
 
-	// Load templates for rendering
-	resource.SetupView(3)
-
-	router := mux.New()
-	mux.SetDefault(router)
-
-	// FIXME - would prefer to load real routes from app pkg
-	router.Add("/users", nil)
-	router.Add("/users/create", nil)
-	router.Add("/users/create", nil).Post()
-	router.Add("/users/login", nil)
-	router.Add("/users/login", nil).Post()
-	router.Add("/users/login", nil).Post()
-	router.Add("/users/logout", nil).Post()
-	router.Add("/users/{id:\\d+}/update", nil)
-	router.Add("/users/{id:\\d+}/update", nil).Post()
-	router.Add("/users/{id:\\d+}/destroy", nil).Post()
-	router.Add("/users/{id:\\d+}", nil)
+	// reader is a user for testing destroy.
+	reader *users.User
+)
 
-	// Delete all users to ensure we get consistent results?
-	_, err = query.ExecSQL("delete from users;")
+// TestSetup performs setup for integration tests
+// using an isolated test database, real views and routes, and test users.
+func TestSetup(t *testing.T) {
+	var err error
+	router, err = apptest.Setup()
 	if err != nil {
-		t.Fatalf("error setting up:%s", err)
+		t.Fatalf("useractions: setup failed %s", err)
 	}
-	// Insert a test admin user for checking logins - never delete as will
-	// be required for other resources testing
-	_, err = query.ExecSQL("INSERT INTO users (id,email,name,status,role,password_hash) VALUES(1,'example@example.com','test',100,100,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
+
+	// Insert a test admin user for checking logins
+	admin, err = apptest.CreateAdmin()
 	if err != nil {
-		t.Fatalf("error setting up:%s", err)
+		t.Fatalf("useractions: error creating admin %s", err)
 	}
+
 	// Insert user to delete
-	_, err = query.ExecSQL("INSERT INTO users (id,email,name,status,role,password_hash) VALUES(2,'example@example.com','test',100,0,'$2a$10$2IUzpI/yH0Xc.qs9Z5UUL.3f9bqi0ThvbKs6Q91UOlyCEGY8hdBw6');")
-	if err != nil {
-		t.Fatalf("error setting up:%s", err)
-	}
-	_, err = query.ExecSQL("ALTER SEQUENCE users_id_seq RESTART WITH 3;")
+	reader, err = apptest.CreateUser(nil)
 	if err != nil {
-		t.Fatalf("error setting up:%s", err)
+		t.Fatalf("useractions: error creating user %s", err)
 	}
 }
 
 // Test GET /users/create
 func TestShowCreateUser(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/users/create", nil)
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for admin user above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleCreateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/users/create", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Errorf("useractions: error handling HandleCreateShow %s", err)
+		t.Errorf("useractions: error handling HandleCreateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -106,20 +80,8 @@ func TestCreateUser(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("name", names[0])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/users/create", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
 
-	// Set up user session cookie for admin user
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
-
-	// Run the handler to update the user
-	err = HandleCreate(w, r)
+	w, err := apptest.Request(router, "POST", "/users/create", form, admin)
 	if err != nil {
 		t.Errorf("useractions: error handling HandleCreate %s", err)
 	}
@@ -129,13 +91,13 @@ func TestCreateUser(t *testing.T) {
 		t.Errorf("useractions: unexpected response code for HandleCreate expected:%d got:%d", http.StatusFound, w.Code)
 	}
 
-	// Check the user name is in now value names[1]
+	// Check the user name is in now value names[0]
 	allUsers, err := users.FindAll(users.Query().Order("id desc"))
 	if err != nil || len(allUsers) == 0 {
 		t.Fatalf("useractions: error finding created user %s", err)
 	}
 	newUser := allUsers[0]
-	if newUser.ID < 2 || newUser.Name != names[0] {
+	if newUser.ID < 3 || newUser.Name != names[0] {
 		t.Errorf("useractions: error with created user values: %v %s", newUser.ID, newUser.Name)
 	}
 }
@@ -143,22 +105,11 @@ func TestCreateUser(t *testing.T) {
 // Test GET /users
 func TestListUsers(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/users", nil)
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for admin user above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleIndex(w, r)
+	w, err := apptest.Request(router, "GET", "/users", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Errorf("useractions: error handling HandleIndex %s", err)
+		t.Errorf("useractions: error handling HandleIndex %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -172,27 +123,16 @@ func TestListUsers(t *testing.T) {
 // Test of GET /users/1
 func TestShowUser(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/users/1", nil)
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for admin user above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleShow(w, r)
+	w, err := apptest.Request(router, "GET", "/users/1", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Errorf("useractions: error handling HandleShow %s", err)
+		t.Errorf("useractions: error handling HandleShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
-	pattern := names[0]
-	if !strings.Contains(w.Body.String(), names[0]) {
+	pattern := admin.Name
+	if !strings.Contains(w.Body.String(), pattern) {
 		t.Errorf("useractions: unexpected response for HandleShow expected:%s got:%s", pattern, w.Body.String())
 	}
 }
@@ -200,28 +140,17 @@ func TestShowUser(t *testing.T) {
 // Test GET /users/123/update
 func TestShowUpdateUser(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/users/1/update", nil)
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for admin user above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleUpdateShow(w, r)
+	w, err := apptest.Request(router, "GET", "/users/1/update", nil, admin)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Errorf("useractions: error handling HandleCreateShow %s", err)
+		t.Errorf("useractions: error handling HandleUpdateShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
 	pattern := "resource-update-form"
 	if !strings.Contains(w.Body.String(), pattern) {
-		t.Errorf("useractions: unexpected response for HandleCreateShow expected:%s got:%s", pattern, w.Body.String())
+		t.Errorf("useractions: unexpected response for HandleUpdateShow expected:%s got:%s", pattern, w.Body.String())
 	}
 
 }
@@ -231,20 +160,8 @@ func TestUpdateUser(t *testing.T) {
 
 	form := url.Values{}
 	form.Add("name", names[1])
-	body := strings.NewReader(form.Encode())
-
-	r := httptest.NewRequest("POST", "/users/1/update", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for admin user
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
 
-	// Run the handler to update the user
-	err = HandleUpdate(w, r)
+	w, err := apptest.Request(router, "POST", "/users/1/update", form, admin)
 	if err != nil {
 		t.Errorf("useractions: error handling HandleUpdateUser %s", err)
 	}
@@ -265,26 +182,50 @@ func TestUpdateUser(t *testing.T) {
 
 }
 
-// Test of POST /users/123/destroy
-func TestDeleteUser(t *testing.T) {
+// Test POST /users/123/update is refused for users who may not update the user
+func TestUpdateUserAuthorisation(t *testing.T) {
+
+	form := url.Values{}
+	form.Add("name", "Mallory")
+	form.Add("role", fmt.Sprintf("%d", users.Admin))
 
-	body := strings.NewReader(``)
+	// Readers may not update admins, they may update themselves but not their role
+	w, err := apptest.Request(router, "POST", admin.UpdateURL(), form, reader)
+	if err != nil || w.Code == http.StatusFound {
+		t.Errorf("useractions: unexpected response for HandleUpdate of admin as reader %v %d", err, w.Code)
+	}
+	updated, err := users.Find(admin.ID)
+	if err != nil || updated.Name == "Mallory" {
+		t.Errorf("useractions: admin updated by reader %v %s", updated, err)
+	}
 
-	// Now test deleting the user created above as admin
-	r := httptest.NewRequest("POST", "/users/2/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
+	w, err = apptest.Request(router, "POST", reader.UpdateURL(), form, reader)
+	if err != nil || w.Code != http.StatusFound {
+		t.Errorf("useractions: unexpected response for HandleUpdate of self as reader %v %d", err, w.Code)
+	}
+	updated, err = users.Find(reader.ID)
+	if err != nil || updated.Name != "Mallory" || updated.Role != users.Reader {
+		t.Errorf("useractions: unexpected values after reader updated self %v %s", updated, err)
+	}
 
-	// Set up user session cookie for admin user
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
+	// Anon may not update users
+	w, err = apptest.Request(router, "POST", admin.UpdateURL(), form, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Errorf("useractions: unexpected response for HandleUpdate as anon %v %d", err, w.Code)
 	}
+}
 
-	// Run the handler
-	err = HandleDestroy(w, r)
+// Test of POST /users/123/destroy
+func TestDeleteUser(t *testing.T) {
 
-	// Test the error response is 302 StatusFound
+	// Test deleting the reader as anon
+	w, err := apptest.Request(router, "POST", reader.DestroyURL(), nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Errorf("useractions: unexpected response for HandleDestroy as anon, expected failure")
+	}
+
+	// Now test deleting the reader as admin
+	w, err = apptest.Request(router, "POST", reader.DestroyURL(), nil, admin)
 	if err != nil {
 		t.Errorf("useractions: error handling HandleDestroy %s", err)
 	}
@@ -293,50 +234,25 @@ func TestDeleteUser(t *testing.T) {
 	if w.Code != http.StatusFound {
 		t.Errorf("useractions: unexpected response code for HandleDestroy expected:%d got:%d", http.StatusFound, w.Code)
 	}
-	// Now test as anon
-	r = httptest.NewRequest("POST", "/users/2/destroy", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w = httptest.NewRecorder()
-
-	// Run the handler to test failure as anon
-	err = HandleDestroy(w, r)
-	if err == nil { // failure expected
-		t.Errorf("useractions: unexpected response for HandleDestroy as anon, expected failure")
-	}
 
 }
 
 // Test GET /users/login
 func TestShowLogin(t *testing.T) {
 
-	// Setup request and recorder
-	r := httptest.NewRequest("GET", "/users/login", nil)
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for admin user above
-	err := resource.AddUserSessionCookie(w, r, 1)
-	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
-	}
-
-	// Run the handler
-	err = HandleLoginShow(w, r)
+	w, err := apptest.Request(router, "GET", "/users/login", nil, admin)
 
 	// Check for redirect as they are considered logged in
 	if err != nil || w.Code != http.StatusFound {
-		t.Errorf("useractions: error handling HandleLoginShow %s %d", err, w.Code)
+		t.Errorf("useractions: error handling HandleLoginShow %v %d", err, w.Code)
 	}
 
-	// Setup new request and recorder with no session
-	r = httptest.NewRequest("GET", "/users/login", nil)
-	w = httptest.NewRecorder()
-
-	// Run the handler
-	err = HandleLoginShow(w, r)
+	// Now try again with no session
+	w, err = apptest.Request(router, "GET", "/users/login", nil, nil)
 
 	// Test the error response
 	if err != nil || w.Code != http.StatusOK {
-		t.Errorf("useractions: error handling HandleLoginShow %s", err)
+		t.Errorf("useractions: error handling HandleLoginShow %v %d", err, w.Code)
 	}
 
 	// Test the body for a known pattern
@@ -350,28 +266,29 @@ func TestShowLogin(t *testing.T) {
 // Test POST /users/login
 func TestLogin(t *testing.T) {
 
-	// These need to match entries in the test db for this to work
-	form := url.Values{}
-	form.Add("email", "example@example.com")
-	form.Add("password", "Hunter2")
-	body := strings.NewReader(form.Encode())
-
-	// Test posting to the login link,
-	// we expect success as setup inserts this user
-	r := httptest.NewRequest("POST", "/users/login", body)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
-
-	// Set up user session cookie for anon user (for the CSRF cookie token)
-	err := resource.AddUserSessionCookie(w, r, 0)
+	user, err := apptest.CreateUser(nil)
 	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
+		t.Fatalf("useractions: error creating user %s", err)
 	}
 
-	// Run the handler
-	err = HandleLogin(w, r)
-	if err != nil || w.Code != http.StatusFound {
-		t.Errorf("useractions: error on HandleLogin %s", err)
+	// Test posting to the login link with the wrong password
+	form := url.Values{}
+	form.Add("email", user.Email)
+	form.Add("password", "wrong")
+
+	w, err := apptest.Request(router, "POST", "/users/login", form, nil)
+	if err != nil || w.Header().Get("Location") != "/users/login?error=failed_password" {
+		t.Errorf("useractions: unexpected response for HandleLogin with bad password %v %s", err, w.Header().Get("Location"))
+	}
+
+	// We expect success with the password set for the user
+	form = url.Values{}
+	form.Add("email", user.Email)
+	form.Add("password", apptest.Password)
+
+	w, err = apptest.Request(router, "POST", "/users/login", form, nil)
+	if err != nil || w.Code != http.StatusFound || w.Header().Get("Location") != "/" {
+		t.Errorf("useractions: error on HandleLogin %v %d", err, w.Code)
 	}
 
 }
@@ -379,31 +296,269 @@ func TestLogin(t *testing.T) {
 // Test POST /users/logout
 func TestLogout(t *testing.T) {
 
-	r := httptest.NewRequest("POST", "/users/logout", nil)
-	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	w := httptest.NewRecorder()
+	w, err := apptest.Request(router, "POST", "/users/logout", nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Errorf("useractions: error on HandleLogout %v %d", err, w.Code)
+	}
+
+	// Check we've set an empty session on this outgoing writer
+	if !strings.Contains(string(w.Header().Get("Set-Cookie")), auth.SessionName+"=;") {
+		t.Errorf("useractions: error on HandleLogout - session not cleared")
+	}
+
+}
+
+// register posts the registration form and returns the user created.
+func register(t *testing.T, email string) *users.User {
+	form := url.Values{}
+	form.Add("name", "New Reader")
+	form.Add("email", email)
+	form.Add("password", apptest.Password)
+
+	w, err := apptest.Request(router, "POST", "/users/register", form, nil)
+	if err != nil || w.Code != http.StatusFound || w.Header().Get("Location") != "/users/verify/sent" {
+		t.Fatalf("useractions: error on HandleRegister %v %d", err, w.Code)
+	}
 
-	// Set up user session cookie for admin user
-	err := resource.AddUserSessionCookie(w, r, 1)
+	user, err := users.FindFirst("email=?", email)
 	if err != nil {
-		t.Errorf("useractions: error setting session %s", err)
+		t.Fatalf("useractions: registered user not found %s", err)
 	}
+	return user
+}
+
+// login posts the login form for user and returns the redirect location.
+func login(user *users.User) (string, error) {
+	form := url.Values{}
+	form.Add("email", user.Email)
+	form.Add("password", apptest.Password)
 
-	// Run the handler
-	err = HandleLogout(w, r)
+	w, err := apptest.Request(router, "POST", "/users/login", form, nil)
 	if err != nil {
-		t.Errorf("useractions: error on HandleLogout %s", err)
+		return "", err
 	}
+	return w.Header().Get("Location"), nil
+}
 
-	t.Logf("SESSION CLEAR: %s", w.Header().Get("Set-Cookie"))
+// Test POST /users/register and GET /users/verify
+func TestRegister(t *testing.T) {
 
-	t.Logf("SESSION AFTER: %s", w.Header())
+	// Test registration is closed by default
+	w, err := apptest.Request(router, "GET", "/users/register", nil, nil)
+	if err != nil || w.Code != http.StatusNotFound {
+		t.Errorf("useractions: unexpected response for HandleRegisterShow when closed %v %d", err, w.Code)
+	}
 
-	// Check we've set an empty session on this outgoing writer
-	if !strings.Contains(string(w.Header().Get("Set-Cookie")), auth.SessionName+"=;") {
-		t.Errorf("useractions: error on HandleLogout - session not cleared")
+	settings.Current.Register.Open = true
+	defer func() { settings.Current.Register.Open = false }()
+
+	// Test a short password is rejected
+	form := url.Values{}
+	form.Add("name", "New Reader")
+	form.Add("email", "new@example.com")
+	form.Add("password", "short")
+	w, err = apptest.Request(router, "POST", "/users/register", form, nil)
+	if err != nil || w.Code != http.StatusBadRequest {
+		t.Errorf("useractions: unexpected response for HandleRegister with short password %v %d", err, w.Code)
 	}
 
-	// TODO - to better test this we should have an integration test with a server
+	user := register(t, "new@example.com")
+	if !user.Unverified() || user.IsPublished() || user.Role != users.Reader {
+		t.Fatalf("useractions: unexpected registered user %v", user)
+	}
+
+	// Test the verification email includes the token
+	m := apptest.Mail.Last()
+	if m == nil || m.Recipients[0] != user.Email || !strings.Contains(m.Body, user.VerificationToken) {
+		t.Fatalf("useractions: unexpected verification email %v", m)
+	}
+
+	// Test the user may not log in until verified
+	location, err := login(user)
+	if err != nil || location != "/users/login?error=unverified" {
+		t.Errorf("useractions: unexpected response for HandleLogin when unverified %v %s", err, location)
+	}
+
+	w, err = apptest.Request(router, "GET", "/users/verify?token="+user.VerificationToken, nil, nil)
+	if err != nil || w.Code != http.StatusFound || w.Header().Get("Set-Cookie") == "" {
+		t.Fatalf("useractions: error on HandleVerify %v %d", err, w.Code)
+	}
+
+	user, err = users.Find(user.ID)
+	if err != nil || user.Unverified() || !user.IsPublished() {
+		t.Fatalf("useractions: user not verified %v %v", user, err)
+	}
+
+	location, err = login(user)
+	if err != nil || location != "/" {
+		t.Errorf("useractions: error on HandleLogin after verify %v %s", err, location)
+	}
+
+	// Test registering again with the email, in any case, looks the same
+	// but tells the owner instead of creating a user
+	form = url.Values{}
+	form.Add("name", "Someone Else")
+	form.Add("email", " NEW@Example.com ")
+	form.Add("password", apptest.Password)
+	w, err = apptest.Request(router, "POST", "/users/register", form, nil)
+	if err != nil || w.Code != http.StatusFound || w.Header().Get("Location") != "/users/verify/sent" {
+		t.Errorf("useractions: unexpected response for HandleRegister with existing email %v %d", err, w.Code)
+	}
+
+	m = apptest.Mail.Last()
+	if m == nil || m.Recipients[0] != user.Email || !strings.Contains(m.Subject, "already have an account") {
+		t.Errorf("useractions: unexpected existing account email %v", m)
+	}
+
+	count, err := users.Query().Where("lower(email)=?", "new@example.com").Count()
+	if err != nil || count != 1 {
+		t.Errorf("useractions: unexpected users with registered email %d %v", count, err)
+	}
+
+	// Test the owner is not sent another notice straight away
+	sent := len(apptest.Mail.Sent())
+	w, err = apptest.Request(router, "POST", "/users/register", form, nil)
+	if err != nil || w.Code != http.StatusFound || w.Header().Get("Location") != "/users/verify/sent" {
+		t.Errorf("useractions: unexpected response for HandleRegister with existing email again %v %d", err, w.Code)
+	}
+	if len(apptest.Mail.Sent()) != sent {
+		t.Errorf("useractions: existing account notice sent again %v", apptest.Mail.Last())
+	}
+}
+
+// Test registration with approval by an admin, and POST /users/1/approve
+func TestRegisterApproval(t *testing.T) {
+	settings.Current.Register.Open = true
+	settings.Current.Register.Approve = true
+	defer func() {
+		settings.Current.Register.Open = false
+		settings.Current.Register.Approve = false
+	}()
+
+	user := register(t, "closed@example.com")
+
+	w, err := apptest.Request(router, "GET", "/users/verify?token="+user.VerificationToken, nil, nil)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "approved") {
+		t.Fatalf("useractions: error on HandleVerify with approval %v %d", err, w.Code)
+	}
+
+	// Test admins are asked to approve the user
+	m := apptest.Mail.Last()
+	if m == nil || !strings.Contains(m.Subject, "New registration") {
+		t.Fatalf("useractions: unexpected approval email %v", m)
+	}
+
+	location, err := login(user)
+	if err != nil || location != "/users/login?error=pending" {
+		t.Errorf("useractions: unexpected response for HandleLogin when pending %v %s", err, location)
+	}
+
+	// Test only admins may approve users
+	path := fmt.Sprintf("/users/%d/approve", user.ID)
+	w, err = apptest.Request(router, "POST", path, nil, nil)
+	if err != nil || w.Code == http.StatusFound {
+		t.Errorf("useractions: unexpected response for HandleApprove as anon, expected failure")
+	}
 
+	w, err = apptest.Request(router, "POST", path, nil, admin)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("useractions: error on HandleApprove %v %d", err, w.Code)
+	}
+
+	user, err = users.Find(user.ID)
+	if err != nil || !user.IsPublished() {
+		t.Fatalf("useractions: user not approved %v %v", user, err)
+	}
+
+	m = apptest.Mail.Last()
+	if m == nil || m.Recipients[0] != user.Email {
+		t.Errorf("useractions: unexpected approved email %v", m)
+	}
+
+	location, err = login(user)
+	if err != nil || location != "/" {
+		t.Errorf("useractions: error on HandleLogin after approval %v %s", err, location)
+	}
+}
+
+// Test users may update their own profile and avatar, but not their role or other users
+func TestUpdateProfile(t *testing.T) {
+	settings.Current.Uploads.Path = t.TempDir()
+
+	user, err := apptest.CreateUser(map[string]string{"name": "Grace Hopper"})
+	if err != nil {
+		t.Fatalf("useractions: error creating user %s", err)
+	}
+	other, err := apptest.CreateUser(nil)
+	if err != nil {
+		t.Fatalf("useractions: error creating user %s", err)
+	}
+
+	// Users may not update other users
+	form := url.Values{"name": {"Changed"}}
+	w, err := apptest.Request(router, "POST", other.UpdateURL(), form, user)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("useractions: unexpected response updating another user %v %d", err, w.Code)
+	}
+	w, err = apptest.Request(router, "POST", admin.UpdateURL(), form, user)
+	if err != nil || w.Code == http.StatusFound {
+		t.Fatalf("useractions: unexpected response updating an admin %v %d", err, w.Code)
+	}
+
+	// Users may see their update form, without role and status
+	w, err = apptest.Request(router, "GET", user.UpdateURL(), nil, user)
+	if err != nil || w.Code != http.StatusOK || strings.Contains(w.Body.String(), `name="role"`) || !strings.Contains(w.Body.String(), `name="avatar"`) {
+		t.Fatalf("useractions: unexpected response for own update form %v %d", err, w.Code)
+	}
+
+	var b bytes.Buffer
+	err = jpeg.Encode(&b, image.NewRGBA(image.Rect(0, 0, 600, 400)), nil)
+	if err != nil {
+		t.Fatalf("useractions: error encoding jpeg %s", err)
+	}
+
+	form = url.Values{
+		"title": {"Rear Admiral"},
+		"role":  {fmt.Sprintf("%d", users.Admin)},
+	}
+	w, err = apptest.Upload(router, user.UpdateURL(), form, "avatar", "me.jpg", b.Bytes(), user)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("useractions: error updating own profile %v %d %s", err, w.Code, w.Body.String())
+	}
+
+	updated, err := users.Find(user.ID)
+	if err != nil || updated.Title != "Rear Admiral" || updated.Role != users.Reader || updated.PasswordHash != user.PasswordHash {
+		t.Fatalf("useractions: unexpected values after updating own profile %v %s", updated, err)
+	}
+
+	avatar := updated.Avatar()
+	if avatar == nil || avatar.Width != users.AvatarSize || avatar.Height != users.AvatarSize || avatar.IsPublished() {
+		t.Fatalf("useractions: unexpected avatar after updating own profile %v", avatar)
+	}
+	file := filepath.Join(settings.Current.Uploads.Path, "images", filepath.Base(avatar.Path))
+	if _, err = os.Stat(file); err != nil {
+		t.Fatalf("useractions: avatar file not found %s", err)
+	}
+
+	// Users may see their own profile with their avatar
+	w, err = apptest.Request(router, "GET", user.ShowURL(), nil, user)
+	if err != nil || w.Code != http.StatusOK || !strings.Contains(w.Body.String(), fmt.Sprintf(`<img class="avatar" src="%s"`, avatar.Path)) {
+		t.Fatalf("useractions: unexpected response for own profile %v %d", err, w.Code)
+	}
+
+	// Removing the avatar destroys the image and its file
+	w, err = apptest.Request(router, "POST", user.UpdateURL(), url.Values{"remove_avatar": {"1"}}, user)
+	if err != nil || w.Code != http.StatusFound {
+		t.Fatalf("useractions: error removing avatar %v %d", err, w.Code)
+	}
+	updated, err = users.Find(user.ID)
+	if err != nil || updated.ImageID != 0 {
+		t.Fatalf("useractions: avatar not removed %v %s", updated, err)
+	}
+	if _, err = images.Find(avatar.ID); err == nil {
+		t.Fatalf("useractions: avatar image not destroyed")
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("useractions: avatar file not removed %s", err)
+	}
 }
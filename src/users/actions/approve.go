@@ -0,0 +1,74 @@
+package useractions
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fragmenta/auth/can"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+// HandleApprove responds to POST /users/n/approve by publishing a user
+// awaiting approval after registering, and letting them know by email.
+func HandleApprove(w http.ResponseWriter, r *http.Request) error {
+
+	// Fetch the  params
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Find the user
+	user, err := users.Find(params.GetInt(users.KeyName))
+	if err != nil {
+		return server.NotFoundError(err)
+	}
+
+	// Check the authenticity token
+	err = session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Authorise update user
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(user, currentUser)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+
+	// Only users who have verified their email may be approved
+	if !user.AwaitingApproval() {
+		return server.Redirect(w, r, user.ShowURL())
+	}
+
+	err = user.Update(map[string]string{"status": fmt.Sprintf("%d", status.Published)})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "approved user", "user_email": user.Email, "user_id": user.ID, "admin_id": currentUser.ID})
+
+	// The user is approved, so log any failure to notify rather than reporting it
+	e := mail.New(user.Email)
+	e.Subject = "Your account has been approved"
+	e.Template = "users/views/approved_mail.html.got"
+	err = mail.Send(e, map[string]interface{}{
+		"name": user.Name,
+		"url":  fmt.Sprintf("%s/users/login", settings.Current.RootURL),
+	})
+	if err != nil {
+		log.Error(log.V{"msg": "unable to send approved notification", "user_id": user.ID, "error": err})
+	}
+
+	// Redirect to the user
+	return server.Redirect(w, r, user.ShowURL())
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -35,7 +36,12 @@ func HandleLoginShow(w http.ResponseWriter, r *http.Request) error {
 		view.AddKey("warning", "Sorry, we couldn't find a user with that email.")
 	case "failed_password":
 		view.AddKey("warning", "Sorry, the password was incorrect, please try again.")
+	case "unverified":
+		view.AddKey("warning", "Please verify your email using the link we sent you before logging in.")
+	case "pending":
+		view.AddKey("warning", "Your account is waiting for approval, we'll email you when you can log in.")
 	}
+	view.AddKey("registration", settings.Current.Register.Open)
 	return view.Render()
 }
 
@@ -61,11 +67,11 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) error {
 		return server.NotFoundError(err)
 	}
 
-	email := params.Get("email")
+	email := users.NormaliseEmail(params.Get("email"))
 	password := params.Get("password")
 
 	// Fetch the first user by email
-	user, err := users.FindFirst("email=?", email)
+	user, err := users.FindFirst("lower(email)=?", email)
 	if err != nil {
 		log.Info(log.V{"msg": "login failed", "email": email, "status": http.StatusNotFound})
 		return server.Redirect(w, r, "/users/login?error=failed_email")
@@ -78,6 +84,16 @@ func HandleLogin(w http.ResponseWriter, r *http.Request) error {
 		return server.Redirect(w, r, "/users/login?error=failed_password")
 	}
 
+	// Check registered users have verified their email and been approved
+	if user.Unverified() {
+		log.Info(log.V{"msg": "login failed", "email": email, "user_id": user.ID, "status": http.StatusForbidden})
+		return server.Redirect(w, r, "/users/login?error=unverified")
+	}
+	if user.AwaitingApproval() {
+		log.Info(log.V{"msg": "login failed", "email": email, "user_id": user.ID, "status": http.StatusForbidden})
+		return server.Redirect(w, r, "/users/login?error=pending")
+	}
+
 	// Now save the user details in a secure cookie, so that we remember the next request
 	session, err := auth.Session(w, r)
 	if err != nil {
@@ -9,12 +9,12 @@ import (
 	"github.com/fragmenta/mux"
 	"github.com/fragmenta/query"
 	"github.com/fragmenta/server"
-	"github.com/fragmenta/server/config"
 	"github.com/fragmenta/server/log"
 	"github.com/fragmenta/view"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -50,8 +50,8 @@ func HandlePasswordResetSend(w http.ResponseWriter, r *http.Request) error {
 
 	// Find the user by email (if not found let them know)
 	// Find the user by hex token in the db
-	email := params.Get("email")
-	user, err := users.FindFirst("email=?", email)
+	email := users.NormaliseEmail(params.Get("email"))
+	user, err := users.FindFirst("lower(email)=?", email)
 	if err != nil {
 		return server.Redirect(w, r, "/users/password/reset?message=invalid_email")
 	}
@@ -68,7 +68,7 @@ func HandlePasswordResetSend(w http.ResponseWriter, r *http.Request) error {
 	user.Update(userParams)
 
 	// Generate the url to use in our email
-	url := fmt.Sprintf("%s/users/password?token=%s", config.Get("root_url"), token)
+	url := fmt.Sprintf("%s/users/password?token=%s", settings.Current.RootURL, token)
 
 	// Send a password reset email out to this user
 	emailContext := map[string]interface{}{
@@ -0,0 +1,317 @@
+package useractions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/auth"
+	"github.com/fragmenta/mux"
+	"github.com/fragmenta/query"
+	"github.com/fragmenta/server"
+	"github.com/fragmenta/server/log"
+	"github.com/fragmenta/view"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/mail"
+	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+	"github.com/fragmenta/fragmenta-cms/src/users"
+)
+
+// errRegistrationClosed is returned for registration pages when registration is not enabled.
+var errRegistrationClosed = errors.New("users: registration is closed")
+
+// HandleRegisterShow responds to GET /users/register by showing the registration form.
+func HandleRegisterShow(w http.ResponseWriter, r *http.Request) error {
+
+	// Registration is only available if enabled in config
+	if !settings.Current.Register.Open {
+		return server.NotFoundError(errRegistrationClosed)
+	}
+
+	// Check they're not logged in already.
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Anon() {
+		return server.Redirect(w, r, "/?warn=already_logged_in")
+	}
+
+	// No authorisation required, just show the view
+	view := view.NewRenderer(w, r)
+	view.AddKey("currentUser", currentUser)
+	view.Template("users/views/register.html.got")
+	return view.Render()
+}
+
+// HandleRegister responds to POST /users/register by creating a reader
+// who must verify their email before they can log in.
+func HandleRegister(w http.ResponseWriter, r *http.Request) error {
+
+	// Registration is only available if enabled in config
+	if !settings.Current.Register.Open {
+		return server.NotFoundError(errRegistrationClosed)
+	}
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	// Check they're not logged in already.
+	currentUser := session.CurrentUser(w, r)
+	if !currentUser.Anon() {
+		return server.Redirect(w, r, "/?warn=already_logged_in")
+	}
+
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	name := strings.TrimSpace(params.Get("name"))
+	email := users.NormaliseEmail(params.Get("email"))
+	password := params.Get("password")
+
+	err = users.ValidateRegistration(name, email, password)
+	if err != nil {
+		return server.BadRequestError(err, "Please check your details", err.Error())
+	}
+
+	// If the email is in use, tell the owner rather than showing an error,
+	// so that the response does not reveal which emails have accounts
+	existing, err := users.FindFirst("lower(email)=?", email)
+	if err == nil {
+		log.Info(log.V{"msg": "register with existing email", "user_id": existing.ID})
+		if existing.AccountNoticeDue() {
+			err = sendExisting(existing)
+			if err != nil {
+				return server.InternalError(err)
+			}
+		}
+		return server.Redirect(w, r, "/users/verify/sent")
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return server.InternalError(err, "Problem hashing password")
+	}
+
+	// Readers are created as drafts until they verify their email (and are approved if required)
+	user := users.New()
+	userParams := map[string]string{
+		"name":               name,
+		"email":              email,
+		"password_hash":      hash,
+		"role":               fmt.Sprintf("%d", users.Reader),
+		"status":             fmt.Sprintf("%d", status.Draft),
+		"verification_token": auth.BytesToHex(auth.RandomToken(32)),
+		"verification_at":    query.TimeString(time.Now().UTC()),
+	}
+
+	id, err := user.Create(userParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	user, err = users.Find(id)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "registered", "user_email": user.Email, "user_id": user.ID})
+
+	err = sendVerification(user)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, "/users/verify/sent")
+}
+
+// HandleVerify responds to GET /users/verify?token=DEADFISH by verifying the email of the user,
+// and logging them in unless they must be approved first.
+func HandleVerify(w http.ResponseWriter, r *http.Request) error {
+
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Note we have no authenticity check, just a random token to check
+	token := params.Get("token")
+	if len(token) < 10 || len(token) > 64 {
+		return server.NotAuthorizedError(fmt.Errorf("Invalid verification token"), "Invalid Token")
+	}
+
+	// Find the user by hex token in the db
+	user, err := users.FindFirst("verification_token=?", token)
+	if err != nil {
+		return server.NotAuthorizedError(err, "Invalid Token", "Your verification link is invalid, it may have been used already.")
+	}
+
+	if user.VerificationExpired() {
+		return server.NotAuthorizedError(nil, "Token expired", "Your verification link has expired, please request another.")
+	}
+
+	// Remove the token, and publish the user unless approval is required
+	userParams := map[string]string{
+		"verification_token": "",
+		"verified_at":        query.TimeString(time.Now().UTC()),
+	}
+	if !settings.Current.Register.Approve {
+		userParams["status"] = fmt.Sprintf("%d", status.Published)
+	}
+	err = user.Update(userParams)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	log.Info(log.V{"msg": "verified email", "user_email": user.Email, "user_id": user.ID})
+
+	if settings.Current.Register.Approve {
+		err = notifyAdmins(user)
+		if err != nil {
+			log.Error(log.V{"msg": "unable to send approval notification", "user_id": user.ID, "error": err})
+		}
+
+		view := view.NewRenderer(w, r)
+		view.Template("users/views/register_approval.html.got")
+		return view.Render()
+	}
+
+	// Log in the user and store in the session
+	session, err := auth.Session(w, r)
+	if err != nil {
+		return server.NotAuthorizedError(err)
+	}
+	session.Set(auth.SessionUserKey, fmt.Sprintf("%d", user.ID))
+	session.Save(w)
+
+	return server.Redirect(w, r, "/")
+}
+
+// HandleVerifySentShow responds to GET /users/verify/sent
+func HandleVerifySentShow(w http.ResponseWriter, r *http.Request) error {
+	view := view.NewRenderer(w, r)
+	view.Template("users/views/register_sent.html.got")
+	return view.Render()
+}
+
+// HandleVerifyResendShow responds to GET /users/verify/resend
+// by showing a form to request another verification email.
+func HandleVerifyResendShow(w http.ResponseWriter, r *http.Request) error {
+	// No authorisation required, just show the view
+	view := view.NewRenderer(w, r)
+	view.Template("users/views/register_resend.html.got")
+	return view.Render()
+}
+
+// HandleVerifyResend responds to POST /users/verify/resend by sending
+// a new verification email, if the user has not yet verified their email.
+func HandleVerifyResend(w http.ResponseWriter, r *http.Request) error {
+
+	// Check the authenticity token
+	err := session.CheckAuthenticity(w, r)
+	if err != nil {
+		return err
+	}
+
+	params, err := mux.Params(r)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	// Show the same page whether or not the user is found, so that emails are not revealed
+	user, err := users.FindFirst("lower(email)=?", users.NormaliseEmail(params.Get("email")))
+	if err != nil || !user.Unverified() {
+		return server.Redirect(w, r, "/users/verify/sent")
+	}
+
+	// Generate a new token, so that the link sent is valid for the full lifetime
+	err = user.Update(map[string]string{
+		"verification_token": auth.BytesToHex(auth.RandomToken(32)),
+		"verification_at":    query.TimeString(time.Now().UTC()),
+	})
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	user, err = users.Find(user.ID)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	err = sendVerification(user)
+	if err != nil {
+		return server.InternalError(err)
+	}
+
+	return server.Redirect(w, r, "/users/verify/sent")
+}
+
+// sendVerification sends an email with a link to verify their email to the user.
+func sendVerification(user *users.User) error {
+	emailContext := map[string]interface{}{
+		"url":  fmt.Sprintf("%s/users/verify?token=%s", settings.Current.RootURL, user.VerificationToken),
+		"name": user.Name,
+	}
+
+	e := mail.New(user.Email)
+	e.Subject = "Please verify your email"
+	e.Template = "users/views/verify_mail.html.got"
+	return mail.Send(e, emailContext)
+}
+
+// sendExisting emails a user when someone tries to register with their email,
+// with links to log in or reset their password, and records when it was sent.
+func sendExisting(user *users.User) error {
+	err := user.Update(map[string]string{
+		"account_notice_at": query.TimeString(time.Now().UTC()),
+	})
+	if err != nil {
+		return err
+	}
+
+	emailContext := map[string]interface{}{
+		"login": fmt.Sprintf("%s/users/login", settings.Current.RootURL),
+		"reset": fmt.Sprintf("%s/users/password/reset", settings.Current.RootURL),
+		"name":  user.Name,
+	}
+
+	e := mail.New(user.Email)
+	e.Subject = "You already have an account"
+	e.Template = "users/views/register_exists_mail.html.got"
+	return mail.Send(e, emailContext)
+}
+
+// notifyAdmins emails the admins about a user awaiting their approval.
+func notifyAdmins(user *users.User) error {
+	admins, err := users.FindAll(users.Admins())
+	if err != nil {
+		return err
+	}
+
+	var addresses []string
+	for _, a := range admins {
+		if a.Email != "" {
+			addresses = append(addresses, a.Email)
+		}
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+
+	emailContext := map[string]interface{}{
+		"user": user,
+		"url":  fmt.Sprintf("%s%s", settings.Current.RootURL, user.ShowURL()),
+	}
+
+	e := mail.New(addresses[0])
+	e.Recipients = addresses
+	e.Subject = fmt.Sprintf("New registration from %s", user.Name)
+	e.Template = "users/views/approval_mail.html.got"
+	return mail.Send(e, emailContext)
+}
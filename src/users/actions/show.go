@@ -34,9 +34,8 @@ func HandleShow(w http.ResponseWriter, r *http.Request) error {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Render the template
+	// Render the template, which is not cached as admins see more details
 	view := view.NewRenderer(w, r)
-	view.CacheKey(user.CacheKey())
 	view.AddKey("user", user)
 	view.AddKey("currentUser", currentUser)
 	return view.Render()
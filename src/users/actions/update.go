@@ -1,6 +1,8 @@
 package useractions
 
 import (
+	"fmt"
+	"mime/multipart"
 	"net/http"
 
 	"github.com/fragmenta/auth"
@@ -10,6 +12,7 @@ import (
 	"github.com/fragmenta/view"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/session"
+	"github.com/fragmenta/fragmenta-cms/src/lib/settings"
 	"github.com/fragmenta/fragmenta-cms/src/users"
 )
 
@@ -64,26 +67,76 @@ func HandleUpdate(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	// Authorise update user
-	err = can.Update(user, user)
+	currentUser := session.CurrentUser(w, r)
+	err = can.Update(user, currentUser)
 	if err != nil {
 		return server.NotAuthorizedError(err)
 	}
 
-	// Convert the password param to a password_hash
-	hash, err := auth.HashPassword(params.Get("password"))
-	if err != nil {
-		return server.InternalError(err, "Problem hashing password")
+	// Convert the password param to a password_hash, if a new password was given
+	if params.Get("password") != "" {
+		hash, err := auth.HashPassword(params.Get("password"))
+		if err != nil {
+			return server.InternalError(err, "Problem hashing password")
+		}
+		params.SetString("password_hash", hash)
 	}
-	params.SetString("password_hash", hash)
 
 	// Validate the params, removing any we don't accept
 	userParams := user.ValidateParams(params.Map(), users.AllowedParams())
 
+	// Only admins may change the role and status of users
+	if !currentUser.Admin() {
+		delete(userParams, "role")
+		delete(userParams, "status")
+	}
+
+	// The avatar is set by uploading an image, or removed
+	delete(userParams, "image_id")
+	previousAvatar := user.Avatar()
+	files := params.Files["avatar"]
+	if len(files) > 0 {
+		id, err := uploadAvatar(user, files[0])
+		if err != nil {
+			return server.BadRequestError(err, "Invalid image", "Please upload a jpeg, png or gif image no larger than the maximum size.")
+		}
+		userParams["image_id"] = fmt.Sprintf("%d", id)
+	} else if params.Get("remove_avatar") != "" {
+		userParams["image_id"] = "0"
+	}
+
 	err = user.Update(userParams)
 	if err != nil {
 		return server.InternalError(err)
 	}
 
+	// Remove the avatar replaced, if any
+	if previousAvatar != nil && userParams["image_id"] != "" {
+		err = previousAvatar.Destroy()
+		if err != nil {
+			return server.InternalError(err)
+		}
+		err = previousAvatar.RemoveFiles()
+		if err != nil {
+			return server.InternalError(err)
+		}
+	}
+
 	// Redirect to user
 	return server.Redirect(w, r, user.ShowURL())
 }
+
+// uploadAvatar stores the avatar uploaded for user, and returns the id of its image.
+func uploadAvatar(user *users.User, fh *multipart.FileHeader) (int64, error) {
+	if fh.Size > settings.Current.Uploads.MaxSize {
+		return 0, fmt.Errorf("users: file %s is larger than %d bytes", fh.Filename, settings.Current.Uploads.MaxSize)
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return user.UploadAvatar(file)
+}
@@ -0,0 +1,104 @@
+package users
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/fragmenta/fragmenta-cms/src/images"
+	"github.com/fragmenta/fragmenta-cms/src/lib/sanitize"
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// AvatarSize is the size of the square avatars stored for users, twice the largest
+// size they are shown at so that they are sharp on high resolution screens.
+const AvatarSize = 256
+
+// AvatarColors is the number of colours used for initials avatars, set in users.css.
+const AvatarColors = 8
+
+// Avatar returns the image for the avatar of the user, or nil if they have none.
+func (u *User) Avatar() *images.Image {
+	if u.ImageID == 0 {
+		return nil
+	}
+	image, err := images.Find(u.ImageID)
+	if err != nil {
+		return nil
+	}
+	return image
+}
+
+// Initials returns up to two initials from the name of the user, or from their
+// email if they have no name, for avatars when the user has no image.
+func (u *User) Initials() string {
+	name := u.Name
+	if strings.TrimSpace(name) == "" {
+		name = strings.Split(u.Email, "@")[0]
+	}
+
+	var initials []rune
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		initials = append(initials, unicode.ToUpper([]rune(word)[0]))
+		if len(initials) == 2 {
+			break
+		}
+	}
+	if len(initials) == 0 {
+		return "?"
+	}
+	return string(initials)
+}
+
+// UploadAvatar stores the image uploaded in r cropped to a square, as an image
+// for the avatar of the user, and returns the id of the image.
+func (u *User) UploadAvatar(r io.Reader) (int64, error) {
+	params, err := images.UploadSquare(r, u.Name+" avatar", AvatarSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Avatars are drafts so that they are left out of published lists of images
+	params["name"] = strings.TrimSpace(u.Name + " avatar")
+	params["alt"] = u.Name
+	params["author_id"] = fmt.Sprintf("%d", u.ID)
+	params["status"] = fmt.Sprintf("%d", status.Draft)
+
+	return images.New().Create(params)
+}
+
+// RemoveAvatar destroys the image for the avatar of the user, and its files.
+// It does not update the user.
+func (u *User) RemoveAvatar() error {
+	image := u.Avatar()
+	if image == nil {
+		return nil
+	}
+	err := image.Destroy()
+	if err != nil {
+		return err
+	}
+	return image.RemoveFiles()
+}
+
+// AvatarTag returns the avatar of the user at size pixels square, their image
+// if they have one or their initials, for use in templates with the avatar helper:
+//
+//	{{ avatar .author 48 }}
+func AvatarTag(u *User, size int) template.HTML {
+	if u == nil {
+		return ""
+	}
+
+	if image := u.Avatar(); image != nil && image.Path != "" && sanitize.AllowURL(image.Path) {
+		return template.HTML(fmt.Sprintf(`<img class="avatar" src="%s" alt="%s" width="%d" height="%d" loading="lazy">`,
+			template.HTMLEscapeString(image.Path), template.HTMLEscapeString(u.Name), size, size))
+	}
+
+	return template.HTML(fmt.Sprintf(`<span class="avatar avatar-initials avatar-color-%d" style="width:%dpx;height:%dpx;line-height:%dpx;font-size:%dpx" title="%s">%s</span>`,
+		u.ID%AvatarColors, size, size, size, size*2/5, template.HTMLEscapeString(u.Name), template.HTMLEscapeString(u.Initials())))
+}
@@ -43,6 +43,10 @@ func NewWithColumns(cols map[string]interface{}) *User {
 	user.PasswordHash = resource.ValidateString(cols["password_hash"])
 	user.PasswordResetToken = resource.ValidateString(cols["password_reset_token"])
 	user.PasswordResetAt = resource.ValidateTime(cols["password_reset_at"])
+	user.VerificationToken = resource.ValidateString(cols["verification_token"])
+	user.VerificationAt = resource.ValidateTime(cols["verification_at"])
+	user.VerifiedAt = resource.ValidateTime(cols["verified_at"])
+	user.AccountNoticeAt = resource.ValidateTime(cols["account_notice_at"])
 
 	return user
 }
@@ -0,0 +1,69 @@
+package users
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/fragmenta/fragmenta-cms/src/lib/status"
+)
+
+// This file contains functions related to registration and email verification.
+
+// VerificationLifetime is the maximum time verification tokens are valid for.
+const VerificationLifetime = 48 * time.Hour
+
+// AccountNoticeInterval is the minimum time between notices sent to a user
+// when someone registers with their email, so that they can't be flooded with them.
+const AccountNoticeInterval = 24 * time.Hour
+
+// MinPasswordLength is the minimum length of passwords chosen on registration.
+const MinPasswordLength = 8
+
+// Unverified returns true if this user registered and has not yet verified their email.
+func (u *User) Unverified() bool {
+	return u.VerificationToken != ""
+}
+
+// VerificationExpired returns true if the verification token sent to this user has expired.
+func (u *User) VerificationExpired() bool {
+	return time.Since(u.VerificationAt) > VerificationLifetime
+}
+
+// AccountNoticeDue returns true if a notice may be sent to this user about
+// someone registering with their email.
+func (u *User) AccountNoticeDue() bool {
+	return time.Since(u.AccountNoticeAt) > AccountNoticeInterval
+}
+
+// AwaitingApproval returns true if this user has verified their email
+// after registering, but has not yet been approved by an admin.
+func (u *User) AwaitingApproval() bool {
+	return !u.VerifiedAt.IsZero() && u.Status == status.Draft
+}
+
+// NormaliseEmail returns email without surrounding space and in lower case,
+// as emails are stored on registration and compared when finding users.
+func NormaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateRegistration checks the name, email and password given on registration are acceptable.
+func ValidateRegistration(name, email, password string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("Please enter your name")
+	}
+
+	_, err := mail.ParseAddress(email)
+	if err != nil {
+		return errors.New("Please enter a valid email address")
+	}
+
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("Please choose a password of at least %d characters", MinPasswordLength)
+	}
+
+	return nil
+}
@@ -86,6 +86,12 @@ func (u *User) UserID() int64 {
 	return u.ID
 }
 
+// OwnedBy returns true if uid is the id of this user, so that users may
+// be authorised to show and update themselves.
+func (u *User) OwnedBy(uid int64) bool {
+	return u.ID != 0 && u.ID == uid
+}
+
 // MockAnon returns a mock user for testing with Role Anon.
 func MockAnon() *User {
 	return &User{Role: Anon, Email: "anon@example.com"}
@@ -24,6 +24,14 @@ type User struct {
 	PasswordResetToken string
 	PasswordResetAt    time.Time
 
+	// Email verification for users who register themselves
+	VerificationToken string
+	VerificationAt    time.Time
+	VerifiedAt        time.Time
+
+	// The last notice sent when someone registered with the email of this user
+	AccountNoticeAt time.Time
+
 	// User details
 	Email   string
 	Name    string
@@ -3,6 +3,7 @@ package users
 
 import (
 	"testing"
+	"time"
 
 	"github.com/fragmenta/fragmenta-cms/src/lib/resource"
 )
@@ -151,9 +152,73 @@ func TestRoles(t *testing.T) {
 
 }
 
+func TestRegistration(t *testing.T) {
+	u := New()
+	u.VerificationToken = "abc"
+	u.VerificationAt = time.Now().Add(-VerificationLifetime - time.Minute)
+	if !u.Unverified() || !u.VerificationExpired() || u.AwaitingApproval() {
+		t.Errorf("users: error testing unverified user")
+	}
+
+	u.VerificationToken = ""
+	u.VerifiedAt = time.Now()
+	if u.Unverified() || !u.AwaitingApproval() {
+		t.Errorf("users: error testing verified user")
+	}
+
+	err := ValidateRegistration("Alice", "alice@example.com", "password")
+	if err != nil {
+		t.Errorf("users: ValidateRegistration failed :%s", err)
+	}
+
+	invalid := [][]string{
+		{" ", "alice@example.com", "password"},
+		{"Alice", "alice", "password"},
+		{"Alice", "alice@example.com", "short"},
+	}
+	for _, v := range invalid {
+		err = ValidateRegistration(v[0], v[1], v[2])
+		if err == nil {
+			t.Errorf("users: ValidateRegistration accepted invalid details :%v", v)
+		}
+	}
+}
+
 // TestAllowedParams should always return some params
 func TestAllowedParams(t *testing.T) {
 	if len(AllowedParams()) == 0 {
 		t.Errorf("users: no allowed params")
 	}
 }
+
+// TestAvatar tests users without an image are shown with their initials.
+func TestAvatar(t *testing.T) {
+	initials := map[string]string{
+		"Alice":                  "A",
+		"alice liddell":          "AL",
+		"Jean-Luc Picard Junior": "JL",
+		"Émile Zola":             "ÉZ",
+		" ":                      "BO",
+		"!":                      "?",
+	}
+	for name, expected := range initials {
+		u := &User{Name: name, Email: "bob.owen@example.com"}
+		if name == "!" {
+			u.Email = ""
+		}
+		if got := u.Initials(); got != expected {
+			t.Errorf("users: unexpected initials for %s expected:%s got:%s", name, expected, got)
+		}
+	}
+
+	u := &User{Name: `Alice "Liddell"`}
+	u.ID = 11
+	expected := `<span class="avatar avatar-initials avatar-color-3" style="width:48px;height:48px;line-height:48px;font-size:19px" title="Alice &#34;Liddell&#34;">AL</span>`
+	if got := AvatarTag(u, 48); string(got) != expected {
+		t.Errorf("users: unexpected avatar expected:%s got:%s", expected, got)
+	}
+
+	if u.OwnedBy(12) || !u.OwnedBy(11) || (&User{}).OwnedBy(0) {
+		t.Errorf("users: unexpected owner for user")
+	}
+}